@@ -57,9 +57,16 @@ func (a *Agent) Run(ctx context.Context) error {
 
 	startTime := time.Now()
 
+	log.Printf("D! [agent] Starting discovery")
+	err = a.startDiscoveries()
+	if err != nil {
+		return err
+	}
+
 	log.Printf("D! [agent] Starting service inputs")
 	err = a.startServiceInputs(ctx, inputC)
 	if err != nil {
+		a.stopDiscoveries()
 		return err
 	}
 
@@ -80,6 +87,9 @@ func (a *Agent) Run(ctx context.Context) error {
 		log.Printf("D! [agent] Stopping service inputs")
 		a.stopServiceInputs()
 
+		log.Printf("D! [agent] Stopping discovery")
+		a.stopDiscoveries()
+
 		close(dst)
 		log.Printf("D! [agent] Input channel closed")
 	}(dst)
@@ -174,6 +184,20 @@ func (a *Agent) Test(ctx context.Context, waitDuration time.Duration) error {
 		}
 	}()
 
+	for _, d := range a.Config.Discoveries {
+		err := d.Init()
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("D! [agent] Starting discovery")
+	err := a.startDiscoveries()
+	if err != nil {
+		return err
+	}
+	defer a.stopDiscoveries()
+
 	hasServiceInputs := false
 	for _, input := range a.Config.Inputs {
 		if _, ok := input.Input.(telegraf.ServiceInput); ok {
@@ -637,6 +661,13 @@ func (a *Agent) initPlugins() error {
 				output.Config.Name, err)
 		}
 	}
+	for _, d := range a.Config.Discoveries {
+		err := d.Init()
+		if err != nil {
+			return fmt.Errorf("could not initialize discovery %s: %v",
+				d.Config.Name, err)
+		}
+	}
 	return nil
 }
 
@@ -715,6 +746,44 @@ func (a *Agent) stopServiceInputs() {
 	}
 }
 
+// startDiscoveries subscribes the inputs accepting targets to the
+// discoveries and starts all discoveries.
+func (a *Agent) startDiscoveries() error {
+	started := []*models.RunningDiscovery{}
+
+	for _, d := range a.Config.Discoveries {
+		for _, input := range a.Config.Inputs {
+			if d.Subscribe(input) {
+				log.Printf("D! [agent] Sending targets of %s to %s",
+					d.Name(), input.Name())
+			}
+		}
+
+		err := d.Start()
+		if err != nil {
+			log.Printf("E! [agent] Discovery %s failed to start: %v",
+				d.Config.Name, err)
+
+			for _, d := range started {
+				d.Stop()
+			}
+
+			return err
+		}
+
+		started = append(started, d)
+	}
+
+	return nil
+}
+
+// stopDiscoveries stops all discoveries.
+func (a *Agent) stopDiscoveries() {
+	for _, d := range a.Config.Discoveries {
+		d.Stop()
+	}
+}
+
 // Returns the rounding precision for metrics.
 func (a *Agent) Precision() time.Duration {
 	precision := a.Config.Agent.Precision.Duration
@@ -0,0 +1,47 @@
+package telegraf
+
+// Target is a scrape target found by a Discovery plugin.
+type Target struct {
+	// Address of the target, either host:port or a URL.
+	Address string
+
+	// Tags added to the metrics gathered from the target.
+	Tags map[string]string
+
+	// Source identifies the discovery that found the target.  It is set by
+	// the agent, the same address found by several discoveries makes
+	// separate targets.
+	Source string
+}
+
+// TargetHandler receives the targets found by a Discovery plugin.
+type TargetHandler interface {
+	// AddTarget adds or updates a target, targets are identified by their
+	// source and address.
+	AddTarget(target Target)
+
+	// RemoveTarget removes a previously added target.
+	RemoveTarget(target Target)
+}
+
+type Discovery interface {
+	// SampleConfig returns the default configuration of the Discovery
+	SampleConfig() string
+
+	// Description returns a one-sentence description on the Discovery
+	Description() string
+
+	// Start starts discovering targets.  The TargetHandler may be retained
+	// and used until Stop returns.
+	Start(TargetHandler) error
+
+	// Stop stops discovering targets.
+	Stop()
+}
+
+// TargetInput is an Input gathering metrics from the targets found by
+// Discovery plugins in addition to its configured targets.
+type TargetInput interface {
+	Input
+	TargetHandler
+}
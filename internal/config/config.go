@@ -22,6 +22,7 @@ import (
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/models"
 	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/discovery"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
@@ -64,6 +65,7 @@ type Config struct {
 	Inputs      []*models.RunningInput
 	Outputs     []*models.RunningOutput
 	Aggregators []*models.RunningAggregator
+	Discoveries []*models.RunningDiscovery
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
 }
@@ -192,6 +194,15 @@ func (c *Config) ProcessorNames() []string {
 	return name
 }
 
+// DiscoveryNames returns a list of strings of the configured discoveries.
+func (c *Config) DiscoveryNames() []string {
+	var name []string
+	for _, d := range c.Discoveries {
+		name = append(name, d.Config.Name)
+	}
+	return name
+}
+
 // Outputs returns a list of strings of the configured outputs.
 func (c *Config) OutputNames() []string {
 	var name []string
@@ -328,6 +339,13 @@ var aggregatorHeader = `
 
 `
 
+var discoveryHeader = `
+###############################################################################
+#                            DISCOVERY PLUGINS                                #
+###############################################################################
+
+`
+
 var inputHeader = `
 ###############################################################################
 #                            INPUT PLUGINS                                    #
@@ -416,6 +434,20 @@ func PrintSampleConfig(
 		}
 	}
 
+	// print discovery plugins, only when requested with the section filter
+	if sliceContains("discovery", sectionFilters) {
+		fmt.Printf(discoveryHeader)
+		var dnames []string
+		for dname := range discovery.Discoveries {
+			dnames = append(dnames, dname)
+		}
+		sort.Strings(dnames)
+		for _, dname := range dnames {
+			creator := discovery.Discoveries[dname]
+			printConfig(dname, creator(), "discovery", true)
+		}
+	}
+
 	// print input plugins
 	if sliceContains("inputs", sectionFilters) {
 		if len(inputFilters) != 0 {
@@ -786,6 +818,20 @@ func (c *Config) LoadConfig(path string) error {
 						pluginName, path)
 				}
 			}
+		case "discovery":
+			for pluginName, pluginVal := range subTable.Fields {
+				switch pluginSubTable := pluginVal.(type) {
+				case []*ast.Table:
+					for _, t := range pluginSubTable {
+						if err = c.addDiscovery(pluginName, t); err != nil {
+							return fmt.Errorf("Error parsing %s, %s", path, err)
+						}
+					}
+				default:
+					return fmt.Errorf("Unsupported config format: %s, file %s",
+						pluginName, path)
+				}
+			}
 		// Assume it's an input input for legacy config file support if no other
 		// identifiers are present
 		default:
@@ -903,6 +949,26 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 	return nil
 }
 
+func (c *Config) addDiscovery(name string, table *ast.Table) error {
+	creator, ok := discovery.Discoveries[name]
+	if !ok {
+		return fmt.Errorf("Undefined but requested discovery: %s", name)
+	}
+	d := creator()
+
+	conf, err := buildDiscovery(name, table)
+	if err != nil {
+		return err
+	}
+
+	if err := toml.UnmarshalTable(table, d); err != nil {
+		return err
+	}
+
+	c.Discoveries = append(c.Discoveries, models.NewRunningDiscovery(d, conf))
+	return nil
+}
+
 func (c *Config) addProcessor(name string, table *ast.Table) error {
 	creator, ok := processors.Processors[name]
 	if !ok {
@@ -1277,6 +1343,37 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 	return f, nil
 }
 
+// buildDiscovery parses Discovery specific items from the ast.Table and
+// returns a models.DiscoveryConfig to be inserted into
+// models.RunningDiscovery
+func buildDiscovery(name string, tbl *ast.Table) (*models.DiscoveryConfig, error) {
+	conf := &models.DiscoveryConfig{Name: name}
+	if node, ok := tbl.Fields["inputs"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						conf.Inputs = append(conf.Inputs, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	conf.Tags = make(map[string]string)
+	if node, ok := tbl.Fields["tags"]; ok {
+		if subtbl, ok := node.(*ast.Table); ok {
+			if err := toml.UnmarshalTable(subtbl, conf.Tags); err != nil {
+				return nil, fmt.Errorf("Could not parse tags for discovery %s", name)
+			}
+		}
+	}
+
+	delete(tbl.Fields, "inputs")
+	delete(tbl.Fields, "tags")
+	return conf, nil
+}
+
 // buildInput parses input specific items from the ast.Table,
 // builds the filter and returns a
 // models.InputConfig to be inserted into models.RunningInput
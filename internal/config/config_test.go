@@ -7,6 +7,7 @@ import (
 
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/plugins/discovery/dns"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
 	"github.com/influxdata/telegraf/plugins/inputs/http_listener_v2"
@@ -251,3 +252,22 @@ func TestConfig_BadOrdering(t *testing.T) {
 	require.Error(t, err, "bad ordering")
 	assert.Equal(t, "Error parsing ./testdata/non_slice_slice.toml, line 4: cannot unmarshal TOML array into string (need slice)", err.Error())
 }
+
+func TestConfig_LoadDiscovery(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/discovery.toml")
+	require.NoError(t, err)
+	require.Len(t, c.Discoveries, 1)
+
+	require.Equal(t, &models.DiscoveryConfig{
+		Name:   "dns",
+		Inputs: []string{"prometheus"},
+		Tags:   map[string]string{"source": "dns"},
+	}, c.Discoveries[0].Config)
+
+	d, ok := c.Discoveries[0].Discovery.(*dns.DNS)
+	require.True(t, ok)
+	require.Equal(t, []string{"_metrics._tcp.example.com"}, d.Names)
+	require.Equal(t, "SRV", d.RecordType)
+	require.Equal(t, time.Minute, d.RefreshInterval.Duration)
+}
@@ -5,6 +5,9 @@ import (
 	"compress/gzip"
 	"errors"
 	"io"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 )
 
 // NewContentEncoder returns a ContentEncoder for the encoding type.
@@ -12,7 +15,10 @@ func NewContentEncoder(encoding string) (ContentEncoder, error) {
 	switch encoding {
 	case "gzip":
 		return NewGzipEncoder()
-
+	case "zstd":
+		return NewZstdEncoder()
+	case "snappy":
+		return NewSnappyEncoder(), nil
 	case "identity", "":
 		return NewIdentityEncoder(), nil
 	default:
@@ -25,6 +31,10 @@ func NewContentDecoder(encoding string) (ContentDecoder, error) {
 	switch encoding {
 	case "gzip":
 		return NewGzipDecoder()
+	case "zstd":
+		return NewZstdDecoder()
+	case "snappy":
+		return NewSnappyDecoder(), nil
 	case "identity", "":
 		return NewIdentityDecoder(), nil
 	default:
@@ -66,6 +76,39 @@ func (e *GzipEncoder) Encode(data []byte) ([]byte, error) {
 	return e.buf.Bytes(), nil
 }
 
+// ZstdEncoder compresses the buffer using zstd at the default level.
+type ZstdEncoder struct {
+	encoder *zstd.Encoder
+	buf     []byte
+}
+
+func NewZstdEncoder() (*ZstdEncoder, error) {
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ZstdEncoder{encoder: encoder}, nil
+}
+
+func (e *ZstdEncoder) Encode(data []byte) ([]byte, error) {
+	e.buf = e.encoder.EncodeAll(data, e.buf[:0])
+	return e.buf, nil
+}
+
+// SnappyEncoder compresses the buffer using the snappy block format.
+type SnappyEncoder struct {
+	buf []byte
+}
+
+func NewSnappyEncoder() *SnappyEncoder {
+	return &SnappyEncoder{}
+}
+
+func (e *SnappyEncoder) Encode(data []byte) ([]byte, error) {
+	e.buf = snappy.Encode(e.buf[:cap(e.buf)], data)
+	return e.buf, nil
+}
+
 // IdentityEncoder is a null encoder that applies no transformation.
 type IdentityEncoder struct{}
 
@@ -110,6 +153,41 @@ func (d *GzipDecoder) Decode(data []byte) ([]byte, error) {
 	return d.buf.Bytes(), nil
 }
 
+// ZstdDecoder decompresses buffers with zstd compression.
+type ZstdDecoder struct {
+	decoder *zstd.Decoder
+	buf     []byte
+}
+
+func NewZstdDecoder() (*ZstdDecoder, error) {
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ZstdDecoder{decoder: decoder}, nil
+}
+
+func (d *ZstdDecoder) Decode(data []byte) ([]byte, error) {
+	var err error
+	d.buf, err = d.decoder.DecodeAll(data, d.buf[:0])
+	return d.buf, err
+}
+
+// SnappyDecoder decompresses buffers in the snappy block format.
+type SnappyDecoder struct {
+	buf []byte
+}
+
+func NewSnappyDecoder() *SnappyDecoder {
+	return &SnappyDecoder{}
+}
+
+func (d *SnappyDecoder) Decode(data []byte) ([]byte, error) {
+	var err error
+	d.buf, err = snappy.Decode(d.buf[:cap(d.buf)], data)
+	return d.buf, err
+}
+
 // IdentityDecoder is a null decoder that returns the input.
 type IdentityDecoder struct{}
 
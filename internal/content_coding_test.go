@@ -56,3 +56,31 @@ func TestIdentityEncodeDecode(t *testing.T) {
 
 	require.Equal(t, "howdy", string(actual))
 }
+
+func TestZstdEncodeDecode(t *testing.T) {
+	enc, err := NewContentEncoder("zstd")
+	require.NoError(t, err)
+	dec, err := NewContentDecoder("zstd")
+	require.NoError(t, err)
+
+	payload, err := enc.Encode([]byte("howdy"))
+	require.NoError(t, err)
+
+	actual, err := dec.Decode(payload)
+	require.NoError(t, err)
+
+	require.Equal(t, "howdy", string(actual))
+}
+
+func TestSnappyEncodeDecode(t *testing.T) {
+	enc := NewSnappyEncoder()
+	dec := NewSnappyDecoder()
+
+	payload, err := enc.Encode([]byte("howdy"))
+	require.NoError(t, err)
+
+	actual, err := dec.Decode(payload)
+	require.NoError(t, err)
+
+	require.Equal(t, "howdy", string(actual))
+}
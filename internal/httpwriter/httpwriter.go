@@ -0,0 +1,280 @@
+// Package httpwriter implements the sending of serialized metrics for HTTP
+// based outputs.  Batches are split by request size, the request body is
+// compressed with the configured content encoding, and the throttling and
+// size limit responses of servers are handled.  Request latencies and status
+// codes are reported as internal statistics.
+package httpwriter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const (
+	// DefaultMaxRetryWait is the default maximum time to wait after a
+	// throttling response.
+	DefaultMaxRetryWait = 10 * time.Second
+
+	// maxErrorBody is the maximum size of a response body kept in errors.
+	maxErrorBody = 64 * 1024
+)
+
+// SerializeFunc serializes a batch of metrics into a request body.
+type SerializeFunc func(metrics []telegraf.Metric) ([]byte, error)
+
+// RequestFunc creates the request for a body.  The Content-Encoding header
+// is set by the writer.
+type RequestFunc func(body io.Reader) (*http.Request, error)
+
+// ErrorFunc handles an error response.  Returning nil drops the metrics of
+// the request and continues with the remaining requests of the batch.
+type ErrorFunc func(err *ResponseError) error
+
+// ResponseError is the error of a request answered with a non-2xx status.
+type ResponseError struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *ResponseError) Error() string {
+	if len(e.Body) > 0 {
+		return fmt.Sprintf("received status %s: %s", e.Status, bytes.TrimSpace(e.Body))
+	}
+	return fmt.Sprintf("received status %s", e.Status)
+}
+
+// Config is the configuration of a Writer.
+type Config struct {
+	// ContentEncoding is the encoding of the request bodies, one of "gzip",
+	// "zstd", "snappy" or "identity".
+	ContentEncoding string
+
+	// MaxRequestBytes limits the size of the uncompressed request bodies;
+	// batches are split until they fit.  Zero disables the limit.
+	MaxRequestBytes int
+
+	// MaxRetryWait caps the time to wait after a throttling response, with
+	// DefaultMaxRetryWait used if zero.
+	MaxRetryWait time.Duration
+
+	// OnError handles error responses.  If nil, error responses fail the
+	// write.
+	OnError ErrorFunc
+
+	// Tags of the internal statistics, identifying the output.
+	Tags map[string]string
+}
+
+// Writer sends batches of metrics over HTTP.  It is not safe for concurrent
+// use.
+type Writer struct {
+	client   *http.Client
+	encoding string
+	encoder  internal.ContentEncoder
+	maxBytes int
+	maxWait  time.Duration
+	onError  ErrorFunc
+	tags     map[string]string
+
+	requestTime selfstat.Stat
+	retryAt     time.Time
+}
+
+// New returns a Writer sending requests with the client.
+func New(client *http.Client, config Config) (*Writer, error) {
+	encoder, err := internal.NewContentEncoder(config.ContentEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := config.ContentEncoding
+	if encoding == "" {
+		encoding = "identity"
+	}
+
+	maxWait := config.MaxRetryWait
+	if maxWait == 0 {
+		maxWait = DefaultMaxRetryWait
+	}
+
+	return &Writer{
+		client:      client,
+		encoding:    encoding,
+		encoder:     encoder,
+		maxBytes:    config.MaxRequestBytes,
+		maxWait:     maxWait,
+		onError:     config.OnError,
+		tags:        config.Tags,
+		requestTime: selfstat.RegisterTiming("http_output", "request_time_ns", config.Tags),
+	}, nil
+}
+
+// Write serializes the metrics and sends them in one or more requests.  An
+// error is returned if any request fails, in which case the whole batch is
+// retried by the caller.
+func (w *Writer) Write(metrics []telegraf.Metric, serialize SerializeFunc, newRequest RequestFunc) error {
+	if now := time.Now(); now.Before(w.retryAt) {
+		return fmt.Errorf("throttled by server, retrying in %s", w.retryAt.Sub(now).Round(time.Millisecond))
+	}
+
+	bodies, err := split(metrics, serialize, w.maxBytes)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range bodies {
+		if err := w.send(b, serialize, newRequest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// body is a serialized request body and its metrics.
+type body struct {
+	metrics []telegraf.Metric
+	data    []byte
+}
+
+// split serializes the metrics into bodies of at most maxBytes, halving the
+// batch until each part fits.  A single metric exceeding the limit is sent
+// in a body of its own.
+func split(metrics []telegraf.Metric, serialize SerializeFunc, maxBytes int) ([]body, error) {
+	data, err := serialize(metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxBytes <= 0 || len(data) <= maxBytes || len(metrics) < 2 {
+		if len(data) == 0 {
+			return nil, nil
+		}
+		return []body{{metrics: metrics, data: data}}, nil
+	}
+
+	half := len(metrics) / 2
+	first, err := split(metrics[:half], serialize, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	second, err := split(metrics[half:], serialize, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	return append(first, second...), nil
+}
+
+// send sends a body.  Bodies rejected as too large are split in halves and
+// sent again.
+func (w *Writer) send(b body, serialize SerializeFunc, newRequest RequestFunc) error {
+	err := w.do(b.data, newRequest)
+	rerr, ok := err.(*ResponseError)
+	if !ok {
+		return err
+	}
+
+	switch rerr.StatusCode {
+	case http.StatusRequestEntityTooLarge:
+		if len(b.metrics) < 2 {
+			break
+		}
+		half := len(b.metrics) / 2
+		for _, part := range [][]telegraf.Metric{b.metrics[:half], b.metrics[half:]} {
+			data, err := serialize(part)
+			if err != nil {
+				return err
+			}
+			if err := w.send(body{metrics: part, data: data}, serialize, newRequest); err != nil {
+				return err
+			}
+		}
+		return nil
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		wait := retryAfter(rerr.Header.Get("Retry-After"), time.Now())
+		if wait > w.maxWait {
+			wait = w.maxWait
+		}
+		w.retryAt = time.Now().Add(wait)
+		return fmt.Errorf("%v, waiting %s before sending again", rerr, wait)
+	}
+
+	if w.onError != nil {
+		return w.onError(rerr)
+	}
+	return rerr
+}
+
+// do encodes the data and sends the request.
+func (w *Writer) do(data []byte, newRequest RequestFunc) error {
+	encoded, err := w.encoder.Encode(data)
+	if err != nil {
+		return err
+	}
+
+	req, err := newRequest(bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	if w.encoding != "identity" {
+		req.Header.Set("Content-Encoding", w.encoding)
+	}
+
+	start := time.Now()
+	resp, err := w.client.Do(req)
+	w.requestTime.Incr(time.Since(start).Nanoseconds())
+	if err != nil {
+		w.countStatus("error")
+		return err
+	}
+	defer resp.Body.Close()
+	w.countStatus(strconv.Itoa(resp.StatusCode))
+
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return &ResponseError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       respBody,
+	}
+}
+
+// countStatus increments the request count of the status code.
+func (w *Writer) countStatus(code string) {
+	tags := make(map[string]string, len(w.tags)+1)
+	for k, v := range w.tags {
+		tags[k] = v
+	}
+	tags["status_code"] = code
+	selfstat.Register("http_output", "requests", tags).Incr(1)
+}
+
+// retryAfter parses the Retry-After header, given in seconds or as a date.
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
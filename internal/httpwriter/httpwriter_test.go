@@ -0,0 +1,249 @@
+package httpwriter
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// server records the received bodies and replies with the next status.
+type server struct {
+	sync.Mutex
+
+	statuses []int
+	header   http.Header
+	bodies   []string
+	encoding []string
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	data, _ := ioutil.ReadAll(r.Body)
+	enc := r.Header.Get("Content-Encoding")
+	dec, err := internal.NewContentDecoder(enc)
+	if err == nil {
+		data, err = dec.Decode(data)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.encoding = append(s.encoding, enc)
+
+	status := http.StatusNoContent
+	if len(s.statuses) > 0 {
+		status, s.statuses = s.statuses[0], s.statuses[1:]
+	}
+	for k, v := range s.header {
+		w.Header()[k] = v
+	}
+	if status < 300 {
+		s.bodies = append(s.bodies, string(data))
+	}
+	w.WriteHeader(status)
+	if status >= 300 {
+		w.Write([]byte("request failed\n"))
+	}
+}
+
+func testMetrics(n int) []telegraf.Metric {
+	var metrics []telegraf.Metric
+	for i := 0; i < n; i++ {
+		metrics = append(metrics, testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"value": int64(i)},
+			time.Unix(0, 0),
+		))
+	}
+	return metrics
+}
+
+func serialize(metrics []telegraf.Metric) ([]byte, error) {
+	return influx.NewSerializer().SerializeBatch(metrics)
+}
+
+func newRequest(url string) RequestFunc {
+	return func(body io.Reader) (*http.Request, error) {
+		return http.NewRequest("POST", url, body)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	encodings := []string{"", "identity", "gzip", "snappy"}
+	for _, encoding := range encodings {
+		t.Run(encoding, func(t *testing.T) {
+			s := &server{}
+			ts := httptest.NewServer(s)
+			defer ts.Close()
+
+			w, err := New(ts.Client(), Config{ContentEncoding: encoding})
+			require.NoError(t, err)
+			require.NoError(t, w.Write(testMetrics(2), serialize, newRequest(ts.URL)))
+			require.Equal(t, []string{"cpu value=0i 0\ncpu value=1i 0\n"}, s.bodies)
+
+			expected := encoding
+			if encoding == "identity" {
+				expected = ""
+			}
+			require.Equal(t, []string{expected}, s.encoding)
+		})
+	}
+}
+
+func TestMaxRequestBytes(t *testing.T) {
+	s := &server{}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// Each metric is 15 bytes.
+	w, err := New(ts.Client(), Config{MaxRequestBytes: 30})
+	require.NoError(t, err)
+	require.NoError(t, w.Write(testMetrics(5), serialize, newRequest(ts.URL)))
+	require.Equal(t, []string{
+		"cpu value=0i 0\ncpu value=1i 0\n",
+		"cpu value=2i 0\n",
+		"cpu value=3i 0\ncpu value=4i 0\n",
+	}, s.bodies)
+
+	// A metric larger than the limit is sent on its own.
+	s.bodies = nil
+	w.maxBytes = 1
+	require.NoError(t, w.Write(testMetrics(2), serialize, newRequest(ts.URL)))
+	require.Len(t, s.bodies, 2)
+}
+
+func TestRequestEntityTooLarge(t *testing.T) {
+	s := &server{statuses: []int{http.StatusRequestEntityTooLarge, http.StatusRequestEntityTooLarge}}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	w, err := New(ts.Client(), Config{})
+	require.NoError(t, err)
+	require.NoError(t, w.Write(testMetrics(4), serialize, newRequest(ts.URL)))
+	require.Equal(t, []string{
+		"cpu value=0i 0\n",
+		"cpu value=1i 0\n",
+		"cpu value=2i 0\ncpu value=3i 0\n",
+	}, s.bodies)
+
+	// A single metric rejected as too large is an error response.
+	s.statuses = []int{http.StatusRequestEntityTooLarge}
+	err = w.Write(testMetrics(1), serialize, newRequest(ts.URL))
+	require.Error(t, err)
+	require.Equal(t, http.StatusRequestEntityTooLarge, err.(*ResponseError).StatusCode)
+}
+
+func TestRetryAfter(t *testing.T) {
+	s := &server{
+		statuses: []int{http.StatusTooManyRequests},
+		header:   http.Header{"Retry-After": []string{"1"}},
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	w, err := New(ts.Client(), Config{MaxRetryWait: 50 * time.Millisecond})
+	require.NoError(t, err)
+
+	err = w.Write(testMetrics(1), serialize, newRequest(ts.URL))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "waiting 50ms")
+
+	err = w.Write(testMetrics(1), serialize, newRequest(ts.URL))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "throttled")
+
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, w.Write(testMetrics(1), serialize, newRequest(ts.URL)))
+	require.Len(t, s.bodies, 1)
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	now := time.Date(2019, 9, 17, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Duration(0), retryAfter("", now))
+	require.Equal(t, 5*time.Second, retryAfter("5", now))
+	require.Equal(t, time.Duration(0), retryAfter("-5", now))
+	require.Equal(t, 30*time.Second, retryAfter("Tue, 17 Sep 2019 12:00:30 GMT", now))
+	require.Equal(t, time.Duration(0), retryAfter("Tue, 17 Sep 2019 11:00:00 GMT", now))
+	require.Equal(t, time.Duration(0), retryAfter("soon", now))
+}
+
+func TestOnError(t *testing.T) {
+	s := &server{statuses: []int{http.StatusBadRequest}}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var errs []*ResponseError
+	w, err := New(ts.Client(), Config{
+		MaxRequestBytes: 15,
+		OnError: func(err *ResponseError) error {
+			errs = append(errs, err)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	// The rejected request is dropped and the batch continues.
+	require.NoError(t, w.Write(testMetrics(2), serialize, newRequest(ts.URL)))
+	require.Equal(t, []string{"cpu value=1i 0\n"}, s.bodies)
+	require.Len(t, errs, 1)
+	require.Equal(t, "received status 400 Bad Request: request failed", errs[0].Error())
+}
+
+func TestStats(t *testing.T) {
+	s := &server{statuses: []int{http.StatusInternalServerError}}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	tags := map[string]string{"output": "test", "url": ts.URL}
+	w, err := New(ts.Client(), Config{Tags: tags})
+	require.NoError(t, err)
+	require.Error(t, w.Write(testMetrics(1), serialize, newRequest(ts.URL)))
+	require.NoError(t, w.Write(testMetrics(1), serialize, newRequest(ts.URL)))
+
+	counts := make(map[string]interface{})
+	var timed bool
+	for _, m := range selfstat.Metrics() {
+		if m.Name() != "internal_http_output" {
+			continue
+		}
+		if url, _ := m.GetTag("url"); url != ts.URL {
+			continue
+		}
+		if code, ok := m.GetTag("status_code"); ok {
+			counts[code], _ = m.GetField("requests")
+		} else {
+			_, timed = m.GetField("request_time_ns")
+		}
+	}
+	require.Equal(t, map[string]interface{}{"500": int64(1), "204": int64(1)}, counts)
+	require.True(t, timed)
+}
+
+func TestInvalidEncoding(t *testing.T) {
+	_, err := New(http.DefaultClient, Config{ContentEncoding: "br"})
+	require.Error(t, err)
+}
+
+func TestSplitSerializeError(t *testing.T) {
+	_, err := split(testMetrics(2), func([]telegraf.Metric) ([]byte, error) {
+		return nil, io.ErrUnexpectedEOF
+	}, 0)
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+
+	bodies, err := split(nil, serialize, 0)
+	require.NoError(t, err)
+	require.Empty(t, bodies)
+}
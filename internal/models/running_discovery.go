@@ -0,0 +1,134 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/influxdata/telegraf"
+)
+
+// discoveries counts the created discoveries to give each a unique source.
+var discoveries uint64
+
+type RunningDiscovery struct {
+	Discovery telegraf.Discovery
+	Config    *DiscoveryConfig
+
+	sync.Mutex
+	handlers []telegraf.TargetHandler
+	source   string
+}
+
+// DiscoveryConfig is the common config for all discovery plugins.
+type DiscoveryConfig struct {
+	Name string
+
+	// Inputs are the plugin names of the inputs receiving the targets, all
+	// inputs accepting targets if empty.
+	Inputs []string
+
+	// Tags added to all targets.
+	Tags map[string]string
+}
+
+func NewRunningDiscovery(
+	discovery telegraf.Discovery,
+	config *DiscoveryConfig,
+) *RunningDiscovery {
+	return &RunningDiscovery{
+		Discovery: discovery,
+		Config:    config,
+		source:    fmt.Sprintf("%s#%d", config.Name, atomic.AddUint64(&discoveries, 1)),
+	}
+}
+
+func (r *RunningDiscovery) Name() string {
+	return "discovery." + r.Config.Name
+}
+
+func (r *RunningDiscovery) Init() error {
+	if p, ok := r.Discovery.(telegraf.Initializer); ok {
+		err := p.Init()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe adds the input to the receivers of the targets if it accepts
+// targets and is selected by the inputs option.  It returns true if the
+// input was added.  Inputs are selected by their plugin name only, so all
+// instances of a selected plugin are added.
+func (r *RunningDiscovery) Subscribe(input *RunningInput) bool {
+	handler, ok := input.Input.(telegraf.TargetHandler)
+	if !ok {
+		return false
+	}
+
+	if len(r.Config.Inputs) > 0 {
+		var selected bool
+		for _, name := range r.Config.Inputs {
+			if name == input.Config.Name {
+				selected = true
+				break
+			}
+		}
+		if !selected {
+			return false
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	r.handlers = append(r.handlers, handler)
+	return true
+}
+
+// Start starts the discovery, passing the targets to the subscribed inputs.
+func (r *RunningDiscovery) Start() error {
+	return r.Discovery.Start(r)
+}
+
+func (r *RunningDiscovery) Stop() {
+	r.Discovery.Stop()
+}
+
+// AddTarget adds the target to all subscribed inputs.
+func (r *RunningDiscovery) AddTarget(target telegraf.Target) {
+	target = r.makeTarget(target)
+	log.Printf("D! [%s] Adding target %s", r.Name(), target.Address)
+
+	r.Lock()
+	defer r.Unlock()
+	for _, handler := range r.handlers {
+		handler.AddTarget(target)
+	}
+}
+
+// RemoveTarget removes the target from all subscribed inputs.
+func (r *RunningDiscovery) RemoveTarget(target telegraf.Target) {
+	target = r.makeTarget(target)
+	log.Printf("D! [%s] Removing target %s", r.Name(), target.Address)
+
+	r.Lock()
+	defer r.Unlock()
+	for _, handler := range r.handlers {
+		handler.RemoveTarget(target)
+	}
+}
+
+// makeTarget returns a copy of the target with the configured tags and the
+// source of the discovery added.
+func (r *RunningDiscovery) makeTarget(target telegraf.Target) telegraf.Target {
+	tags := make(map[string]string, len(target.Tags)+len(r.Config.Tags))
+	for k, v := range target.Tags {
+		tags[k] = v
+	}
+	for k, v := range r.Config.Tags {
+		tags[k] = v
+	}
+	return telegraf.Target{Address: target.Address, Tags: tags, Source: r.source}
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDiscovery struct {
+	handler telegraf.TargetHandler
+}
+
+func (d *mockDiscovery) SampleConfig() string {
+	return ""
+}
+
+func (d *mockDiscovery) Description() string {
+	return ""
+}
+
+func (d *mockDiscovery) Start(handler telegraf.TargetHandler) error {
+	d.handler = handler
+	return nil
+}
+
+func (d *mockDiscovery) Stop() {
+}
+
+type mockTargetInput struct {
+	testInput
+	targets map[string]telegraf.Target
+}
+
+func (i *mockTargetInput) AddTarget(target telegraf.Target) {
+	i.targets[target.Address] = target
+}
+
+func (i *mockTargetInput) RemoveTarget(target telegraf.Target) {
+	delete(i.targets, target.Address)
+}
+
+func TestRunningDiscovery(t *testing.T) {
+	d := &mockDiscovery{}
+	rd := NewRunningDiscovery(d, &DiscoveryConfig{
+		Name: "mock",
+		Tags: map[string]string{"source": "mock"},
+	})
+	require.Equal(t, "discovery.mock", rd.Name())
+
+	input := &mockTargetInput{targets: map[string]telegraf.Target{}}
+	require.True(t, rd.Subscribe(NewRunningInput(input, &InputConfig{Name: "target"})))
+	require.False(t, rd.Subscribe(NewRunningInput(&testInput{}, &InputConfig{Name: "mock"})))
+
+	require.NoError(t, rd.Start())
+	defer rd.Stop()
+
+	d.handler.AddTarget(telegraf.Target{
+		Address: "localhost:80",
+		Tags:    map[string]string{"env": "prod"},
+	})
+	require.Equal(t, map[string]telegraf.Target{
+		"localhost:80": {
+			Address: "localhost:80",
+			Tags:    map[string]string{"env": "prod", "source": "mock"},
+			Source:  rd.source,
+		},
+	}, input.targets)
+
+	d.handler.RemoveTarget(telegraf.Target{Address: "localhost:80"})
+	require.Empty(t, input.targets)
+}
+
+func TestRunningDiscoverySelectInputs(t *testing.T) {
+	rd := NewRunningDiscovery(&mockDiscovery{}, &DiscoveryConfig{
+		Name:   "mock",
+		Inputs: []string{"prometheus"},
+	})
+
+	input := &mockTargetInput{targets: map[string]telegraf.Target{}}
+	require.False(t, rd.Subscribe(NewRunningInput(input, &InputConfig{Name: "http"})))
+	require.True(t, rd.Subscribe(NewRunningInput(input, &InputConfig{Name: "prometheus"})))
+
+	// Each discovery is a separate source, even with the same plugin.
+	other := NewRunningDiscovery(&mockDiscovery{}, &DiscoveryConfig{Name: "mock"})
+	require.NotEqual(t, rd.source, other.source)
+
+	// All instances of a selected plugin receive the targets.
+	second := &mockTargetInput{targets: map[string]telegraf.Target{}}
+	require.True(t, rd.Subscribe(NewRunningInput(second, &InputConfig{Name: "prometheus"})))
+}
@@ -0,0 +1,541 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: opentelemetry/proto/common/v1/common.proto
+
+package otlp // import "github.com/influxdata/telegraf/internal/otlp"
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type AnyValue struct {
+	// Types that are valid to be assigned to Value:
+	//	*AnyValue_StringValue
+	//	*AnyValue_BoolValue
+	//	*AnyValue_IntValue
+	//	*AnyValue_DoubleValue
+	//	*AnyValue_ArrayValue
+	//	*AnyValue_KvlistValue
+	//	*AnyValue_BytesValue
+	Value                isAnyValue_Value `protobuf_oneof:"value"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *AnyValue) Reset()         { *m = AnyValue{} }
+func (m *AnyValue) String() string { return proto.CompactTextString(m) }
+func (*AnyValue) ProtoMessage()    {}
+func (*AnyValue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_common_802c53ac4692178b, []int{0}
+}
+func (m *AnyValue) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AnyValue.Unmarshal(m, b)
+}
+func (m *AnyValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AnyValue.Marshal(b, m, deterministic)
+}
+func (dst *AnyValue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AnyValue.Merge(dst, src)
+}
+func (m *AnyValue) XXX_Size() int {
+	return xxx_messageInfo_AnyValue.Size(m)
+}
+func (m *AnyValue) XXX_DiscardUnknown() {
+	xxx_messageInfo_AnyValue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AnyValue proto.InternalMessageInfo
+
+type isAnyValue_Value interface {
+	isAnyValue_Value()
+}
+
+type AnyValue_StringValue struct {
+	StringValue string `protobuf:"bytes,1,opt,name=string_value,json=stringValue,proto3,oneof"`
+}
+
+type AnyValue_BoolValue struct {
+	BoolValue bool `protobuf:"varint,2,opt,name=bool_value,json=boolValue,proto3,oneof"`
+}
+
+type AnyValue_IntValue struct {
+	IntValue int64 `protobuf:"varint,3,opt,name=int_value,json=intValue,proto3,oneof"`
+}
+
+type AnyValue_DoubleValue struct {
+	DoubleValue float64 `protobuf:"fixed64,4,opt,name=double_value,json=doubleValue,proto3,oneof"`
+}
+
+type AnyValue_ArrayValue struct {
+	ArrayValue *ArrayValue `protobuf:"bytes,5,opt,name=array_value,json=arrayValue,proto3,oneof"`
+}
+
+type AnyValue_KvlistValue struct {
+	KvlistValue *KeyValueList `protobuf:"bytes,6,opt,name=kvlist_value,json=kvlistValue,proto3,oneof"`
+}
+
+type AnyValue_BytesValue struct {
+	BytesValue []byte `protobuf:"bytes,7,opt,name=bytes_value,json=bytesValue,proto3,oneof"`
+}
+
+func (*AnyValue_StringValue) isAnyValue_Value() {}
+
+func (*AnyValue_BoolValue) isAnyValue_Value() {}
+
+func (*AnyValue_IntValue) isAnyValue_Value() {}
+
+func (*AnyValue_DoubleValue) isAnyValue_Value() {}
+
+func (*AnyValue_ArrayValue) isAnyValue_Value() {}
+
+func (*AnyValue_KvlistValue) isAnyValue_Value() {}
+
+func (*AnyValue_BytesValue) isAnyValue_Value() {}
+
+func (m *AnyValue) GetValue() isAnyValue_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (m *AnyValue) GetStringValue() string {
+	if x, ok := m.GetValue().(*AnyValue_StringValue); ok {
+		return x.StringValue
+	}
+	return ""
+}
+
+func (m *AnyValue) GetBoolValue() bool {
+	if x, ok := m.GetValue().(*AnyValue_BoolValue); ok {
+		return x.BoolValue
+	}
+	return false
+}
+
+func (m *AnyValue) GetIntValue() int64 {
+	if x, ok := m.GetValue().(*AnyValue_IntValue); ok {
+		return x.IntValue
+	}
+	return 0
+}
+
+func (m *AnyValue) GetDoubleValue() float64 {
+	if x, ok := m.GetValue().(*AnyValue_DoubleValue); ok {
+		return x.DoubleValue
+	}
+	return 0
+}
+
+func (m *AnyValue) GetArrayValue() *ArrayValue {
+	if x, ok := m.GetValue().(*AnyValue_ArrayValue); ok {
+		return x.ArrayValue
+	}
+	return nil
+}
+
+func (m *AnyValue) GetKvlistValue() *KeyValueList {
+	if x, ok := m.GetValue().(*AnyValue_KvlistValue); ok {
+		return x.KvlistValue
+	}
+	return nil
+}
+
+func (m *AnyValue) GetBytesValue() []byte {
+	if x, ok := m.GetValue().(*AnyValue_BytesValue); ok {
+		return x.BytesValue
+	}
+	return nil
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*AnyValue) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _AnyValue_OneofMarshaler, _AnyValue_OneofUnmarshaler, _AnyValue_OneofSizer, []interface{}{
+		(*AnyValue_StringValue)(nil),
+		(*AnyValue_BoolValue)(nil),
+		(*AnyValue_IntValue)(nil),
+		(*AnyValue_DoubleValue)(nil),
+		(*AnyValue_ArrayValue)(nil),
+		(*AnyValue_KvlistValue)(nil),
+		(*AnyValue_BytesValue)(nil),
+	}
+}
+
+func _AnyValue_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*AnyValue)
+	// value
+	switch x := m.Value.(type) {
+	case *AnyValue_StringValue:
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		b.EncodeStringBytes(x.StringValue)
+	case *AnyValue_BoolValue:
+		t := uint64(0)
+		if x.BoolValue {
+			t = 1
+		}
+		b.EncodeVarint(2<<3 | proto.WireVarint)
+		b.EncodeVarint(t)
+	case *AnyValue_IntValue:
+		b.EncodeVarint(3<<3 | proto.WireVarint)
+		b.EncodeVarint(uint64(x.IntValue))
+	case *AnyValue_DoubleValue:
+		b.EncodeVarint(4<<3 | proto.WireFixed64)
+		b.EncodeFixed64(math.Float64bits(x.DoubleValue))
+	case *AnyValue_ArrayValue:
+		b.EncodeVarint(5<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.ArrayValue); err != nil {
+			return err
+		}
+	case *AnyValue_KvlistValue:
+		b.EncodeVarint(6<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.KvlistValue); err != nil {
+			return err
+		}
+	case *AnyValue_BytesValue:
+		b.EncodeVarint(7<<3 | proto.WireBytes)
+		b.EncodeRawBytes(x.BytesValue)
+	case nil:
+	default:
+		return fmt.Errorf("AnyValue.Value has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _AnyValue_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*AnyValue)
+	switch tag {
+	case 1: // value.string_value
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeStringBytes()
+		m.Value = &AnyValue_StringValue{x}
+		return true, err
+	case 2: // value.bool_value
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeVarint()
+		m.Value = &AnyValue_BoolValue{x != 0}
+		return true, err
+	case 3: // value.int_value
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeVarint()
+		m.Value = &AnyValue_IntValue{int64(x)}
+		return true, err
+	case 4: // value.double_value
+		if wire != proto.WireFixed64 {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeFixed64()
+		m.Value = &AnyValue_DoubleValue{math.Float64frombits(x)}
+		return true, err
+	case 5: // value.array_value
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ArrayValue)
+		err := b.DecodeMessage(msg)
+		m.Value = &AnyValue_ArrayValue{msg}
+		return true, err
+	case 6: // value.kvlist_value
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(KeyValueList)
+		err := b.DecodeMessage(msg)
+		m.Value = &AnyValue_KvlistValue{msg}
+		return true, err
+	case 7: // value.bytes_value
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeRawBytes(true)
+		m.Value = &AnyValue_BytesValue{x}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _AnyValue_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*AnyValue)
+	// value
+	switch x := m.Value.(type) {
+	case *AnyValue_StringValue:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(len(x.StringValue)))
+		n += len(x.StringValue)
+	case *AnyValue_BoolValue:
+		n += 1 // tag and wire
+		n += 1
+	case *AnyValue_IntValue:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(x.IntValue))
+	case *AnyValue_DoubleValue:
+		n += 1 // tag and wire
+		n += 8
+	case *AnyValue_ArrayValue:
+		s := proto.Size(x.ArrayValue)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *AnyValue_KvlistValue:
+		s := proto.Size(x.KvlistValue)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *AnyValue_BytesValue:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(len(x.BytesValue)))
+		n += len(x.BytesValue)
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+type ArrayValue struct {
+	Values               []*AnyValue `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *ArrayValue) Reset()         { *m = ArrayValue{} }
+func (m *ArrayValue) String() string { return proto.CompactTextString(m) }
+func (*ArrayValue) ProtoMessage()    {}
+func (*ArrayValue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_common_802c53ac4692178b, []int{1}
+}
+func (m *ArrayValue) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ArrayValue.Unmarshal(m, b)
+}
+func (m *ArrayValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ArrayValue.Marshal(b, m, deterministic)
+}
+func (dst *ArrayValue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ArrayValue.Merge(dst, src)
+}
+func (m *ArrayValue) XXX_Size() int {
+	return xxx_messageInfo_ArrayValue.Size(m)
+}
+func (m *ArrayValue) XXX_DiscardUnknown() {
+	xxx_messageInfo_ArrayValue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ArrayValue proto.InternalMessageInfo
+
+func (m *ArrayValue) GetValues() []*AnyValue {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+type KeyValueList struct {
+	Values               []*KeyValue `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *KeyValueList) Reset()         { *m = KeyValueList{} }
+func (m *KeyValueList) String() string { return proto.CompactTextString(m) }
+func (*KeyValueList) ProtoMessage()    {}
+func (*KeyValueList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_common_802c53ac4692178b, []int{2}
+}
+func (m *KeyValueList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyValueList.Unmarshal(m, b)
+}
+func (m *KeyValueList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyValueList.Marshal(b, m, deterministic)
+}
+func (dst *KeyValueList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyValueList.Merge(dst, src)
+}
+func (m *KeyValueList) XXX_Size() int {
+	return xxx_messageInfo_KeyValueList.Size(m)
+}
+func (m *KeyValueList) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyValueList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyValueList proto.InternalMessageInfo
+
+func (m *KeyValueList) GetValues() []*KeyValue {
+	if m != nil {
+		return m.Values
+	}
+	return nil
+}
+
+type KeyValue struct {
+	Key                  string    `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value                *AnyValue `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *KeyValue) Reset()         { *m = KeyValue{} }
+func (m *KeyValue) String() string { return proto.CompactTextString(m) }
+func (*KeyValue) ProtoMessage()    {}
+func (*KeyValue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_common_802c53ac4692178b, []int{3}
+}
+func (m *KeyValue) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyValue.Unmarshal(m, b)
+}
+func (m *KeyValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyValue.Marshal(b, m, deterministic)
+}
+func (dst *KeyValue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyValue.Merge(dst, src)
+}
+func (m *KeyValue) XXX_Size() int {
+	return xxx_messageInfo_KeyValue.Size(m)
+}
+func (m *KeyValue) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyValue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyValue proto.InternalMessageInfo
+
+func (m *KeyValue) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *KeyValue) GetValue() *AnyValue {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+type InstrumentationScope struct {
+	Name                   string      `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version                string      `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Attributes             []*KeyValue `protobuf:"bytes,3,rep,name=attributes,proto3" json:"attributes,omitempty"`
+	DroppedAttributesCount uint32      `protobuf:"varint,4,opt,name=dropped_attributes_count,json=droppedAttributesCount,proto3" json:"dropped_attributes_count,omitempty"`
+	XXX_NoUnkeyedLiteral   struct{}    `json:"-"`
+	XXX_unrecognized       []byte      `json:"-"`
+	XXX_sizecache          int32       `json:"-"`
+}
+
+func (m *InstrumentationScope) Reset()         { *m = InstrumentationScope{} }
+func (m *InstrumentationScope) String() string { return proto.CompactTextString(m) }
+func (*InstrumentationScope) ProtoMessage()    {}
+func (*InstrumentationScope) Descriptor() ([]byte, []int) {
+	return fileDescriptor_common_802c53ac4692178b, []int{4}
+}
+func (m *InstrumentationScope) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_InstrumentationScope.Unmarshal(m, b)
+}
+func (m *InstrumentationScope) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_InstrumentationScope.Marshal(b, m, deterministic)
+}
+func (dst *InstrumentationScope) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_InstrumentationScope.Merge(dst, src)
+}
+func (m *InstrumentationScope) XXX_Size() int {
+	return xxx_messageInfo_InstrumentationScope.Size(m)
+}
+func (m *InstrumentationScope) XXX_DiscardUnknown() {
+	xxx_messageInfo_InstrumentationScope.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_InstrumentationScope proto.InternalMessageInfo
+
+func (m *InstrumentationScope) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *InstrumentationScope) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *InstrumentationScope) GetAttributes() []*KeyValue {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
+func (m *InstrumentationScope) GetDroppedAttributesCount() uint32 {
+	if m != nil {
+		return m.DroppedAttributesCount
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*AnyValue)(nil), "opentelemetry.proto.common.v1.AnyValue")
+	proto.RegisterType((*ArrayValue)(nil), "opentelemetry.proto.common.v1.ArrayValue")
+	proto.RegisterType((*KeyValueList)(nil), "opentelemetry.proto.common.v1.KeyValueList")
+	proto.RegisterType((*KeyValue)(nil), "opentelemetry.proto.common.v1.KeyValue")
+	proto.RegisterType((*InstrumentationScope)(nil), "opentelemetry.proto.common.v1.InstrumentationScope")
+}
+
+func init() {
+	proto.RegisterFile("opentelemetry/proto/common/v1/common.proto", fileDescriptor_common_802c53ac4692178b)
+}
+
+var fileDescriptor_common_802c53ac4692178b = []byte{
+	// 486 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x52, 0x4f, 0x8b, 0xd3, 0x40,
+	0x14, 0xef, 0xb4, 0xdb, 0x7f, 0x2f, 0x15, 0x64, 0x10, 0xc9, 0xa5, 0x18, 0xeb, 0xc1, 0xa8, 0x90,
+	0xd0, 0xf5, 0x22, 0x88, 0x48, 0xbb, 0x07, 0x2b, 0xbb, 0xb2, 0x25, 0xca, 0x1e, 0xf4, 0x50, 0x26,
+	0xed, 0x6c, 0x1d, 0x36, 0x99, 0x09, 0x93, 0x49, 0x30, 0x77, 0x3f, 0x8d, 0x5f, 0xc4, 0xaf, 0xe1,
+	0x47, 0x91, 0xf9, 0xd3, 0x76, 0xf5, 0xb0, 0x4b, 0x2f, 0xe1, 0xcd, 0xef, 0xfd, 0xfe, 0xbc, 0xc7,
+	0x0b, 0xbc, 0x14, 0x05, 0xe5, 0x8a, 0x66, 0x34, 0xa7, 0x4a, 0x36, 0x71, 0x21, 0x85, 0x12, 0xf1,
+	0x5a, 0xe4, 0xb9, 0xe0, 0x71, 0x3d, 0x75, 0x55, 0x64, 0x60, 0x3c, 0xfe, 0x87, 0x6b, 0xc1, 0xc8,
+	0x31, 0xea, 0xe9, 0xe4, 0x4f, 0x1b, 0x06, 0x33, 0xde, 0x5c, 0x91, 0xac, 0xa2, 0xf8, 0x19, 0x8c,
+	0x4a, 0x25, 0x19, 0xdf, 0xae, 0x6a, 0xfd, 0xf6, 0x51, 0x80, 0xc2, 0xe1, 0xa2, 0x95, 0x78, 0x16,
+	0xb5, 0xa4, 0x27, 0x00, 0xa9, 0x10, 0x99, 0xa3, 0xb4, 0x03, 0x14, 0x0e, 0x16, 0xad, 0x64, 0xa8,
+	0x31, 0x4b, 0x18, 0xc3, 0x90, 0x71, 0xe5, 0xfa, 0x9d, 0x00, 0x85, 0x9d, 0x45, 0x2b, 0x19, 0x30,
+	0xae, 0xf6, 0x21, 0x1b, 0x51, 0xa5, 0x19, 0x75, 0x8c, 0x93, 0x00, 0x85, 0x48, 0x87, 0x58, 0xd4,
+	0x92, 0x2e, 0xc0, 0x23, 0x52, 0x92, 0xc6, 0x71, 0xba, 0x01, 0x0a, 0xbd, 0xd3, 0x17, 0xd1, 0x9d,
+	0xbb, 0x44, 0x33, 0xad, 0x30, 0xfa, 0x45, 0x2b, 0x01, 0xb2, 0x7f, 0xe1, 0x25, 0x8c, 0x6e, 0xea,
+	0x8c, 0x95, 0xbb, 0xa1, 0x7a, 0xc6, 0xee, 0xd5, 0x3d, 0x76, 0xe7, 0xd4, 0xca, 0x2f, 0x58, 0xa9,
+	0xf4, 0x7c, 0xd6, 0xc2, 0x3a, 0x3e, 0x05, 0x2f, 0x6d, 0x14, 0x2d, 0x9d, 0x61, 0x3f, 0x40, 0xe1,
+	0x48, 0x87, 0x1a, 0xd0, 0x50, 0xe6, 0x7d, 0xe8, 0x9a, 0xe6, 0xe4, 0x13, 0xc0, 0x61, 0x32, 0xfc,
+	0x1e, 0x7a, 0x06, 0x2e, 0x7d, 0x14, 0x74, 0x42, 0xef, 0xf4, 0xf9, 0x7d, 0x4b, 0xb9, 0xe3, 0x24,
+	0x4e, 0x36, 0xb9, 0x84, 0xd1, 0xed, 0xc9, 0x8e, 0x36, 0x3c, 0xa7, 0xff, 0x19, 0x7e, 0x83, 0xc1,
+	0x0e, 0xc3, 0x0f, 0xa1, 0x73, 0x43, 0x1b, 0x7b, 0xf8, 0x44, 0x97, 0xf8, 0x1d, 0x74, 0x0f, 0x97,
+	0x3e, 0x62, 0x5c, 0xb7, 0xfc, 0x6f, 0x04, 0x8f, 0x3e, 0xf2, 0x52, 0xc9, 0x2a, 0xa7, 0x5c, 0x11,
+	0xc5, 0x04, 0xff, 0xbc, 0x16, 0x05, 0xc5, 0x18, 0x4e, 0x38, 0xc9, 0xdd, 0x3f, 0x96, 0x98, 0x1a,
+	0xfb, 0xd0, 0xaf, 0xa9, 0x2c, 0x99, 0xe0, 0x26, 0x6d, 0x98, 0xec, 0x9e, 0xf8, 0x03, 0x00, 0x51,
+	0x4a, 0xb2, 0xb4, 0x52, 0xb4, 0xf4, 0x3b, 0xc7, 0x2d, 0x7a, 0x4b, 0x8a, 0xdf, 0x80, 0xbf, 0x91,
+	0xa2, 0x28, 0xe8, 0x66, 0x75, 0x40, 0x57, 0x6b, 0x51, 0x71, 0x65, 0xfe, 0xc4, 0x07, 0xc9, 0x63,
+	0xd7, 0x9f, 0xed, 0xdb, 0x67, 0xba, 0x3b, 0xff, 0x89, 0x20, 0x60, 0xe2, 0xee, 0xcc, 0xb9, 0x77,
+	0x66, 0xca, 0xa5, 0x86, 0x97, 0xe8, 0xeb, 0x74, 0xcb, 0xd4, 0xf7, 0x2a, 0xd5, 0x84, 0x98, 0xf1,
+	0xeb, 0xac, 0xfa, 0xb1, 0x21, 0x8a, 0xc4, 0x5a, 0xbf, 0x95, 0xe4, 0x3a, 0x66, 0x5c, 0x51, 0xc9,
+	0x49, 0x16, 0x0b, 0x95, 0x15, 0x6f, 0xf5, 0xe7, 0x57, 0x7b, 0x7c, 0x59, 0x50, 0xfe, 0x65, 0x9f,
+	0x60, 0xac, 0x22, 0x6b, 0x1b, 0x5d, 0x4d, 0xd3, 0x9e, 0x89, 0x7c, 0xfd, 0x77, 0x00, 0x43, 0xe5,
+	0x81, 0x57, 0x04, 0x04, 0x00, 0x00,
+}
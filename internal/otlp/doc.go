@@ -0,0 +1,15 @@
+// Package otlp contains the OpenTelemetry protocol (OTLP) messages and
+// service for metrics.
+//
+// The code is generated with protoc-gen-go v1.2.0 and the grpc plugin from the
+// v1.0.0 definitions of opentelemetry-proto in the opentelemetry directory,
+// with all files placed in this package.
+//
+// metrics.proto declares proto3 optional fields, which protoc refuses to pass
+// to a generator of that age.  protoc-gen-go is therefore not run by protoc,
+// but fed a CodeGeneratorRequest with the parameter "plugins=grpc" and the
+// file descriptors of the go.opentelemetry.io/proto/otlp v1.0.0 module, after
+// setting their go_package to this package.  The descriptors describe each
+// optional field as a synthetic oneof, which is generated as the XSum, XMin
+// and XMax oneofs of the histogram data points.
+package otlp
@@ -0,0 +1,257 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: opentelemetry/proto/collector/metrics/v1/metrics_service.proto
+
+package otlp // import "github.com/influxdata/telegraf/internal/otlp"
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type ExportMetricsServiceRequest struct {
+	ResourceMetrics      []*ResourceMetrics `protobuf:"bytes,1,rep,name=resource_metrics,json=resourceMetrics,proto3" json:"resource_metrics,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *ExportMetricsServiceRequest) Reset()         { *m = ExportMetricsServiceRequest{} }
+func (m *ExportMetricsServiceRequest) String() string { return proto.CompactTextString(m) }
+func (*ExportMetricsServiceRequest) ProtoMessage()    {}
+func (*ExportMetricsServiceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_metrics_service_4505fdd5011d5810, []int{0}
+}
+func (m *ExportMetricsServiceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExportMetricsServiceRequest.Unmarshal(m, b)
+}
+func (m *ExportMetricsServiceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExportMetricsServiceRequest.Marshal(b, m, deterministic)
+}
+func (dst *ExportMetricsServiceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExportMetricsServiceRequest.Merge(dst, src)
+}
+func (m *ExportMetricsServiceRequest) XXX_Size() int {
+	return xxx_messageInfo_ExportMetricsServiceRequest.Size(m)
+}
+func (m *ExportMetricsServiceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExportMetricsServiceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExportMetricsServiceRequest proto.InternalMessageInfo
+
+func (m *ExportMetricsServiceRequest) GetResourceMetrics() []*ResourceMetrics {
+	if m != nil {
+		return m.ResourceMetrics
+	}
+	return nil
+}
+
+type ExportMetricsServiceResponse struct {
+	PartialSuccess       *ExportMetricsPartialSuccess `protobuf:"bytes,1,opt,name=partial_success,json=partialSuccess,proto3" json:"partial_success,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
+	XXX_unrecognized     []byte                       `json:"-"`
+	XXX_sizecache        int32                        `json:"-"`
+}
+
+func (m *ExportMetricsServiceResponse) Reset()         { *m = ExportMetricsServiceResponse{} }
+func (m *ExportMetricsServiceResponse) String() string { return proto.CompactTextString(m) }
+func (*ExportMetricsServiceResponse) ProtoMessage()    {}
+func (*ExportMetricsServiceResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_metrics_service_4505fdd5011d5810, []int{1}
+}
+func (m *ExportMetricsServiceResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExportMetricsServiceResponse.Unmarshal(m, b)
+}
+func (m *ExportMetricsServiceResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExportMetricsServiceResponse.Marshal(b, m, deterministic)
+}
+func (dst *ExportMetricsServiceResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExportMetricsServiceResponse.Merge(dst, src)
+}
+func (m *ExportMetricsServiceResponse) XXX_Size() int {
+	return xxx_messageInfo_ExportMetricsServiceResponse.Size(m)
+}
+func (m *ExportMetricsServiceResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExportMetricsServiceResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExportMetricsServiceResponse proto.InternalMessageInfo
+
+func (m *ExportMetricsServiceResponse) GetPartialSuccess() *ExportMetricsPartialSuccess {
+	if m != nil {
+		return m.PartialSuccess
+	}
+	return nil
+}
+
+type ExportMetricsPartialSuccess struct {
+	RejectedDataPoints   int64    `protobuf:"varint,1,opt,name=rejected_data_points,json=rejectedDataPoints,proto3" json:"rejected_data_points,omitempty"`
+	ErrorMessage         string   `protobuf:"bytes,2,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ExportMetricsPartialSuccess) Reset()         { *m = ExportMetricsPartialSuccess{} }
+func (m *ExportMetricsPartialSuccess) String() string { return proto.CompactTextString(m) }
+func (*ExportMetricsPartialSuccess) ProtoMessage()    {}
+func (*ExportMetricsPartialSuccess) Descriptor() ([]byte, []int) {
+	return fileDescriptor_metrics_service_4505fdd5011d5810, []int{2}
+}
+func (m *ExportMetricsPartialSuccess) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExportMetricsPartialSuccess.Unmarshal(m, b)
+}
+func (m *ExportMetricsPartialSuccess) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExportMetricsPartialSuccess.Marshal(b, m, deterministic)
+}
+func (dst *ExportMetricsPartialSuccess) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExportMetricsPartialSuccess.Merge(dst, src)
+}
+func (m *ExportMetricsPartialSuccess) XXX_Size() int {
+	return xxx_messageInfo_ExportMetricsPartialSuccess.Size(m)
+}
+func (m *ExportMetricsPartialSuccess) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExportMetricsPartialSuccess.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExportMetricsPartialSuccess proto.InternalMessageInfo
+
+func (m *ExportMetricsPartialSuccess) GetRejectedDataPoints() int64 {
+	if m != nil {
+		return m.RejectedDataPoints
+	}
+	return 0
+}
+
+func (m *ExportMetricsPartialSuccess) GetErrorMessage() string {
+	if m != nil {
+		return m.ErrorMessage
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterType((*ExportMetricsServiceRequest)(nil), "opentelemetry.proto.collector.metrics.v1.ExportMetricsServiceRequest")
+	proto.RegisterType((*ExportMetricsServiceResponse)(nil), "opentelemetry.proto.collector.metrics.v1.ExportMetricsServiceResponse")
+	proto.RegisterType((*ExportMetricsPartialSuccess)(nil), "opentelemetry.proto.collector.metrics.v1.ExportMetricsPartialSuccess")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// MetricsServiceClient is the client API for MetricsService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type MetricsServiceClient interface {
+	Export(ctx context.Context, in *ExportMetricsServiceRequest, opts ...grpc.CallOption) (*ExportMetricsServiceResponse, error)
+}
+
+type metricsServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewMetricsServiceClient(cc *grpc.ClientConn) MetricsServiceClient {
+	return &metricsServiceClient{cc}
+}
+
+func (c *metricsServiceClient) Export(ctx context.Context, in *ExportMetricsServiceRequest, opts ...grpc.CallOption) (*ExportMetricsServiceResponse, error) {
+	out := new(ExportMetricsServiceResponse)
+	err := c.cc.Invoke(ctx, "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MetricsServiceServer is the server API for MetricsService service.
+type MetricsServiceServer interface {
+	Export(context.Context, *ExportMetricsServiceRequest) (*ExportMetricsServiceResponse, error)
+}
+
+func RegisterMetricsServiceServer(s *grpc.Server, srv MetricsServiceServer) {
+	s.RegisterService(&_MetricsService_serviceDesc, srv)
+}
+
+func _MetricsService_Export_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportMetricsServiceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServiceServer).Export(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServiceServer).Export(ctx, req.(*ExportMetricsServiceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _MetricsService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "opentelemetry.proto.collector.metrics.v1.MetricsService",
+	HandlerType: (*MetricsServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Export",
+			Handler:    _MetricsService_Export_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "opentelemetry/proto/collector/metrics/v1/metrics_service.proto",
+}
+
+func init() {
+	proto.RegisterFile("opentelemetry/proto/collector/metrics/v1/metrics_service.proto", fileDescriptor_metrics_service_4505fdd5011d5810)
+}
+
+var fileDescriptor_metrics_service_4505fdd5011d5810 = []byte{
+	// 387 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x91, 0xc1, 0xcf, 0xd2, 0x30,
+	0x18, 0xc6, 0xed, 0xf7, 0x25, 0x24, 0x16, 0x05, 0x53, 0x3d, 0x10, 0xf0, 0x40, 0xe6, 0x65, 0x89,
+	0xa6, 0x75, 0x78, 0x34, 0xf1, 0x80, 0xe2, 0x8d, 0xb8, 0x0c, 0xe3, 0x81, 0xcb, 0x52, 0xca, 0x0b,
+	0xd6, 0x8c, 0xb6, 0xb6, 0x1d, 0x81, 0x7f, 0xc2, 0xbb, 0x57, 0x8f, 0xc6, 0x3f, 0xd2, 0x6c, 0x1d,
+	0x98, 0x45, 0x62, 0x88, 0xdf, 0x65, 0xd9, 0x9e, 0xbe, 0xcf, 0xef, 0x79, 0xf6, 0x16, 0xbf, 0xd1,
+	0x06, 0x94, 0x87, 0x02, 0x76, 0xe0, 0xed, 0x91, 0x19, 0xab, 0xbd, 0x66, 0x42, 0x17, 0x05, 0x08,
+	0xaf, 0x2d, 0xab, 0x54, 0x29, 0x1c, 0xdb, 0x27, 0xa7, 0xd7, 0xdc, 0x81, 0xdd, 0x4b, 0x01, 0xb4,
+	0x1e, 0x25, 0x71, 0xcb, 0x1f, 0x44, 0x7a, 0xf6, 0xd3, 0xc6, 0x44, 0xf7, 0xc9, 0xf0, 0xc5, 0xa5,
+	0xa4, 0xbf, 0xf9, 0x01, 0x11, 0x1d, 0xf1, 0x68, 0x76, 0x30, 0xda, 0xfa, 0x79, 0x90, 0x17, 0x21,
+	0x35, 0x83, 0xaf, 0x25, 0x38, 0x4f, 0x96, 0xf8, 0x91, 0x05, 0xa7, 0x4b, 0x2b, 0x20, 0x6f, 0x8c,
+	0x03, 0x34, 0xbe, 0x8d, 0xbb, 0x13, 0x46, 0x2f, 0x35, 0xfa, 0xd3, 0x83, 0x66, 0x8d, 0xaf, 0x01,
+	0x67, 0x7d, 0xdb, 0x16, 0xa2, 0x6f, 0x08, 0x3f, 0xbd, 0x9c, 0xed, 0x8c, 0x56, 0x0e, 0x88, 0xc2,
+	0x7d, 0xc3, 0xad, 0x97, 0xbc, 0xc8, 0x5d, 0x29, 0x04, 0xb8, 0x2a, 0x1b, 0xc5, 0xdd, 0xc9, 0x8c,
+	0x5e, 0xbb, 0x0d, 0xda, 0x0a, 0x48, 0x03, 0x6d, 0x11, 0x60, 0x59, 0xcf, 0xb4, 0xbe, 0x23, 0x8f,
+	0x47, 0xff, 0x18, 0x27, 0x2f, 0xf1, 0x13, 0x0b, 0x5f, 0x40, 0x78, 0x58, 0xe7, 0x6b, 0xee, 0x79,
+	0x6e, 0xb4, 0x54, 0x3e, 0x74, 0xba, 0xcd, 0xc8, 0xe9, 0xec, 0x1d, 0xf7, 0x3c, 0xad, 0x4f, 0xc8,
+	0x33, 0xfc, 0x10, 0xac, 0xd5, 0x36, 0xdf, 0x81, 0x73, 0x7c, 0x0b, 0x83, 0x9b, 0x31, 0x8a, 0xef,
+	0x67, 0x0f, 0x6a, 0x71, 0x1e, 0xb4, 0xc9, 0x2f, 0x84, 0x7b, 0xed, 0x05, 0x90, 0xef, 0x08, 0x77,
+	0x42, 0x13, 0xf2, 0xbf, 0xbf, 0xda, 0xbe, 0xc7, 0xe1, 0xfb, 0xbb, 0x62, 0xc2, 0x95, 0x44, 0xf7,
+	0xa6, 0x3f, 0x10, 0x7e, 0x2e, 0xf5, 0xd5, 0xb8, 0xe9, 0xe3, 0x36, 0x29, 0xad, 0x26, 0x53, 0xb4,
+	0x4c, 0xb6, 0xd2, 0x7f, 0x2e, 0x57, 0x54, 0xe8, 0x1d, 0x93, 0x6a, 0x53, 0x94, 0x87, 0x6a, 0xa5,
+	0xac, 0x42, 0x6e, 0x2d, 0xdf, 0x30, 0xa9, 0x3c, 0x58, 0xc5, 0x0b, 0xa6, 0x7d, 0x61, 0x5e, 0x57,
+	0x8f, 0x9f, 0x37, 0xf1, 0x07, 0x03, 0xea, 0xe3, 0x39, 0xb4, 0x46, 0xd1, 0xb7, 0xe7, 0xd0, 0x26,
+	0x88, 0x7e, 0x4a, 0x56, 0x9d, 0xba, 0xd0, 0xab, 0xdf, 0x03, 0x00, 0x90, 0x2d, 0x09, 0x6d, 0x76,
+	0x03, 0x00, 0x00,
+}
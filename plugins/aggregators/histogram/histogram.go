@@ -1,8 +1,11 @@
 package histogram
 
 import (
+	"fmt"
+	"math"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/aggregators"
@@ -14,10 +17,18 @@ const bucketTag = "le"
 // bucketInf is the right bucket border for infinite values
 const bucketInf = "+Inf"
 
+// bucketLeftTag is the tag, which contains left bucket border (exclusive)
+const bucketLeftTag = "gt"
+
+// bucketNegInf is the left bucket border for infinite values
+const bucketNegInf = "-Inf"
+
 // HistogramAggregator is aggregator with histogram configs and particular histograms for defined metrics
 type HistogramAggregator struct {
 	Configs      []config `toml:"config"`
 	ResetBuckets bool     `toml:"reset"`
+	Cumulative   bool     `toml:"cumulative"`
+	SumAndCount  bool     `toml:"sum_and_count"`
 
 	buckets bucketsByMetrics
 	cache   map[uint64]metricHistogramCollection
@@ -28,6 +39,13 @@ type config struct {
 	Metric  string   `toml:"measurement_name"`
 	Fields  []string `toml:"fields"`
 	Buckets buckets  `toml:"buckets"`
+
+	// Options to generate the buckets instead of listing them
+	BucketLayout string  `toml:"bucket_layout"`
+	BucketStart  float64 `toml:"bucket_start"`
+	BucketWidth  float64 `toml:"bucket_width"`
+	BucketFactor float64 `toml:"bucket_factor"`
+	BucketCount  int     `toml:"bucket_count"`
 }
 
 // bucketsByMetrics contains the buckets grouped by metric and field name
@@ -42,6 +60,7 @@ type buckets []float64
 // metricHistogramCollection aggregates the histogram data
 type metricHistogramCollection struct {
 	histogramCollection map[string]counts
+	sums                map[string]float64
 	name                string
 	tags                map[string]string
 }
@@ -58,7 +77,9 @@ type groupedByCountFields struct {
 
 // NewHistogramAggregator creates new histogram aggregator
 func NewHistogramAggregator() telegraf.Aggregator {
-	h := &HistogramAggregator{}
+	h := &HistogramAggregator{
+		Cumulative: true,
+	}
 	h.buckets = make(bucketsByMetrics)
 	h.resetCache()
 
@@ -77,6 +98,14 @@ var sampleConfig = `
   ## of accumulating the results.
   reset = false
 
+  ## Whether bucket values should be accumulated. If set to false, "gt" tag
+  ## will be added with the left border of the bucket.
+  cumulative = true
+
+  ## If true, the sum and count of the values are added as "<field>_sum" and
+  ## "<field>_count" fields in a metric without bucket tags.
+  # sum_and_count = false
+
   ## Example config that aggregates all fields of the metric.
   # [[aggregators.histogram.config]]
   #   ## The set of buckets.
@@ -92,6 +121,23 @@ var sampleConfig = `
   #   measurement_name = "diskio"
   #   ## The concrete fields of metric
   #   fields = ["io_time", "read_time", "write_time"]
+
+  ## Example config that generates the buckets.
+  # [[aggregators.histogram.config]]
+  #   ## Layout of the generated buckets, "linear" or "exponential".  Linear
+  #   ## buckets start at bucket_start and are bucket_width wide, exponential
+  #   ## buckets start at bucket_start and each border is bucket_factor times
+  #   ## the previous one.
+  #   bucket_layout = "exponential"
+  #   bucket_start = 0.001
+  #   # bucket_width = 0.1
+  #   bucket_factor = 2.0
+  #   ## The number of buckets, not including the "+Inf" bucket.
+  #   bucket_count = 16
+  #   ## The name of metric.
+  #   measurement_name = "http_response"
+  #   ## The concrete fields of metric
+  #   fields = ["response_time"]
 `
 
 // SampleConfig returns sample of config
@@ -104,6 +150,76 @@ func (h *HistogramAggregator) Description() string {
 	return "Create aggregate histograms."
 }
 
+// Init generates the configured bucket layouts
+func (h *HistogramAggregator) Init() error {
+	for i := range h.Configs {
+		cfg := &h.Configs[i]
+		if cfg.BucketLayout == "" {
+			continue
+		}
+
+		if len(cfg.Buckets) != 0 {
+			return fmt.Errorf("histogram %q: buckets and bucket_layout are mutually exclusive", cfg.Metric)
+		}
+
+		buckets, err := generateBuckets(cfg)
+		if err != nil {
+			return fmt.Errorf("histogram %q: %v", cfg.Metric, err)
+		}
+		cfg.Buckets = buckets
+	}
+	return nil
+}
+
+// generateBuckets creates the borders of the buckets for a bucket layout
+func generateBuckets(cfg *config) (buckets, error) {
+	if cfg.BucketCount < 1 {
+		return nil, fmt.Errorf("bucket_count must be at least 1")
+	}
+
+	result := make(buckets, 0, cfg.BucketCount)
+	switch cfg.BucketLayout {
+	case "linear":
+		if cfg.BucketWidth <= 0 {
+			return nil, fmt.Errorf("bucket_width must be greater than 0")
+		}
+		// round the borders to the precision of the start and width, so
+		// 0.1 wide buckets have borders like 0.3 and not 0.30000000000000004
+		prec := decimals(cfg.BucketStart)
+		if d := decimals(cfg.BucketWidth); d > prec {
+			prec = d
+		}
+		for i := 0; i < cfg.BucketCount; i++ {
+			border := cfg.BucketStart + float64(i)*cfg.BucketWidth
+			border, _ = strconv.ParseFloat(strconv.FormatFloat(border, 'f', prec, 64), 64)
+			result = append(result, border)
+		}
+	case "exponential":
+		if cfg.BucketStart <= 0 {
+			return nil, fmt.Errorf("bucket_start must be greater than 0")
+		}
+		if cfg.BucketFactor <= 1 {
+			return nil, fmt.Errorf("bucket_factor must be greater than 1")
+		}
+		for i := 0; i < cfg.BucketCount; i++ {
+			result = append(result, cfg.BucketStart*math.Pow(cfg.BucketFactor, float64(i)))
+		}
+	default:
+		return nil, fmt.Errorf("unknown bucket_layout %q", cfg.BucketLayout)
+	}
+	return result, nil
+}
+
+// decimals returns the number of decimal places of the shortest
+// representation of the value
+func decimals(value float64) int {
+	s := strconv.FormatFloat(value, 'f', -1, 64)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
 // Add adds new hit to the buckets
 func (h *HistogramAggregator) Add(in telegraf.Metric) {
 	bucketsByField := make(map[string][]float64)
@@ -125,6 +241,7 @@ func (h *HistogramAggregator) Add(in telegraf.Metric) {
 			name:                in.Name(),
 			tags:                in.Tags(),
 			histogramCollection: make(map[string]counts),
+			sums:                make(map[string]float64),
 		}
 	}
 
@@ -137,6 +254,7 @@ func (h *HistogramAggregator) Add(in telegraf.Metric) {
 			if value, ok := convert(value); ok {
 				index := sort.SearchFloat64s(buckets, value)
 				agr.histogramCollection[field][index]++
+				agr.sums[field] += value
 			}
 		}
 	}
@@ -157,6 +275,21 @@ func (h *HistogramAggregator) Push(acc telegraf.Accumulator) {
 	for _, metric := range metricsWithGroupedFields {
 		acc.AddFields(metric.name, makeFieldsWithCount(metric.fieldsWithCount), metric.tags)
 	}
+
+	if h.SumAndCount {
+		for _, aggregate := range h.cache {
+			fields := make(map[string]interface{}, 2*len(aggregate.histogramCollection))
+			for field, counts := range aggregate.histogramCollection {
+				count := int64(0)
+				for _, c := range counts {
+					count += c
+				}
+				fields[field+"_sum"] = aggregate.sums[field]
+				fields[field+"_count"] = count
+			}
+			acc.AddFields(aggregate.name, fields, copyTags(aggregate.tags))
+		}
+	}
 }
 
 // groupFieldsByBuckets groups fields by metric buckets which are represented as tags
@@ -167,14 +300,25 @@ func (h *HistogramAggregator) groupFieldsByBuckets(
 	tags map[string]string,
 	counts []int64,
 ) {
+	left := bucketNegInf
 	count := int64(0)
 	for index, bucket := range h.getBuckets(name, field) {
+		right := strconv.FormatFloat(bucket, 'f', -1, 64)
+		if !h.Cumulative {
+			count = 0 // reset hits count
+			tags[bucketLeftTag] = left
+			left = right
+		}
 		count += counts[index]
 
-		tags[bucketTag] = strconv.FormatFloat(bucket, 'f', -1, 64)
+		tags[bucketTag] = right
 		h.groupField(metricsWithGroupedFields, name, field, count, copyTags(tags))
 	}
 
+	if !h.Cumulative {
+		count = 0
+		tags[bucketLeftTag] = left
+	}
 	count += counts[len(counts)-1]
 	tags[bucketTag] = bucketInf
 
@@ -2,6 +2,7 @@ package histogram
 
 import (
 	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
@@ -13,7 +14,7 @@ import (
 
 // NewTestHistogram creates new test histogram aggregation with specified config
 func NewTestHistogram(cfg []config, reset bool) telegraf.Aggregator {
-	htm := &HistogramAggregator{Configs: cfg, ResetBuckets: reset}
+	htm := &HistogramAggregator{Configs: cfg, ResetBuckets: reset, Cumulative: true}
 	htm.buckets = make(bucketsByMetrics)
 	htm.resetCache()
 
@@ -233,3 +234,124 @@ func assertContainsTaggedField(t *testing.T, acc *testutil.Accumulator, metricNa
 
 	assert.Fail(t, fmt.Sprintf("unknown measurement '%s' with tags: %v, fields: %v", metricName, map[string]string{"le": le}, fields))
 }
+
+// TestHistogramNonCumulative tests that bucket counts are not accumulated and "gt" tags are added
+func TestHistogramNonCumulative(t *testing.T) {
+	var cfg []config
+	cfg = append(cfg, config{Metric: "first_metric_name", Fields: []string{"a"}, Buckets: []float64{0.0, 10.0, 20.0, 30.0, 40.0}})
+	histogram := &HistogramAggregator{Configs: cfg, Cumulative: false}
+	histogram.buckets = make(bucketsByMetrics)
+	histogram.resetCache()
+
+	acc := &testutil.Accumulator{}
+
+	histogram.Add(firstMetric1)
+	histogram.Add(firstMetric2)
+	histogram.Push(acc)
+
+	expected := []telegraf.Metric{}
+	borders := []string{bucketNegInf, "0", "10", "20", "30", "40", bucketInf}
+	bucketCounts := []int64{0, 0, 2, 0, 0, 0}
+	for i, count := range bucketCounts {
+		expected = append(expected, testutil.MustMetric(
+			"first_metric_name",
+			map[string]string{"tag_name": "tag_value", bucketLeftTag: borders[i], bucketTag: borders[i+1]},
+			map[string]interface{}{"a_bucket": count},
+			time.Unix(0, 0),
+		))
+	}
+
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics(), testutil.IgnoreTime())
+}
+
+// TestHistogramSumAndCount tests that the sum and count fields are added
+func TestHistogramSumAndCount(t *testing.T) {
+	var cfg []config
+	cfg = append(cfg, config{Metric: "first_metric_name", Fields: []string{"a", "b"}, Buckets: []float64{0.0, 20.0}})
+	histogram := NewTestHistogram(cfg, false).(*HistogramAggregator)
+	histogram.SumAndCount = true
+
+	acc := &testutil.Accumulator{}
+
+	histogram.Add(firstMetric1)
+	histogram.Add(firstMetric2)
+	histogram.Push(acc)
+
+	a1, _ := firstMetric1.GetField("a")
+	a2, _ := firstMetric2.GetField("a")
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"first_metric_name",
+			map[string]string{"tag_name": "tag_value", bucketTag: "0"},
+			map[string]interface{}{"a_bucket": int64(0), "b_bucket": int64(0)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"first_metric_name",
+			map[string]string{"tag_name": "tag_value", bucketTag: "20"},
+			map[string]interface{}{"a_bucket": int64(2), "b_bucket": int64(0)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"first_metric_name",
+			map[string]string{"tag_name": "tag_value", bucketTag: bucketInf},
+			map[string]interface{}{"a_bucket": int64(2), "b_bucket": int64(1)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"first_metric_name",
+			map[string]string{"tag_name": "tag_value"},
+			map[string]interface{}{
+				"a_sum":   a1.(float64) + a2.(float64),
+				"a_count": int64(2),
+				"b_sum":   float64(40),
+				"b_count": int64(1),
+			},
+			time.Unix(0, 0),
+		),
+	}
+
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics(), testutil.IgnoreTime())
+}
+
+// TestGeneratedBuckets tests the linear and exponential bucket layouts
+func TestGeneratedBuckets(t *testing.T) {
+	histogram := &HistogramAggregator{
+		Configs: []config{
+			{Metric: "linear", BucketLayout: "linear", BucketStart: 10, BucketWidth: 5, BucketCount: 4},
+			{Metric: "exponential", BucketLayout: "exponential", BucketStart: 0.5, BucketFactor: 2, BucketCount: 5},
+			{Metric: "fractional", BucketLayout: "linear", BucketStart: 0, BucketWidth: 0.1, BucketCount: 4},
+			{Metric: "offset", BucketLayout: "linear", BucketStart: 0.05, BucketWidth: 0.1, BucketCount: 3},
+		},
+	}
+	assert.NoError(t, histogram.Init())
+
+	assert.Equal(t, buckets{10, 15, 20, 25}, histogram.Configs[0].Buckets)
+	assert.Equal(t, buckets{0.5, 1, 2, 4, 8}, histogram.Configs[1].Buckets)
+	assert.Equal(t, buckets{0, 0.1, 0.2, 0.3}, histogram.Configs[2].Buckets)
+	assert.Equal(t, buckets{0.05, 0.15, 0.25}, histogram.Configs[3].Buckets)
+
+	// the borders are tagged without rounding errors
+	var tags []string
+	for _, border := range histogram.Configs[2].Buckets {
+		tags = append(tags, strconv.FormatFloat(border, 'f', -1, 64))
+	}
+	assert.Equal(t, []string{"0", "0.1", "0.2", "0.3"}, tags)
+}
+
+// TestGeneratedBucketsErrors tests the validation of the bucket layouts
+func TestGeneratedBucketsErrors(t *testing.T) {
+	tests := []config{
+		{Metric: "m", BucketLayout: "linear", BucketWidth: 5, BucketCount: 0},
+		{Metric: "m", BucketLayout: "linear", BucketWidth: 0, BucketCount: 3},
+		{Metric: "m", BucketLayout: "exponential", BucketStart: 0, BucketFactor: 2, BucketCount: 3},
+		{Metric: "m", BucketLayout: "exponential", BucketStart: 1, BucketFactor: 1, BucketCount: 3},
+		{Metric: "m", BucketLayout: "quadratic", BucketCount: 3},
+		{Metric: "m", BucketLayout: "linear", BucketWidth: 5, BucketCount: 3, Buckets: []float64{1}},
+	}
+	for _, cfg := range tests {
+		histogram := &HistogramAggregator{Configs: []config{cfg}}
+		assert.Error(t, histogram.Init())
+	}
+}
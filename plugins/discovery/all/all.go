@@ -0,0 +1,7 @@
+package all
+
+import (
+	_ "github.com/influxdata/telegraf/plugins/discovery/dns"
+	_ "github.com/influxdata/telegraf/plugins/discovery/docker"
+	_ "github.com/influxdata/telegraf/plugins/discovery/file"
+)
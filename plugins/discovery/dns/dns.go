@@ -0,0 +1,170 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/discovery"
+)
+
+const sampleConfig = `
+  ## DNS names to resolve.
+  names = ["_metrics._tcp.example.com"]
+
+  ## Type of the records, one of "SRV", "A" or "AAAA".  The targets of SRV
+  ## records use the host and port of the records, the targets of A and AAAA
+  ## records the addresses and the port option.
+  # record_type = "SRV"
+  # port = 9100
+
+  ## Interval to resolve the names again.
+  # refresh_interval = "30s"
+`
+
+var (
+	lookupSRV = net.LookupSRV
+	lookupIP  = net.LookupIP
+)
+
+type DNS struct {
+	Names           []string          `toml:"names"`
+	RecordType      string            `toml:"record_type"`
+	Port            uint16            `toml:"port"`
+	RefreshInterval internal.Duration `toml:"refresh_interval"`
+
+	tracker *discovery.Tracker
+	targets map[string][]telegraf.Target
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func (*DNS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*DNS) Description() string {
+	return "Discover targets from DNS SRV, A or AAAA records"
+}
+
+func (d *DNS) Init() error {
+	if len(d.Names) == 0 {
+		return fmt.Errorf("no names configured")
+	}
+	if d.RefreshInterval.Duration <= 0 {
+		return fmt.Errorf("refresh_interval must be positive")
+	}
+
+	d.RecordType = strings.ToUpper(d.RecordType)
+	switch d.RecordType {
+	case "SRV":
+	case "A", "AAAA":
+		if d.Port == 0 {
+			return fmt.Errorf("port is required for %s records", d.RecordType)
+		}
+	default:
+		return fmt.Errorf("invalid record_type %q, must be SRV, A or AAAA", d.RecordType)
+	}
+	return nil
+}
+
+func (d *DNS) Start(handler telegraf.TargetHandler) error {
+	d.tracker = discovery.NewTracker(handler)
+	d.targets = make(map[string][]telegraf.Target)
+	d.refresh()
+
+	var ctx context.Context
+	ctx, d.cancel = context.WithCancel(context.Background())
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(d.RefreshInterval.Duration):
+				d.refresh()
+			}
+		}
+	}()
+	return nil
+}
+
+func (d *DNS) Stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+// refresh resolves the names.  The targets of names that fail to resolve
+// are kept from the previous refresh.
+func (d *DNS) refresh() {
+	names := make(map[string][]telegraf.Target)
+	for _, name := range d.Names {
+		targets, err := d.resolve(name)
+		if err != nil {
+			log.Printf("E! [discovery.dns] Could not resolve %s: %v", name, err)
+			targets = d.targets[name]
+		}
+		names[name] = targets
+	}
+	d.targets = names
+
+	var targets []telegraf.Target
+	for _, t := range names {
+		targets = append(targets, t...)
+	}
+	d.tracker.Update(targets)
+}
+
+// resolve returns the targets of the name.
+func (d *DNS) resolve(name string) ([]telegraf.Target, error) {
+	tags := map[string]string{"dns_name": name}
+
+	var targets []telegraf.Target
+	switch d.RecordType {
+	case "SRV":
+		_, records, err := lookupSRV("", "", name)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			host := strings.TrimSuffix(record.Target, ".")
+			targets = append(targets, telegraf.Target{
+				Address: net.JoinHostPort(host, strconv.Itoa(int(record.Port))),
+				Tags:    tags,
+			})
+		}
+	default:
+		ips, err := lookupIP(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if (ip.To4() != nil) != (d.RecordType == "A") {
+				continue
+			}
+			targets = append(targets, telegraf.Target{
+				Address: net.JoinHostPort(ip.String(), strconv.Itoa(int(d.Port))),
+				Tags:    tags,
+			})
+		}
+	}
+	return targets, nil
+}
+
+func init() {
+	discovery.Add("dns", func() telegraf.Discovery {
+		return &DNS{
+			RecordType:      "SRV",
+			RefreshInterval: internal.Duration{Duration: 30 * time.Second},
+		}
+	})
+}
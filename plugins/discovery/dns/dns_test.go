@@ -0,0 +1,102 @@
+package dns
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/require"
+)
+
+type handler struct {
+	targets map[string]telegraf.Target
+}
+
+func (h *handler) AddTarget(target telegraf.Target) {
+	h.targets[target.Address] = target
+}
+
+func (h *handler) RemoveTarget(target telegraf.Target) {
+	delete(h.targets, target.Address)
+}
+
+func TestSRV(t *testing.T) {
+	defer func() { lookupSRV = net.LookupSRV }()
+
+	records := []*net.SRV{
+		{Target: "a.example.com.", Port: 9100},
+		{Target: "b.example.com.", Port: 9101},
+	}
+	var err error
+	lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		return "", records, err
+	}
+
+	plugin := &DNS{
+		Names:      []string{"_metrics._tcp.example.com"},
+		RecordType: "srv",
+	}
+	plugin.RefreshInterval.Duration = time.Hour
+	require.NoError(t, plugin.Init())
+
+	h := &handler{targets: map[string]telegraf.Target{}}
+	require.NoError(t, plugin.Start(h))
+	defer plugin.Stop()
+
+	tags := map[string]string{"dns_name": "_metrics._tcp.example.com"}
+	require.Equal(t, map[string]telegraf.Target{
+		"a.example.com:9100": {Address: "a.example.com:9100", Tags: tags},
+		"b.example.com:9101": {Address: "b.example.com:9101", Tags: tags},
+	}, h.targets)
+
+	records = records[1:]
+	plugin.refresh()
+	require.Len(t, h.targets, 1)
+
+	// Targets are kept when the lookup fails.
+	err = errors.New("no such host")
+	plugin.refresh()
+	require.Len(t, h.targets, 1)
+}
+
+func TestA(t *testing.T) {
+	defer func() { lookupIP = net.LookupIP }()
+
+	lookupIP = func(host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("::1")}, nil
+	}
+
+	plugin := &DNS{
+		Names:      []string{"node.example.com"},
+		RecordType: "A",
+		Port:       161,
+	}
+	plugin.RefreshInterval.Duration = time.Hour
+	require.NoError(t, plugin.Init())
+	targets, err := plugin.resolve("node.example.com")
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	require.Equal(t, "10.0.0.1:161", targets[0].Address)
+
+	plugin.RecordType = "AAAA"
+	targets, err = plugin.resolve("node.example.com")
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	require.Equal(t, "[::1]:161", targets[0].Address)
+}
+
+func TestInitErrors(t *testing.T) {
+	plugin := &DNS{RecordType: "SRV"}
+	require.Error(t, plugin.Init())
+
+	plugin = &DNS{Names: []string{"example.com"}, RecordType: "MX"}
+	require.Error(t, plugin.Init())
+
+	plugin = &DNS{Names: []string{"example.com"}, RecordType: "A"}
+	require.Error(t, plugin.Init())
+
+	plugin = &DNS{Names: []string{"example.com"}, RecordType: "SRV"}
+	require.Error(t, plugin.Init())
+}
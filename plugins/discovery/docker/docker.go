@@ -0,0 +1,244 @@
+package docker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/discovery"
+)
+
+const sampleConfig = `
+  ## Docker Endpoint
+  ##   To use TCP, set endpoint = "tcp://[ip]:[port]"
+  ##   To use environment variables (ie, docker-machine), set endpoint = "ENV"
+  endpoint = "unix:///var/run/docker.sock"
+
+  ## Running containers with this label are targets, the value of the label
+  ## is the port of the target.
+  # port_label = "telegraf.port"
+
+  ## Labels starting with this prefix are added as tags to the target, with
+  ## the prefix removed.
+  # tag_label_prefix = "telegraf.tag."
+
+  ## Network to take the container address from, if empty the first network
+  ## of the container with an address is used.
+  # network = ""
+
+  ## Interval to list the containers again.
+  # refresh_interval = "30s"
+
+  ## Timeout for docker list commands.
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+type Docker struct {
+	Endpoint        string            `toml:"endpoint"`
+	PortLabel       string            `toml:"port_label"`
+	TagLabelPrefix  string            `toml:"tag_label_prefix"`
+	Network         string            `toml:"network"`
+	RefreshInterval internal.Duration `toml:"refresh_interval"`
+	Timeout         internal.Duration `toml:"timeout"`
+	tlsint.ClientConfig
+
+	newEnvClient func() (Client, error)
+	newClient    func(string, *tls.Config) (Client, error)
+
+	client  Client
+	tracker *discovery.Tracker
+	targets []telegraf.Target
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func (*Docker) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*Docker) Description() string {
+	return "Discover targets from the labels of Docker containers"
+}
+
+func (d *Docker) Init() error {
+	if d.PortLabel == "" {
+		return fmt.Errorf("port_label must be set")
+	}
+	if d.RefreshInterval.Duration <= 0 {
+		return fmt.Errorf("refresh_interval must be positive")
+	}
+
+	if d.Endpoint == "ENV" {
+		client, err := d.newEnvClient()
+		if err != nil {
+			return err
+		}
+		d.client = client
+		return nil
+	}
+
+	tlsConfig, err := d.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	client, err := d.newClient(d.Endpoint, tlsConfig)
+	if err != nil {
+		return err
+	}
+	d.client = client
+	return nil
+}
+
+func (d *Docker) Start(handler telegraf.TargetHandler) error {
+	d.tracker = discovery.NewTracker(handler)
+	d.refresh()
+
+	var ctx context.Context
+	ctx, d.cancel = context.WithCancel(context.Background())
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(d.RefreshInterval.Duration):
+				d.refresh()
+			}
+		}
+	}()
+	return nil
+}
+
+func (d *Docker) Stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+// refresh lists the containers.  The targets are kept from the previous
+// refresh if the containers can not be listed.
+func (d *Docker) refresh() {
+	targets, err := d.listTargets()
+	if err != nil {
+		log.Printf("E! [discovery.docker] Could not list containers: %v", err)
+		targets = d.targets
+	}
+	d.targets = targets
+	d.tracker.Update(targets)
+}
+
+// listTargets returns the targets of the running containers with the port
+// label.
+func (d *Docker) listTargets() ([]telegraf.Target, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d.Timeout.Duration)
+	defer cancel()
+
+	opts := types.ContainerListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("status", "running"),
+			filters.Arg("label", d.PortLabel),
+		),
+	}
+	containers, err := d.client.ContainerList(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []telegraf.Target
+	for _, container := range containers {
+		port, ok := container.Labels[d.PortLabel]
+		if !ok {
+			continue
+		}
+
+		name := container.ID
+		if len(container.Names) > 0 {
+			name = strings.TrimPrefix(container.Names[0], "/")
+		}
+
+		ip := d.containerIP(container)
+		if ip == "" {
+			log.Printf("W! [discovery.docker] No address found for container %s", name)
+			continue
+		}
+
+		tags := map[string]string{
+			"container_name":  name,
+			"container_image": container.Image,
+		}
+		if d.TagLabelPrefix != "" {
+			for k, v := range container.Labels {
+				if strings.HasPrefix(k, d.TagLabelPrefix) {
+					tags[strings.TrimPrefix(k, d.TagLabelPrefix)] = v
+				}
+			}
+		}
+
+		targets = append(targets, telegraf.Target{
+			Address: net.JoinHostPort(ip, port),
+			Tags:    tags,
+		})
+	}
+	return targets, nil
+}
+
+// containerIP returns the address of the container in the configured
+// network, or in the first network by name with an address.
+func (d *Docker) containerIP(container types.Container) string {
+	if container.NetworkSettings == nil {
+		return ""
+	}
+	networks := container.NetworkSettings.Networks
+
+	if d.Network != "" {
+		if network, ok := networks[d.Network]; ok && network != nil {
+			return network.IPAddress
+		}
+		return ""
+	}
+
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if network := networks[name]; network != nil && network.IPAddress != "" {
+			return network.IPAddress
+		}
+	}
+	return ""
+}
+
+func init() {
+	discovery.Add("docker", func() telegraf.Discovery {
+		return &Docker{
+			Endpoint:        "unix:///var/run/docker.sock",
+			PortLabel:       "telegraf.port",
+			TagLabelPrefix:  "telegraf.tag.",
+			RefreshInterval: internal.Duration{Duration: 30 * time.Second},
+			Timeout:         internal.Duration{Duration: 5 * time.Second},
+			newEnvClient:    NewEnvClient,
+			newClient:       NewClient,
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package docker
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/require"
+)
+
+type mockClient struct {
+	containers []types.Container
+	err        error
+}
+
+func (c *mockClient) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	return c.containers, c.err
+}
+
+type handler struct {
+	targets map[string]telegraf.Target
+}
+
+func (h *handler) AddTarget(target telegraf.Target) {
+	h.targets[target.Address] = target
+}
+
+func (h *handler) RemoveTarget(target telegraf.Target) {
+	delete(h.targets, target.Address)
+}
+
+func container(name, ip string, labels map[string]string) types.Container {
+	return types.Container{
+		ID:     name + "-id",
+		Names:  []string{"/" + name},
+		Image:  "app:1.0",
+		Labels: labels,
+		NetworkSettings: &types.SummaryNetworkSettings{
+			Networks: map[string]*network.EndpointSettings{
+				"bridge": {IPAddress: ip},
+			},
+		},
+	}
+}
+
+func newPlugin(client Client) *Docker {
+	plugin := &Docker{
+		Endpoint:       "unix:///var/run/docker.sock",
+		PortLabel:      "telegraf.port",
+		TagLabelPrefix: "telegraf.tag.",
+		newClient: func(string, *tls.Config) (Client, error) {
+			return client, nil
+		},
+	}
+	plugin.RefreshInterval.Duration = time.Hour
+	plugin.Timeout.Duration = time.Second
+	return plugin
+}
+
+func TestDocker(t *testing.T) {
+	client := &mockClient{
+		containers: []types.Container{
+			container("web", "172.17.0.2", map[string]string{
+				"telegraf.port":    "8080",
+				"telegraf.tag.env": "prod",
+				"maintainer":       "ops",
+			}),
+			container("db", "", map[string]string{"telegraf.port": "9187"}),
+		},
+	}
+
+	plugin := newPlugin(client)
+	require.NoError(t, plugin.Init())
+
+	h := &handler{targets: map[string]telegraf.Target{}}
+	require.NoError(t, plugin.Start(h))
+	defer plugin.Stop()
+
+	require.Equal(t, map[string]telegraf.Target{
+		"172.17.0.2:8080": {
+			Address: "172.17.0.2:8080",
+			Tags: map[string]string{
+				"container_name":  "web",
+				"container_image": "app:1.0",
+				"env":             "prod",
+			},
+		},
+	}, h.targets)
+
+	// Targets are kept when the containers can not be listed.
+	client.err = errors.New("connection refused")
+	plugin.refresh()
+	require.Len(t, h.targets, 1)
+
+	client.err = nil
+	client.containers = nil
+	plugin.refresh()
+	require.Empty(t, h.targets)
+}
+
+func TestNetwork(t *testing.T) {
+	c := container("web", "172.17.0.2", nil)
+	c.NetworkSettings.Networks["backend"] = &network.EndpointSettings{IPAddress: "10.0.0.2"}
+
+	plugin := newPlugin(&mockClient{})
+	require.Equal(t, "10.0.0.2", plugin.containerIP(c))
+
+	plugin.Network = "bridge"
+	require.Equal(t, "172.17.0.2", plugin.containerIP(c))
+
+	plugin.Network = "frontend"
+	require.Equal(t, "", plugin.containerIP(c))
+}
+
+func TestInitErrors(t *testing.T) {
+	plugin := newPlugin(&mockClient{})
+	plugin.PortLabel = ""
+	require.Error(t, plugin.Init())
+
+	plugin = newPlugin(&mockClient{})
+	plugin.RefreshInterval.Duration = 0
+	require.Error(t, plugin.Init())
+}
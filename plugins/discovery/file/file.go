@@ -0,0 +1,208 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/discovery"
+)
+
+const sampleConfig = `
+  ## Files containing the targets, glob patterns are supported.  The files
+  ## are in the Prometheus file_sd format, either JSON or YAML:
+  ##   [{"targets": ["10.0.0.1:9100"], "labels": {"env": "prod"}}]
+  files = ["/etc/telegraf/targets/*.json"]
+
+  ## Interval to reread the files.
+  # refresh_interval = "1m"
+`
+
+// targetGroup is a group of targets in the Prometheus file_sd format.
+type targetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+type File struct {
+	Files           []string          `toml:"files"`
+	RefreshInterval internal.Duration `toml:"refresh_interval"`
+
+	tracker *discovery.Tracker
+	targets map[string][]telegraf.Target
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func (*File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (*File) Description() string {
+	return "Discover targets from files in the Prometheus file_sd format"
+}
+
+func (f *File) Init() error {
+	if len(f.Files) == 0 {
+		return fmt.Errorf("no files configured")
+	}
+	if f.RefreshInterval.Duration <= 0 {
+		return fmt.Errorf("refresh_interval must be positive")
+	}
+	for _, pattern := range f.Files {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+func (f *File) Start(handler telegraf.TargetHandler) error {
+	f.tracker = discovery.NewTracker(handler)
+	f.targets = make(map[string][]telegraf.Target)
+	f.refresh()
+
+	var ctx context.Context
+	ctx, f.cancel = context.WithCancel(context.Background())
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(f.RefreshInterval.Duration):
+				f.refresh()
+			}
+		}
+	}()
+	return nil
+}
+
+func (f *File) Stop() {
+	f.cancel()
+	f.wg.Wait()
+}
+
+// refresh reads the files matching the patterns.  The targets of files that
+// can not be read are kept from the previous refresh.
+func (f *File) refresh() {
+	files := make(map[string][]telegraf.Target)
+	for _, pattern := range f.Files {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Printf("E! [discovery.file] Invalid pattern %q: %v", pattern, err)
+			continue
+		}
+
+		for _, file := range matches {
+			targets, err := readFile(file)
+			if err != nil {
+				log.Printf("E! [discovery.file] Could not read targets from %s: %v", file, err)
+				targets = f.targets[file]
+			}
+			files[file] = targets
+		}
+	}
+	f.targets = files
+
+	var targets []telegraf.Target
+	for _, t := range files {
+		targets = append(targets, t...)
+	}
+	f.tracker.Update(targets)
+}
+
+// readFile reads the target groups of a JSON or YAML file.
+func readFile(file string) ([]telegraf.Target, error) {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json", ".yml", ".yaml":
+	default:
+		return nil, fmt.Errorf("unsupported file extension, expected .json, .yml or .yaml")
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON, so both formats are parsed as YAML.
+	var groups []targetGroup
+	if err := yaml.Unmarshal(data, &groups); err != nil {
+		return nil, err
+	}
+
+	var targets []telegraf.Target
+	for _, group := range groups {
+		tags := make(map[string]string, len(group.Labels))
+		for k, v := range group.Labels {
+			// Labels starting with a double underscore are reserved.
+			if strings.HasPrefix(k, "__") {
+				continue
+			}
+			tags[k] = v
+		}
+
+		for _, address := range group.Targets {
+			address, err := targetAddress(address, group.Labels)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, telegraf.Target{Address: address, Tags: tags})
+		}
+	}
+	return targets, nil
+}
+
+// targetAddress returns the address of the target.  The __scheme__,
+// __metrics_path__ and __param_<name> labels turn the address into the URL
+// to scrape, defaulting to http and /metrics like Prometheus.
+func targetAddress(address string, labels map[string]string) (string, error) {
+	if address == "" {
+		return "", fmt.Errorf("empty target address")
+	}
+
+	u := &url.URL{Scheme: "http", Host: address, Path: "/metrics"}
+	params := url.Values{}
+	isURL := false
+	for k, v := range labels {
+		switch {
+		case k == "__scheme__":
+			u.Scheme = v
+		case k == "__metrics_path__":
+			u.Path = v
+		case strings.HasPrefix(k, "__param_"):
+			params.Set(strings.TrimPrefix(k, "__param_"), v)
+		default:
+			continue
+		}
+		isURL = true
+	}
+	if !isURL {
+		return address, nil
+	}
+
+	if strings.Contains(address, "/") {
+		return "", fmt.Errorf("invalid target address %q", address)
+	}
+	u.RawQuery = params.Encode()
+	return u.String(), nil
+}
+
+func init() {
+	discovery.Add("file", func() telegraf.Discovery {
+		return &File{
+			RefreshInterval: internal.Duration{Duration: time.Minute},
+		}
+	})
+}
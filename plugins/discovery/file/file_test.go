@@ -0,0 +1,113 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/require"
+)
+
+type handler struct {
+	targets map[string]telegraf.Target
+}
+
+func (h *handler) AddTarget(target telegraf.Target) {
+	h.targets[target.Address] = target
+}
+
+func (h *handler) RemoveTarget(target telegraf.Target) {
+	delete(h.targets, target.Address)
+}
+
+func (h *handler) addresses() []string {
+	var addresses []string
+	for address := range h.targets {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "discovery")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	jsonFile := filepath.Join(dir, "targets.json")
+	require.NoError(t, ioutil.WriteFile(jsonFile, []byte(`[
+		{"targets": ["a:9100", "b:9100"], "labels": {"env": "prod", "__scheme__": "https"}}
+	]`), 0644))
+
+	yamlFile := filepath.Join(dir, "targets.yaml")
+	require.NoError(t, ioutil.WriteFile(yamlFile, []byte(`
+- targets:
+    - c:161
+`), 0644))
+
+	plugin := &File{Files: []string{filepath.Join(dir, "*")}}
+	plugin.RefreshInterval.Duration = time.Hour
+	require.NoError(t, plugin.Init())
+
+	h := &handler{targets: map[string]telegraf.Target{}}
+	require.NoError(t, plugin.Start(h))
+	defer plugin.Stop()
+
+	require.Equal(t, []string{"c:161", "https://a:9100/metrics", "https://b:9100/metrics"}, h.addresses())
+	require.Equal(t, map[string]string{"env": "prod"}, h.targets["https://a:9100/metrics"].Tags)
+
+	// Targets of invalid files are kept, targets of removed files removed.
+	require.NoError(t, ioutil.WriteFile(yamlFile, []byte(`- targets: [`), 0644))
+	require.NoError(t, os.Remove(jsonFile))
+	plugin.refresh()
+	require.Equal(t, []string{"c:161"}, h.addresses())
+}
+
+func TestTargetAddress(t *testing.T) {
+	address, err := targetAddress("a:9100", map[string]string{"env": "prod"})
+	require.NoError(t, err)
+	require.Equal(t, "a:9100", address)
+
+	address, err = targetAddress("c:8080", map[string]string{
+		"__scheme__":       "https",
+		"__metrics_path__": "/stats",
+		"__param_format":   "text",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://c:8080/stats?format=text", address)
+
+	_, err = targetAddress("http://c:8080/", map[string]string{"__metrics_path__": "/stats"})
+	require.Error(t, err)
+}
+
+func TestReadFileErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "discovery")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = readFile(filepath.Join(dir, "targets.txt"))
+	require.Error(t, err)
+
+	_, err = readFile(filepath.Join(dir, "missing.json"))
+	require.Error(t, err)
+
+	empty := filepath.Join(dir, "empty.json")
+	require.NoError(t, ioutil.WriteFile(empty, []byte(`[{"targets": [""]}]`), 0644))
+	_, err = readFile(empty)
+	require.Error(t, err)
+}
+
+func TestInitErrors(t *testing.T) {
+	plugin := &File{}
+	require.Error(t, plugin.Init())
+
+	plugin = &File{Files: []string{"["}}
+	require.Error(t, plugin.Init())
+
+	plugin = &File{Files: []string{"targets.json"}}
+	require.Error(t, plugin.Init())
+}
@@ -0,0 +1,11 @@
+package discovery
+
+import "github.com/influxdata/telegraf"
+
+type Creator func() telegraf.Discovery
+
+var Discoveries = map[string]Creator{}
+
+func Add(name string, creator Creator) {
+	Discoveries[name] = creator
+}
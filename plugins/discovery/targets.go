@@ -0,0 +1,103 @@
+package discovery
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Targets is the set of discovered targets of an input.  It is safe for
+// concurrent use.
+type Targets struct {
+	sync.Mutex
+	targets map[targetKey]telegraf.Target
+}
+
+// targetKey identifies a target, an address found by several discoveries
+// is kept until all of them removed it.
+type targetKey struct {
+	source  string
+	address string
+}
+
+func keyOf(target telegraf.Target) targetKey {
+	return targetKey{source: target.Source, address: target.Address}
+}
+
+// Add adds or updates the target.
+func (t *Targets) Add(target telegraf.Target) {
+	t.Lock()
+	defer t.Unlock()
+	if t.targets == nil {
+		t.targets = make(map[targetKey]telegraf.Target)
+	}
+	t.targets[keyOf(target)] = target
+}
+
+// Remove removes the target.
+func (t *Targets) Remove(target telegraf.Target) {
+	t.Lock()
+	defer t.Unlock()
+	delete(t.targets, keyOf(target))
+}
+
+// List returns the targets sorted by address and source.  An address found
+// by several discoveries is listed once for each of them.
+func (t *Targets) List() []telegraf.Target {
+	t.Lock()
+	defer t.Unlock()
+	targets := make([]telegraf.Target, 0, len(t.targets))
+	for _, target := range t.targets {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Address != targets[j].Address {
+			return targets[i].Address < targets[j].Address
+		}
+		return targets[i].Source < targets[j].Source
+	})
+	return targets
+}
+
+// Tracker reports the changes between successive lists of targets to a
+// TargetHandler, for discovery plugins that periodically list all targets.
+type Tracker struct {
+	handler telegraf.TargetHandler
+	targets map[string]telegraf.Target
+}
+
+// NewTracker returns a Tracker reporting to the handler.
+func NewTracker(handler telegraf.TargetHandler) *Tracker {
+	return &Tracker{
+		handler: handler,
+		targets: make(map[string]telegraf.Target),
+	}
+}
+
+// Update adds the new and changed targets and removes the targets no longer
+// listed.
+func (t *Tracker) Update(targets []telegraf.Target) {
+	current := make(map[string]telegraf.Target, len(targets))
+	for _, target := range targets {
+		current[target.Address] = target
+	}
+
+	for address, target := range t.targets {
+		if _, ok := current[address]; !ok {
+			t.handler.RemoveTarget(target)
+		}
+	}
+	for address, target := range current {
+		if prev, ok := t.targets[address]; !ok || !reflect.DeepEqual(prev.Tags, target.Tags) {
+			t.handler.AddTarget(target)
+		}
+	}
+	t.targets = current
+}
+
+// Clear removes all targets.
+func (t *Tracker) Clear() {
+	t.Update(nil)
+}
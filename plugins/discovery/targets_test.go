@@ -0,0 +1,84 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/require"
+)
+
+type handler struct {
+	events []string
+}
+
+func (h *handler) AddTarget(target telegraf.Target) {
+	h.events = append(h.events, "add "+target.Address)
+}
+
+func (h *handler) RemoveTarget(target telegraf.Target) {
+	h.events = append(h.events, "remove "+target.Address)
+}
+
+func TestTargets(t *testing.T) {
+	var targets Targets
+	require.Empty(t, targets.List())
+
+	targets.Add(telegraf.Target{Address: "b:80"})
+	targets.Add(telegraf.Target{Address: "a:80"})
+	targets.Add(telegraf.Target{Address: "b:80", Tags: map[string]string{"env": "prod"}})
+	require.Equal(t, []telegraf.Target{
+		{Address: "a:80"},
+		{Address: "b:80", Tags: map[string]string{"env": "prod"}},
+	}, targets.List())
+
+	targets.Remove(telegraf.Target{Address: "a:80"})
+	targets.Remove(telegraf.Target{Address: "c:80"})
+	require.Equal(t, []telegraf.Target{
+		{Address: "b:80", Tags: map[string]string{"env": "prod"}},
+	}, targets.List())
+}
+
+func TestTargetsSources(t *testing.T) {
+	var targets Targets
+	targets.Add(telegraf.Target{Address: "a:80", Source: "file#2"})
+	targets.Add(telegraf.Target{Address: "a:80", Source: "dns#1"})
+	require.Equal(t, []telegraf.Target{
+		{Address: "a:80", Source: "dns#1"},
+		{Address: "a:80", Source: "file#2"},
+	}, targets.List())
+
+	// Removing the target of one discovery keeps the others.
+	targets.Remove(telegraf.Target{Address: "a:80", Source: "dns#1"})
+	require.Equal(t, []telegraf.Target{
+		{Address: "a:80", Source: "file#2"},
+	}, targets.List())
+}
+
+func TestTracker(t *testing.T) {
+	h := &handler{}
+	tracker := NewTracker(h)
+
+	tracker.Update([]telegraf.Target{{Address: "a:80"}})
+	require.Equal(t, []string{"add a:80"}, h.events)
+
+	// Unchanged targets are not added again, changed tags update the target.
+	h.events = nil
+	tracker.Update([]telegraf.Target{
+		{Address: "a:80", Tags: map[string]string{"env": "prod"}},
+	})
+	require.Equal(t, []string{"add a:80"}, h.events)
+
+	h.events = nil
+	tracker.Update([]telegraf.Target{
+		{Address: "a:80", Tags: map[string]string{"env": "prod"}},
+	})
+	require.Empty(t, h.events)
+
+	h.events = nil
+	tracker.Update([]telegraf.Target{{Address: "b:80"}})
+	require.Equal(t, []string{"remove a:80", "add b:80"}, h.events)
+
+	h.events = nil
+	tracker.Clear()
+	require.Equal(t, []string{"remove b:80"}, h.events)
+}
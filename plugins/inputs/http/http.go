@@ -12,6 +12,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/discovery"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
 )
@@ -33,6 +34,9 @@ type HTTP struct {
 
 	client *http.Client
 
+	// targets found by discovery plugins
+	targets discovery.Targets
+
 	// The parser will automatically be set by Telegraf core code because
 	// this plugin implements the ParserInput interface (i.e. the SetParser method)
 	parser parsers.Parser
@@ -112,17 +116,41 @@ func (h *HTTP) Gather(acc telegraf.Accumulator) error {
 		wg.Add(1)
 		go func(url string) {
 			defer wg.Done()
-			if err := h.gatherURL(acc, url); err != nil {
+			if err := h.gatherURL(acc, url, nil); err != nil {
 				acc.AddError(fmt.Errorf("[url=%s]: %s", url, err))
 			}
 		}(u)
 	}
+	for _, target := range h.targets.List() {
+		wg.Add(1)
+		go func(target telegraf.Target) {
+			defer wg.Done()
+			url := target.Address
+			if !strings.Contains(url, "://") {
+				url = "http://" + url
+			}
+			if err := h.gatherURL(acc, url, target.Tags); err != nil {
+				acc.AddError(fmt.Errorf("[url=%s]: %s", url, err))
+			}
+		}(target)
+	}
 
 	wg.Wait()
 
 	return nil
 }
 
+// AddTarget adds a target found by a discovery plugin, addresses without a
+// scheme are requested over http.
+func (h *HTTP) AddTarget(target telegraf.Target) {
+	h.targets.Add(target)
+}
+
+// RemoveTarget removes a target found by a discovery plugin.
+func (h *HTTP) RemoveTarget(target telegraf.Target) {
+	h.targets.Remove(target)
+}
+
 // SetParser takes the data_format from the config and finds the right parser for that format
 func (h *HTTP) SetParser(parser parsers.Parser) {
 	h.parser = parser
@@ -132,12 +160,14 @@ func (h *HTTP) SetParser(parser parsers.Parser) {
 // Parameters:
 //     acc    : The telegraf Accumulator to use
 //     url    : endpoint to send request to
+//     tags   : tags to add to the metrics
 //
 // Returns:
 //     error: Any error that may have occurred
 func (h *HTTP) gatherURL(
 	acc telegraf.Accumulator,
 	url string,
+	tags map[string]string,
 ) error {
 	body, err := makeRequestBodyReader(h.ContentEncoding, h.Body)
 	if err != nil {
@@ -193,6 +223,9 @@ func (h *HTTP) gatherURL(
 		if !metric.HasTag("url") {
 			metric.AddTag("url", url)
 		}
+		for k, v := range tags {
+			metric.AddTag(k, v)
+		}
 		acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
 	}
 
@@ -6,8 +6,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/influxdata/telegraf"
 	plugin "github.com/influxdata/telegraf/plugins/inputs/http"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
@@ -228,3 +230,34 @@ func TestBodyAndContentEncoding(t *testing.T) {
 		})
 	}
 }
+
+func TestDiscoveredTargets(t *testing.T) {
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(simpleJSON))
+	}))
+	defer fakeServer.Close()
+
+	address := strings.TrimPrefix(fakeServer.URL, "http://")
+	plugin := &plugin.HTTP{}
+	plugin.AddTarget(telegraf.Target{
+		Address: address,
+		Tags:    map[string]string{"env": "prod"},
+	})
+
+	p, _ := parsers.NewParser(&parsers.Config{
+		DataFormat: "json",
+		MetricName: "metricName",
+	})
+	plugin.SetParser(p)
+
+	var acc testutil.Accumulator
+	plugin.Init()
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, map[string]string{"url": fakeServer.URL, "env": "prod"}, acc.Metrics[0].Tags)
+
+	plugin.RemoveTarget(telegraf.Target{Address: address})
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(plugin.Gather))
+	require.Empty(t, acc.Metrics)
+}
@@ -0,0 +1,428 @@
+package http_response
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptrace"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/tidwall/gjson"
+)
+
+// variableRe matches the references to extracted values, like {{token}}
+var variableRe = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// Check is a scripted sequence of requests.  The steps of a check share
+// cookies, and values extracted from the response of a step can be used in
+// the url, body and headers of the following steps.
+type Check struct {
+	Name  string
+	Steps []Step `toml:"step"`
+}
+
+// Step is a single request of a check and the assertions on its response.
+type Step struct {
+	Name                string
+	URL                 string `toml:"url"`
+	Method              string
+	Body                string
+	Headers             map[string]string
+	ExpectedStatusCode  int `toml:"expected_status_code"`
+	ResponseStringMatch string
+	MaxResponseTime     internal.Duration
+	Extract             []Extract `toml:"extract"`
+
+	compiledStringMatch *regexp.Regexp
+}
+
+// Extract stores a value of the response of a step in a variable.  The value
+// is taken from the JSON body, the first submatch of a regex on the body or a
+// response header.
+type Extract struct {
+	Name     string
+	JSONPath string `toml:"json_path"`
+	Regex    string
+	Header   string
+
+	compiledRegex *regexp.Regexp
+}
+
+// compile checks the configuration of the check, and that all variables are
+// extracted by a previous step before they are used.
+func (c *Check) compile() error {
+	if c.Name == "" {
+		return errors.New("check name is required")
+	}
+	if len(c.Steps) == 0 {
+		return fmt.Errorf("check %q has no steps", c.Name)
+	}
+
+	defined := make(map[string]bool)
+	for i := range c.Steps {
+		step := &c.Steps[i]
+		if step.Name == "" {
+			step.Name = strconv.Itoa(i + 1)
+		}
+		if step.URL == "" {
+			return fmt.Errorf("check %q step %q: url is required", c.Name, step.Name)
+		}
+		if step.Method == "" {
+			step.Method = "GET"
+		}
+
+		templates := []string{step.URL, step.Body}
+		for _, v := range step.Headers {
+			templates = append(templates, v)
+		}
+		for _, template := range templates {
+			for _, match := range variableRe.FindAllStringSubmatch(template, -1) {
+				if !defined[match[1]] {
+					return fmt.Errorf("check %q step %q: variable %q is not extracted by a previous step",
+						c.Name, step.Name, match[1])
+				}
+			}
+		}
+
+		if step.ResponseStringMatch != "" {
+			re, err := regexp.Compile(step.ResponseStringMatch)
+			if err != nil {
+				return fmt.Errorf("check %q step %q: %s", c.Name, step.Name, err)
+			}
+			step.compiledStringMatch = re
+		}
+
+		for j := range step.Extract {
+			e := &step.Extract[j]
+			if e.Name == "" {
+				return fmt.Errorf("check %q step %q: extract name is required", c.Name, step.Name)
+			}
+
+			sources := 0
+			for _, source := range []string{e.JSONPath, e.Regex, e.Header} {
+				if source != "" {
+					sources++
+				}
+			}
+			if sources != 1 {
+				return fmt.Errorf("check %q step %q: extract %q requires one of json_path, regex or header",
+					c.Name, step.Name, e.Name)
+			}
+
+			if e.Regex != "" {
+				re, err := regexp.Compile(e.Regex)
+				if err != nil {
+					return fmt.Errorf("check %q step %q: extract %q: %s", c.Name, step.Name, e.Name, err)
+				}
+				e.compiledRegex = re
+			}
+			defined[e.Name] = true
+		}
+	}
+	return nil
+}
+
+// value returns the extracted value from the response.
+func (e *Extract) value(resp *http.Response, body []byte) (string, error) {
+	switch {
+	case e.JSONPath != "":
+		result := gjson.GetBytes(body, e.JSONPath)
+		if !result.Exists() {
+			return "", fmt.Errorf("json path %q not found", e.JSONPath)
+		}
+		return result.String(), nil
+	case e.Header != "":
+		values, ok := resp.Header[http.CanonicalHeaderKey(e.Header)]
+		if !ok || len(values) == 0 {
+			return "", fmt.Errorf("header %q not found", e.Header)
+		}
+		return values[0], nil
+	default:
+		match := e.compiledRegex.FindSubmatch(body)
+		if match == nil {
+			return "", fmt.Errorf("regex %q does not match", e.Regex)
+		}
+		if len(match) > 1 {
+			return string(match[1]), nil
+		}
+		return string(match[0]), nil
+	}
+}
+
+// expand replaces the variable references with their values.
+func expand(s string, vars map[string]string) string {
+	return variableRe.ReplaceAllStringFunc(s, func(ref string) string {
+		return vars[variableRe.FindStringSubmatch(ref)[1]]
+	})
+}
+
+// stepTrace records the durations of the phases of a request.  When
+// redirects are followed the durations of all requests are summed.
+type stepTrace struct {
+	sync.Mutex
+	start time.Time
+
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+
+	dns       time.Duration
+	connect   time.Duration
+	tls       time.Duration
+	firstByte time.Duration
+
+	resolved  bool
+	connected bool
+	handshake bool
+}
+
+func (t *stepTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.Lock()
+			defer t.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.Lock()
+			defer t.Unlock()
+			t.dns += time.Since(t.dnsStart)
+			t.resolved = true
+		},
+		ConnectStart: func(string, string) {
+			t.Lock()
+			defer t.Unlock()
+			t.connectStart = time.Now()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.Lock()
+			defer t.Unlock()
+			if err == nil {
+				t.connect += time.Since(t.connectStart)
+				t.connected = true
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.Lock()
+			defer t.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			t.Lock()
+			defer t.Unlock()
+			if err == nil {
+				t.tls += time.Since(t.tlsStart)
+				t.handshake = true
+			}
+		},
+		GotFirstResponseByte: func() {
+			t.Lock()
+			defer t.Unlock()
+			t.firstByte = time.Since(t.start)
+		},
+	}
+}
+
+func (t *stepTrace) addFields(fields map[string]interface{}) {
+	t.Lock()
+	defer t.Unlock()
+	if t.resolved {
+		fields["dns_lookup_time"] = t.dns.Seconds()
+	}
+	if t.connected {
+		fields["tcp_connect_time"] = t.connect.Seconds()
+	}
+	if t.handshake {
+		fields["tls_handshake_time"] = t.tls.Seconds()
+	}
+	if t.firstByte > 0 {
+		fields["first_byte_time"] = t.firstByte.Seconds()
+	}
+}
+
+// timingFields are the fields of the request phases, summed over the steps
+// for the check.
+var timingFields = []string{
+	"dns_lookup_time",
+	"tcp_connect_time",
+	"tls_handshake_time",
+	"first_byte_time",
+}
+
+// runCheck runs the steps of the check in order, stopping at the first step
+// that fails, and adds a metric for each step and one for the whole check.
+func (h *HTTPResponse) runCheck(check *Check, acc telegraf.Accumulator) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		acc.AddError(err)
+		return
+	}
+	client := *h.client
+	client.Jar = jar
+
+	vars := make(map[string]string)
+	result := "success"
+	passed := 0
+	totals := make(map[string]float64)
+
+	start := time.Now()
+	for i := range check.Steps {
+		step := &check.Steps[i]
+		fields, tags := h.runStep(&client, step, vars)
+		tags["check"] = check.Name
+		tags["step"] = step.Name
+		acc.AddFields("http_response_step", fields, tags)
+
+		for _, name := range timingFields {
+			if v, ok := fields[name].(float64); ok {
+				totals[name] += v
+			}
+		}
+
+		if tags["result"] != "success" {
+			result = tags["result"]
+			break
+		}
+		passed++
+	}
+
+	fields := map[string]interface{}{
+		"response_time": time.Since(start).Seconds(),
+		"steps_passed":  passed,
+	}
+	for name, total := range totals {
+		fields[name] = total
+	}
+	tags := map[string]string{"check": check.Name}
+	setResult(result, fields, tags)
+	acc.AddFields("http_response_check", fields, tags)
+}
+
+// runStep requests a step and checks the response, storing the extracted
+// values in vars.  The returned result tag is "success" if all assertions
+// passed.  The server tag is the configured url, so that it does not change
+// with the values of the variables.
+func (h *HTTPResponse) runStep(
+	client *http.Client,
+	step *Step,
+	vars map[string]string,
+) (map[string]interface{}, map[string]string) {
+	u := expand(step.URL, vars)
+	fields := make(map[string]interface{})
+	tags := map[string]string{"server": step.URL, "method": step.Method}
+
+	var body io.Reader
+	if step.Body != "" {
+		body = strings.NewReader(expand(step.Body, vars))
+	}
+	request, err := http.NewRequest(step.Method, u, body)
+	if err != nil {
+		log.Printf("D! [inputs.http_response] Invalid request for step %s: %s", step.Name, err)
+		setResult("connection_failed", fields, tags)
+		return fields, tags
+	}
+
+	headers := make(map[string]string)
+	for key, val := range h.Headers {
+		headers[key] = val
+	}
+	for key, val := range step.Headers {
+		headers[key] = expand(val, vars)
+	}
+	for key, val := range headers {
+		request.Header.Add(key, val)
+		if key == "Host" {
+			request.Host = val
+		}
+	}
+
+	trace := &stepTrace{}
+	request = request.WithContext(httptrace.WithClientTrace(request.Context(), trace.clientTrace()))
+
+	trace.start = time.Now()
+	resp, err := client.Do(request)
+	redirected := false
+	if err != nil {
+		log.Printf("D! Network error while polling %s: %s", u, err.Error())
+
+		urlErr, isURLErr := err.(*url.Error)
+		if !h.FollowRedirects && isURLErr && urlErr.Err == ErrRedirectAttempted {
+			// The response of the redirect is returned with its body
+			// already closed
+			redirected = true
+			err = nil
+		} else {
+			trace.addFields(fields)
+			if result := errorResult(err); result != "" {
+				setResult(result, fields, tags)
+			} else {
+				setResult("connection_failed", fields, tags)
+			}
+			return fields, tags
+		}
+	}
+
+	var bodyBytes []byte
+	if !redirected {
+		bodyBytes, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	responseTime := time.Since(trace.start)
+
+	trace.addFields(fields)
+	fields["response_time"] = responseTime.Seconds()
+	fields["content_length"] = len(bodyBytes)
+	tags["status_code"] = strconv.Itoa(resp.StatusCode)
+	fields["http_response_code"] = resp.StatusCode
+
+	if err != nil {
+		log.Printf("D! Failed to read body of HTTP Response : %s", err)
+		setResult("body_read_error", fields, tags)
+		return fields, tags
+	}
+
+	if step.ExpectedStatusCode != 0 && resp.StatusCode != step.ExpectedStatusCode {
+		setResult("status_code_mismatch", fields, tags)
+		return fields, tags
+	}
+
+	if step.compiledStringMatch != nil {
+		if !step.compiledStringMatch.Match(bodyBytes) {
+			fields["response_string_match"] = 0
+			setResult("response_string_mismatch", fields, tags)
+			return fields, tags
+		}
+		fields["response_string_match"] = 1
+	}
+
+	if step.MaxResponseTime.Duration > 0 && responseTime > step.MaxResponseTime.Duration {
+		setResult("response_time_exceeded", fields, tags)
+		return fields, tags
+	}
+
+	for i := range step.Extract {
+		e := &step.Extract[i]
+		value, err := e.value(resp, bodyBytes)
+		if err != nil {
+			log.Printf("D! [inputs.http_response] Failed to extract %s in step %s: %s", e.Name, step.Name, err)
+			setResult("extract_failed", fields, tags)
+			return fields, tags
+		}
+		vars[e.Name] = value
+	}
+
+	setResult("success", fields, tags)
+	return fields, tags
+}
@@ -0,0 +1,339 @@
+package http_response
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/influxdata/toml"
+	"github.com/stretchr/testify/require"
+)
+
+func setUpCheckServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		w.Header().Set("X-Request-Id", "42")
+		fmt.Fprintf(w, `{"data": {"token": "secret-token"}}`)
+	})
+	mux.HandleFunc("/profile", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != "abc" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `<input name="csrf" value="x1y2z3"> request %s`, r.URL.Query().Get("id"))
+	})
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("csrf") != "x1y2z3" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		http.Redirect(w, r, "/login", http.StatusFound)
+	})
+	return httptest.NewServer(mux)
+}
+
+func loginCheck(url string) Check {
+	return Check{
+		Name: "login",
+		Steps: []Step{
+			{
+				Name:               "login",
+				URL:                url + "/login",
+				Method:             "POST",
+				Body:               `{"user": "telegraf"}`,
+				Headers:            map[string]string{"Content-Type": "application/json"},
+				ExpectedStatusCode: 200,
+				Extract: []Extract{
+					{Name: "token", JSONPath: "data.token"},
+					{Name: "id", Header: "X-Request-Id"},
+				},
+			},
+			{
+				Name:                "profile",
+				URL:                 url + "/profile?id={{id}}",
+				Headers:             map[string]string{"Authorization": "Bearer {{token}}"},
+				ExpectedStatusCode:  200,
+				ResponseStringMatch: "request 42",
+				Extract: []Extract{
+					{Name: "csrf", Regex: `name="csrf" value="(\w+)"`},
+				},
+			},
+			{
+				Name:               "logout",
+				URL:                url + "/logout",
+				Method:             "POST",
+				Body:               "csrf={{csrf}}",
+				Headers:            map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+				ExpectedStatusCode: 302,
+				Extract: []Extract{
+					{Name: "location", Header: "Location"},
+				},
+			},
+		},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ts := setUpCheckServer()
+	defer ts.Close()
+
+	h := &HTTPResponse{
+		URLs:            []string{ts.URL + "/login"},
+		Method:          "GET",
+		ResponseTimeout: internal.Duration{Duration: time.Second * 20},
+		Checks:          []Check{loginCheck(ts.URL)},
+	}
+	require.NoError(t, h.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+
+	var steps []string
+	for _, m := range acc.Metrics {
+		switch m.Measurement {
+		case "http_response_step":
+			require.Equal(t, "login", m.Tags["check"])
+			require.Equal(t, "success", m.Tags["result"], "step %s", m.Tags["step"])
+			require.Equal(t, 0, m.Fields["result_code"])
+			require.Equal(t, "success", m.Fields["result_type"])
+			require.Contains(t, m.Fields, "response_time")
+			require.Contains(t, m.Fields, "tcp_connect_time")
+			require.Contains(t, m.Fields, "first_byte_time")
+			steps = append(steps, m.Tags["step"])
+		case "http_response_check":
+			require.Equal(t, map[string]string{"check": "login", "result": "success"}, m.Tags)
+			require.Equal(t, 3, m.Fields["steps_passed"])
+			require.Equal(t, 0, m.Fields["result_code"])
+			require.Equal(t, "success", m.Fields["result_type"])
+			require.Contains(t, m.Fields, "tcp_connect_time")
+			require.Contains(t, m.Fields, "first_byte_time")
+			require.NotContains(t, m.Fields, "tls_handshake_time")
+		}
+	}
+	require.Equal(t, []string{"login", "profile", "logout"}, steps)
+
+	// the server tag is the configured url, not the one with the variables
+	// replaced
+	servers := make(map[string]bool)
+	for _, m := range acc.Metrics {
+		if m.Measurement == "http_response_step" {
+			servers[m.Tags["server"]] = true
+		}
+	}
+	require.True(t, servers[ts.URL+"/profile?id={{id}}"])
+	require.True(t, acc.HasMeasurement("http_response"))
+	require.True(t, acc.HasMeasurement("http_response_check"))
+}
+
+func TestCheckStopsAtFailedStep(t *testing.T) {
+	ts := setUpCheckServer()
+	defer ts.Close()
+
+	check := loginCheck(ts.URL)
+	check.Steps[0].Headers = nil
+
+	h := &HTTPResponse{
+		Method:          "GET",
+		ResponseTimeout: internal.Duration{Duration: time.Second * 20},
+		Checks:          []Check{check},
+	}
+	require.NoError(t, h.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+
+	require.False(t, acc.HasMeasurement("http_response"))
+	for _, m := range acc.Metrics {
+		if m.Measurement == "http_response_step" {
+			require.Equal(t, map[string]string{
+				"check":       "login",
+				"step":        "login",
+				"server":      ts.URL + "/login",
+				"method":      "POST",
+				"status_code": "400",
+				"result":      "status_code_mismatch",
+			}, m.Tags)
+			require.Equal(t, 6, m.Fields["result_code"])
+			require.Equal(t, 400, m.Fields["http_response_code"])
+			require.Equal(t, 0, m.Fields["content_length"])
+		}
+		if m.Measurement == "http_response_check" {
+			require.Equal(t, "status_code_mismatch", m.Tags["result"])
+			require.Equal(t, 0, m.Fields["steps_passed"])
+		}
+	}
+}
+
+func TestCheckExtractFailed(t *testing.T) {
+	ts := setUpCheckServer()
+	defer ts.Close()
+
+	check := loginCheck(ts.URL)
+	check.Steps[0].Extract[0].JSONPath = "data.missing"
+
+	h := &HTTPResponse{
+		Method:          "GET",
+		ResponseTimeout: internal.Duration{Duration: time.Second * 20},
+		Checks:          []Check{check},
+	}
+	require.NoError(t, h.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+
+	tags, ok := acc.Get("http_response_check")
+	require.True(t, ok)
+	require.Equal(t, "extract_failed", tags.Tags["result"])
+	require.Equal(t, 8, tags.Fields["result_code"])
+	require.Equal(t, "extract_failed", tags.Fields["result_type"])
+}
+
+func TestCheckTLSTiming(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "ok")
+	}))
+	defer ts.Close()
+
+	h := &HTTPResponse{
+		Method:          "GET",
+		ResponseTimeout: internal.Duration{Duration: time.Second * 20},
+		Checks: []Check{
+			{Name: "tls", Steps: []Step{{URL: ts.URL, ResponseStringMatch: "ok"}}},
+		},
+	}
+	h.InsecureSkipVerify = true
+	require.NoError(t, h.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, h.Gather(&acc))
+
+	m, ok := acc.Get("http_response_step")
+	require.True(t, ok)
+	require.Equal(t, "success", m.Tags["result"])
+	require.Equal(t, 1, m.Fields["response_string_match"])
+	require.Contains(t, m.Fields, "tls_handshake_time")
+	require.NotContains(t, m.Fields, "dns_lookup_time")
+
+	check, ok := acc.Get("http_response_check")
+	require.True(t, ok)
+	require.Equal(t, m.Fields["tls_handshake_time"], check.Fields["tls_handshake_time"])
+	require.Equal(t, m.Fields["first_byte_time"], check.Fields["first_byte_time"])
+	require.NotContains(t, check.Fields, "dns_lookup_time")
+}
+
+func TestCheckCompile(t *testing.T) {
+	tests := []struct {
+		name  string
+		check Check
+	}{
+		{
+			name:  "missing name",
+			check: Check{Steps: []Step{{URL: "http://localhost"}}},
+		},
+		{
+			name:  "no steps",
+			check: Check{Name: "check"},
+		},
+		{
+			name:  "missing url",
+			check: Check{Name: "check", Steps: []Step{{Name: "step"}}},
+		},
+		{
+			name: "undefined variable",
+			check: Check{Name: "check", Steps: []Step{
+				{URL: "http://localhost/{{id}}"},
+			}},
+		},
+		{
+			name: "variable extracted by a later step",
+			check: Check{Name: "check", Steps: []Step{
+				{URL: "http://localhost", Headers: map[string]string{"X-Id": "{{id}}"}},
+				{URL: "http://localhost", Extract: []Extract{{Name: "id", Header: "X-Id"}}},
+			}},
+		},
+		{
+			name: "extract without source",
+			check: Check{Name: "check", Steps: []Step{
+				{URL: "http://localhost", Extract: []Extract{{Name: "id"}}},
+			}},
+		},
+		{
+			name: "extract with two sources",
+			check: Check{Name: "check", Steps: []Step{
+				{URL: "http://localhost", Extract: []Extract{{Name: "id", Header: "X-Id", Regex: "id"}}},
+			}},
+		},
+		{
+			name: "invalid regex",
+			check: Check{Name: "check", Steps: []Step{
+				{URL: "http://localhost", ResponseStringMatch: "("},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.check.compile())
+		})
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	config := `
+[[inputs.http_response]]
+  [[inputs.http_response.check]]
+    name = "login"
+
+    [[inputs.http_response.check.step]]
+      url = "http://localhost/login"
+      method = "POST"
+      expected_status_code = 200
+      max_response_time = "1s"
+      [inputs.http_response.check.step.headers]
+        Content-Type = "application/json"
+      [[inputs.http_response.check.step.extract]]
+        name = "token"
+        json_path = "data.token"
+
+    [[inputs.http_response.check.step]]
+      url = "http://localhost/profile"
+      [inputs.http_response.check.step.headers]
+        Authorization = "Bearer {{token}}"
+`
+	conf := struct {
+		Inputs struct {
+			HTTPResponse []*HTTPResponse `toml:"http_response"`
+		}
+	}{}
+	require.NoError(t, toml.Unmarshal([]byte(config), &conf))
+	require.Len(t, conf.Inputs.HTTPResponse, 1)
+
+	h := conf.Inputs.HTTPResponse[0]
+	require.NoError(t, h.Init())
+	require.Len(t, h.Checks, 1)
+
+	steps := h.Checks[0].Steps
+	require.Len(t, steps, 2)
+	require.Equal(t, "1", steps[0].Name)
+	require.Equal(t, "POST", steps[0].Method)
+	require.Equal(t, 200, steps[0].ExpectedStatusCode)
+	require.Equal(t, time.Second, steps[0].MaxResponseTime.Duration)
+	require.Equal(t, []Extract{{Name: "token", JSONPath: "data.token"}}, steps[0].Extract)
+	require.Equal(t, "2", steps[1].Name)
+	require.Equal(t, "GET", steps[1].Method)
+	require.Equal(t, "Bearer {{token}}", steps[1].Headers["Authorization"])
+}
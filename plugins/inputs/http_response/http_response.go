@@ -17,6 +17,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/discovery"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -32,10 +33,12 @@ type HTTPResponse struct {
 	FollowRedirects     bool
 	ResponseStringMatch string
 	Interface           string
+	Checks              []Check `toml:"check"`
 	tls.ClientConfig
 
 	compiledStringMatch *regexp.Regexp
 	client              *http.Client
+	targets             discovery.Targets
 }
 
 // Description returns the plugin Description
@@ -86,6 +89,49 @@ var sampleConfig = `
 
   ## Interface to use when dialing an address
   # interface = "eth0"
+
+  ## Scripted checks, requested in addition to the urls.  The steps of a
+  ## check are requested in order and share cookies, the check stops at the
+  ## first step that fails.  The timeout, proxy, redirect, TLS, header and
+  ## interface settings above apply to all steps.
+  # [[inputs.http_response.check]]
+  #   name = "login"
+  #
+  #   [[inputs.http_response.check.step]]
+  #     name = "login"
+  #     url = "https://example.org/api/login"
+  #     method = "POST"
+  #     body = '{"user": "telegraf", "password": "secret"}'
+  #
+  #     ## Expected response status code, 0 accepts any status code
+  #     # expected_status_code = 200
+  #
+  #     ## Optional substring or regex match in body of the response
+  #     # response_string_match = "token"
+  #
+  #     ## Maximum time to receive the full response
+  #     # max_response_time = "1s"
+  #
+  #     ## HTTP Request Headers, may use extracted values
+  #     [inputs.http_response.check.step.headers]
+  #       Content-Type = "application/json"
+  #
+  #     ## Values extracted from the response are available to the following
+  #     ## steps as {{name}} in the url, body and headers.  The value is taken
+  #     ## with a gjson path on the JSON body, the first submatch of a regex
+  #     ## on the body, or a response header.
+  #     [[inputs.http_response.check.step.extract]]
+  #       name = "token"
+  #       json_path = "data.token"
+  #       # regex = 'name="csrf" value="(\w+)"'
+  #       # header = "Location"
+  #
+  #   [[inputs.http_response.check.step]]
+  #     name = "profile"
+  #     url = "https://example.org/api/profile"
+  #     expected_status_code = 200
+  #     [inputs.http_response.check.step.headers]
+  #       Authorization = "Bearer {{token}}"
 `
 
 // SampleConfig returns the plugin SampleConfig
@@ -93,6 +139,16 @@ func (h *HTTPResponse) SampleConfig() string {
 	return sampleConfig
 }
 
+// Init checks the scripted checks.
+func (h *HTTPResponse) Init() error {
+	for i := range h.Checks {
+		if err := h.Checks[i].compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ErrRedirectAttempted indicates that a redirect occurred
 var ErrRedirectAttempted = errors.New("redirect")
 
@@ -168,47 +224,58 @@ func localAddress(interfaceName string) (net.Addr, error) {
 	return nil, fmt.Errorf("cannot create local address for interface %q", interfaceName)
 }
 
-func setResult(result_string string, fields map[string]interface{}, tags map[string]string) {
-	result_codes := map[string]int{
-		"success":                  0,
-		"response_string_mismatch": 1,
-		"body_read_error":          2,
-		"connection_failed":        3,
-		"timeout":                  4,
-		"dns_error":                5,
-	}
+var resultCodes = map[string]int{
+	"success":                  0,
+	"response_string_mismatch": 1,
+	"body_read_error":          2,
+	"connection_failed":        3,
+	"timeout":                  4,
+	"dns_error":                5,
+	"status_code_mismatch":     6,
+	"response_time_exceeded":   7,
+	"extract_failed":           8,
+}
 
+func setResult(result_string string, fields map[string]interface{}, tags map[string]string) {
 	tags["result"] = result_string
 	fields["result_type"] = result_string
-	fields["result_code"] = result_codes[result_string]
+	fields["result_code"] = resultCodes[result_string]
 }
 
 func setError(err error, fields map[string]interface{}, tags map[string]string) error {
+	result := errorResult(err)
+	if result == "" {
+		return nil
+	}
+	setResult(result, fields, tags)
+	return err
+}
+
+// errorResult returns the result of a network error, or an empty string if
+// the error is not recognized.
+func errorResult(err error) string {
 	if timeoutError, ok := err.(net.Error); ok && timeoutError.Timeout() {
-		setResult("timeout", fields, tags)
-		return timeoutError
+		return "timeout"
 	}
 
 	urlErr, isUrlErr := err.(*url.Error)
 	if !isUrlErr {
-		return nil
+		return ""
 	}
 
 	opErr, isNetErr := (urlErr.Err).(*net.OpError)
 	if isNetErr {
-		switch e := (opErr.Err).(type) {
+		switch (opErr.Err).(type) {
 		case (*net.DNSError):
-			setResult("dns_error", fields, tags)
-			return e
+			return "dns_error"
 		case (*net.ParseError):
 			// Parse error has to do with parsing of IP addresses, so we
 			// group it with address errors
-			setResult("address_error", fields, tags)
-			return e
+			return "address_error"
 		}
 	}
 
-	return nil
+	return ""
 }
 
 // HTTPGather gathers all fields and returns any errors it encounters
@@ -326,12 +393,13 @@ func (h *HTTPResponse) Gather(acc telegraf.Accumulator) error {
 		h.Method = "GET"
 	}
 
+	targets := h.targets.List()
 	if len(h.URLs) == 0 {
-		if h.Address == "" {
-			h.URLs = []string{"http://localhost"}
-		} else {
+		if h.Address != "" {
 			log.Printf("W! [inputs.http_response] 'address' deprecated in telegraf 1.12, please use 'urls'")
 			h.URLs = []string{h.Address}
+		} else if len(targets) == 0 && len(h.Checks) == 0 {
+			h.URLs = []string{"http://localhost"}
 		}
 	}
 
@@ -370,9 +438,42 @@ func (h *HTTPResponse) Gather(acc telegraf.Accumulator) error {
 		acc.AddFields("http_response", fields, tags)
 	}
 
+	for _, target := range targets {
+		u := target.Address
+		if !strings.Contains(u, "://") {
+			u = "http://" + u
+		}
+
+		fields, tags, err := h.httpGather(u)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		for k, v := range target.Tags {
+			tags[k] = v
+		}
+
+		acc.AddFields("http_response", fields, tags)
+	}
+
+	for i := range h.Checks {
+		h.runCheck(&h.Checks[i], acc)
+	}
+
 	return nil
 }
 
+// AddTarget adds a target found by a discovery plugin, addresses without a
+// scheme are requested over http.
+func (h *HTTPResponse) AddTarget(target telegraf.Target) {
+	h.targets.Add(target)
+}
+
+// RemoveTarget removes a target found by a discovery plugin.
+func (h *HTTPResponse) RemoveTarget(target telegraf.Target) {
+	h.targets.Remove(target)
+}
+
 func init() {
 	inputs.Add("http_response", func() telegraf.Input {
 		return &HTTPResponse{}
@@ -0,0 +1,288 @@
+package modbus
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	mb "github.com/goburrow/modbus"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const defaultTCPPort = "502"
+
+// Modbus reads the coils and registers of a Modbus TCP, RTU or ASCII device.
+type Modbus struct {
+	Name             string            `toml:"name"`
+	Controller       string            `toml:"controller"`
+	TransmissionMode string            `toml:"transmission_mode"`
+	BaudRate         int               `toml:"baud_rate"`
+	DataBits         int               `toml:"data_bits"`
+	Parity           string            `toml:"parity"`
+	StopBits         int               `toml:"stop_bits"`
+	SlaveID          int               `toml:"slave_id"`
+	Timeout          internal.Duration `toml:"timeout"`
+
+	Coils            []Register `toml:"coil"`
+	DiscreteInputs   []Register `toml:"discrete_input"`
+	HoldingRegisters []Register `toml:"holding_register"`
+	InputRegisters   []Register `toml:"input_register"`
+
+	handler handler
+	client  mb.Client
+	tables  []table
+}
+
+// handler is a client handler of goburrow/modbus that keeps its connection
+// open between requests.
+type handler interface {
+	mb.ClientHandler
+	Connect() error
+	Close() error
+}
+
+// table holds the read requests of one type of coils or registers.
+type table struct {
+	kind     string
+	bits     bool
+	read     func(address, quantity uint16) ([]byte, error)
+	requests []request
+}
+
+const sampleConfig = `
+  ## Name of the device, added as the name tag.
+  name = "device"
+
+  ## Address of the device:
+  ##   tcp://<host>[:<port>]  Modbus TCP, the port defaults to 502
+  ##   file://<device>        Modbus RTU or ASCII over a serial line
+  controller = "tcp://localhost:502"
+
+  ## Slave id (unit identifier) of the device.
+  # slave_id = 1
+
+  ## Timeout of each request.
+  # timeout = "1s"
+
+  ## Serial line settings, only used with file:// controllers.
+  # transmission_mode = "RTU"  # or "ASCII"
+  # baud_rate = 9600
+  # data_bits = 8
+  # parity = "N"  # "N", "E" or "O"
+  # stop_bits = 1
+
+  ## Coils and discrete inputs are reported as 0 or 1.
+  # [[inputs.modbus.coil]]
+  #   name = "pump_running"
+  #   address = 0
+  # [[inputs.modbus.discrete_input]]
+  #   name = "door_open"
+  #   address = 0
+
+  ## Holding and input registers are decoded as one of INT16, UINT16, INT32,
+  ## UINT32, INT64, UINT64, FLOAT32 or FLOAT64 (default UINT16).
+  ##
+  ## The byte order names the bytes of the value, from the most significant
+  ## one, in the order they are sent by the device; for example "ABCD" is big
+  ## endian, "DCBA" little endian and "CDAB" big endian with the low word
+  ## first.  It defaults to big endian.
+  ##
+  ## When scale is set the value is multiplied by it and reported as a float.
+  # [[inputs.modbus.holding_register]]
+  #   name = "voltage"
+  #   address = 0
+  #   data_type = "UINT16"
+  #   scale = 0.1
+  # [[inputs.modbus.input_register]]
+  #   name = "energy"
+  #   address = 10
+  #   data_type = "FLOAT32"
+  #   byte_order = "CDAB"
+`
+
+func (m *Modbus) Description() string {
+	return "Read coils and registers of Modbus TCP, RTU and ASCII devices"
+}
+
+func (m *Modbus) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *Modbus) Init() error {
+	if m.Name == "" {
+		return fmt.Errorf("device name is empty")
+	}
+	if m.SlaveID < 0 || m.SlaveID > 255 {
+		return fmt.Errorf("invalid slave_id %d", m.SlaveID)
+	}
+
+	h, err := m.newHandler()
+	if err != nil {
+		return err
+	}
+	m.handler = h
+	m.client = mb.NewClient(h)
+
+	definitions := []struct {
+		kind      string
+		bits      bool
+		registers []Register
+		read      func(address, quantity uint16) ([]byte, error)
+	}{
+		{"coil", true, m.Coils, m.client.ReadCoils},
+		{"discrete_input", true, m.DiscreteInputs, m.client.ReadDiscreteInputs},
+		{"holding_register", false, m.HoldingRegisters, m.client.ReadHoldingRegisters},
+		{"input_register", false, m.InputRegisters, m.client.ReadInputRegisters},
+	}
+
+	m.tables = nil
+	for _, d := range definitions {
+		if len(d.registers) == 0 {
+			continue
+		}
+
+		var fields []field
+		var max uint16
+		if d.bits {
+			fields, err = compileBits(d.registers)
+			max = maxBitsPerRequest
+		} else {
+			fields, err = compileRegisters(d.registers)
+			max = maxRegistersPerRequest
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %s", d.kind, err)
+		}
+
+		seen := make(map[string]bool, len(fields))
+		for _, f := range fields {
+			if seen[f.name] {
+				return fmt.Errorf("invalid %s: duplicate name '%s'", d.kind, f.name)
+			}
+			seen[f.name] = true
+		}
+
+		m.tables = append(m.tables, table{
+			kind:     d.kind,
+			bits:     d.bits,
+			read:     d.read,
+			requests: groupRequests(fields, max),
+		})
+	}
+	if len(m.tables) == 0 {
+		return fmt.Errorf("no coils or registers defined")
+	}
+	return nil
+}
+
+// newHandler returns the client handler of the controller.
+func (m *Modbus) newHandler() (handler, error) {
+	u, err := url.Parse(m.Controller)
+	if err != nil {
+		return nil, fmt.Errorf("invalid controller '%s': %s", m.Controller, err)
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		host, port, err := net.SplitHostPort(u.Host)
+		if err != nil {
+			host, port = u.Host, defaultTCPPort
+		}
+		h := mb.NewTCPClientHandler(net.JoinHostPort(host, port))
+		h.Timeout = m.Timeout.Duration
+		h.SlaveId = byte(m.SlaveID)
+		return h, nil
+	case "file":
+		switch strings.ToUpper(m.TransmissionMode) {
+		case "", "RTU":
+			h := mb.NewRTUClientHandler(u.Path)
+			h.Timeout = m.Timeout.Duration
+			h.SlaveId = byte(m.SlaveID)
+			h.BaudRate = m.BaudRate
+			h.DataBits = m.DataBits
+			h.Parity = strings.ToUpper(m.Parity)
+			h.StopBits = m.StopBits
+			return h, nil
+		case "ASCII":
+			h := mb.NewASCIIClientHandler(u.Path)
+			h.Timeout = m.Timeout.Duration
+			h.SlaveId = byte(m.SlaveID)
+			h.BaudRate = m.BaudRate
+			h.DataBits = m.DataBits
+			h.Parity = strings.ToUpper(m.Parity)
+			h.StopBits = m.StopBits
+			return h, nil
+		default:
+			return nil, fmt.Errorf("invalid transmission_mode '%s'", m.TransmissionMode)
+		}
+	default:
+		return nil, fmt.Errorf("invalid controller '%s': unsupported scheme '%s'", m.Controller, u.Scheme)
+	}
+}
+
+func (m *Modbus) Gather(acc telegraf.Accumulator) error {
+	if err := m.handler.Connect(); err != nil {
+		return fmt.Errorf("connecting to %s failed: %s", m.Controller, err)
+	}
+
+	now := time.Now()
+	for _, t := range m.tables {
+		fields, err := t.gather()
+		if err != nil {
+			if _, ok := err.(*mb.ModbusError); !ok {
+				// the connection is left in an unknown state by
+				// transport errors, reconnect on the next gather
+				m.handler.Close()
+				return fmt.Errorf("reading %ss of %s failed: %s", t.kind, m.Controller, err)
+			}
+			acc.AddError(fmt.Errorf("reading %ss of %s failed: %s", t.kind, m.Controller, err))
+			continue
+		}
+
+		tags := map[string]string{
+			"name":     m.Name,
+			"slave_id": strconv.Itoa(m.SlaveID),
+			"type":     t.kind,
+		}
+		acc.AddFields("modbus", fields, tags, now)
+	}
+	return nil
+}
+
+func (t *table) gather() (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	for _, r := range t.requests {
+		b, err := t.read(r.address, r.quantity)
+		if err != nil {
+			return nil, err
+		}
+		if t.bits {
+			err = r.bitValues(b, fields)
+		} else {
+			err = r.registerValues(b, fields)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+func init() {
+	inputs.Add("modbus", func() telegraf.Input {
+		return &Modbus{
+			SlaveID:          1,
+			Timeout:          internal.Duration{Duration: time.Second},
+			TransmissionMode: "RTU",
+			BaudRate:         9600,
+			DataBits:         8,
+			Parity:           "N",
+			StopBits:         1,
+		}
+	})
+}
@@ -0,0 +1,394 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/influxdata/toml"
+	"github.com/stretchr/testify/require"
+)
+
+// server is an in-process Modbus TCP server with the memory of one device.
+type server struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	conns    []net.Conn
+	coils    []bool
+	discrete []bool
+	holding  []uint16
+	input    []uint16
+	// requests holds the function, address and quantity of the requests
+	requests [][3]uint16
+}
+
+func newServer(t *testing.T) *server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &server{
+		listener: listener,
+		coils:    make([]bool, 4096),
+		discrete: make([]bool, 4096),
+		holding:  make([]uint16, 4096),
+		input:    make([]uint16, 4096),
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serve(conn)
+			}()
+		}
+	}()
+	return s
+}
+
+func (s *server) controller() string {
+	return "tcp://" + s.listener.Addr().String()
+}
+
+func (s *server) close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *server) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		header := make([]byte, 7)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		pdu := make([]byte, binary.BigEndian.Uint16(header[4:])-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+
+		response := s.handle(pdu)
+		binary.BigEndian.PutUint16(header[4:], uint16(len(response)+1))
+		if _, err := conn.Write(append(header, response...)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *server) handle(pdu []byte) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	function := pdu[0]
+	address := binary.BigEndian.Uint16(pdu[1:])
+	quantity := binary.BigEndian.Uint16(pdu[3:])
+	s.requests = append(s.requests, [3]uint16{uint16(function), address, quantity})
+	end := int(address) + int(quantity)
+
+	switch function {
+	case 1, 2:
+		bits := s.coils
+		if function == 2 {
+			bits = s.discrete
+		}
+		if end > len(bits) {
+			return []byte{function | 0x80, 2}
+		}
+		data := make([]byte, (quantity+7)/8)
+		for i := 0; i < int(quantity); i++ {
+			if bits[int(address)+i] {
+				data[i/8] |= 1 << uint(i%8)
+			}
+		}
+		return append([]byte{function, byte(len(data))}, data...)
+	case 3, 4:
+		registers := s.holding
+		if function == 4 {
+			registers = s.input
+		}
+		if end > len(registers) {
+			return []byte{function | 0x80, 2}
+		}
+		data := make([]byte, 2*int(quantity))
+		for i := 0; i < int(quantity); i++ {
+			binary.BigEndian.PutUint16(data[2*i:], registers[int(address)+i])
+		}
+		return append([]byte{function, byte(len(data))}, data...)
+	default:
+		return []byte{function | 0x80, 1}
+	}
+}
+
+func TestGather(t *testing.T) {
+	s := newServer(t)
+	defer s.close()
+
+	s.coils[0] = true
+	s.coils[2] = true
+	s.discrete[9] = true
+	s.holding[0] = 2305
+	s.holding[1] = 0xfffe
+	// 123456789 with the low word first
+	s.holding[2] = 0xcd15
+	s.holding[3] = 0x075b
+	f32 := math.Float32bits(3.5)
+	s.input[10] = uint16(f32 >> 16)
+	s.input[11] = uint16(f32)
+	f64 := math.Float64bits(-1.25)
+	for i := 0; i < 4; i++ {
+		s.input[12+i] = uint16(f64 >> uint(48-16*i))
+	}
+	s.input[20] = 0x3412
+
+	m := &Modbus{
+		Name:       "meter",
+		Controller: s.controller(),
+		SlaveID:    1,
+		Coils: []Register{
+			{Name: "pump", Address: 0},
+			{Name: "valve", Address: 1},
+			{Name: "alarm", Address: 2},
+		},
+		DiscreteInputs: []Register{
+			{Name: "door", Address: 9},
+		},
+		HoldingRegisters: []Register{
+			{Name: "voltage", Address: 0, Scale: 0.1},
+			{Name: "offset", Address: 1, DataType: "INT16"},
+			{Name: "counter", Address: 2, DataType: "UINT32", ByteOrder: "CDAB"},
+		},
+		InputRegisters: []Register{
+			{Name: "power", Address: 10, DataType: "FLOAT32"},
+			{Name: "energy", Address: 12, DataType: "FLOAT64"},
+			{Name: "swapped", Address: 20, DataType: "UINT16", ByteOrder: "BA"},
+		},
+	}
+	require.NoError(t, m.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"modbus",
+			map[string]string{"name": "meter", "slave_id": "1", "type": "coil"},
+			map[string]interface{}{"pump": uint64(1), "valve": uint64(0), "alarm": uint64(1)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"modbus",
+			map[string]string{"name": "meter", "slave_id": "1", "type": "discrete_input"},
+			map[string]interface{}{"door": uint64(1)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"modbus",
+			map[string]string{"name": "meter", "slave_id": "1", "type": "holding_register"},
+			map[string]interface{}{"voltage": 230.5, "offset": int64(-2), "counter": uint64(123456789)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"modbus",
+			map[string]string{"name": "meter", "slave_id": "1", "type": "input_register"},
+			map[string]interface{}{"power": 3.5, "energy": -1.25, "swapped": uint64(0x1234)},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+
+	// adjacent coils and registers are read with one request
+	s.mu.Lock()
+	requests := s.requests
+	s.mu.Unlock()
+	require.Equal(t, [][3]uint16{
+		{1, 0, 3},
+		{2, 9, 1},
+		{3, 0, 4},
+		{4, 10, 6},
+		{4, 20, 1},
+	}, requests)
+}
+
+func TestGatherException(t *testing.T) {
+	s := newServer(t)
+	defer s.close()
+	s.holding[0] = 42
+
+	m := &Modbus{
+		Name:       "meter",
+		Controller: s.controller(),
+		SlaveID:    1,
+		HoldingRegisters: []Register{
+			{Name: "value", Address: 0},
+		},
+		InputRegisters: []Register{
+			{Name: "missing", Address: 5000},
+		},
+	}
+	require.NoError(t, m.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, m.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "illegal data address")
+	require.Equal(t, 1, len(acc.GetTelegrafMetrics()))
+	acc.AssertContainsFields(t, "modbus", map[string]interface{}{"value": uint64(42)})
+}
+
+func TestGatherConnectionRefused(t *testing.T) {
+	s := newServer(t)
+	controller := s.controller()
+	s.close()
+
+	m := &Modbus{
+		Name:       "meter",
+		Controller: controller,
+		HoldingRegisters: []Register{
+			{Name: "value", Address: 0},
+		},
+	}
+	require.NoError(t, m.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, m.Gather(&acc))
+}
+
+func TestGroupRequests(t *testing.T) {
+	fields := []field{
+		{name: "c", address: 10, length: 2},
+		{name: "a", address: 0, length: 1},
+		{name: "b", address: 1, length: 2},
+		{name: "d", address: 12, length: 1},
+		{name: "e", address: 13, length: 1},
+		{name: "f", address: 2, length: 1},
+	}
+
+	requests := groupRequests(fields, 3)
+	var got [][2]uint16
+	for _, r := range requests {
+		got = append(got, [2]uint16{r.address, r.quantity})
+	}
+	require.Equal(t, [][2]uint16{{0, 3}, {10, 3}, {13, 1}}, got)
+	require.Len(t, requests[0].fields, 3)
+
+	requests = groupRequests(fields, maxRegistersPerRequest)
+	require.Len(t, requests, 2)
+}
+
+func TestByteOrder(t *testing.T) {
+	value := []byte{0x12, 0x34, 0x56, 0x78}
+	tests := []struct {
+		order     string
+		registers []byte
+	}{
+		{"ABCD", []byte{0x12, 0x34, 0x56, 0x78}},
+		{"DCBA", []byte{0x78, 0x56, 0x34, 0x12}},
+		{"BADC", []byte{0x34, 0x12, 0x78, 0x56}},
+		{"CDAB", []byte{0x56, 0x78, 0x12, 0x34}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			order, err := byteOrder(tt.order, 4)
+			require.NoError(t, err)
+
+			r := request{
+				quantity: 2,
+				fields: []field{{
+					name:    "value",
+					length:  2,
+					order:   order,
+					convert: converter("UINT32"),
+				}},
+			}
+			fields := make(map[string]interface{})
+			require.NoError(t, r.registerValues(tt.registers, fields))
+			require.Equal(t, uint64(binary.BigEndian.Uint32(value)), fields["value"])
+		})
+	}
+
+	_, err := byteOrder("ABCC", 4)
+	require.Error(t, err)
+	_, err = byteOrder("AB", 4)
+	require.Error(t, err)
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name   string
+		modbus *Modbus
+	}{
+		{"no name", &Modbus{Controller: "tcp://localhost", Coils: []Register{{Name: "a"}}}},
+		{"no registers", &Modbus{Name: "d", Controller: "tcp://localhost"}},
+		{"bad scheme", &Modbus{Name: "d", Controller: "udp://localhost", Coils: []Register{{Name: "a"}}}},
+		{"bad mode", &Modbus{Name: "d", Controller: "file:///dev/ttyS0", TransmissionMode: "X", Coils: []Register{{Name: "a"}}}},
+		{"bad slave", &Modbus{Name: "d", Controller: "tcp://localhost", SlaveID: 256, Coils: []Register{{Name: "a"}}}},
+		{"bad data type", &Modbus{Name: "d", Controller: "tcp://localhost", HoldingRegisters: []Register{{Name: "a", DataType: "INT8"}}}},
+		{"coil data type", &Modbus{Name: "d", Controller: "tcp://localhost", Coils: []Register{{Name: "a", DataType: "INT16"}}}},
+		{"duplicate", &Modbus{Name: "d", Controller: "tcp://localhost", Coils: []Register{{Name: "a"}, {Name: "a", Address: 1}}}},
+		{"out of range", &Modbus{Name: "d", Controller: "tcp://localhost", InputRegisters: []Register{{Name: "a", Address: 65535, DataType: "UINT32"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.modbus.Init())
+		})
+	}
+
+	m := &Modbus{
+		Name:             "d",
+		Controller:       "file:///dev/ttyUSB0",
+		TransmissionMode: "ascii",
+		Coils:            []Register{{Name: "a"}},
+	}
+	require.NoError(t, m.Init())
+}
+
+func TestConfig(t *testing.T) {
+	conf := []byte(`
+name = "meter"
+controller = "tcp://localhost:502"
+
+[[coil]]
+  name = "pump"
+  address = 3
+
+[[input_register]]
+  name = "energy"
+  address = 10
+  data_type = "FLOAT32"
+  byte_order = "CDAB"
+  scale = 0.001
+`)
+	m := &Modbus{}
+	require.NoError(t, toml.Unmarshal(conf, m))
+	require.Equal(t, []Register{{Name: "pump", Address: 3}}, m.Coils)
+	require.Equal(t, []Register{{
+		Name:      "energy",
+		Address:   10,
+		DataType:  "FLOAT32",
+		ByteOrder: "CDAB",
+		Scale:     0.001,
+	}}, m.InputRegisters)
+	require.NoError(t, m.Init())
+}
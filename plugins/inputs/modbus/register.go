@@ -0,0 +1,239 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// maximum quantities of a single read request of the Modbus specification
+const (
+	maxBitsPerRequest      = 2000
+	maxRegistersPerRequest = 125
+)
+
+const bytesPerRegister = 2
+
+// Register is a value of one or more consecutive coils or registers.
+type Register struct {
+	Name      string  `toml:"name"`
+	Address   uint16  `toml:"address"`
+	DataType  string  `toml:"data_type"`
+	ByteOrder string  `toml:"byte_order"`
+	Scale     float64 `toml:"scale"`
+}
+
+// field is a compiled register definition.
+type field struct {
+	name    string
+	address uint16
+	length  uint16
+	// order maps the bytes of the value, most significant first, to their
+	// position in the registers
+	order   []int
+	convert func(b []byte) interface{}
+	scale   float64
+}
+
+// request is a read request of adjacent fields.
+type request struct {
+	address  uint16
+	quantity uint16
+	fields   []field
+}
+
+// registerLength returns the number of 16 bit registers used by a data type.
+func registerLength(dataType string) (uint16, error) {
+	switch dataType {
+	case "INT16", "UINT16":
+		return 1, nil
+	case "INT32", "UINT32", "FLOAT32":
+		return 2, nil
+	case "INT64", "UINT64", "FLOAT64":
+		return 4, nil
+	default:
+		return 0, fmt.Errorf("invalid data type '%s'", dataType)
+	}
+}
+
+// byteOrder parses a byte order like "CDAB", where the letters name the
+// bytes of the value from the most significant one as they are sent by the
+// device.
+func byteOrder(order string, size int) ([]int, error) {
+	if order == "" {
+		order = "ABCDEFGH"[:size]
+	}
+	order = strings.ToUpper(order)
+	if len(order) != size {
+		return nil, fmt.Errorf("byte order '%s' does not match a value of %d bytes", order, size)
+	}
+
+	positions := make([]int, size)
+	seen := make([]bool, size)
+	for i, c := range order {
+		n := int(c - 'A')
+		if n < 0 || n >= size || seen[n] {
+			return nil, fmt.Errorf("invalid byte order '%s'", order)
+		}
+		seen[n] = true
+		positions[n] = i
+	}
+	return positions, nil
+}
+
+func converter(dataType string) func(b []byte) interface{} {
+	switch dataType {
+	case "INT16":
+		return func(b []byte) interface{} { return int64(int16(binary.BigEndian.Uint16(b))) }
+	case "UINT16":
+		return func(b []byte) interface{} { return uint64(binary.BigEndian.Uint16(b)) }
+	case "INT32":
+		return func(b []byte) interface{} { return int64(int32(binary.BigEndian.Uint32(b))) }
+	case "UINT32":
+		return func(b []byte) interface{} { return uint64(binary.BigEndian.Uint32(b)) }
+	case "INT64":
+		return func(b []byte) interface{} { return int64(binary.BigEndian.Uint64(b)) }
+	case "UINT64":
+		return func(b []byte) interface{} { return binary.BigEndian.Uint64(b) }
+	case "FLOAT32":
+		return func(b []byte) interface{} { return float64(math.Float32frombits(binary.BigEndian.Uint32(b))) }
+	default:
+		return func(b []byte) interface{} { return math.Float64frombits(binary.BigEndian.Uint64(b)) }
+	}
+}
+
+// compileBits compiles the definitions of coils or discrete inputs.
+func compileBits(registers []Register) ([]field, error) {
+	fields := make([]field, 0, len(registers))
+	for _, r := range registers {
+		if r.Name == "" {
+			return nil, fmt.Errorf("missing name of address %d", r.Address)
+		}
+		if r.DataType != "" || r.ByteOrder != "" || r.Scale != 0 {
+			return nil, fmt.Errorf("%s: data_type, byte_order and scale are not supported for single bits", r.Name)
+		}
+		fields = append(fields, field{
+			name:    r.Name,
+			address: r.Address,
+			length:  1,
+		})
+	}
+	return fields, nil
+}
+
+// compileRegisters compiles the definitions of holding or input registers.
+func compileRegisters(registers []Register) ([]field, error) {
+	fields := make([]field, 0, len(registers))
+	for _, r := range registers {
+		if r.Name == "" {
+			return nil, fmt.Errorf("missing name of address %d", r.Address)
+		}
+		dataType := strings.ToUpper(r.DataType)
+		if dataType == "" {
+			dataType = "UINT16"
+		}
+		length, err := registerLength(dataType)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", r.Name, err)
+		}
+		if int(r.Address)+int(length)-1 > math.MaxUint16 {
+			return nil, fmt.Errorf("%s: address %d out of range for %s", r.Name, r.Address, dataType)
+		}
+		order, err := byteOrder(r.ByteOrder, int(length)*bytesPerRegister)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", r.Name, err)
+		}
+		fields = append(fields, field{
+			name:    r.Name,
+			address: r.Address,
+			length:  length,
+			order:   order,
+			convert: converter(dataType),
+			scale:   r.Scale,
+		})
+	}
+	return fields, nil
+}
+
+// groupRequests groups fields of adjacent or overlapping addresses into as
+// few read requests as possible, each reading at most max coils or
+// registers.
+func groupRequests(fields []field, max uint16) []request {
+	sorted := make([]field, len(fields))
+	copy(sorted, fields)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].address < sorted[j].address
+	})
+
+	var requests []request
+	for _, f := range sorted {
+		end := int(f.address) + int(f.length)
+		if n := len(requests); n > 0 {
+			last := &requests[n-1]
+			lastEnd := int(last.address) + int(last.quantity)
+			if int(f.address) <= lastEnd && end-int(last.address) <= int(max) {
+				if end > lastEnd {
+					last.quantity = uint16(end - int(last.address))
+				}
+				last.fields = append(last.fields, f)
+				continue
+			}
+		}
+		requests = append(requests, request{
+			address:  f.address,
+			quantity: f.length,
+			fields:   []field{f},
+		})
+	}
+	return requests
+}
+
+// bitValues returns the values of the fields of a coil or discrete input
+// request from the packed bits of the response.
+func (r *request) bitValues(b []byte, fields map[string]interface{}) error {
+	if len(b)*8 < int(r.quantity) {
+		return fmt.Errorf("short response of %d bytes for %d bits at address %d", len(b), r.quantity, r.address)
+	}
+	for _, f := range r.fields {
+		n := f.address - r.address
+		fields[f.name] = uint64(b[n/8] >> (n % 8) & 1)
+	}
+	return nil
+}
+
+// registerValues returns the values of the fields of a holding or input
+// register request from the register values of the response.
+func (r *request) registerValues(b []byte, fields map[string]interface{}) error {
+	if len(b) < int(r.quantity)*bytesPerRegister {
+		return fmt.Errorf("short response of %d bytes for %d registers at address %d", len(b), r.quantity, r.address)
+	}
+	for _, f := range r.fields {
+		offset := int(f.address-r.address) * bytesPerRegister
+		raw := b[offset : offset+int(f.length)*bytesPerRegister]
+		value := make([]byte, len(raw))
+		for i, pos := range f.order {
+			value[i] = raw[pos]
+		}
+
+		v := f.convert(value)
+		if f.scale != 0 {
+			v = toFloat(v) * f.scale
+		}
+		fields[f.name] = v
+	}
+	return nil
+}
+
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
@@ -3,6 +3,7 @@ package net_response
 import (
 	"bufio"
 	"errors"
+	"fmt"
 	"net"
 	"net/textproto"
 	"regexp"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/discovery"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -31,6 +33,9 @@ type NetResponse struct {
 	Send        string
 	Expect      string
 	Protocol    string
+
+	// targets found by discovery plugins
+	targets discovery.Targets
 }
 
 var description = "Collect response time of a TCP or UDP connection"
@@ -74,13 +79,17 @@ func (*NetResponse) SampleConfig() string {
 // TCPGather will execute if there are TCP tests defined in the configuration.
 // It will return a map[string]interface{} for fields and a map[string]string for tags
 func (n *NetResponse) TCPGather() (tags map[string]string, fields map[string]interface{}) {
+	return n.tcpGather(n.Address)
+}
+
+func (n *NetResponse) tcpGather(address string) (tags map[string]string, fields map[string]interface{}) {
 	// Prepare returns
 	tags = make(map[string]string)
 	fields = make(map[string]interface{})
 	// Start Timer
 	start := time.Now()
 	// Connecting
-	conn, err := net.DialTimeout("tcp", n.Address, n.Timeout.Duration)
+	conn, err := net.DialTimeout("tcp", address, n.Timeout.Duration)
 	// Stop timer
 	responseTime := time.Since(start).Seconds()
 	// Handle error
@@ -134,13 +143,17 @@ func (n *NetResponse) TCPGather() (tags map[string]string, fields map[string]int
 // UDPGather will execute if there are UDP tests defined in the configuration.
 // It will return a map[string]interface{} for fields and a map[string]string for tags
 func (n *NetResponse) UDPGather() (tags map[string]string, fields map[string]interface{}) {
+	return n.udpGather(n.Address)
+}
+
+func (n *NetResponse) udpGather(address string) (tags map[string]string, fields map[string]interface{}) {
 	// Prepare returns
 	tags = make(map[string]string)
 	fields = make(map[string]interface{})
 	// Start Timer
 	start := time.Now()
 	// Resolving
-	udpAddr, err := net.ResolveUDPAddr("udp", n.Address)
+	udpAddr, err := net.ResolveUDPAddr("udp", address)
 	// Connecting
 	conn, err := net.DialUDP("udp", nil, udpAddr)
 	// Handle error
@@ -198,13 +211,29 @@ func (n *NetResponse) Gather(acc telegraf.Accumulator) error {
 	if n.Protocol == "udp" && n.Expect == "" {
 		return errors.New("Expected string cannot be empty")
 	}
+	targets := n.targets.List()
+	if n.Address != "" || len(targets) == 0 {
+		if err := n.gatherAddress(acc, n.Address, nil); err != nil {
+			return err
+		}
+	}
+	for _, target := range targets {
+		if err := n.gatherAddress(acc, target.Address, target.Tags); err != nil {
+			acc.AddError(fmt.Errorf("target %s: %v", target.Address, err))
+		}
+	}
+	return nil
+}
+
+// gatherAddress checks the address and adds the result with the extra tags.
+func (n *NetResponse) gatherAddress(acc telegraf.Accumulator, address string, extraTags map[string]string) error {
 	// Prepare host and port
-	host, port, err := net.SplitHostPort(n.Address)
+	host, port, err := net.SplitHostPort(address)
 	if err != nil {
 		return err
 	}
 	if host == "" {
-		n.Address = "localhost:" + port
+		address = "localhost:" + port
 	}
 	if port == "" {
 		return errors.New("Bad port")
@@ -215,26 +244,37 @@ func (n *NetResponse) Gather(acc telegraf.Accumulator) error {
 	var returnTags map[string]string
 	// Gather data
 	if n.Protocol == "tcp" {
-		returnTags, fields = n.TCPGather()
+		returnTags, fields = n.tcpGather(address)
 		tags["protocol"] = "tcp"
 	} else if n.Protocol == "udp" {
-		returnTags, fields = n.UDPGather()
+		returnTags, fields = n.udpGather(address)
 		tags["protocol"] = "udp"
 	} else {
 		return errors.New("Bad protocol")
 	}
-	for key, value := range returnTags {
-		tags[key] = value
-	}
 	// Merge the tags
 	for k, v := range returnTags {
 		tags[k] = v
 	}
+	for k, v := range extraTags {
+		tags[k] = v
+	}
 	// Add metrics
 	acc.AddFields("net_response", fields, tags)
 	return nil
 }
 
+// AddTarget adds a target found by a discovery plugin, the address must be
+// in the host:port format.
+func (n *NetResponse) AddTarget(target telegraf.Target) {
+	n.targets.Add(target)
+}
+
+// RemoveTarget removes a target found by a discovery plugin.
+func (n *NetResponse) RemoveTarget(target telegraf.Target) {
+	n.targets.Remove(target)
+}
+
 func setResult(result ResultType, fields map[string]interface{}, tags map[string]string, expect string) {
 	var tag string
 	switch result {
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/testutil"
 
@@ -299,3 +300,41 @@ func TCPServer(t *testing.T, wg *sync.WaitGroup) {
 	tcpServer.Close()
 	wg.Done()
 }
+
+func TestDiscoveredTargets(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	var acc testutil.Accumulator
+	c := NetResponse{
+		Timeout:  internal.Duration{Duration: time.Second},
+		Protocol: "tcp",
+	}
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	c.AddTarget(telegraf.Target{
+		Address: listener.Addr().String(),
+		Tags:    map[string]string{"env": "prod"},
+	})
+
+	// Only the discovered targets are checked if no address is configured.
+	require.NoError(t, c.Gather(&acc))
+	require.Len(t, acc.Metrics, 1)
+	require.Equal(t, map[string]string{
+		"result":   "success",
+		"server":   "127.0.0.1",
+		"port":     port,
+		"protocol": "tcp",
+		"env":      "prod",
+	}, acc.Metrics[0].Tags)
+}
@@ -0,0 +1,410 @@
+package netflow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const (
+	v5HeaderLength    = 24
+	v5RecordLength    = 48
+	v9HeaderLength    = 20
+	ipfixHeaderLength = 16
+
+	// variableLength is the field length of IPFIX variable length elements
+	variableLength = 0xffff
+)
+
+// templateKey identifies a template, template ids are only unique for an
+// exporter and observation domain (source id in NetFlow v9).
+type templateKey struct {
+	source string
+	domain uint32
+	id     uint16
+}
+
+type templateField struct {
+	key    elementKey
+	length uint16
+	scope  bool
+}
+
+type template struct {
+	fields  []templateField
+	options bool
+}
+
+// decoder decodes NetFlow v5, v9 and IPFIX packets into metrics, caching the
+// templates of the exporters.
+type decoder struct {
+	elements  map[elementKey]element
+	tagKeys   map[string]bool
+	templates map[templateKey]*template
+}
+
+func newDecoder(elements map[elementKey]element, tagKeys []string) *decoder {
+	d := &decoder{
+		elements:  elements,
+		tagKeys:   make(map[string]bool),
+		templates: make(map[templateKey]*template),
+	}
+	for _, key := range tagKeys {
+		d.tagKeys[key] = true
+	}
+	return d
+}
+
+// decode decodes a packet from the exporter with the address source.  The
+// metrics of the records decoded before an error are returned with it.
+func (d *decoder) decode(source string, buf []byte) ([]telegraf.Metric, error) {
+	if len(buf) < 2 {
+		return nil, fmt.Errorf("packet too short")
+	}
+
+	switch version := binary.BigEndian.Uint16(buf); version {
+	case 5:
+		return d.decodeV5(source, buf)
+	case 9:
+		return d.decodeV9(source, buf)
+	case 10:
+		return d.decodeIPFIX(source, buf)
+	default:
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+}
+
+func (d *decoder) newMetric(
+	name string,
+	source string,
+	version string,
+	values map[string]interface{},
+	t time.Time,
+) (telegraf.Metric, error) {
+	tags := map[string]string{
+		"source":  source,
+		"version": version,
+	}
+	for k, v := range values {
+		if d.tagKeys[k] {
+			tags[k] = fmt.Sprint(v)
+			delete(values, k)
+		}
+	}
+	return metric.New(name, tags, values, t)
+}
+
+func (d *decoder) decodeV5(source string, buf []byte) ([]telegraf.Metric, error) {
+	if len(buf) < v5HeaderLength {
+		return nil, fmt.Errorf("netflow v5 header too short")
+	}
+	count := int(binary.BigEndian.Uint16(buf[2:]))
+	secs := binary.BigEndian.Uint32(buf[8:])
+	nsecs := binary.BigEndian.Uint32(buf[12:])
+	engineType := buf[20]
+	engineID := buf[21]
+	samplingInterval := binary.BigEndian.Uint16(buf[22:]) & 0x3fff
+	t := time.Unix(int64(secs), int64(nsecs))
+
+	if len(buf) < v5HeaderLength+count*v5RecordLength {
+		return nil, fmt.Errorf("netflow v5 packet too short for %d records", count)
+	}
+
+	metrics := make([]telegraf.Metric, 0, count)
+	for i := 0; i < count; i++ {
+		r := buf[v5HeaderLength+i*v5RecordLength:]
+		values := map[string]interface{}{
+			"src_addr":          decodeValue(typeIP, r[0:4]),
+			"dst_addr":          decodeValue(typeIP, r[4:8]),
+			"next_hop":          decodeValue(typeIP, r[8:12]),
+			"in_snmp":           uint64(binary.BigEndian.Uint16(r[12:])),
+			"out_snmp":          uint64(binary.BigEndian.Uint16(r[14:])),
+			"in_packets":        uint64(binary.BigEndian.Uint32(r[16:])),
+			"in_bytes":          uint64(binary.BigEndian.Uint32(r[20:])),
+			"first_switched":    uint64(binary.BigEndian.Uint32(r[24:])),
+			"last_switched":     uint64(binary.BigEndian.Uint32(r[28:])),
+			"src_port":          uint64(binary.BigEndian.Uint16(r[32:])),
+			"dst_port":          uint64(binary.BigEndian.Uint16(r[34:])),
+			"tcp_flags":         uint64(r[37]),
+			"protocol":          uint64(r[38]),
+			"src_tos":           uint64(r[39]),
+			"src_as":            uint64(binary.BigEndian.Uint16(r[40:])),
+			"dst_as":            uint64(binary.BigEndian.Uint16(r[42:])),
+			"src_mask":          uint64(r[44]),
+			"dst_mask":          uint64(r[45]),
+			"engine_type":       uint64(engineType),
+			"engine_id":         uint64(engineID),
+			"sampling_interval": uint64(samplingInterval),
+		}
+		m, err := d.newMetric("netflow", source, "NetFlowV5", values, t)
+		if err != nil {
+			return metrics, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (d *decoder) decodeV9(source string, buf []byte) ([]telegraf.Metric, error) {
+	if len(buf) < v9HeaderLength {
+		return nil, fmt.Errorf("netflow v9 header too short")
+	}
+	secs := binary.BigEndian.Uint32(buf[8:])
+	sourceID := binary.BigEndian.Uint32(buf[16:])
+	t := time.Unix(int64(secs), 0)
+
+	var metrics []telegraf.Metric
+	for off := v9HeaderLength; off+4 <= len(buf); {
+		setID := binary.BigEndian.Uint16(buf[off:])
+		setLength := int(binary.BigEndian.Uint16(buf[off+2:]))
+		if setLength < 4 || off+setLength > len(buf) {
+			return metrics, fmt.Errorf("invalid netflow v9 flowset length %d", setLength)
+		}
+		body := buf[off+4 : off+setLength]
+		off += setLength
+
+		var err error
+		switch {
+		case setID == 0:
+			err = d.decodeV9Templates(source, sourceID, body)
+		case setID == 1:
+			err = d.decodeV9OptionsTemplates(source, sourceID, body)
+		case setID >= 256:
+			var ms []telegraf.Metric
+			ms, err = d.decodeData(source, sourceID, setID, "NetFlowV9", body, t)
+			metrics = append(metrics, ms...)
+		}
+		if err != nil {
+			return metrics, err
+		}
+	}
+	return metrics, nil
+}
+
+func (d *decoder) decodeV9Templates(source string, domain uint32, body []byte) error {
+	for len(body) >= 4 {
+		id := binary.BigEndian.Uint16(body)
+		count := int(binary.BigEndian.Uint16(body[2:]))
+		body = body[4:]
+		if len(body) < count*4 {
+			return fmt.Errorf("netflow v9 template %d too short", id)
+		}
+
+		tmpl := &template{}
+		for i := 0; i < count; i++ {
+			tmpl.fields = append(tmpl.fields, templateField{
+				key:    elementKey{id: binary.BigEndian.Uint16(body)},
+				length: binary.BigEndian.Uint16(body[2:]),
+			})
+			body = body[4:]
+		}
+		d.templates[templateKey{source, domain, id}] = tmpl
+	}
+	return nil
+}
+
+func (d *decoder) decodeV9OptionsTemplates(source string, domain uint32, body []byte) error {
+	for len(body) >= 6 {
+		id := binary.BigEndian.Uint16(body)
+		scopeLength := int(binary.BigEndian.Uint16(body[2:]))
+		optionLength := int(binary.BigEndian.Uint16(body[4:]))
+		body = body[6:]
+		if scopeLength+optionLength == 0 {
+			// padding
+			return nil
+		}
+		if len(body) < scopeLength+optionLength {
+			return fmt.Errorf("netflow v9 options template %d too short", id)
+		}
+
+		tmpl := &template{options: true}
+		for i := 0; i < (scopeLength+optionLength)/4; i++ {
+			tmpl.fields = append(tmpl.fields, templateField{
+				key:    elementKey{id: binary.BigEndian.Uint16(body[i*4:])},
+				length: binary.BigEndian.Uint16(body[i*4+2:]),
+				scope:  i < scopeLength/4,
+			})
+		}
+		body = body[scopeLength+optionLength:]
+		d.templates[templateKey{source, domain, id}] = tmpl
+	}
+	return nil
+}
+
+func (d *decoder) decodeIPFIX(source string, buf []byte) ([]telegraf.Metric, error) {
+	if len(buf) < ipfixHeaderLength {
+		return nil, fmt.Errorf("ipfix header too short")
+	}
+	length := int(binary.BigEndian.Uint16(buf[2:]))
+	if length < ipfixHeaderLength || length > len(buf) {
+		return nil, fmt.Errorf("invalid ipfix message length %d", length)
+	}
+	buf = buf[:length]
+	secs := binary.BigEndian.Uint32(buf[4:])
+	domain := binary.BigEndian.Uint32(buf[12:])
+	t := time.Unix(int64(secs), 0)
+
+	var metrics []telegraf.Metric
+	for off := ipfixHeaderLength; off+4 <= len(buf); {
+		setID := binary.BigEndian.Uint16(buf[off:])
+		setLength := int(binary.BigEndian.Uint16(buf[off+2:]))
+		if setLength < 4 || off+setLength > len(buf) {
+			return metrics, fmt.Errorf("invalid ipfix set length %d", setLength)
+		}
+		body := buf[off+4 : off+setLength]
+		off += setLength
+
+		var err error
+		switch {
+		case setID == 2:
+			err = d.decodeIPFIXTemplates(source, domain, body, false)
+		case setID == 3:
+			err = d.decodeIPFIXTemplates(source, domain, body, true)
+		case setID >= 256:
+			var ms []telegraf.Metric
+			ms, err = d.decodeData(source, domain, setID, "IPFIX", body, t)
+			metrics = append(metrics, ms...)
+		}
+		if err != nil {
+			return metrics, err
+		}
+	}
+	return metrics, nil
+}
+
+func (d *decoder) decodeIPFIXTemplates(source string, domain uint32, body []byte, options bool) error {
+	headerLength := 4
+	if options {
+		headerLength = 6
+	}
+
+	for len(body) >= headerLength {
+		id := binary.BigEndian.Uint16(body)
+		count := int(binary.BigEndian.Uint16(body[2:]))
+		scopeCount := 0
+		if options && count > 0 {
+			scopeCount = int(binary.BigEndian.Uint16(body[4:]))
+		}
+		if id < 256 {
+			// padding
+			return nil
+		}
+		if count == 0 {
+			// template withdrawal
+			delete(d.templates, templateKey{source, domain, id})
+			body = body[4:]
+			continue
+		}
+		body = body[headerLength:]
+
+		tmpl := &template{options: options}
+		for i := 0; i < count; i++ {
+			if len(body) < 4 {
+				return fmt.Errorf("ipfix template %d too short", id)
+			}
+			field := templateField{
+				key:    elementKey{id: binary.BigEndian.Uint16(body) & 0x7fff},
+				length: binary.BigEndian.Uint16(body[2:]),
+				scope:  i < scopeCount,
+			}
+			enterprise := binary.BigEndian.Uint16(body)&0x8000 != 0
+			body = body[4:]
+			if enterprise {
+				if len(body) < 4 {
+					return fmt.Errorf("ipfix template %d too short", id)
+				}
+				field.key.enterprise = binary.BigEndian.Uint32(body)
+				body = body[4:]
+			}
+			tmpl.fields = append(tmpl.fields, field)
+		}
+		d.templates[templateKey{source, domain, id}] = tmpl
+	}
+	return nil
+}
+
+// decodeData decodes the records of a data set with the template.  Data sets
+// of unknown templates are dropped until the exporter sends the template.
+func (d *decoder) decodeData(
+	source string,
+	domain uint32,
+	id uint16,
+	version string,
+	body []byte,
+	t time.Time,
+) ([]telegraf.Metric, error) {
+	tmpl, ok := d.templates[templateKey{source, domain, id}]
+	if !ok {
+		log.Printf("D! [inputs.netflow] Dropping data set of unknown template %d from %s domain %d", id, source, domain)
+		return nil, nil
+	}
+
+	minLength := 0
+	for _, f := range tmpl.fields {
+		if f.length == variableLength {
+			minLength++
+		} else {
+			minLength += int(f.length)
+		}
+	}
+	if minLength == 0 {
+		return nil, nil
+	}
+
+	name := "netflow"
+	if tmpl.options {
+		name = "netflow_options"
+	}
+
+	var metrics []telegraf.Metric
+	for len(body) >= minLength {
+		values := make(map[string]interface{}, len(tmpl.fields))
+		for _, f := range tmpl.fields {
+			length := int(f.length)
+			if f.length == variableLength {
+				if len(body) < 1 {
+					return metrics, fmt.Errorf("record of template %d too short", id)
+				}
+				length = int(body[0])
+				body = body[1:]
+				if length == 255 {
+					if len(body) < 2 {
+						return metrics, fmt.Errorf("record of template %d too short", id)
+					}
+					length = int(binary.BigEndian.Uint16(body))
+					body = body[2:]
+				}
+			}
+			if len(body) < length {
+				return metrics, fmt.Errorf("record of template %d too short", id)
+			}
+
+			e, ok := d.element(f, version)
+			if !ok {
+				e = element{name: unknownName(f.key), typ: typeHex}
+			}
+			values[e.name] = decodeValue(e.typ, body[:length])
+			body = body[length:]
+		}
+
+		m, err := d.newMetric(name, source, version, values, t)
+		if err != nil {
+			return metrics, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (d *decoder) element(f templateField, version string) (element, bool) {
+	if f.scope && version == "NetFlowV9" {
+		e, ok := v9ScopeElements[f.key.id]
+		return e, ok
+	}
+	e, ok := d.elements[f.key]
+	return e, ok
+}
@@ -0,0 +1,385 @@
+package netflow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// packet builds packets in network byte order.
+type packet struct {
+	bytes.Buffer
+}
+
+func (p *packet) put(values ...interface{}) *packet {
+	for _, v := range values {
+		binary.Write(&p.Buffer, binary.BigEndian, v)
+	}
+	return p
+}
+
+// set appends a flowset or set with its header.
+func (p *packet) set(id uint16, body []byte) *packet {
+	p.put(id, uint16(len(body)+4))
+	p.Write(body)
+	return p
+}
+
+// fixLength sets the length field of an IPFIX message.
+func (p *packet) fixLength() []byte {
+	b := p.Bytes()
+	binary.BigEndian.PutUint16(b[2:], uint16(len(b)))
+	return b
+}
+
+func v5Packet() []byte {
+	p := &packet{}
+	// header
+	p.put(uint16(5), uint16(1), uint32(360000), uint32(1563000000), uint32(500),
+		uint32(42), uint8(1), uint8(2), uint16(0x4000|100))
+	// record
+	p.put([]byte{10, 0, 0, 1}, []byte{10, 0, 0, 2}, []byte{10, 0, 0, 254},
+		uint16(3), uint16(4), uint32(10), uint32(1500), uint32(350000), uint32(359000),
+		uint16(51000), uint16(443), uint8(0), uint8(0x12), uint8(6), uint8(0),
+		uint16(64512), uint16(64513), uint8(24), uint8(16), uint16(0))
+	return p.Bytes()
+}
+
+func TestDecodeV5(t *testing.T) {
+	d := newDecoder(defaultElements(), []string{"protocol"})
+	metrics, err := d.decode("192.0.2.1", v5Packet())
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"netflow",
+			map[string]string{
+				"source":   "192.0.2.1",
+				"version":  "NetFlowV5",
+				"protocol": "6",
+			},
+			map[string]interface{}{
+				"src_addr":          "10.0.0.1",
+				"dst_addr":          "10.0.0.2",
+				"next_hop":          "10.0.0.254",
+				"in_snmp":           uint64(3),
+				"out_snmp":          uint64(4),
+				"in_packets":        uint64(10),
+				"in_bytes":          uint64(1500),
+				"first_switched":    uint64(350000),
+				"last_switched":     uint64(359000),
+				"src_port":          uint64(51000),
+				"dst_port":          uint64(443),
+				"tcp_flags":         uint64(0x12),
+				"src_tos":           uint64(0),
+				"src_as":            uint64(64512),
+				"dst_as":            uint64(64513),
+				"src_mask":          uint64(24),
+				"dst_mask":          uint64(16),
+				"engine_type":       uint64(1),
+				"engine_id":         uint64(2),
+				"sampling_interval": uint64(100),
+			},
+			time.Unix(1563000000, 500),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestDecodeV5Truncated(t *testing.T) {
+	d := newDecoder(defaultElements(), nil)
+	_, err := d.decode("192.0.2.1", v5Packet()[:60])
+	require.Error(t, err)
+}
+
+func v9Header(sourceID uint32) *packet {
+	p := &packet{}
+	p.put(uint16(9), uint16(2), uint32(360000), uint32(1563000000), uint32(7), sourceID)
+	return p
+}
+
+func v9TemplateSet() []byte {
+	t := &packet{}
+	t.put(uint16(256), uint16(5),
+		uint16(8), uint16(4), // src_addr
+		uint16(12), uint16(4), // dst_addr
+		uint16(4), uint16(1), // protocol
+		uint16(1), uint16(8), // in_bytes
+		uint16(1000), uint16(2), // unknown
+	)
+	return t.Bytes()
+}
+
+func v9DataSet() []byte {
+	data := &packet{}
+	data.put([]byte{10, 0, 0, 1}, []byte{10, 0, 0, 2}, uint8(17), uint64(1234), uint16(0xabcd))
+	data.put([]byte{10, 0, 0, 3}, []byte{10, 0, 0, 4}, uint8(6), uint64(42), uint16(1))
+	data.put(uint8(0), uint8(0), uint8(0)) // padding
+	return data.Bytes()
+}
+
+func TestDecodeV9(t *testing.T) {
+	d := newDecoder(defaultElements(), nil)
+
+	p := v9Header(1)
+	p.set(0, v9TemplateSet())
+	p.set(256, v9DataSet())
+
+	metrics, err := d.decode("192.0.2.1", p.Bytes())
+	require.NoError(t, err)
+
+	tags := map[string]string{"source": "192.0.2.1", "version": "NetFlowV9"}
+	ts := time.Unix(1563000000, 0)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("netflow", tags,
+			map[string]interface{}{
+				"src_addr":  "10.0.0.1",
+				"dst_addr":  "10.0.0.2",
+				"protocol":  uint64(17),
+				"in_bytes":  uint64(1234),
+				"type_1000": "abcd",
+			},
+			ts,
+		),
+		testutil.MustMetric("netflow", tags,
+			map[string]interface{}{
+				"src_addr":  "10.0.0.3",
+				"dst_addr":  "10.0.0.4",
+				"protocol":  uint64(6),
+				"in_bytes":  uint64(42),
+				"type_1000": "0001",
+			},
+			ts,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestDecodeV9TemplateCache(t *testing.T) {
+	d := newDecoder(defaultElements(), nil)
+
+	// data before its template is dropped
+	p := v9Header(1)
+	p.set(256, v9DataSet())
+	metrics, err := d.decode("192.0.2.1", p.Bytes())
+	require.NoError(t, err)
+	require.Len(t, metrics, 0)
+
+	p = v9Header(1)
+	p.set(0, v9TemplateSet())
+	_, err = d.decode("192.0.2.1", p.Bytes())
+	require.NoError(t, err)
+
+	p = v9Header(1)
+	p.set(256, v9DataSet())
+	metrics, err = d.decode("192.0.2.1", p.Bytes())
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+
+	// templates are not shared between exporters or source ids
+	p = v9Header(2)
+	p.set(256, v9DataSet())
+	metrics, err = d.decode("192.0.2.1", p.Bytes())
+	require.NoError(t, err)
+	require.Len(t, metrics, 0)
+
+	p = v9Header(1)
+	p.set(256, v9DataSet())
+	metrics, err = d.decode("192.0.2.2", p.Bytes())
+	require.NoError(t, err)
+	require.Len(t, metrics, 0)
+}
+
+func TestDecodeV9Options(t *testing.T) {
+	d := newDecoder(defaultElements(), nil)
+
+	tmpl := &packet{}
+	tmpl.put(uint16(257), uint16(4), uint16(8),
+		uint16(1), uint16(4), // scope system
+		uint16(34), uint16(4), // sampling_interval
+		uint16(35), uint16(1), // sampling_algorithm
+	)
+	tmpl.put(uint8(0), uint8(0)) // padding
+
+	data := &packet{}
+	data.put([]byte{192, 0, 2, 1}, uint32(1000), uint8(2))
+	data.put(uint8(0), uint8(0), uint8(0))
+
+	p := v9Header(1)
+	p.set(1, tmpl.Bytes())
+	p.set(257, data.Bytes())
+
+	metrics, err := d.decode("192.0.2.1", p.Bytes())
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("netflow_options",
+			map[string]string{"source": "192.0.2.1", "version": "NetFlowV9"},
+			map[string]interface{}{
+				"scope_system":       "c0000201",
+				"sampling_interval":  uint64(1000),
+				"sampling_algorithm": uint64(2),
+			},
+			time.Unix(1563000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func ipfixHeader(domain uint32) *packet {
+	p := &packet{}
+	p.put(uint16(10), uint16(0), uint32(1563000000), uint32(1), domain)
+	return p
+}
+
+func ipfixTemplateSet() []byte {
+	t := &packet{}
+	t.put(uint16(300), uint16(5),
+		uint16(27), uint16(16), // src_addr_v6
+		uint16(7), uint16(2), // src_port
+		uint16(96), uint16(0xffff), // application_name
+		uint16(0x8000|1), uint16(4), uint32(29305), // enterprise
+		uint16(0x8000|2), uint16(4), uint32(29305), // enterprise
+	)
+	return t.Bytes()
+}
+
+func ipfixDataSet() []byte {
+	data := &packet{}
+	data.put([]byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, uint16(53))
+	data.put(uint8(3), []byte("dns"))
+	data.put(uint32(7), float32(1.5))
+	return data.Bytes()
+}
+
+func TestDecodeIPFIX(t *testing.T) {
+	elements := defaultElements()
+	elements[elementKey{enterprise: 29305, id: 2}] = element{"ratio", typeFloat}
+	d := newDecoder(elements, []string{"application_name"})
+
+	p := ipfixHeader(5)
+	p.set(2, ipfixTemplateSet())
+	p.set(300, ipfixDataSet())
+
+	metrics, err := d.decode("2001:db8::ff", p.fixLength())
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("netflow",
+			map[string]string{
+				"source":           "2001:db8::ff",
+				"version":          "IPFIX",
+				"application_name": "dns",
+			},
+			map[string]interface{}{
+				"src_addr_v6": "2001:db8::1",
+				"src_port":    uint64(53),
+				"pen29305_1":  "00000007",
+				"ratio":       1.5,
+			},
+			time.Unix(1563000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestDecodeIPFIXWithdrawal(t *testing.T) {
+	d := newDecoder(defaultElements(), nil)
+
+	p := ipfixHeader(5)
+	p.set(2, ipfixTemplateSet())
+	_, err := d.decode("192.0.2.1", p.fixLength())
+	require.NoError(t, err)
+
+	p = ipfixHeader(5)
+	p.set(2, (&packet{}).put(uint16(300), uint16(0)).Bytes())
+	p.set(300, ipfixDataSet())
+	metrics, err := d.decode("192.0.2.1", p.fixLength())
+	require.NoError(t, err)
+	require.Len(t, metrics, 0)
+}
+
+func TestDecodeIPFIXLongVariableLength(t *testing.T) {
+	d := newDecoder(defaultElements(), nil)
+
+	tmpl := &packet{}
+	tmpl.put(uint16(256), uint16(1), uint16(83), uint16(0xffff))
+
+	desc := bytes.Repeat([]byte("x"), 300)
+	data := &packet{}
+	data.put(uint8(255), uint16(len(desc)), desc)
+
+	p := ipfixHeader(0)
+	p.set(2, tmpl.Bytes())
+	p.set(256, data.Bytes())
+
+	metrics, err := d.decode("192.0.2.1", p.fixLength())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, string(desc), metrics[0].Fields()["if_desc"])
+}
+
+func TestDecodeUnsupportedVersion(t *testing.T) {
+	d := newDecoder(defaultElements(), nil)
+	_, err := d.decode("192.0.2.1", []byte{0, 7, 0, 0})
+	require.Error(t, err)
+}
+
+func TestLoadFieldDefinitions(t *testing.T) {
+	f, err := ioutil.TempFile("", "netflow")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	f.WriteString(`# id,name,type
+29305.1, app_tag, string
+95,application_id,uint
+
+9.12,cisco_counter,int
+`)
+	f.Close()
+
+	elements := defaultElements()
+	require.NoError(t, loadFieldDefinitions(f.Name(), elements))
+	require.Equal(t, element{"app_tag", typeString}, elements[elementKey{29305, 1}])
+	require.Equal(t, element{"application_id", typeUint}, elements[elementKey{0, 95}])
+	require.Equal(t, element{"cisco_counter", typeInt}, elements[elementKey{9, 12}])
+	require.Equal(t, element{"in_bytes", typeUint}, elements[elementKey{0, 1}])
+}
+
+func TestLoadFieldDefinitionsErrors(t *testing.T) {
+	tests := []string{
+		"1,in_bytes",
+		"x,in_bytes,uint",
+		"1.x,in_bytes,uint",
+		"40000,in_bytes,uint",
+		"1,,uint",
+		"1,in_bytes,double",
+	}
+	for _, line := range tests {
+		f, err := ioutil.TempFile("", "netflow")
+		require.NoError(t, err)
+		f.WriteString(line + "\n")
+		f.Close()
+
+		err = loadFieldDefinitions(f.Name(), defaultElements())
+		os.Remove(f.Name())
+		require.Error(t, err, line)
+	}
+}
+
+func TestDecodeValue(t *testing.T) {
+	require.Equal(t, uint64(0x010203), decodeValue(typeUint, []byte{1, 2, 3}))
+	require.Equal(t, int64(-2), decodeValue(typeInt, []byte{0xff, 0xfe}))
+	require.Equal(t, true, decodeValue(typeBool, []byte{1}))
+	require.Equal(t, false, decodeValue(typeBool, []byte{2}))
+	require.Equal(t, "eth0", decodeValue(typeString, []byte("eth0\x00\x00")))
+	require.Equal(t, "00:11:22:33:44:55", decodeValue(typeMAC, []byte{0, 0x11, 0x22, 0x33, 0x44, 0x55}))
+	require.Equal(t, "0102", decodeValue(typeIP, []byte{1, 2}))
+}
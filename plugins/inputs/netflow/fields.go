@@ -0,0 +1,322 @@
+package netflow
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// fieldType is the decoding of the value of an information element.
+type fieldType int
+
+const (
+	typeUint fieldType = iota
+	typeInt
+	typeFloat
+	typeBool
+	typeString
+	typeIP
+	typeMAC
+	typeHex
+)
+
+var fieldTypes = map[string]fieldType{
+	"uint":   typeUint,
+	"int":    typeInt,
+	"float":  typeFloat,
+	"bool":   typeBool,
+	"string": typeString,
+	"ip":     typeIP,
+	"mac":    typeMAC,
+	"hex":    typeHex,
+}
+
+// elementKey identifies an information element, the enterprise number is 0
+// for the elements defined by IANA and NetFlow v9.
+type elementKey struct {
+	enterprise uint32
+	id         uint16
+}
+
+type element struct {
+	name string
+	typ  fieldType
+}
+
+// ianaElements are the standard information elements.  The elements up to
+// 127 are shared by NetFlow v9 and IPFIX and use the NetFlow v9 names.
+var ianaElements = map[uint16]element{
+	1:   {"in_bytes", typeUint},
+	2:   {"in_packets", typeUint},
+	3:   {"flows", typeUint},
+	4:   {"protocol", typeUint},
+	5:   {"src_tos", typeUint},
+	6:   {"tcp_flags", typeUint},
+	7:   {"src_port", typeUint},
+	8:   {"src_addr", typeIP},
+	9:   {"src_mask", typeUint},
+	10:  {"in_snmp", typeUint},
+	11:  {"dst_port", typeUint},
+	12:  {"dst_addr", typeIP},
+	13:  {"dst_mask", typeUint},
+	14:  {"out_snmp", typeUint},
+	15:  {"next_hop", typeIP},
+	16:  {"src_as", typeUint},
+	17:  {"dst_as", typeUint},
+	18:  {"bgp_next_hop", typeIP},
+	19:  {"mul_dst_packets", typeUint},
+	20:  {"mul_dst_bytes", typeUint},
+	21:  {"last_switched", typeUint},
+	22:  {"first_switched", typeUint},
+	23:  {"out_bytes", typeUint},
+	24:  {"out_packets", typeUint},
+	25:  {"min_packet_length", typeUint},
+	26:  {"max_packet_length", typeUint},
+	27:  {"src_addr_v6", typeIP},
+	28:  {"dst_addr_v6", typeIP},
+	29:  {"src_mask_v6", typeUint},
+	30:  {"dst_mask_v6", typeUint},
+	31:  {"flow_label_v6", typeUint},
+	32:  {"icmp_type", typeUint},
+	33:  {"igmp_type", typeUint},
+	34:  {"sampling_interval", typeUint},
+	35:  {"sampling_algorithm", typeUint},
+	36:  {"flow_active_timeout", typeUint},
+	37:  {"flow_inactive_timeout", typeUint},
+	38:  {"engine_type", typeUint},
+	39:  {"engine_id", typeUint},
+	40:  {"total_bytes_exported", typeUint},
+	41:  {"total_packets_exported", typeUint},
+	42:  {"total_flows_exported", typeUint},
+	44:  {"src_prefix", typeIP},
+	45:  {"dst_prefix", typeIP},
+	46:  {"mpls_top_label_type", typeUint},
+	47:  {"mpls_top_label_addr", typeIP},
+	48:  {"sampler_id", typeUint},
+	49:  {"sampler_mode", typeUint},
+	50:  {"sampler_random_interval", typeUint},
+	52:  {"min_ttl", typeUint},
+	53:  {"max_ttl", typeUint},
+	54:  {"fragment_id", typeUint},
+	55:  {"dst_tos", typeUint},
+	56:  {"in_src_mac", typeMAC},
+	57:  {"out_dst_mac", typeMAC},
+	58:  {"src_vlan", typeUint},
+	59:  {"dst_vlan", typeUint},
+	60:  {"ip_version", typeUint},
+	61:  {"direction", typeUint},
+	62:  {"next_hop_v6", typeIP},
+	63:  {"bgp_next_hop_v6", typeIP},
+	64:  {"option_headers_v6", typeUint},
+	70:  {"mpls_label_1", typeUint},
+	71:  {"mpls_label_2", typeUint},
+	72:  {"mpls_label_3", typeUint},
+	73:  {"mpls_label_4", typeUint},
+	74:  {"mpls_label_5", typeUint},
+	75:  {"mpls_label_6", typeUint},
+	76:  {"mpls_label_7", typeUint},
+	77:  {"mpls_label_8", typeUint},
+	78:  {"mpls_label_9", typeUint},
+	79:  {"mpls_label_10", typeUint},
+	80:  {"in_dst_mac", typeMAC},
+	81:  {"out_src_mac", typeMAC},
+	82:  {"if_name", typeString},
+	83:  {"if_desc", typeString},
+	84:  {"sampler_name", typeString},
+	85:  {"in_permanent_bytes", typeUint},
+	86:  {"in_permanent_packets", typeUint},
+	88:  {"fragment_offset", typeUint},
+	89:  {"forwarding_status", typeUint},
+	90:  {"mpls_pal_rd", typeHex},
+	91:  {"mpls_prefix_len", typeUint},
+	92:  {"src_traffic_index", typeUint},
+	93:  {"dst_traffic_index", typeUint},
+	94:  {"application_description", typeString},
+	95:  {"application_id", typeHex},
+	96:  {"application_name", typeString},
+	98:  {"post_ip_dscp", typeUint},
+	99:  {"replication_factor", typeUint},
+	128: {"bgp_next_adjacent_as", typeUint},
+	129: {"bgp_prev_adjacent_as", typeUint},
+	130: {"exporter_addr", typeIP},
+	131: {"exporter_addr_v6", typeIP},
+	132: {"dropped_bytes", typeUint},
+	133: {"dropped_packets", typeUint},
+	136: {"flow_end_reason", typeUint},
+	137: {"common_properties_id", typeUint},
+	138: {"observation_point_id", typeUint},
+	139: {"icmp_type_code_v6", typeUint},
+	144: {"exporting_process_id", typeUint},
+	148: {"flow_id", typeUint},
+	149: {"observation_domain_id", typeUint},
+	150: {"flow_start_seconds", typeUint},
+	151: {"flow_end_seconds", typeUint},
+	152: {"flow_start_milliseconds", typeUint},
+	153: {"flow_end_milliseconds", typeUint},
+	160: {"system_init_time_milliseconds", typeUint},
+	161: {"flow_duration_milliseconds", typeUint},
+	176: {"icmp_type_v4", typeUint},
+	177: {"icmp_code_v4", typeUint},
+	178: {"icmp_type_v6", typeUint},
+	179: {"icmp_code_v6", typeUint},
+	192: {"ip_ttl", typeUint},
+	195: {"ip_dscp", typeUint},
+	196: {"ip_precedence", typeUint},
+	197: {"fragment_flags", typeUint},
+	224: {"ip_total_length", typeUint},
+	225: {"post_nat_src_addr", typeIP},
+	226: {"post_nat_dst_addr", typeIP},
+	227: {"post_napt_src_port", typeUint},
+	228: {"post_napt_dst_port", typeUint},
+	233: {"firewall_event", typeUint},
+	234: {"ingress_vrf_id", typeUint},
+	235: {"egress_vrf_id", typeUint},
+	239: {"biflow_direction", typeUint},
+	243: {"dot1q_vlan_id", typeUint},
+	244: {"dot1q_priority", typeUint},
+	256: {"ethernet_type", typeUint},
+	352: {"layer2_octet_delta_count", typeUint},
+}
+
+// v9ScopeElements are the field types of the scope fields of NetFlow v9
+// options templates, which overlap with the standard elements.
+var v9ScopeElements = map[uint16]element{
+	1: {"scope_system", typeHex},
+	2: {"scope_interface", typeUint},
+	3: {"scope_line_card", typeUint},
+	4: {"scope_cache", typeHex},
+	5: {"scope_template", typeUint},
+}
+
+// defaultElements returns the standard information elements.
+func defaultElements() map[elementKey]element {
+	elements := make(map[elementKey]element, len(ianaElements))
+	for id, e := range ianaElements {
+		elements[elementKey{id: id}] = e
+	}
+	return elements
+}
+
+// loadFieldDefinitions adds the elements defined in a file to the elements.
+// Each line has the form "<id>,<name>,<type>", where the id of enterprise
+// specific elements is "<enterprise number>.<element id>".  Empty lines and
+// lines starting with '#' are ignored.
+func loadFieldDefinitions(path string, elements map[elementKey]element) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, ",")
+		if len(parts) != 3 {
+			return fmt.Errorf("%s:%d: expected <id>,<name>,<type>", path, lineno)
+		}
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+
+		key, err := parseElementKey(parts[0])
+		if err != nil {
+			return fmt.Errorf("%s:%d: %s", path, lineno, err)
+		}
+		if parts[1] == "" {
+			return fmt.Errorf("%s:%d: empty name", path, lineno)
+		}
+		typ, ok := fieldTypes[parts[2]]
+		if !ok {
+			return fmt.Errorf("%s:%d: unknown type %q", path, lineno, parts[2])
+		}
+		elements[key] = element{name: parts[1], typ: typ}
+	}
+	return scanner.Err()
+}
+
+func parseElementKey(s string) (elementKey, error) {
+	var key elementKey
+	id := s
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		enterprise, err := strconv.ParseUint(s[:i], 10, 32)
+		if err != nil {
+			return key, fmt.Errorf("invalid enterprise number %q", s[:i])
+		}
+		key.enterprise = uint32(enterprise)
+		id = s[i+1:]
+	}
+	n, err := strconv.ParseUint(id, 10, 15)
+	if err != nil {
+		return key, fmt.Errorf("invalid element id %q", id)
+	}
+	key.id = uint16(n)
+	return key, nil
+}
+
+// unknownName returns the field name of an element without a definition.
+func unknownName(key elementKey) string {
+	if key.enterprise != 0 {
+		return fmt.Sprintf("pen%d_%d", key.enterprise, key.id)
+	}
+	return fmt.Sprintf("type_%d", key.id)
+}
+
+// decodeValue decodes the value of an element.  Values with a length not
+// matching the type are decoded as hex.
+func decodeValue(typ fieldType, b []byte) interface{} {
+	switch typ {
+	case typeUint:
+		if len(b) > 0 && len(b) <= 8 {
+			var v uint64
+			for _, c := range b {
+				v = v<<8 | uint64(c)
+			}
+			return v
+		}
+	case typeInt:
+		if len(b) > 0 && len(b) <= 8 {
+			var v uint64
+			for _, c := range b {
+				v = v<<8 | uint64(c)
+			}
+			shift := uint(64 - 8*len(b))
+			return int64(v<<shift) >> shift
+		}
+	case typeFloat:
+		switch len(b) {
+		case 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+		case 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(b))
+		}
+	case typeBool:
+		if len(b) == 1 {
+			// IPFIX encodes true as 1 and false as 2
+			return b[0] == 1
+		}
+	case typeString:
+		return strings.TrimRight(string(b), "\x00")
+	case typeIP:
+		if len(b) == net.IPv4len || len(b) == net.IPv6len {
+			return net.IP(b).String()
+		}
+	case typeMAC:
+		if len(b) == 6 {
+			return net.HardwareAddr(b).String()
+		}
+	}
+	return hex.EncodeToString(b)
+}
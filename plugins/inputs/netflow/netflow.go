@@ -0,0 +1,171 @@
+package netflow
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+type setReadBufferer interface {
+	SetReadBuffer(bytes int) error
+}
+
+type NetFlow struct {
+	ServiceAddress   string        `toml:"service_address"`
+	ReadBufferSize   internal.Size `toml:"read_buffer_size"`
+	TagKeys          []string      `toml:"tag_keys"`
+	FieldDefinitions string        `toml:"field_definitions"`
+
+	conn    net.PacketConn
+	decoder *decoder
+	wg      sync.WaitGroup
+}
+
+const sampleConfig = `
+  ## URL to listen on
+  # service_address = "udp://:2055"
+  # service_address = "udp4://:2055"
+  # service_address = "udp6://:2055"
+
+  ## Maximum socket buffer size (in bytes when no unit specified).
+  ## Once the buffer fills up, packets will start dropping.
+  ## Defaults to the OS default.
+  # read_buffer_size = "64KiB"
+
+  ## Information elements to add as tags instead of fields.
+  # tag_keys = ["protocol", "in_snmp", "out_snmp"]
+
+  ## Path to a CSV file with additional or overriding information element
+  ## definitions, one "<id>,<name>,<type>" definition per line.  The id of
+  ## enterprise specific elements is "<enterprise number>.<element id>", the
+  ## type is one of uint, int, float, bool, string, ip, mac or hex.
+  # field_definitions = "/etc/telegraf/netflow_fields.csv"
+`
+
+func (n *NetFlow) Description() string {
+	return "NetFlow v5, v9 and IPFIX collector"
+}
+
+func (n *NetFlow) SampleConfig() string {
+	return sampleConfig
+}
+
+func (n *NetFlow) Init() error {
+	elements := defaultElements()
+	if n.FieldDefinitions != "" {
+		if err := loadFieldDefinitions(n.FieldDefinitions, elements); err != nil {
+			return err
+		}
+	}
+	n.decoder = newDecoder(elements, n.TagKeys)
+	return nil
+}
+
+func (n *NetFlow) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (n *NetFlow) Start(acc telegraf.Accumulator) error {
+	spl := strings.SplitN(n.ServiceAddress, "://", 2)
+	if len(spl) != 2 {
+		return fmt.Errorf("invalid service address: %s", n.ServiceAddress)
+	}
+
+	protocol := spl[0]
+	switch protocol {
+	case "udp", "udp4", "udp6":
+	default:
+		return fmt.Errorf("unknown protocol '%s' in '%s'", protocol, n.ServiceAddress)
+	}
+
+	conn, err := udpListen(protocol, spl[1])
+	if err != nil {
+		return err
+	}
+
+	if n.ReadBufferSize.Size > 0 {
+		if srb, ok := conn.(setReadBufferer); ok {
+			srb.SetReadBuffer(int(n.ReadBufferSize.Size))
+		} else {
+			log.Printf("W! Unable to set read buffer on a %s socket", protocol)
+		}
+	}
+
+	log.Printf("I! [inputs.netflow] Listening on %s://%s", protocol, conn.LocalAddr())
+
+	n.conn = conn
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.listen(conn, acc)
+	}()
+	return nil
+}
+
+func (n *NetFlow) listen(conn net.PacketConn, acc telegraf.Accumulator) {
+	buf := make([]byte, 64*1024) // 64kb - maximum size of IP packet
+	for {
+		size, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), ": use of closed network connection") {
+				acc.AddError(err)
+			}
+			break
+		}
+
+		source := addr.String()
+		if udpAddr, ok := addr.(*net.UDPAddr); ok {
+			source = udpAddr.IP.String()
+		}
+
+		metrics, err := n.decoder.decode(source, buf[:size])
+		for _, m := range metrics {
+			acc.AddMetric(m)
+		}
+		if err != nil {
+			acc.AddError(fmt.Errorf("unable to decode packet from %s: %s", source, err))
+		}
+	}
+}
+
+func (n *NetFlow) Stop() {
+	if n.conn != nil {
+		n.conn.Close()
+	}
+	n.wg.Wait()
+	n.conn = nil
+}
+
+func udpListen(network string, address string) (net.PacketConn, error) {
+	var ifi *net.Interface
+	if spl := strings.SplitN(address, "%", 2); len(spl) == 2 {
+		address = spl[0]
+		var err error
+		ifi, err = net.InterfaceByName(spl[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	addr, err := net.ResolveUDPAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if addr.IP.IsMulticast() {
+		return net.ListenMulticastUDP(network, ifi, addr)
+	}
+	return net.ListenUDP(network, addr)
+}
+
+func init() {
+	inputs.Add("netflow", func() telegraf.Input {
+		return &NetFlow{
+			ServiceAddress: "udp://:2055",
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package netflow
+
+import (
+	"net"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetFlowListener(t *testing.T) {
+	n := &NetFlow{
+		ServiceAddress: "udp://127.0.0.1:0",
+		TagKeys:        []string{"protocol"},
+	}
+	require.NoError(t, n.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, n.Start(acc))
+	defer n.Stop()
+
+	client, err := net.Dial("udp", n.conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Write(v5Packet())
+	require.NoError(t, err)
+
+	acc.Wait(1)
+	acc.AssertContainsTaggedFields(t, "netflow",
+		map[string]interface{}{
+			"src_addr":          "10.0.0.1",
+			"dst_addr":          "10.0.0.2",
+			"next_hop":          "10.0.0.254",
+			"in_snmp":           uint64(3),
+			"out_snmp":          uint64(4),
+			"in_packets":        uint64(10),
+			"in_bytes":          uint64(1500),
+			"first_switched":    uint64(350000),
+			"last_switched":     uint64(359000),
+			"src_port":          uint64(51000),
+			"dst_port":          uint64(443),
+			"tcp_flags":         uint64(0x12),
+			"src_tos":           uint64(0),
+			"src_as":            uint64(64512),
+			"dst_as":            uint64(64513),
+			"src_mask":          uint64(24),
+			"dst_mask":          uint64(16),
+			"engine_type":       uint64(1),
+			"engine_id":         uint64(2),
+			"sampling_interval": uint64(100),
+		},
+		map[string]string{
+			"source":   "127.0.0.1",
+			"version":  "NetFlowV5",
+			"protocol": "6",
+		},
+	)
+}
+
+func TestNetFlowDecodeError(t *testing.T) {
+	n := &NetFlow{ServiceAddress: "udp://127.0.0.1:0"}
+	require.NoError(t, n.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, n.Start(acc))
+	defer n.Stop()
+
+	client, err := net.Dial("udp", n.conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Write([]byte{0, 1, 0, 0})
+	require.NoError(t, err)
+
+	acc.WaitError(1)
+	require.Contains(t, acc.FirstError().Error(), "unsupported version 1")
+}
+
+func TestNetFlowInvalidAddress(t *testing.T) {
+	n := &NetFlow{ServiceAddress: "tcp://127.0.0.1:0"}
+	require.NoError(t, n.Init())
+	require.Error(t, n.Start(&testutil.Accumulator{}))
+}
@@ -0,0 +1,351 @@
+package opcua
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gopcua/opcua"
+	"github.com/gopcua/opcua/ua"
+)
+
+// client is the connection to an OPC UA server.
+type client interface {
+	Connect(ctx context.Context) error
+	Close() error
+	// Read reads the values of nodes.
+	Read(nodes []*ua.NodeID) ([]*ua.DataValue, error)
+	// Subscribe creates a subscription with a monitored item for each node
+	// and returns the status of each item.  Value changes are sent on the
+	// channel until ctx is done.
+	Subscribe(ctx context.Context, interval time.Duration, nodes []*ua.NodeID, ch chan<- notification) ([]ua.StatusCode, error)
+}
+
+// notification is a value change of the node at index handle, or an error
+// ending the subscription.
+type notification struct {
+	handle uint32
+	value  *ua.DataValue
+	err    error
+}
+
+// uaClient is a client using gopcua.
+type uaClient struct {
+	o      *OpcUA
+	client *opcua.Client
+}
+
+// Connect looks up the endpoints of the server and connects to it, giving
+// up when the context is done.  The handshakes after dialing do not watch
+// the context, so the connection is abandoned rather than canceled and the
+// client is closed once its handshake returns.
+func (c *uaClient) Connect(ctx context.Context) error {
+	type result struct {
+		client *opcua.Client
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		opts, err := c.options(ctx)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		client := opcua.NewClient(c.o.Endpoint, opts...)
+		if err := client.Connect(ctx); err != nil {
+			done <- result{err: err}
+			return
+		}
+		done <- result{client: client}
+	}()
+
+	select {
+	case r := <-done:
+		c.client = r.client
+		return r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-done; r.client != nil {
+				r.client.Close()
+			}
+		}()
+		return ctx.Err()
+	}
+}
+
+// options returns the security and authentication options of the endpoint
+// matching the configured security policy and mode.
+func (c *uaClient) options(ctx context.Context) ([]opcua.Option, error) {
+	endpoints, err := c.endpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting endpoints failed: %s", err)
+	}
+
+	policy, mode := c.o.SecurityPolicy, ua.MessageSecurityModeInvalid
+	if policy == "auto" {
+		policy = ""
+	}
+	if c.o.SecurityMode != "auto" {
+		mode = ua.MessageSecurityModeFromString(c.o.SecurityMode)
+	}
+	endpoint := opcua.SelectEndpoint(endpoints, policy, mode)
+	if endpoint == nil {
+		return nil, fmt.Errorf("no endpoint with security policy '%s' and mode '%s'", c.o.SecurityPolicy, c.o.SecurityMode)
+	}
+
+	var authType ua.UserTokenType
+	switch c.o.AuthMethod {
+	case "Anonymous":
+		authType = ua.UserTokenTypeAnonymous
+	case "UserName":
+		authType = ua.UserTokenTypeUserName
+	case "Certificate":
+		authType = ua.UserTokenTypeCertificate
+	}
+
+	opts := []opcua.Option{
+		opcua.SecurityFromEndpoint(endpoint, authType),
+		opcua.RequestTimeout(c.o.RequestTimeout.Duration),
+	}
+
+	// a client certificate is needed to sign or encrypt messages and to
+	// authenticate with it
+	if endpoint.SecurityMode != ua.MessageSecurityModeNone || c.o.AuthMethod == "Certificate" {
+		cert, key, err := c.o.certificate()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, opcua.Certificate(cert), opcua.PrivateKey(key))
+		if c.o.AuthMethod == "Certificate" {
+			opts = append(opts, opcua.AuthCertificate(cert))
+		}
+	}
+
+	switch c.o.AuthMethod {
+	case "Anonymous":
+		opts = append(opts, opcua.AuthAnonymous())
+	case "UserName":
+		opts = append(opts, opcua.AuthUsername(c.o.Username, c.o.Password))
+	}
+	return opts, nil
+}
+
+// endpoints returns the endpoints of the server like opcua.GetEndpoints,
+// dialing with the context.
+func (c *uaClient) endpoints(ctx context.Context) ([]*ua.EndpointDescription, error) {
+	client := opcua.NewClient(c.o.Endpoint, opcua.RequestTimeout(c.o.RequestTimeout.Duration))
+	if err := client.Dial(ctx); err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	res, err := client.GetEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	return res.Endpoints, nil
+}
+
+func (c *uaClient) Close() error {
+	if c.client == nil {
+		return nil
+	}
+	return c.client.Close()
+}
+
+func (c *uaClient) Read(nodes []*ua.NodeID) ([]*ua.DataValue, error) {
+	req := &ua.ReadRequest{
+		TimestampsToReturn: ua.TimestampsToReturnBoth,
+		NodesToRead:        make([]*ua.ReadValueID, 0, len(nodes)),
+	}
+	for _, id := range nodes {
+		req.NodesToRead = append(req.NodesToRead, &ua.ReadValueID{NodeID: id})
+	}
+
+	res, err := c.client.Read(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Results) != len(nodes) {
+		return nil, fmt.Errorf("read %d values of %d nodes", len(res.Results), len(nodes))
+	}
+	return res.Results, nil
+}
+
+func (c *uaClient) Subscribe(ctx context.Context, interval time.Duration, nodes []*ua.NodeID, ch chan<- notification) ([]ua.StatusCode, error) {
+	publish := make(chan *opcua.PublishNotificationData)
+	sub, err := c.client.Subscribe(&opcua.SubscriptionParameters{Interval: interval}, publish)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*ua.MonitoredItemCreateRequest, 0, len(nodes))
+	for i, id := range nodes {
+		item := opcua.NewMonitoredItemCreateRequestWithDefaults(id, ua.AttributeIDValue, uint32(i))
+		item.RequestedParameters.SamplingInterval = float64(interval / time.Millisecond)
+		items = append(items, item)
+	}
+	res, err := sub.Monitor(ua.TimestampsToReturnBoth, items...)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Results) != len(nodes) {
+		return nil, fmt.Errorf("created %d monitored items of %d nodes", len(res.Results), len(nodes))
+	}
+	statuses := make([]ua.StatusCode, 0, len(nodes))
+	for _, r := range res.Results {
+		statuses = append(statuses, r.StatusCode)
+	}
+
+	go sub.Run(ctx)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case data := <-publish:
+				if data.Error != nil {
+					send(ctx, ch, notification{err: data.Error})
+					continue
+				}
+				changes, ok := data.Value.(*ua.DataChangeNotification)
+				if !ok {
+					continue
+				}
+				for _, item := range changes.MonitoredItems {
+					send(ctx, ch, notification{handle: item.ClientHandle, value: item.Value})
+				}
+			}
+		}
+	}()
+	return statuses, nil
+}
+
+func send(ctx context.Context, ch chan<- notification, n notification) {
+	select {
+	case <-ctx.Done():
+	case ch <- n:
+	}
+}
+
+// certificate returns the configured client certificate and key, or
+// creates a self-signed certificate if none is configured.  It is safe to
+// call from an abandoned connection attempt.
+func (o *OpcUA) certificate() ([]byte, *rsa.PrivateKey, error) {
+	if o.Certificate != "" {
+		cert, err := loadCertificate(o.Certificate)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, err := loadPrivateKey(o.PrivateKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		return cert, key, nil
+	}
+
+	o.generatedMu.Lock()
+	defer o.generatedMu.Unlock()
+	if o.generated == nil {
+		cert, key, err := selfSignedCertificate()
+		if err != nil {
+			return nil, nil, err
+		}
+		o.generated = &generatedCertificate{cert: cert, key: key}
+	}
+	return o.generated.cert, o.generated.key, nil
+}
+
+type generatedCertificate struct {
+	cert []byte
+	key  *rsa.PrivateKey
+}
+
+// selfSignedCertificate creates a certificate for the application URI of
+// telegraf on this host.
+func selfSignedCertificate() ([]byte, *rsa.PrivateKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	notBefore := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"Telegraf"},
+			CommonName:   "Telegraf OPC UA Client",
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageDataEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{host},
+		URIs:                  []*url.URL{{Scheme: "urn", Opaque: "telegraf:" + host}},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
+// loadCertificate loads a PEM or DER encoded certificate.
+func loadCertificate(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(b); block != nil {
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("%s: unexpected PEM block type '%s'", path, block.Type)
+		}
+		b = block.Bytes
+	}
+	if _, err := x509.ParseCertificate(b); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return b, nil
+}
+
+// loadPrivateKey loads a PEM or DER encoded PKCS #1 or PKCS #8 RSA key.
+func loadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(b); block != nil {
+		b = block.Bytes
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(b); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an RSA private key", path)
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,426 @@
+package opcua
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopcua/opcua/ua"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// OpcUA reads the values of nodes of an OPC UA server.
+type OpcUA struct {
+	MetricName           string            `toml:"name"`
+	Endpoint             string            `toml:"endpoint"`
+	SecurityPolicy       string            `toml:"security_policy"`
+	SecurityMode         string            `toml:"security_mode"`
+	Certificate          string            `toml:"certificate"`
+	PrivateKey           string            `toml:"private_key"`
+	AuthMethod           string            `toml:"auth_method"`
+	Username             string            `toml:"username"`
+	Password             string            `toml:"password"`
+	ConnectTimeout       internal.Duration `toml:"connect_timeout"`
+	RequestTimeout       internal.Duration `toml:"request_timeout"`
+	Mode                 string            `toml:"mode"`
+	SubscriptionInterval internal.Duration `toml:"subscription_interval"`
+	Timestamp            string            `toml:"timestamp"`
+	Nodes                []Node            `toml:"node"`
+
+	nodeIDs     []*ua.NodeID
+	newClient   func() client
+	generatedMu sync.Mutex
+	generated   *generatedCertificate
+
+	acc    telegraf.Accumulator
+	mu     sync.Mutex
+	client client
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Node is a node whose value is read.
+type Node struct {
+	Name string            `toml:"name"`
+	ID   string            `toml:"id"`
+	Tags map[string]string `toml:"tags"`
+}
+
+const sampleConfig = `
+  ## Metric name
+  # name = "opcua"
+
+  ## OPC UA endpoint URL
+  endpoint = "opc.tcp://localhost:4840"
+
+  ## Security policy, one of "None", "Basic128Rsa15", "Basic256",
+  ## "Basic256Sha256", or "auto" for the most secure policy of the server.
+  # security_policy = "auto"
+
+  ## Security mode, one of "None", "Sign", "SignAndEncrypt", or "auto" for
+  ## the most secure mode of the server.
+  # security_mode = "auto"
+
+  ## Client certificate and private key in PEM or DER format, used when
+  ## messages are signed or encrypted and for certificate authentication.  A
+  ## self-signed certificate is created if unset.
+  # certificate = "/etc/telegraf/cert.pem"
+  # private_key = "/etc/telegraf/key.pem"
+
+  ## Authentication method, one of "Anonymous", "UserName" or "Certificate".
+  # auth_method = "Anonymous"
+  # username = ""
+  # password = ""
+
+  ## Timeouts of connecting and of each request
+  # connect_timeout = "10s"
+  # request_timeout = "5s"
+
+  ## How values are read:
+  ##   poll      - the nodes are read on every interval
+  ##   subscribe - the server reports changes of the values of the nodes,
+  ##               sampled every subscription_interval
+  # mode = "poll"
+  # subscription_interval = "1s"
+
+  ## Timestamp of the metrics, one of "gather", "source" or "server".
+  # timestamp = "gather"
+
+  ## Nodes to read, the value of a node is added as the field of its name.
+  ## Node ids use the format "ns=<namespace>;<i|s|g|b>=<identifier>".
+  # [[inputs.opcua.node]]
+  #   name = "temperature"
+  #   id = "ns=2;s=Line1.Oven.Temperature"
+  #   tags = { line = "1" }
+`
+
+func (o *OpcUA) Description() string {
+	return "Read node values of OPC UA servers"
+}
+
+func (o *OpcUA) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpcUA) Init() error {
+	if o.Endpoint == "" {
+		return fmt.Errorf("endpoint is empty")
+	}
+
+	switch o.SecurityPolicy {
+	case "auto", "None", "Basic128Rsa15", "Basic256", "Basic256Sha256":
+	default:
+		return fmt.Errorf("invalid security_policy '%s'", o.SecurityPolicy)
+	}
+	switch o.SecurityMode {
+	case "auto", "None", "Sign", "SignAndEncrypt":
+	default:
+		return fmt.Errorf("invalid security_mode '%s'", o.SecurityMode)
+	}
+	if (o.SecurityPolicy == "None") != (o.SecurityMode == "None") && o.SecurityPolicy != "auto" && o.SecurityMode != "auto" {
+		return fmt.Errorf("security_policy '%s' cannot be used with security_mode '%s'", o.SecurityPolicy, o.SecurityMode)
+	}
+	if (o.Certificate == "") != (o.PrivateKey == "") {
+		return fmt.Errorf("certificate and private_key must be set together")
+	}
+
+	switch o.AuthMethod {
+	case "Anonymous", "Certificate":
+	case "UserName":
+		if o.Username == "" {
+			return fmt.Errorf("username is empty")
+		}
+	default:
+		return fmt.Errorf("invalid auth_method '%s'", o.AuthMethod)
+	}
+
+	switch o.Mode {
+	case "poll":
+	case "subscribe":
+		if o.SubscriptionInterval.Duration <= 0 {
+			return fmt.Errorf("invalid subscription_interval %s", o.SubscriptionInterval.Duration)
+		}
+	default:
+		return fmt.Errorf("invalid mode '%s'", o.Mode)
+	}
+
+	switch o.Timestamp {
+	case "gather", "source", "server":
+	default:
+		return fmt.Errorf("invalid timestamp '%s'", o.Timestamp)
+	}
+
+	if len(o.Nodes) == 0 {
+		return fmt.Errorf("no nodes defined")
+	}
+	names := make(map[string]bool, len(o.Nodes))
+	o.nodeIDs = make([]*ua.NodeID, 0, len(o.Nodes))
+	for _, n := range o.Nodes {
+		if n.Name == "" {
+			return fmt.Errorf("missing name of node '%s'", n.ID)
+		}
+		if n.Name == "status_code" || n.Name == "status" {
+			return fmt.Errorf("node name '%s' is reserved", n.Name)
+		}
+		if names[n.Name] {
+			return fmt.Errorf("duplicate node name '%s'", n.Name)
+		}
+		names[n.Name] = true
+
+		id, err := ua.ParseNodeID(n.ID)
+		if err != nil {
+			return fmt.Errorf("invalid id of node '%s': %s", n.Name, err)
+		}
+		o.nodeIDs = append(o.nodeIDs, id)
+	}
+
+	if o.newClient == nil {
+		o.newClient = func() client {
+			return &uaClient{o: o}
+		}
+	}
+	return nil
+}
+
+func (o *OpcUA) Start(acc telegraf.Accumulator) error {
+	o.acc = acc
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// the server may not be up yet, connecting is retried on each gather
+	if err := o.connect(); err != nil {
+		acc.AddError(err)
+	}
+	return nil
+}
+
+// connect connects to the server and subscribes to the nodes in the
+// subscribe mode.  It must be called with the mutex held.
+func (o *OpcUA) connect() error {
+	c := o.newClient()
+	ctx, cancel := context.WithTimeout(context.Background(), o.ConnectTimeout.Duration)
+	err := c.Connect(ctx)
+	cancel()
+	if err != nil {
+		c.Close()
+		return fmt.Errorf("connecting to %s failed: %s", o.Endpoint, err)
+	}
+
+	if o.Mode == "subscribe" {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := make(chan notification)
+		statuses, err := c.Subscribe(ctx, o.SubscriptionInterval.Duration, o.nodeIDs, ch)
+		if err != nil {
+			cancel()
+			c.Close()
+			return fmt.Errorf("subscribing to %s failed: %s", o.Endpoint, err)
+		}
+		for i, status := range statuses {
+			if status != ua.StatusOK {
+				o.acc.AddError(fmt.Errorf("monitoring node '%s' failed: %s", o.Nodes[i].ID, status))
+			}
+		}
+
+		o.cancel = cancel
+		o.wg.Add(1)
+		go func() {
+			defer o.wg.Done()
+			o.receive(ctx, c, ch)
+		}()
+	}
+
+	o.client = c
+	return nil
+}
+
+// disconnect closes the connection.  It must be called with the mutex held.
+func (o *OpcUA) disconnect() {
+	if o.cancel != nil {
+		o.cancel()
+		o.cancel = nil
+	}
+	if o.client != nil {
+		o.client.Close()
+		o.client = nil
+	}
+}
+
+// receive adds the value changes of the subscription until ctx is done or
+// the subscription fails, then the connection is closed and opened again
+// on the next gather.
+func (o *OpcUA) receive(ctx context.Context, c client, ch <-chan notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-ch:
+			if n.err != nil {
+				o.acc.AddError(fmt.Errorf("subscription to %s failed: %s", o.Endpoint, n.err))
+				o.mu.Lock()
+				if o.client == c {
+					o.disconnect()
+				}
+				o.mu.Unlock()
+				return
+			}
+			if int(n.handle) >= len(o.Nodes) || n.value == nil {
+				continue
+			}
+			o.addValue(&o.Nodes[n.handle], n.value, time.Now())
+		}
+	}
+}
+
+func (o *OpcUA) Gather(acc telegraf.Accumulator) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.client == nil {
+		if err := o.connect(); err != nil {
+			return err
+		}
+	}
+	if o.Mode != "poll" {
+		return nil
+	}
+
+	values, err := o.client.Read(o.nodeIDs)
+	if err != nil {
+		o.disconnect()
+		return fmt.Errorf("reading nodes of %s failed: %s", o.Endpoint, err)
+	}
+
+	now := time.Now()
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		o.addValue(&o.Nodes[i], v, now)
+	}
+	return nil
+}
+
+func (o *OpcUA) Stop() {
+	o.mu.Lock()
+	o.disconnect()
+	o.mu.Unlock()
+	o.wg.Wait()
+}
+
+// addValue adds the value and status of a node.  The value is omitted if
+// the server did not return one, usually with a bad status.
+func (o *OpcUA) addValue(n *Node, v *ua.DataValue, now time.Time) {
+	tags := map[string]string{
+		"id":      n.ID,
+		"quality": quality(v.Status),
+	}
+	for k, v := range n.Tags {
+		tags[k] = v
+	}
+
+	fields := map[string]interface{}{
+		"status_code": uint64(v.Status),
+		"status":      statusName(v.Status),
+	}
+	if v.Value != nil {
+		if value := convertValue(v.Value.Value()); value != nil {
+			fields[n.Name] = value
+		}
+	}
+
+	t := now
+	switch o.Timestamp {
+	case "source":
+		if !v.SourceTimestamp.IsZero() {
+			t = v.SourceTimestamp
+		}
+	case "server":
+		if !v.ServerTimestamp.IsZero() {
+			t = v.ServerTimestamp
+		}
+	}
+	o.acc.AddFields(o.MetricName, fields, tags, t)
+}
+
+// quality returns the severity of a status code.
+func quality(status ua.StatusCode) string {
+	switch uint32(status) >> 30 {
+	case 0:
+		return "good"
+	case 1:
+		return "uncertain"
+	default:
+		return "bad"
+	}
+}
+
+// statusName returns the symbolic name of a status code, like
+// "BadNodeIDUnknown", or its hexadecimal value if it is unknown.
+func statusName(status ua.StatusCode) string {
+	if status == ua.StatusOK {
+		return "Good"
+	}
+	if d, ok := ua.StatusCodes[status]; ok {
+		return strings.TrimPrefix(d.Name, "Status")
+	}
+	return fmt.Sprintf("0x%08X", uint32(status))
+}
+
+// convertValue converts the value of a variant to a field value.  Values
+// of types that cannot be represented as a field, like arrays, return nil.
+func convertValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case bool, int64, uint64, float64, string:
+		return v
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint8:
+		return uint64(v)
+	case uint16:
+		return uint64(v)
+	case uint32:
+		return uint64(v)
+	case float32:
+		return float64(v)
+	case time.Time:
+		return v.UnixNano()
+	case *ua.LocalizedText:
+		if v == nil {
+			return nil
+		}
+		return v.Text
+	case *ua.NodeID:
+		if v == nil {
+			return nil
+		}
+		return v.String()
+	case ua.StatusCode:
+		return uint64(v)
+	default:
+		return nil
+	}
+}
+
+func init() {
+	inputs.Add("opcua", func() telegraf.Input {
+		return &OpcUA{
+			MetricName:           "opcua",
+			SecurityPolicy:       "auto",
+			SecurityMode:         "auto",
+			AuthMethod:           "Anonymous",
+			ConnectTimeout:       internal.Duration{Duration: 10 * time.Second},
+			RequestTimeout:       internal.Duration{Duration: 5 * time.Second},
+			Mode:                 "poll",
+			SubscriptionInterval: internal.Duration{Duration: time.Second},
+			Timestamp:            "gather",
+		}
+	})
+}
@@ -0,0 +1,320 @@
+package opcua
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gopcua/opcua/ua"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/influxdata/toml"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient serves the values of nodes by their id.
+type fakeClient struct {
+	mu         sync.Mutex
+	values     map[string]*ua.DataValue
+	connectErr error
+	readErr    error
+	closed     bool
+	ch         chan<- notification
+	subscribed chan struct{}
+}
+
+func (c *fakeClient) Connect(ctx context.Context) error {
+	return c.connectErr
+}
+
+func (c *fakeClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *fakeClient) Read(nodes []*ua.NodeID) ([]*ua.DataValue, error) {
+	if c.readErr != nil {
+		return nil, c.readErr
+	}
+	values := make([]*ua.DataValue, 0, len(nodes))
+	for _, id := range nodes {
+		v, ok := c.values[id.String()]
+		if !ok {
+			v = &ua.DataValue{Status: ua.StatusBadNodeIDUnknown}
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (c *fakeClient) Subscribe(ctx context.Context, interval time.Duration, nodes []*ua.NodeID, ch chan<- notification) ([]ua.StatusCode, error) {
+	statuses := make([]ua.StatusCode, 0, len(nodes))
+	for _, id := range nodes {
+		if _, ok := c.values[id.String()]; ok {
+			statuses = append(statuses, ua.StatusOK)
+		} else {
+			statuses = append(statuses, ua.StatusBadNodeIDUnknown)
+		}
+	}
+	c.ch = ch
+	close(c.subscribed)
+	return statuses, nil
+}
+
+func newTestOpcUA(c *fakeClient) *OpcUA {
+	return &OpcUA{
+		MetricName:           "opcua",
+		Endpoint:             "opc.tcp://localhost:4840",
+		SecurityPolicy:       "auto",
+		SecurityMode:         "auto",
+		AuthMethod:           "Anonymous",
+		ConnectTimeout:       internal.Duration{Duration: time.Second},
+		RequestTimeout:       internal.Duration{Duration: time.Second},
+		Mode:                 "poll",
+		SubscriptionInterval: internal.Duration{Duration: time.Second},
+		Timestamp:            "gather",
+		Nodes: []Node{
+			{Name: "temperature", ID: "ns=2;s=Oven.Temperature", Tags: map[string]string{"line": "1"}},
+			{Name: "running", ID: "ns=2;i=1001"},
+			{Name: "missing", ID: "ns=2;s=Missing"},
+		},
+		newClient: func() client { return c },
+	}
+}
+
+func TestGatherPoll(t *testing.T) {
+	source := time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC)
+	c := &fakeClient{
+		values: map[string]*ua.DataValue{
+			"ns=2;s=Oven.Temperature": {
+				Value:           ua.MustVariant(float32(181.5)),
+				Status:          ua.StatusOK,
+				SourceTimestamp: source,
+			},
+			"ns=2;i=1001": {
+				Value:  ua.MustVariant(true),
+				Status: ua.StatusUncertain,
+			},
+		},
+	}
+	o := newTestOpcUA(c)
+	o.Timestamp = "source"
+	require.NoError(t, o.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, o.Start(&acc))
+	defer o.Stop()
+	require.NoError(t, o.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"opcua",
+			map[string]string{"id": "ns=2;s=Oven.Temperature", "quality": "good", "line": "1"},
+			map[string]interface{}{"temperature": 181.5, "status_code": uint64(0), "status": "Good"},
+			source,
+		),
+		testutil.MustMetric(
+			"opcua",
+			map[string]string{"id": "ns=2;i=1001", "quality": "uncertain"},
+			map[string]interface{}{"running": true, "status_code": uint64(0x40000000), "status": "Uncertain"},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"opcua",
+			map[string]string{"id": "ns=2;s=Missing", "quality": "bad"},
+			map[string]interface{}{"status_code": uint64(0x80340000), "status": "BadNodeIDUnknown"},
+			time.Unix(0, 0),
+		),
+	}
+	metrics := acc.GetTelegrafMetrics()
+	testutil.RequireMetricsEqual(t, expected, metrics, testutil.IgnoreTime())
+
+	// values without a source timestamp use the time of the gather
+	require.Equal(t, source, metrics[0].Time())
+	require.NotEqual(t, source, metrics[1].Time())
+}
+
+func TestGatherReconnect(t *testing.T) {
+	c := &fakeClient{
+		connectErr: errors.New("connection refused"),
+		values:     map[string]*ua.DataValue{},
+	}
+	o := newTestOpcUA(c)
+	require.NoError(t, o.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, o.Start(&acc))
+	defer o.Stop()
+	require.Len(t, acc.Errors, 1)
+	require.Error(t, o.Gather(&acc))
+
+	c.connectErr = nil
+	c.closed = false
+	c.readErr = errors.New("timeout")
+	require.Error(t, o.Gather(&acc))
+	require.True(t, c.closed)
+	require.Nil(t, o.client)
+
+	c.readErr = nil
+	require.NoError(t, o.Gather(&acc))
+	require.Len(t, acc.Metrics, 3)
+}
+
+func TestGatherSubscribe(t *testing.T) {
+	c := &fakeClient{
+		values: map[string]*ua.DataValue{
+			"ns=2;s=Oven.Temperature": {},
+			"ns=2;i=1001":             {},
+		},
+		subscribed: make(chan struct{}),
+	}
+	o := newTestOpcUA(c)
+	o.Mode = "subscribe"
+	require.NoError(t, o.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, o.Start(&acc))
+	defer o.Stop()
+	<-c.subscribed
+
+	// the missing node cannot be monitored
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "ns=2;s=Missing")
+
+	c.ch <- notification{handle: 1, value: &ua.DataValue{Value: ua.MustVariant(int16(-3))}}
+	c.ch <- notification{handle: 0, value: &ua.DataValue{Value: ua.MustVariant(uint32(7)), Status: ua.StatusOK}}
+	acc.Wait(2)
+	require.NoError(t, o.Gather(&acc))
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "opcua",
+		map[string]interface{}{"running": int64(-3), "status_code": uint64(0), "status": "Good"},
+		map[string]string{"id": "ns=2;i=1001", "quality": "good"})
+	acc.AssertContainsTaggedFields(t, "opcua",
+		map[string]interface{}{"temperature": uint64(7), "status_code": uint64(0), "status": "Good"},
+		map[string]string{"id": "ns=2;s=Oven.Temperature", "quality": "good", "line": "1"})
+
+	// a failed subscription closes the connection
+	c.ch <- notification{err: errors.New("secure channel closed")}
+	acc.WaitError(2)
+	o.mu.Lock()
+	require.Nil(t, o.client)
+	o.mu.Unlock()
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(o *OpcUA)
+	}{
+		{"no endpoint", func(o *OpcUA) { o.Endpoint = "" }},
+		{"bad policy", func(o *OpcUA) { o.SecurityPolicy = "Basic512" }},
+		{"bad mode", func(o *OpcUA) { o.SecurityMode = "Encrypt" }},
+		{"policy mismatch", func(o *OpcUA) { o.SecurityPolicy = "None"; o.SecurityMode = "Sign" }},
+		{"certificate only", func(o *OpcUA) { o.Certificate = "/etc/cert.pem" }},
+		{"bad auth", func(o *OpcUA) { o.AuthMethod = "Token" }},
+		{"no username", func(o *OpcUA) { o.AuthMethod = "UserName" }},
+		{"bad read mode", func(o *OpcUA) { o.Mode = "push" }},
+		{"bad timestamp", func(o *OpcUA) { o.Timestamp = "local" }},
+		{"no nodes", func(o *OpcUA) { o.Nodes = nil }},
+		{"bad node id", func(o *OpcUA) { o.Nodes[0].ID = "ns=x;i=1" }},
+		{"duplicate name", func(o *OpcUA) { o.Nodes[1].Name = o.Nodes[0].Name }},
+		{"reserved name", func(o *OpcUA) { o.Nodes[0].Name = "status_code" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newTestOpcUA(&fakeClient{})
+			tt.modify(o)
+			require.Error(t, o.Init())
+		})
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	now := time.Unix(1565000000, 5)
+	tests := []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{int8(-1), int64(-1)},
+		{uint16(2), uint64(2)},
+		{int64(-3), int64(-3)},
+		{float32(0.5), 0.5},
+		{"on", "on"},
+		{now, now.UnixNano()},
+		{&ua.LocalizedText{Text: "open"}, "open"},
+		{ua.NewNumericNodeID(0, 85), "i=85"},
+		{[]float64{1, 2}, nil},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, convertValue(tt.value))
+	}
+}
+
+func TestCertificate(t *testing.T) {
+	o := newTestOpcUA(&fakeClient{})
+	cert, key, err := o.certificate()
+	require.NoError(t, err)
+	require.NotNil(t, key)
+
+	// the generated certificate is reused on reconnects
+	cert2, _, err := o.certificate()
+	require.NoError(t, err)
+	require.Equal(t, cert, cert2)
+}
+
+func TestConnectTimeout(t *testing.T) {
+	// the server accepts the connection but never answers the handshake
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	o := newTestOpcUA(&fakeClient{})
+	o.Endpoint = "opc.tcp://" + l.Addr().String()
+	c := &uaClient{o: o}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = c.Connect(ctx)
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.True(t, time.Since(start) < time.Second)
+	require.NoError(t, c.Close())
+}
+
+func TestConfig(t *testing.T) {
+	conf := []byte(`
+endpoint = "opc.tcp://plc:4840"
+security_policy = "Basic256Sha256"
+security_mode = "SignAndEncrypt"
+auth_method = "UserName"
+username = "telegraf"
+mode = "subscribe"
+
+[[node]]
+  name = "temperature"
+  id = "ns=2;s=Oven.Temperature"
+  tags = { line = "1" }
+`)
+	o := &OpcUA{}
+	require.NoError(t, toml.Unmarshal(conf, o))
+	require.Equal(t, []Node{{
+		Name: "temperature",
+		ID:   "ns=2;s=Oven.Temperature",
+		Tags: map[string]string{"line": "1"},
+	}}, o.Nodes)
+	require.Equal(t, "subscribe", o.Mode)
+}
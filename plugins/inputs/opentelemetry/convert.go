@@ -0,0 +1,178 @@
+package opentelemetry
+
+import (
+	"encoding/base64"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/otlp"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// converter converts OTLP metrics to telegraf metrics in the format of the
+// prometheus input: gauges and sums have a "gauge" or "counter" field,
+// histograms and summaries have "count" and "sum" fields and a field for each
+// bucket upper bound or quantile.
+type converter struct {
+	now func() time.Time
+
+	metrics  []telegraf.Metric
+	rejected int64
+}
+
+func (c *converter) AddResourceMetrics(rm *otlp.ResourceMetrics) {
+	resourceTags := tagsOf(nil, rm.GetResource().GetAttributes())
+	for _, sm := range rm.GetScopeMetrics() {
+		for _, m := range sm.GetMetrics() {
+			c.addMetric(resourceTags, m)
+		}
+	}
+}
+
+// Metrics returns the converted metrics.
+func (c *converter) Metrics() []telegraf.Metric {
+	return c.metrics
+}
+
+// Rejected returns the number of data points that could not be converted.
+func (c *converter) Rejected() int64 {
+	return c.rejected
+}
+
+func (c *converter) addMetric(resourceTags map[string]string, m *otlp.Metric) {
+	name := m.GetName()
+	switch data := m.Data.(type) {
+	case *otlp.Metric_Gauge:
+		for _, dp := range data.Gauge.GetDataPoints() {
+			c.addNumber(name, "gauge", telegraf.Gauge, resourceTags, dp)
+		}
+	case *otlp.Metric_Sum:
+		field, tp := "gauge", telegraf.Gauge
+		if data.Sum.GetIsMonotonic() {
+			field, tp = "counter", telegraf.Counter
+		}
+		for _, dp := range data.Sum.GetDataPoints() {
+			c.addNumber(name, field, tp, resourceTags, dp)
+		}
+	case *otlp.Metric_Histogram:
+		for _, dp := range data.Histogram.GetDataPoints() {
+			c.addHistogram(name, resourceTags, dp)
+		}
+	case *otlp.Metric_Summary:
+		for _, dp := range data.Summary.GetDataPoints() {
+			c.addSummary(name, resourceTags, dp)
+		}
+	case *otlp.Metric_ExponentialHistogram:
+		c.rejected += int64(len(data.ExponentialHistogram.GetDataPoints()))
+	}
+}
+
+func (c *converter) addNumber(
+	name string,
+	field string,
+	tp telegraf.ValueType,
+	resourceTags map[string]string,
+	dp *otlp.NumberDataPoint,
+) {
+	var value interface{}
+	switch v := dp.Value.(type) {
+	case *otlp.NumberDataPoint_AsDouble:
+		value = v.AsDouble
+	case *otlp.NumberDataPoint_AsInt:
+		value = v.AsInt
+	default:
+		// No recorded value
+		return
+	}
+
+	fields := map[string]interface{}{field: value}
+	c.add(name, tagsOf(resourceTags, dp.GetAttributes()), fields, dp.GetTimeUnixNano(), tp)
+}
+
+func (c *converter) addHistogram(name string, resourceTags map[string]string, dp *otlp.HistogramDataPoint) {
+	fields := map[string]interface{}{
+		"count": float64(dp.GetCount()),
+	}
+	if sum, ok := dp.XSum.(*otlp.HistogramDataPoint_Sum); ok {
+		fields["sum"] = sum.Sum
+	}
+
+	var cumulative uint64
+	for i, count := range dp.GetBucketCounts() {
+		cumulative += count
+		bound := math.Inf(1)
+		if i < len(dp.GetExplicitBounds()) {
+			bound = dp.GetExplicitBounds()[i]
+		}
+		fields[formatBound(bound)] = float64(cumulative)
+	}
+
+	c.add(name, tagsOf(resourceTags, dp.GetAttributes()), fields, dp.GetTimeUnixNano(), telegraf.Histogram)
+}
+
+func (c *converter) addSummary(name string, resourceTags map[string]string, dp *otlp.SummaryDataPoint) {
+	fields := map[string]interface{}{
+		"count": float64(dp.GetCount()),
+		"sum":   dp.GetSum(),
+	}
+	for _, q := range dp.GetQuantileValues() {
+		fields[formatBound(q.GetQuantile())] = q.GetValue()
+	}
+
+	c.add(name, tagsOf(resourceTags, dp.GetAttributes()), fields, dp.GetTimeUnixNano(), telegraf.Summary)
+}
+
+func (c *converter) add(
+	name string,
+	tags map[string]string,
+	fields map[string]interface{},
+	ts uint64,
+	tp telegraf.ValueType,
+) {
+	t := c.now()
+	if ts > 0 {
+		t = time.Unix(0, int64(ts))
+	}
+
+	m, err := metric.New(name, tags, fields, t, tp)
+	if err != nil {
+		c.rejected++
+		return
+	}
+	c.metrics = append(c.metrics, m)
+}
+
+// formatBound formats a bucket bound or quantile like the prometheus input.
+func formatBound(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// tagsOf returns the base tags with the attributes added.  Attributes with
+// array or key-value list values are skipped.
+func tagsOf(base map[string]string, attributes []*otlp.KeyValue) map[string]string {
+	tags := make(map[string]string, len(base)+len(attributes))
+	for k, v := range base {
+		tags[k] = v
+	}
+
+	for _, kv := range attributes {
+		switch v := kv.GetValue().GetValue().(type) {
+		case *otlp.AnyValue_StringValue:
+			tags[kv.GetKey()] = v.StringValue
+		case *otlp.AnyValue_BoolValue:
+			tags[kv.GetKey()] = strconv.FormatBool(v.BoolValue)
+		case *otlp.AnyValue_IntValue:
+			tags[kv.GetKey()] = strconv.FormatInt(v.IntValue, 10)
+		case *otlp.AnyValue_DoubleValue:
+			tags[kv.GetKey()] = strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+		case *otlp.AnyValue_BytesValue:
+			tags[kv.GetKey()] = base64.StdEncoding.EncodeToString(v.BytesValue)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,234 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/otlp"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/encoding/gzip" // Register the gzip compressor
+)
+
+const sampleConfig = `
+  ## Address and port to accept OTLP exports over gRPC on.
+  service_address = ":4317"
+
+  ## Address and port to accept OTLP exports over HTTP on, disabled if empty.
+  ## Metrics are accepted as protobuf on the /v1/metrics path.
+  # http_service_address = ":4318"
+
+  ## Maximum size of an export request.
+  # max_msg_size = "4MB"
+
+  ## Set one or more allowed client CA certificate file names to
+  ## enable mutually authenticated TLS connections
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Add service certificate and key
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+`
+
+const metricsPath = "/v1/metrics"
+
+// OpenTelemetry is an input plugin that accepts OTLP metric exports.
+type OpenTelemetry struct {
+	ServiceAddress     string        `toml:"service_address"`
+	HTTPServiceAddress string        `toml:"http_service_address"`
+	MaxMsgSize         internal.Size `toml:"max_msg_size"`
+	tlsint.ServerConfig
+
+	grpcServer   *grpc.Server
+	grpcListener net.Listener
+	httpServer   *http.Server
+	httpListener net.Listener
+
+	acc telegraf.Accumulator
+	now func() time.Time
+	wg  sync.WaitGroup
+}
+
+func (o *OpenTelemetry) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpenTelemetry) Description() string {
+	return "Receive metrics from OpenTelemetry exporters using OTLP"
+}
+
+func (o *OpenTelemetry) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+// Start starts the gRPC and HTTP listeners.
+func (o *OpenTelemetry) Start(acc telegraf.Accumulator) error {
+	o.acc = acc
+
+	tlsConfig, err := o.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	if o.ServiceAddress != "" {
+		o.grpcListener, err = net.Listen("tcp", o.ServiceAddress)
+		if err != nil {
+			return err
+		}
+
+		opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(int(o.MaxMsgSize.Size))}
+		if tlsConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+		o.grpcServer = grpc.NewServer(opts...)
+		otlp.RegisterMetricsServiceServer(o.grpcServer, o)
+
+		o.wg.Add(1)
+		go func() {
+			defer o.wg.Done()
+			o.grpcServer.Serve(o.grpcListener)
+		}()
+		log.Printf("I! [inputs.opentelemetry] Listening for gRPC on %s", o.grpcListener.Addr())
+	}
+
+	if o.HTTPServiceAddress != "" {
+		o.httpListener, err = net.Listen("tcp", o.HTTPServiceAddress)
+		if err != nil {
+			o.Stop()
+			return err
+		}
+
+		mux := http.NewServeMux()
+		mux.HandleFunc(metricsPath, o.serveHTTP)
+		o.httpServer = &http.Server{
+			Handler:   mux,
+			TLSConfig: tlsConfig,
+		}
+
+		o.wg.Add(1)
+		go func() {
+			defer o.wg.Done()
+			if tlsConfig != nil {
+				o.httpServer.ServeTLS(o.httpListener, "", "")
+			} else {
+				o.httpServer.Serve(o.httpListener)
+			}
+		}()
+		log.Printf("I! [inputs.opentelemetry] Listening for HTTP on %s", o.httpListener.Addr())
+	}
+	return nil
+}
+
+// Stop stops the listeners and waits for running requests.
+func (o *OpenTelemetry) Stop() {
+	if o.grpcServer != nil {
+		o.grpcServer.Stop()
+	}
+	if o.httpServer != nil {
+		o.httpServer.Close()
+	}
+	o.wg.Wait()
+}
+
+// Export implements the OTLP MetricsService.
+func (o *OpenTelemetry) Export(_ context.Context, req *otlp.ExportMetricsServiceRequest) (*otlp.ExportMetricsServiceResponse, error) {
+	return o.export(req), nil
+}
+
+// export adds the metrics of the request to the accumulator and returns the
+// response.
+func (o *OpenTelemetry) export(req *otlp.ExportMetricsServiceRequest) *otlp.ExportMetricsServiceResponse {
+	c := &converter{now: o.now}
+	for _, rm := range req.GetResourceMetrics() {
+		c.AddResourceMetrics(rm)
+	}
+
+	for _, m := range c.Metrics() {
+		o.acc.AddMetric(m)
+	}
+
+	resp := &otlp.ExportMetricsServiceResponse{}
+	if c.Rejected() > 0 {
+		resp.PartialSuccess = &otlp.ExportMetricsPartialSuccess{
+			RejectedDataPoints: c.Rejected(),
+			ErrorMessage:       "exponential histograms are not supported",
+		}
+	}
+	return resp
+}
+
+func (o *OpenTelemetry) serveHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if req.Header.Get("Content-Type") != "application/x-protobuf" {
+		http.Error(res, "unsupported content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	buf, err := ioutil.ReadAll(http.MaxBytesReader(res, req.Body, o.MaxMsgSize.Size))
+	if err != nil {
+		http.Error(res, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		r, err := gzip.NewReader(bytes.NewReader(buf))
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		buf, err = ioutil.ReadAll(io.LimitReader(r, o.MaxMsgSize.Size+1))
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if int64(len(buf)) > o.MaxMsgSize.Size {
+			http.Error(res, "request too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+	}
+
+	exportReq := &otlp.ExportMetricsServiceRequest{}
+	if err := proto.Unmarshal(buf, exportReq); err != nil {
+		http.Error(res, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	out, err := proto.Marshal(o.export(exportReq))
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res.Header().Set("Content-Type", "application/x-protobuf")
+	res.Write(out)
+}
+
+func newOpenTelemetry() *OpenTelemetry {
+	return &OpenTelemetry{
+		ServiceAddress: ":4317",
+		MaxMsgSize:     internal.Size{Size: 4 * 1024 * 1024},
+		now:            time.Now,
+	}
+}
+
+func init() {
+	inputs.Add("opentelemetry", func() telegraf.Input {
+		return newOpenTelemetry()
+	})
+}
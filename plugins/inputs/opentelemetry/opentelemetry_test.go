@@ -0,0 +1,316 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/otlp"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func stringKV(key, value string) *otlp.KeyValue {
+	return &otlp.KeyValue{
+		Key:   key,
+		Value: &otlp.AnyValue{Value: &otlp.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func newRequest() *otlp.ExportMetricsServiceRequest {
+	ts := uint64(time.Unix(10, 0).UnixNano())
+	return &otlp.ExportMetricsServiceRequest{
+		ResourceMetrics: []*otlp.ResourceMetrics{
+			{
+				Resource: &otlp.Resource{
+					Attributes: []*otlp.KeyValue{
+						stringKV("service.name", "app"),
+						stringKV("host", "resource"),
+					},
+				},
+				ScopeMetrics: []*otlp.ScopeMetrics{
+					{
+						Metrics: []*otlp.Metric{
+							{
+								Name: "temperature",
+								Data: &otlp.Metric_Gauge{Gauge: &otlp.Gauge{
+									DataPoints: []*otlp.NumberDataPoint{
+										{
+											Attributes: []*otlp.KeyValue{
+												stringKV("host", "a"),
+												{Key: "core", Value: &otlp.AnyValue{Value: &otlp.AnyValue_IntValue{IntValue: 3}}},
+												{Key: "hot", Value: &otlp.AnyValue{Value: &otlp.AnyValue_BoolValue{BoolValue: true}}},
+											},
+											TimeUnixNano: ts,
+											Value:        &otlp.NumberDataPoint_AsDouble{AsDouble: 21.5},
+										},
+										{
+											// No recorded value
+											TimeUnixNano: ts,
+											Flags:        1,
+										},
+									},
+								}},
+							},
+							{
+								Name: "requests",
+								Data: &otlp.Metric_Sum{Sum: &otlp.Sum{
+									IsMonotonic: true,
+									DataPoints: []*otlp.NumberDataPoint{
+										{TimeUnixNano: ts, Value: &otlp.NumberDataPoint_AsInt{AsInt: 42}},
+									},
+								}},
+							},
+							{
+								Name: "queue_length",
+								Data: &otlp.Metric_Sum{Sum: &otlp.Sum{
+									DataPoints: []*otlp.NumberDataPoint{
+										{TimeUnixNano: ts, Value: &otlp.NumberDataPoint_AsInt{AsInt: -2}},
+									},
+								}},
+							},
+							{
+								Name: "latency",
+								Data: &otlp.Metric_Histogram{Histogram: &otlp.Histogram{
+									DataPoints: []*otlp.HistogramDataPoint{
+										{
+											TimeUnixNano:   ts,
+											Count:          10,
+											XSum:           &otlp.HistogramDataPoint_Sum{Sum: 4.5},
+											ExplicitBounds: []float64{0.1, 0.5},
+											BucketCounts:   []uint64{2, 5, 3},
+										},
+									},
+								}},
+							},
+							{
+								Name: "rpc",
+								Data: &otlp.Metric_Summary{Summary: &otlp.Summary{
+									DataPoints: []*otlp.SummaryDataPoint{
+										{
+											TimeUnixNano: ts,
+											Count:        5,
+											Sum:          2,
+											QuantileValues: []*otlp.SummaryDataPoint_ValueAtQuantile{
+												{Quantile: 0.5, Value: 0.3},
+												{Quantile: 0.99, Value: 0.9},
+											},
+										},
+									},
+								}},
+							},
+							{
+								Name: "exponential",
+								Data: &otlp.Metric_ExponentialHistogram{ExponentialHistogram: &otlp.ExponentialHistogram{
+									DataPoints: []*otlp.ExponentialHistogramDataPoint{
+										{TimeUnixNano: ts, Count: 1},
+									},
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func expectedMetrics() []telegraf.Metric {
+	ts := time.Unix(10, 0)
+	resource := func(tags map[string]string) map[string]string {
+		tags["service.name"] = "app"
+		if _, ok := tags["host"]; !ok {
+			tags["host"] = "resource"
+		}
+		return tags
+	}
+	return []telegraf.Metric{
+		testutil.MustMetric("temperature",
+			resource(map[string]string{"host": "a", "core": "3", "hot": "true"}),
+			map[string]interface{}{"gauge": 21.5},
+			ts,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("requests",
+			resource(map[string]string{}),
+			map[string]interface{}{"counter": int64(42)},
+			ts,
+			telegraf.Counter,
+		),
+		testutil.MustMetric("queue_length",
+			resource(map[string]string{}),
+			map[string]interface{}{"gauge": int64(-2)},
+			ts,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("latency",
+			resource(map[string]string{}),
+			map[string]interface{}{
+				"count": 10.0,
+				"sum":   4.5,
+				"0.1":   2.0,
+				"0.5":   7.0,
+				"+Inf":  10.0,
+			},
+			ts,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric("rpc",
+			resource(map[string]string{}),
+			map[string]interface{}{
+				"count": 5.0,
+				"sum":   2.0,
+				"0.5":   0.3,
+				"0.99":  0.9,
+			},
+			ts,
+			telegraf.Summary,
+		),
+	}
+}
+
+// untyped returns the metrics without their value type, which is not kept by
+// the test accumulator.
+func untyped(metrics []telegraf.Metric) []telegraf.Metric {
+	var result []telegraf.Metric
+	for _, m := range metrics {
+		result = append(result, testutil.MustMetric(m.Name(), m.Tags(), m.Fields(), m.Time()))
+	}
+	return result
+}
+
+func TestConvert(t *testing.T) {
+	c := &converter{now: time.Now}
+	for _, rm := range newRequest().GetResourceMetrics() {
+		c.AddResourceMetrics(rm)
+	}
+
+	testutil.RequireMetricsEqual(t, expectedMetrics(), c.Metrics())
+	require.Equal(t, int64(1), c.Rejected())
+}
+
+func startPlugin(t *testing.T, acc *testutil.Accumulator) *OpenTelemetry {
+	plugin := newOpenTelemetry()
+	plugin.ServiceAddress = "127.0.0.1:0"
+	plugin.HTTPServiceAddress = "127.0.0.1:0"
+	require.NoError(t, plugin.Start(acc))
+	return plugin
+}
+
+func TestExportGRPC(t *testing.T) {
+	var acc testutil.Accumulator
+	plugin := startPlugin(t, &acc)
+	defer plugin.Stop()
+
+	conn, err := grpc.Dial(plugin.grpcListener.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := otlp.NewMetricsServiceClient(conn)
+	resp, err := client.Export(ctx, newRequest())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), resp.GetPartialSuccess().GetRejectedDataPoints())
+
+	testutil.RequireMetricsEqual(t, untyped(expectedMetrics()), acc.GetTelegrafMetrics(), testutil.SortMetrics())
+}
+
+func TestExportHTTP(t *testing.T) {
+	var acc testutil.Accumulator
+	plugin := startPlugin(t, &acc)
+	defer plugin.Stop()
+
+	body, err := proto.Marshal(newRequest())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(body)
+	w.Close()
+
+	url := "http://" + plugin.httpListener.Addr().String() + "/v1/metrics"
+	req, err := http.NewRequest("POST", url, &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/x-protobuf", resp.Header.Get("Content-Type"))
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	exportResp := &otlp.ExportMetricsServiceResponse{}
+	require.NoError(t, proto.Unmarshal(respBody, exportResp))
+	require.Equal(t, int64(1), exportResp.GetPartialSuccess().GetRejectedDataPoints())
+
+	testutil.RequireMetricsEqual(t, untyped(expectedMetrics()), acc.GetTelegrafMetrics(), testutil.SortMetrics())
+}
+
+func TestExportHTTPErrors(t *testing.T) {
+	var acc testutil.Accumulator
+	plugin := startPlugin(t, &acc)
+	defer plugin.Stop()
+
+	url := "http://" + plugin.httpListener.Addr().String() + "/v1/metrics"
+
+	resp, err := http.Post(url, "application/json", bytes.NewBufferString("{}"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp, err = http.Post(url, "application/x-protobuf", bytes.NewBufferString("\xff\xff"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestMissingTimestamp(t *testing.T) {
+	var acc testutil.Accumulator
+	plugin := newOpenTelemetry()
+	plugin.acc = &acc
+	plugin.now = func() time.Time { return time.Unix(42, 0) }
+
+	plugin.export(&otlp.ExportMetricsServiceRequest{
+		ResourceMetrics: []*otlp.ResourceMetrics{
+			{
+				ScopeMetrics: []*otlp.ScopeMetrics{
+					{
+						Metrics: []*otlp.Metric{
+							{
+								Name: "temperature",
+								Data: &otlp.Metric_Gauge{Gauge: &otlp.Gauge{
+									DataPoints: []*otlp.NumberDataPoint{
+										{Value: &otlp.NumberDataPoint_AsDouble{AsDouble: 1}},
+									},
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 1)
+	require.Equal(t, time.Unix(42, 0), metrics[0].Time())
+}
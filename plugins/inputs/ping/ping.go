@@ -53,8 +53,21 @@ type Ping struct {
 	// when `Arguments` is not empty, other options (ping_interval, timeout, etc) will be ignored
 	Arguments []string
 
+	// Method used to ping, "exec" runs the ping binary, "native" sends ICMP
+	// packets directly
+	Method string
+
+	// Ping IPv6 addresses, native method only
+	IPv6 bool `toml:"ipv6"`
+
+	// Response time percentiles to calculate, native method only
+	Percentiles []int
+
 	// host ping function
 	pingHost HostPinger
+
+	// native host ping function
+	nativePingHost NativePinger
 }
 
 func (_ *Ping) Description() string {
@@ -65,6 +78,11 @@ const sampleConfig = `
   ## List of urls to ping
   urls = ["example.org"]
 
+  ## Method used for sending pings, can be either "exec" or "native".  When set
+  ## to "exec" the systems ping command will be executed.  When set to "native"
+  ## the plugin will send pings directly.
+  # method = "exec"
+
   ## Number of pings to send per collection (ping -c <COUNT>)
   # count = 1
 
@@ -88,17 +106,45 @@ const sampleConfig = `
   ## Arguments for ping command
   ## when arguments is not empty, other options (ping_interval, timeout, etc) will be ignored
   # arguments = ["-c", "3"]
+
+  ## Use only IPv6 addresses when resolving hostnames, native method only.
+  # ipv6 = false
+
+  ## Response time percentiles to calculate, native method only.
+  # percentiles = [50, 95, 99]
 `
 
 func (_ *Ping) SampleConfig() string {
 	return sampleConfig
 }
 
+func (p *Ping) Init() error {
+	switch p.Method {
+	case "", "exec":
+	case "native":
+		if p.nativePingHost == nil {
+			p.nativePingHost = p.nativePing
+		}
+		for _, perc := range p.Percentiles {
+			if perc <= 0 || perc > 100 {
+				return fmt.Errorf("invalid percentile %d", perc)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown method %q", p.Method)
+	}
+	return nil
+}
+
 func (p *Ping) Gather(acc telegraf.Accumulator) error {
 	// Spin off a go routine for each url to ping
 	for _, url := range p.Urls {
 		p.wg.Add(1)
-		go p.pingToURL(url, acc)
+		if p.Method == "native" {
+			go p.pingToURLNative(url, acc)
+		} else {
+			go p.pingToURL(url, acc)
+		}
 	}
 
 	p.wg.Wait()
@@ -337,6 +383,7 @@ func init() {
 			Deadline:     10,
 			Binary:       "ping",
 			Arguments:    []string{},
+			Method:       "exec",
 		}
 	})
 }
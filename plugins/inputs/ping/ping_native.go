@@ -0,0 +1,448 @@
+// +build !windows
+
+package ping
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	// protocol numbers of ICMP and ICMPv6, used to parse the messages
+	protocolICMP     = 1
+	protocolIPv6ICMP = 58
+
+	// payloadSize is the size of the echo request payload, matching the
+	// size sent by the exec method
+	payloadSize = 16
+
+	// defaultNativeTimeout is the time to wait for a reply if no timeout is
+	// set
+	defaultNativeTimeout = 5 * time.Second
+)
+
+// NativePinger is a function that pings a resolved address without the ping
+// binary.  This can be replaced with a mocked function for unit tests.
+type NativePinger func(addr *net.IPAddr) (*pingStats, error)
+
+// pingStats are the results of pinging a host.
+type pingStats struct {
+	sent     int
+	received int
+	ttl      int
+	rtts     []time.Duration
+}
+
+// pingToURLNative pings the host with ICMP sockets.
+func (p *Ping) pingToURLNative(u string, acc telegraf.Accumulator) {
+	defer p.wg.Done()
+	tags := map[string]string{"url": u}
+	fields := map[string]interface{}{"result_code": 0}
+
+	network := "ip4"
+	if p.IPv6 {
+		network = "ip6"
+	}
+	addr, err := net.ResolveIPAddr(network, u)
+	if err != nil {
+		acc.AddError(err)
+		fields["result_code"] = 1
+		acc.AddFields("ping", fields, tags)
+		return
+	}
+
+	stats, err := p.nativePingHost(addr)
+	if err != nil {
+		acc.AddError(fmt.Errorf("host %s: %s", u, err))
+		fields["result_code"] = 2
+		acc.AddFields("ping", fields, tags)
+		return
+	}
+
+	for k, v := range p.statsFields(stats) {
+		fields[k] = v
+	}
+	acc.AddFields("ping", fields, tags)
+}
+
+// statsFields returns the fields of the ping results.  The response time
+// fields are only set if a reply was received.
+func (p *Ping) statsFields(stats *pingStats) map[string]interface{} {
+	fields := map[string]interface{}{
+		"packets_transmitted": stats.sent,
+		"packets_received":    stats.received,
+	}
+	if stats.sent > 0 {
+		fields["percent_packet_loss"] = float64(stats.sent-stats.received) / float64(stats.sent) * 100.0
+	}
+	if len(stats.rtts) == 0 {
+		return fields
+	}
+
+	fields["ttl"] = stats.ttl
+
+	rtts := make([]float64, 0, len(stats.rtts))
+	var sum float64
+	for _, rtt := range stats.rtts {
+		ms := float64(rtt) / float64(time.Millisecond)
+		rtts = append(rtts, ms)
+		sum += ms
+	}
+	sort.Float64s(rtts)
+
+	avg := sum / float64(len(rtts))
+	var variance float64
+	for _, rtt := range rtts {
+		variance += (rtt - avg) * (rtt - avg)
+	}
+	variance /= float64(len(rtts))
+
+	fields["minimum_response_ms"] = rtts[0]
+	fields["average_response_ms"] = avg
+	fields["maximum_response_ms"] = rtts[len(rtts)-1]
+	fields["standard_deviation_ms"] = math.Sqrt(variance)
+
+	for _, perc := range p.Percentiles {
+		fields[fmt.Sprintf("percentile%v_ms", perc)] = percentile(rtts, float64(perc))
+	}
+	return fields
+}
+
+// percentile returns the nearest-rank percentile of the sorted values.
+func percentile(values []float64, perc float64) float64 {
+	if perc <= 0 {
+		return values[0]
+	}
+	rank := int(math.Ceil(perc / 100 * float64(len(values))))
+	if rank > len(values) {
+		rank = len(values)
+	}
+	return values[rank-1]
+}
+
+// icmpConn is an ICMP endpoint, either an unprivileged datagram socket or a
+// raw socket.
+type icmpConn struct {
+	*icmp.PacketConn
+	ipv6     bool
+	datagram bool
+}
+
+// listen opens an unprivileged ICMP datagram socket, falling back to a raw
+// socket if datagram sockets are not permitted.
+func listen(useIPv6 bool, source string) (*icmpConn, error) {
+	datagram, raw := "udp4", "ip4:icmp"
+	if useIPv6 {
+		datagram, raw = "udp6", "ip6:ipv6-icmp"
+	}
+
+	conn, err := icmp.ListenPacket(datagram, source)
+	if err == nil {
+		return &icmpConn{PacketConn: conn, ipv6: useIPv6, datagram: true}, nil
+	}
+	if !isPermissionError(err) {
+		return nil, err
+	}
+
+	conn, err = icmp.ListenPacket(raw, source)
+	if err != nil {
+		return nil, err
+	}
+	return &icmpConn{PacketConn: conn, ipv6: useIPv6}, nil
+}
+
+func isPermissionError(err error) bool {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	return err == syscall.EACCES || err == syscall.EPERM || err == syscall.EPROTONOSUPPORT
+}
+
+// sourceAddress returns the address to send from, the interface option is
+// either an address or the name of an interface.
+func (p *Ping) sourceAddress() (string, error) {
+	any := "0.0.0.0"
+	if p.IPv6 {
+		any = "::"
+	}
+	if p.Interface == "" {
+		return any, nil
+	}
+	if ip := net.ParseIP(p.Interface); ip != nil {
+		return ip.String(), nil
+	}
+
+	iface, err := net.InterfaceByName(p.Interface)
+	if err != nil {
+		return "", err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if (ipnet.IP.To4() == nil) == p.IPv6 {
+			if p.IPv6 && ipnet.IP.IsLinkLocalUnicast() {
+				return ipnet.IP.String() + "%" + iface.Name, nil
+			}
+			return ipnet.IP.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no address found on interface %s", p.Interface)
+}
+
+// nativePing sends the echo requests to the address and collects the
+// replies.  Replies arriving later than the timeout are not counted, and no
+// replies are waited for beyond the deadline.
+func (p *Ping) nativePing(addr *net.IPAddr) (*pingStats, error) {
+	source, err := p.sourceAddress()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := listen(p.IPv6, source)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if conn.ipv6 {
+		err = conn.IPv6PacketConn().SetControlMessage(ipv6.FlagHopLimit, true)
+	} else {
+		err = conn.IPv4PacketConn().SetControlMessage(ipv4.FlagTTL, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	count := p.Count
+	if count < 1 {
+		count = 1
+	}
+	interval := time.Duration(p.PingInterval * float64(time.Second))
+	if interval <= 0 {
+		interval = time.Second
+	}
+	timeout := time.Duration(p.Timeout * float64(time.Second))
+	if timeout <= 0 {
+		timeout = defaultNativeTimeout
+	}
+
+	start := time.Now()
+	end := start.Add(time.Duration(count-1)*interval + timeout)
+	if p.Deadline > 0 {
+		if deadline := start.Add(time.Duration(p.Deadline) * time.Second); deadline.Before(end) {
+			end = deadline
+		}
+	}
+	if err := conn.SetReadDeadline(end); err != nil {
+		return nil, err
+	}
+
+	var dst net.Addr = addr
+	if conn.datagram {
+		dst = &net.UDPAddr{IP: addr.IP, Zone: addr.Zone}
+	}
+
+	r := &receiver{
+		conn:    conn,
+		dst:     addr,
+		id:      rand.Intn(0xffff),
+		count:   count,
+		timeout: timeout,
+		sent:    make(map[int]time.Time),
+		done:    make(chan struct{}),
+	}
+
+	errC := make(chan error, 1)
+	go func() {
+		errC <- r.receive()
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for seq := 0; seq < count; seq++ {
+		if err := r.send(seq, dst); err != nil {
+			conn.Close()
+			<-errC
+			return nil, err
+		}
+		if seq == count-1 {
+			break
+		}
+		select {
+		case <-ticker.C:
+		case <-r.done:
+		}
+		if time.Now().After(end) {
+			break
+		}
+	}
+
+	if err := <-errC; err != nil {
+		return nil, err
+	}
+	r.Lock()
+	defer r.Unlock()
+	return &r.stats, nil
+}
+
+// receiver matches the echo replies to the sent requests.
+type receiver struct {
+	conn    *icmpConn
+	dst     *net.IPAddr
+	id      int
+	count   int
+	timeout time.Duration
+
+	sync.Mutex
+	sent  map[int]time.Time
+	stats pingStats
+	done  chan struct{}
+}
+
+// send sends the echo request with the sequence number.
+func (r *receiver) send(seq int, dst net.Addr) error {
+	var typ icmp.Type = ipv4.ICMPTypeEcho
+	if r.conn.ipv6 {
+		typ = ipv6.ICMPTypeEchoRequest
+	}
+	msg := icmp.Message{
+		Type: typ,
+		Body: &icmp.Echo{
+			ID:   r.id,
+			Seq:  seq,
+			Data: make([]byte, payloadSize),
+		},
+	}
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	r.sent[seq] = time.Now()
+	r.stats.sent++
+	r.Unlock()
+
+	_, err = r.conn.WriteTo(b, dst)
+	return err
+}
+
+// receive reads the replies until all requests are answered or the read
+// deadline is reached.
+func (r *receiver) receive() error {
+	defer close(r.done)
+
+	buf := make([]byte, 1500)
+	for {
+		n, ttl, src, err := r.read(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return nil
+			}
+			return err
+		}
+		now := time.Now()
+
+		if !sameHost(src, r.dst) {
+			continue
+		}
+
+		proto := protocolICMP
+		if r.conn.ipv6 {
+			proto = protocolIPv6ICMP
+		}
+		msg, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil {
+			continue
+		}
+		if msg.Type != ipv4.ICMPTypeEchoReply && msg.Type != ipv6.ICMPTypeEchoReply {
+			continue
+		}
+		echo, ok := msg.Body.(*icmp.Echo)
+		if !ok {
+			continue
+		}
+		// The kernel sets the identifier of datagram sockets.
+		if !r.conn.datagram && echo.ID != r.id {
+			continue
+		}
+
+		if r.reply(echo.Seq, ttl, now) {
+			return nil
+		}
+	}
+}
+
+// read reads a message and the TTL or hop limit it was received with.
+func (r *receiver) read(buf []byte) (int, int, net.Addr, error) {
+	if r.conn.ipv6 {
+		n, cm, src, err := r.conn.IPv6PacketConn().ReadFrom(buf)
+		ttl := -1
+		if cm != nil {
+			ttl = cm.HopLimit
+		}
+		return n, ttl, src, err
+	}
+	n, cm, src, err := r.conn.IPv4PacketConn().ReadFrom(buf)
+	ttl := -1
+	if cm != nil {
+		ttl = cm.TTL
+	}
+	return n, ttl, src, err
+}
+
+// reply records the reply to the request with the sequence number, and
+// returns true when all requests are answered.
+func (r *receiver) reply(seq int, ttl int, now time.Time) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	sentAt, ok := r.sent[seq]
+	if !ok {
+		return false
+	}
+	delete(r.sent, seq)
+
+	rtt := now.Sub(sentAt)
+	if rtt > r.timeout {
+		return false
+	}
+	if r.stats.received == 0 {
+		r.stats.ttl = ttl
+	}
+	r.stats.received++
+	r.stats.rtts = append(r.stats.rtts, rtt)
+	return r.stats.received == r.count
+}
+
+// sameHost returns true if the address is the destination address.
+func sameHost(addr net.Addr, dst *net.IPAddr) bool {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP.Equal(dst.IP)
+	case *net.UDPAddr:
+		return a.IP.Equal(dst.IP)
+	}
+	return false
+}
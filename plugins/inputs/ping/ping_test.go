@@ -4,9 +4,11 @@ package ping
 
 import (
 	"errors"
+	"net"
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
@@ -339,3 +341,129 @@ func TestPingBinary(t *testing.T) {
 	}
 	acc.GatherError(p.Gather)
 }
+
+func TestInitMethod(t *testing.T) {
+	p := Ping{Method: "exec"}
+	require.NoError(t, p.Init())
+
+	p = Ping{Method: "native", Percentiles: []int{50, 99}}
+	require.NoError(t, p.Init())
+	require.NotNil(t, p.nativePingHost)
+
+	p = Ping{Method: "native", Percentiles: []int{0}}
+	require.Error(t, p.Init())
+
+	p = Ping{Method: "icmp"}
+	require.Error(t, p.Init())
+}
+
+func mockNativePinger(addr *net.IPAddr) (*pingStats, error) {
+	return &pingStats{
+		sent:     5,
+		received: 4,
+		ttl:      64,
+		rtts: []time.Duration{
+			4 * time.Millisecond,
+			1 * time.Millisecond,
+			3 * time.Millisecond,
+			2 * time.Millisecond,
+		},
+	}, nil
+}
+
+func TestNativePingGather(t *testing.T) {
+	var acc testutil.Accumulator
+	p := Ping{
+		Urls:           []string{"127.0.0.1"},
+		Method:         "native",
+		Percentiles:    []int{50, 75, 100},
+		nativePingHost: mockNativePinger,
+	}
+	require.NoError(t, p.Init())
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	tags := map[string]string{"url": "127.0.0.1"}
+	fields := map[string]interface{}{
+		"packets_transmitted":   5,
+		"packets_received":      4,
+		"percent_packet_loss":   20.0,
+		"ttl":                   64,
+		"minimum_response_ms":   1.0,
+		"average_response_ms":   2.5,
+		"maximum_response_ms":   4.0,
+		"standard_deviation_ms": 1.118033988749895,
+		"percentile50_ms":       2.0,
+		"percentile75_ms":       3.0,
+		"percentile100_ms":      4.0,
+		"result_code":           0,
+	}
+	acc.AssertContainsTaggedFields(t, "ping", fields, tags)
+}
+
+func TestNativePingNoReply(t *testing.T) {
+	var acc testutil.Accumulator
+	p := Ping{
+		Urls:   []string{"127.0.0.1"},
+		Method: "native",
+		nativePingHost: func(addr *net.IPAddr) (*pingStats, error) {
+			return &pingStats{sent: 3}, nil
+		},
+	}
+	require.NoError(t, acc.GatherError(p.Gather))
+
+	tags := map[string]string{"url": "127.0.0.1"}
+	fields := map[string]interface{}{
+		"packets_transmitted": 3,
+		"packets_received":    0,
+		"percent_packet_loss": 100.0,
+		"result_code":         0,
+	}
+	acc.AssertContainsTaggedFields(t, "ping", fields, tags)
+	assert.False(t, acc.HasField("ping", "average_response_ms"),
+		"No reply should not have response time fields")
+}
+
+func TestNativePingError(t *testing.T) {
+	var acc testutil.Accumulator
+	p := Ping{
+		Urls:   []string{"127.0.0.1"},
+		Method: "native",
+		nativePingHost: func(addr *net.IPAddr) (*pingStats, error) {
+			return nil, errors.New("socket: operation not permitted")
+		},
+	}
+	acc.GatherError(p.Gather)
+	assert.Contains(t, acc.Errors,
+		errors.New("host 127.0.0.1: socket: operation not permitted"))
+
+	tags := map[string]string{"url": "127.0.0.1"}
+	fields := map[string]interface{}{
+		"result_code": 2,
+	}
+	acc.AssertContainsTaggedFields(t, "ping", fields, tags)
+}
+
+func TestNativePingLoopback(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	p := Ping{
+		Count:        2,
+		PingInterval: 0.2,
+		Timeout:      1.0,
+		Deadline:     5,
+	}
+	conn, err := listen(false, "127.0.0.1")
+	if err != nil {
+		t.Skipf("Cannot open ICMP socket: %s", err)
+	}
+	conn.Close()
+
+	stats, err := p.nativePing(&net.IPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.sent)
+	require.Equal(t, 2, stats.received)
+	require.Len(t, stats.rtts, 2)
+	require.True(t, stats.ttl > 0)
+}
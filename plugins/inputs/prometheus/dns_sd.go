@@ -0,0 +1,73 @@
+package prometheus
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/discovery"
+	"github.com/influxdata/telegraf/plugins/discovery/dns"
+)
+
+// dnsTargets collects the targets of the DNS discovery, they are scraped
+// with the dns_sd_scheme and dns_sd_metrics_path.
+type dnsTargets struct {
+	discovery.Targets
+}
+
+func (t *dnsTargets) AddTarget(target telegraf.Target) {
+	t.Add(target)
+}
+
+func (t *dnsTargets) RemoveTarget(target telegraf.Target) {
+	t.Remove(target)
+}
+
+// startDNSSD starts the DNS discovery for the dns_sd_names.
+func (p *Prometheus) startDNSSD() error {
+	sd := &dns.DNS{
+		Names:           p.DNSSDNames,
+		RecordType:      p.DNSSDType,
+		Port:            p.DNSSDPort,
+		RefreshInterval: p.DNSSDRefreshInterval,
+	}
+	if err := sd.Init(); err != nil {
+		return fmt.Errorf("invalid dns_sd options: %v", err)
+	}
+	if err := sd.Start(&p.dnsTargets); err != nil {
+		return err
+	}
+	p.dnsSD = sd
+	return nil
+}
+
+// dnsTargetURLs returns the URLs of the DNS targets.  The targets of A and
+// AAAA records are tagged with their address and the URL of the DNS name,
+// like the kubernetes_services.
+func (p *Prometheus) dnsTargetURLs() []URLAndAddress {
+	var targets []URLAndAddress
+	for _, target := range p.dnsTargets.List() {
+		u := &url.URL{
+			Scheme: p.DNSSDScheme,
+			Host:   target.Address,
+			Path:   p.DNSSDMetricsPath,
+		}
+		t := URLAndAddress{URL: u, OriginalURL: u, Tags: target.Tags}
+
+		if strings.ToUpper(p.DNSSDType) != "SRV" {
+			host, port, err := net.SplitHostPort(target.Address)
+			if err == nil {
+				t.Address = host
+				t.OriginalURL = &url.URL{
+					Scheme: p.DNSSDScheme,
+					Host:   net.JoinHostPort(target.Tags["dns_name"], port),
+					Path:   p.DNSSDMetricsPath,
+				}
+			}
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
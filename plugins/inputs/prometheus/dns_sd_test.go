@@ -0,0 +1,59 @@
+package prometheus
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSSDSRV(t *testing.T) {
+	p := &Prometheus{
+		DNSSDType:        "SRV",
+		DNSSDScheme:      "http",
+		DNSSDMetricsPath: "/metrics",
+	}
+	tags := map[string]string{"dns_name": "_prometheus._tcp.example.com"}
+	p.dnsTargets.AddTarget(telegraf.Target{Address: "a.example.com:9100", Tags: tags})
+	p.dnsTargets.AddTarget(telegraf.Target{Address: "b.example.com:9101", Tags: tags})
+	require.Equal(t, []string{
+		"http://a.example.com:9100/metrics",
+		"http://b.example.com:9101/metrics",
+	}, sortedURLs(p))
+
+	allURLs, _ := p.GetAllURLs()
+	target := allURLs["http://a.example.com:9100/metrics"]
+	require.Equal(t, tags, target.Tags)
+	require.Equal(t, "", target.Address)
+
+	p.dnsTargets.RemoveTarget(telegraf.Target{Address: "a.example.com:9100"})
+	require.Equal(t, []string{"http://b.example.com:9101/metrics"}, sortedURLs(p))
+}
+
+func TestDNSSDA(t *testing.T) {
+	p := &Prometheus{
+		DNSSDType:        "A",
+		DNSSDScheme:      "https",
+		DNSSDMetricsPath: "/metrics",
+	}
+	p.dnsTargets.AddTarget(telegraf.Target{
+		Address: "10.0.0.1:9100",
+		Tags:    map[string]string{"dns_name": "node.example.com"},
+	})
+
+	allURLs, err := p.GetAllURLs()
+	require.NoError(t, err)
+	require.Len(t, allURLs, 1)
+	target := allURLs["https://10.0.0.1:9100/metrics"]
+	require.Equal(t, "10.0.0.1", target.Address)
+	require.Equal(t, "https://node.example.com:9100/metrics", target.OriginalURL.String())
+}
+
+func TestDNSSDInvalidOptions(t *testing.T) {
+	p := &Prometheus{DNSSDNames: []string{"example.com"}, DNSSDType: "MX"}
+	require.Error(t, p.Start(nil))
+
+	// A and AAAA records need a port.
+	p = &Prometheus{DNSSDNames: []string{"example.com"}, DNSSDType: "A"}
+	require.Error(t, p.Start(nil))
+}
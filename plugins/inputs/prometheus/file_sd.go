@@ -0,0 +1,24 @@
+package prometheus
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf/plugins/discovery/file"
+)
+
+// startFileSD starts the file discovery for the file_sd_files, its targets
+// are scraped like the targets of discovery plugins.
+func (p *Prometheus) startFileSD() error {
+	sd := &file.File{
+		Files:           p.FileSDFiles,
+		RefreshInterval: p.FileSDRefreshInterval,
+	}
+	if err := sd.Init(); err != nil {
+		return fmt.Errorf("invalid file_sd_files: %v", err)
+	}
+	if err := sd.Start(p); err != nil {
+		return err
+	}
+	p.fileSD = sd
+	return nil
+}
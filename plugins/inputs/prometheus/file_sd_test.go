@@ -0,0 +1,99 @@
+package prometheus
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func sortedURLs(p *Prometheus) []string {
+	allURLs, _ := p.GetAllURLs()
+	var urls []string
+	for u := range allURLs {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func TestFileSD(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_sd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	jsonFile := filepath.Join(dir, "targets.json")
+	require.NoError(t, ioutil.WriteFile(jsonFile, []byte(`[
+		{"targets": ["a:9100", "b:9100"], "labels": {"env": "prod"}},
+		{"targets": ["c:8080"], "labels": {"__scheme__": "https", "__metrics_path__": "/stats", "__param_format": "text"}}
+	]`), 0644))
+
+	yamlFile := filepath.Join(dir, "targets.yml")
+	require.NoError(t, ioutil.WriteFile(yamlFile, []byte(`
+- targets:
+    - d:9100
+  labels:
+    job: node
+`), 0644))
+
+	p := &Prometheus{FileSDFiles: []string{filepath.Join(dir, "*")}}
+	p.FileSDRefreshInterval.Duration = time.Hour
+	require.NoError(t, p.Start(nil))
+	defer p.Stop()
+
+	require.Equal(t, []string{
+		"http://a:9100/metrics",
+		"http://b:9100/metrics",
+		"http://d:9100/metrics",
+		"https://c:8080/stats?format=text",
+	}, sortedURLs(p))
+
+	allURLs, err := p.GetAllURLs()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"env": "prod"}, allURLs["http://a:9100/metrics"].Tags)
+	require.Equal(t, map[string]string{}, allURLs["https://c:8080/stats?format=text"].Tags)
+	require.Equal(t, map[string]string{"job": "node"}, allURLs["http://d:9100/metrics"].Tags)
+}
+
+func TestFileSDInvalidPattern(t *testing.T) {
+	p := &Prometheus{FileSDFiles: []string{"["}}
+	require.Error(t, p.Start(nil))
+}
+
+func TestFileSDGather(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, sampleTextFormat)
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "file_sd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "targets.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(fmt.Sprintf(
+		`[{"targets": [%q], "labels": {"env": "prod"}}]`, u.Host)), 0644))
+
+	p := &Prometheus{FileSDFiles: []string{file}}
+	p.FileSDRefreshInterval.Duration = time.Hour
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Start(&acc))
+	defer p.Stop()
+
+	require.NoError(t, acc.GatherError(p.Gather))
+	require.True(t, acc.HasFloatField("go_goroutines", "gauge"))
+	require.Equal(t, "prod", acc.TagValue("go_goroutines", "env"))
+	require.Equal(t, ts.URL+"/metrics", acc.TagValue("go_goroutines", "url"))
+}
@@ -9,12 +9,14 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/discovery"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -45,8 +47,27 @@ type Prometheus struct {
 	PodNamespace   string `toml:"monitor_kubernetes_pods_namespace"`
 	lock           sync.Mutex
 	kubernetesPods map[string]URLAndAddress
-	cancel         context.CancelFunc
-	wg             sync.WaitGroup
+
+	// Prometheus file_sd target files to read the targets from
+	FileSDFiles           []string          `toml:"file_sd_files"`
+	FileSDRefreshInterval internal.Duration `toml:"file_sd_refresh_interval"`
+	fileSD                telegraf.Discovery
+
+	// DNS names to resolve the targets from
+	DNSSDNames           []string          `toml:"dns_sd_names"`
+	DNSSDType            string            `toml:"dns_sd_type"`
+	DNSSDPort            uint16            `toml:"dns_sd_port"`
+	DNSSDScheme          string            `toml:"dns_sd_scheme"`
+	DNSSDMetricsPath     string            `toml:"dns_sd_metrics_path"`
+	DNSSDRefreshInterval internal.Duration `toml:"dns_sd_refresh_interval"`
+	dnsSD                telegraf.Discovery
+	dnsTargets           dnsTargets
+
+	// targets found by discovery plugins
+	targets discovery.Targets
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
 var sampleConfig = `
@@ -70,6 +91,23 @@ var sampleConfig = `
   ##   ex: monitor_kubernetes_pods_namespace = "default"
   # monitor_kubernetes_pods_namespace = ""
 
+  ## Files containing targets in the Prometheus file_sd format, JSON or YAML
+  ## and glob patterns are supported.  The files are reread every refresh
+  ## interval; labels of the targets are added as tags, except labels
+  ## starting with "__".
+  # file_sd_files = ["/etc/prometheus/targets/*.json"]
+  # file_sd_refresh_interval = "1m"
+
+  ## DNS names resolved to targets every refresh interval.  With the "SRV"
+  ## type the host and port of the records are used, with the "A" and "AAAA"
+  ## types the addresses and the dns_sd_port.
+  # dns_sd_names = ["_prometheus._tcp.example.com"]
+  # dns_sd_type = "SRV"
+  # dns_sd_port = 9100
+  # dns_sd_scheme = "http"
+  # dns_sd_metrics_path = "/metrics"
+  # dns_sd_refresh_interval = "30s"
+
   ## Use bearer token for authorization. ('bearer_token' takes priority)
   # bearer_token = "/path/to/bearer/token"
   ## OR
@@ -140,6 +178,19 @@ func (p *Prometheus) GetAllURLs() (map[string]URLAndAddress, error) {
 		allURLs[k] = v
 	}
 
+	// targets discovered from DNS, files and discovery plugins
+	for _, target := range p.dnsTargetURLs() {
+		allURLs[target.URL.String()] = target
+	}
+	for _, target := range p.targets.List() {
+		URL, err := targetAddressURL(target.Address)
+		if err != nil {
+			log.Printf("prometheus: Could not parse target %s, skipping it. Error: %s", target.Address, err.Error())
+			continue
+		}
+		allURLs[URL.String()] = URLAndAddress{URL: URL, OriginalURL: URL, Tags: target.Tags}
+	}
+
 	for _, service := range p.KubernetesServices {
 		URL, err := url.Parse(service)
 		if err != nil {
@@ -163,6 +214,25 @@ func (p *Prometheus) GetAllURLs() (map[string]URLAndAddress, error) {
 	return allURLs, nil
 }
 
+// AddTarget adds a target found by a discovery plugin.
+func (p *Prometheus) AddTarget(target telegraf.Target) {
+	p.targets.Add(target)
+}
+
+// RemoveTarget removes a target found by a discovery plugin.
+func (p *Prometheus) RemoveTarget(target telegraf.Target) {
+	p.targets.Remove(target)
+}
+
+// targetAddressURL returns the URL of a discovered target, addresses without
+// a scheme are scraped over http on /metrics.
+func targetAddressURL(address string) (*url.URL, error) {
+	if strings.Contains(address, "://") {
+		return url.Parse(address)
+	}
+	return url.Parse("http://" + address + "/metrics")
+}
+
 // Reads stats from all configured servers accumulates stats.
 // Returns one of the errors encountered while gather stats (if any).
 func (p *Prometheus) Gather(acc telegraf.Accumulator) error {
@@ -308,18 +378,41 @@ func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error
 	return nil
 }
 
-// Start will start the Kubernetes scraping if enabled in the configuration
+// Start will start the Kubernetes scraping and the file and DNS target
+// discovery if enabled in the configuration
 func (p *Prometheus) Start(a telegraf.Accumulator) error {
+	if len(p.FileSDFiles) > 0 {
+		if err := p.startFileSD(); err != nil {
+			return err
+		}
+	}
+	if len(p.DNSSDNames) > 0 {
+		if err := p.startDNSSD(); err != nil {
+			p.Stop()
+			return err
+		}
+	}
 	if p.MonitorPods {
 		var ctx context.Context
 		ctx, p.cancel = context.WithCancel(context.Background())
-		return p.start(ctx)
+		if err := p.start(ctx); err != nil {
+			p.Stop()
+			return err
+		}
 	}
 	return nil
 }
 
 func (p *Prometheus) Stop() {
-	if p.MonitorPods {
+	if p.fileSD != nil {
+		p.fileSD.Stop()
+		p.fileSD = nil
+	}
+	if p.dnsSD != nil {
+		p.dnsSD.Stop()
+		p.dnsSD = nil
+	}
+	if p.cancel != nil {
 		p.cancel()
 	}
 	p.wg.Wait()
@@ -328,8 +421,13 @@ func (p *Prometheus) Stop() {
 func init() {
 	inputs.Add("prometheus", func() telegraf.Input {
 		return &Prometheus{
-			ResponseTimeout: internal.Duration{Duration: time.Second * 3},
-			kubernetesPods:  map[string]URLAndAddress{},
+			ResponseTimeout:       internal.Duration{Duration: time.Second * 3},
+			kubernetesPods:        map[string]URLAndAddress{},
+			FileSDRefreshInterval: internal.Duration{Duration: time.Minute},
+			DNSSDType:             "SRV",
+			DNSSDScheme:           "http",
+			DNSSDMetricsPath:      "/metrics",
+			DNSSDRefreshInterval:  internal.Duration{Duration: 30 * time.Second},
 		}
 	})
 }
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -103,3 +104,30 @@ func TestPrometheusGeneratesMetricsAlthoughFirstDNSFails(t *testing.T) {
 	assert.True(t, acc.HasFloatField("test_metric", "value"))
 	assert.True(t, acc.HasTimestamp("test_metric", time.Unix(1490802350, 0)))
 }
+
+func TestPrometheusDiscoveredTargets(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, sampleTextFormat)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	p := &Prometheus{}
+	p.AddTarget(telegraf.Target{
+		Address: u.Host,
+		Tags:    map[string]string{"env": "prod"},
+	})
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(p.Gather))
+	assert.True(t, acc.HasFloatField("go_goroutines", "gauge"))
+	assert.Equal(t, "prod", acc.TagValue("go_goroutines", "env"))
+	assert.Equal(t, ts.URL+"/metrics", acc.TagValue("go_goroutines", "url"))
+
+	p.RemoveTarget(telegraf.Target{Address: u.Host})
+	acc.ClearMetrics()
+	require.NoError(t, acc.GatherError(p.Gather))
+	assert.Empty(t, acc.Metrics)
+}
@@ -0,0 +1,337 @@
+package sflow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// sample and record formats of the standard sFlow v5 structures, the
+// enterprise number is 0 for all of them
+const (
+	formatFlowSample            = 1
+	formatCounterSample         = 2
+	formatExpandedFlowSample    = 3
+	formatExpandedCounterSample = 4
+
+	formatRawPacketHeader = 1
+	formatExtendedSwitch  = 1001
+
+	formatGenericInterface  = 1
+	formatEthernetInterface = 2
+)
+
+var errTruncated = errors.New("truncated datagram")
+
+// reader reads the XDR encoded values of a datagram.  After the first error
+// all reads return zero values, so the error only has to be checked once
+// after a structure is read.
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) uint32() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) < 4 {
+		r.err = errTruncated
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.buf)
+	r.buf = r.buf[4:]
+	return v
+}
+
+func (r *reader) uint64() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) < 8 {
+		r.err = errTruncated
+		return 0
+	}
+	v := binary.BigEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v
+}
+
+// bytes reads n bytes and the padding to the next 4 byte boundary.
+func (r *reader) bytes(n uint32) []byte {
+	if r.err != nil {
+		return nil
+	}
+	padded := (uint64(n) + 3) &^ 3
+	if padded > uint64(len(r.buf)) {
+		r.err = errTruncated
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[padded:]
+	return b
+}
+
+// opaque reads a length prefixed structure and returns a reader for it.
+func (r *reader) opaque() *reader {
+	length := r.uint32()
+	b := r.bytes(length)
+	return &reader{buf: b, err: r.err}
+}
+
+// decode decodes an sFlow v5 datagram.  The metrics of the samples decoded
+// before an error are returned with it.
+func decode(buf []byte, t time.Time) ([]telegraf.Metric, error) {
+	r := &reader{buf: buf}
+	if version := r.uint32(); r.err == nil && version != 5 {
+		return nil, fmt.Errorf("unsupported version %d", version)
+	}
+
+	var agent net.IP
+	switch addrType := r.uint32(); addrType {
+	case 1:
+		agent = net.IP(r.bytes(net.IPv4len))
+	case 2:
+		agent = net.IP(r.bytes(net.IPv6len))
+	default:
+		if r.err == nil {
+			return nil, fmt.Errorf("unsupported agent address type %d", addrType)
+		}
+	}
+	subAgentID := r.uint32()
+	r.uint32() // sequence number
+	r.uint32() // uptime
+	count := r.uint32()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	d := &datagram{
+		agent:      agent.String(),
+		subAgentID: strconv.FormatUint(uint64(subAgentID), 10),
+		time:       t,
+	}
+
+	var metrics []telegraf.Metric
+	for i := uint32(0); i < count; i++ {
+		format := r.uint32()
+		sample := r.opaque()
+		if r.err != nil {
+			return metrics, r.err
+		}
+
+		var m telegraf.Metric
+		var err error
+		switch format {
+		case formatFlowSample:
+			m, err = d.decodeFlowSample(sample, false)
+		case formatExpandedFlowSample:
+			m, err = d.decodeFlowSample(sample, true)
+		case formatCounterSample:
+			m, err = d.decodeCounterSample(sample, false)
+		case formatExpandedCounterSample:
+			m, err = d.decodeCounterSample(sample, true)
+		default:
+			// samples of other enterprises or formats are skipped
+			continue
+		}
+		if err != nil {
+			return metrics, err
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+// datagram holds the values of the datagram header added to all samples.
+type datagram struct {
+	agent      string
+	subAgentID string
+	time       time.Time
+}
+
+func (d *datagram) tags() map[string]string {
+	return map[string]string{
+		"agent_address": d.agent,
+		"sub_agent_id":  d.subAgentID,
+	}
+}
+
+// sourceID reads the type and index of the data source of a sample.
+func sourceID(r *reader, expanded bool) (uint32, uint32) {
+	if expanded {
+		return r.uint32(), r.uint32()
+	}
+	id := r.uint32()
+	return id >> 24, id & 0x00ffffff
+}
+
+// interfaceTag formats an input or output interface of a flow sample.
+func interfaceTag(format uint32, value uint32) string {
+	switch format {
+	case 0:
+		if value == 0x3fffffff {
+			return "unknown"
+		}
+		return strconv.FormatUint(uint64(value), 10)
+	case 1:
+		return "discarded"
+	default:
+		return "multiple"
+	}
+}
+
+func (d *datagram) decodeFlowSample(r *reader, expanded bool) (telegraf.Metric, error) {
+	r.uint32() // sequence number
+	sourceType, sourceIndex := sourceID(r, expanded)
+	samplingRate := r.uint32()
+	samplePool := r.uint32()
+	drops := r.uint32()
+
+	var inFormat, inValue, outFormat, outValue uint32
+	if expanded {
+		inFormat, inValue = r.uint32(), r.uint32()
+		outFormat, outValue = r.uint32(), r.uint32()
+	} else {
+		in, out := r.uint32(), r.uint32()
+		inFormat, inValue = in>>30, in&0x3fffffff
+		outFormat, outValue = out>>30, out&0x3fffffff
+	}
+	count := r.uint32()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	tags := d.tags()
+	tags["source_id_type"] = strconv.FormatUint(uint64(sourceType), 10)
+	tags["source_id_index"] = strconv.FormatUint(uint64(sourceIndex), 10)
+	tags["input_ifindex"] = interfaceTag(inFormat, inValue)
+	tags["output_ifindex"] = interfaceTag(outFormat, outValue)
+
+	fields := map[string]interface{}{
+		"sampling_rate": uint64(samplingRate),
+		"sample_pool":   uint64(samplePool),
+		"drops":         uint64(drops),
+	}
+
+	for i := uint32(0); i < count; i++ {
+		format := r.uint32()
+		record := r.opaque()
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		switch format {
+		case formatRawPacketHeader:
+			protocol := record.uint32()
+			frameLength := record.uint32()
+			record.uint32() // stripped
+			header := record.bytes(record.uint32())
+			if record.err != nil {
+				return nil, record.err
+			}
+
+			fields["header_protocol"] = uint64(protocol)
+			fields["frame_length"] = uint64(frameLength)
+			fields["header_length"] = uint64(len(header))
+			fields["bytes"] = uint64(frameLength) * uint64(samplingRate)
+			decodeHeader(protocol, header, fields)
+		case formatExtendedSwitch:
+			fields["src_vlan"] = uint64(record.uint32())
+			fields["src_priority"] = uint64(record.uint32())
+			fields["dst_vlan"] = uint64(record.uint32())
+			fields["dst_priority"] = uint64(record.uint32())
+			if record.err != nil {
+				return nil, record.err
+			}
+		}
+	}
+
+	return metric.New("sflow", tags, fields, d.time)
+}
+
+func (d *datagram) decodeCounterSample(r *reader, expanded bool) (telegraf.Metric, error) {
+	r.uint32() // sequence number
+	sourceType, sourceIndex := sourceID(r, expanded)
+	count := r.uint32()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	tags := d.tags()
+	tags["source_id_type"] = strconv.FormatUint(uint64(sourceType), 10)
+	tags["if_index"] = strconv.FormatUint(uint64(sourceIndex), 10)
+	fields := make(map[string]interface{})
+
+	for i := uint32(0); i < count; i++ {
+		format := r.uint32()
+		record := r.opaque()
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		switch format {
+		case formatGenericInterface:
+			tags["if_index"] = strconv.FormatUint(uint64(record.uint32()), 10)
+			fields["if_type"] = uint64(record.uint32())
+			fields["if_speed"] = record.uint64()
+			fields["if_direction"] = uint64(record.uint32())
+			status := record.uint32()
+			fields["if_admin_status"] = uint64(status & 1)
+			fields["if_oper_status"] = uint64(status >> 1 & 1)
+			fields["in_octets"] = record.uint64()
+			fields["in_ucast_pkts"] = uint64(record.uint32())
+			fields["in_multicast_pkts"] = uint64(record.uint32())
+			fields["in_broadcast_pkts"] = uint64(record.uint32())
+			fields["in_discards"] = uint64(record.uint32())
+			fields["in_errors"] = uint64(record.uint32())
+			fields["in_unknown_protos"] = uint64(record.uint32())
+			fields["out_octets"] = record.uint64()
+			fields["out_ucast_pkts"] = uint64(record.uint32())
+			fields["out_multicast_pkts"] = uint64(record.uint32())
+			fields["out_broadcast_pkts"] = uint64(record.uint32())
+			fields["out_discards"] = uint64(record.uint32())
+			fields["out_errors"] = uint64(record.uint32())
+			fields["promiscuous_mode"] = uint64(record.uint32())
+		case formatEthernetInterface:
+			for _, name := range ethernetCounters {
+				fields[name] = uint64(record.uint32())
+			}
+		}
+		if record.err != nil {
+			return nil, record.err
+		}
+	}
+
+	if len(fields) == 0 {
+		// no interface counters in the sample
+		return nil, nil
+	}
+	return metric.New("sflow_interface", tags, fields, d.time)
+}
+
+// ethernetCounters are the counters of the ethernet interface counters
+// record, in order.
+var ethernetCounters = []string{
+	"dot3_alignment_errors",
+	"dot3_fcs_errors",
+	"dot3_single_collision_frames",
+	"dot3_multiple_collision_frames",
+	"dot3_sqe_test_errors",
+	"dot3_deferred_transmissions",
+	"dot3_late_collisions",
+	"dot3_excessive_collisions",
+	"dot3_internal_mac_transmit_errors",
+	"dot3_carrier_sense_errors",
+	"dot3_frame_too_longs",
+	"dot3_internal_mac_receive_errors",
+	"dot3_symbol_errors",
+}
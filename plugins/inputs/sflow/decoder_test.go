@@ -0,0 +1,439 @@
+package sflow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// xdr builds XDR encoded structures.
+type xdr struct {
+	bytes.Buffer
+}
+
+func (x *xdr) put(values ...interface{}) *xdr {
+	for _, v := range values {
+		binary.Write(&x.Buffer, binary.BigEndian, v)
+	}
+	return x
+}
+
+// opaque appends the length prefixed and padded bytes.
+func (x *xdr) opaque(b []byte) *xdr {
+	x.put(uint32(len(b)))
+	x.Write(b)
+	for i := len(b); i%4 != 0; i++ {
+		x.WriteByte(0)
+	}
+	return x
+}
+
+// tcpFrame is an ethernet frame with a VLAN tag carrying an IPv4 TCP SYN.
+var tcpFrame = []byte{
+	// ethernet
+	0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb,
+	0x81, 0x00, 0x00, 0x64, 0x08, 0x00,
+	// ipv4
+	0x45, 0x10, 0x00, 0x3c, 0x1c, 0x46, 0x40, 0x00, 0x40, 0x06, 0x00, 0x00,
+	0x0a, 0x00, 0x00, 0x01, 0x0a, 0x00, 0x00, 0x02,
+	// tcp
+	0xc7, 0x38, 0x01, 0xbb, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+	0xa0, 0x02, 0x72, 0x10, 0x00, 0x00, 0x00, 0x00,
+}
+
+// udpFrame is an ethernet frame carrying an IPv6 UDP DNS query header.
+var udpFrame = []byte{
+	// ethernet
+	0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb,
+	0x86, 0xdd,
+	// ipv6
+	0x60, 0x00, 0x00, 0x01, 0x00, 0x1d, 0x11, 0x40,
+	0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01,
+	0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x02,
+	// udp
+	0xd4, 0x31, 0x00, 0x35, 0x00, 0x1d, 0x00, 0x00,
+}
+
+func flowSample() []byte {
+	header := &xdr{}
+	header.put(uint32(headerProtocolEthernet), uint32(1518), uint32(4))
+	header.opaque(tcpFrame)
+
+	sw := &xdr{}
+	sw.put(uint32(100), uint32(0), uint32(200), uint32(3))
+
+	s := &xdr{}
+	s.put(uint32(7), uint32(0<<24|12), uint32(1024), uint32(65536), uint32(0),
+		uint32(12), uint32(0x3fffffff), uint32(2))
+	s.put(uint32(formatRawPacketHeader)).opaque(header.Bytes())
+	s.put(uint32(formatExtendedSwitch)).opaque(sw.Bytes())
+	return s.Bytes()
+}
+
+func expandedFlowSample() []byte {
+	header := &xdr{}
+	header.put(uint32(headerProtocolEthernet), uint32(80), uint32(4))
+	header.opaque(udpFrame)
+
+	s := &xdr{}
+	s.put(uint32(8), uint32(0), uint32(3), uint32(512), uint32(1024), uint32(1),
+		uint32(0), uint32(3), uint32(2), uint32(4), uint32(2))
+	s.put(uint32(formatRawPacketHeader)).opaque(header.Bytes())
+	// unknown enterprise record is skipped
+	s.put(uint32(4413<<12 | 1)).opaque([]byte{1, 2, 3, 4})
+	return s.Bytes()
+}
+
+func counterSample() []byte {
+	generic := &xdr{}
+	generic.put(uint32(3), uint32(6), uint64(10000000000), uint32(1), uint32(3),
+		uint64(123456789), uint32(1000), uint32(20), uint32(30), uint32(1), uint32(2), uint32(0),
+		uint64(987654321), uint32(2000), uint32(40), uint32(50), uint32(3), uint32(4), uint32(0))
+
+	ethernet := &xdr{}
+	for i := 0; i < len(ethernetCounters); i++ {
+		ethernet.put(uint32(i))
+	}
+
+	s := &xdr{}
+	s.put(uint32(9), uint32(0<<24|3), uint32(2))
+	s.put(uint32(formatGenericInterface)).opaque(generic.Bytes())
+	s.put(uint32(formatEthernetInterface)).opaque(ethernet.Bytes())
+	return s.Bytes()
+}
+
+func datagramWith(samples ...[]byte) []byte {
+	d := &xdr{}
+	d.put(uint32(5), uint32(1), []byte{192, 0, 2, 1}, uint32(0), uint32(42),
+		uint32(3600000), uint32(len(samples)))
+	for i, sample := range samples {
+		format := []uint32{formatFlowSample, formatExpandedFlowSample, formatCounterSample}[i%3]
+		d.put(format).opaque(sample)
+	}
+	return d.Bytes()
+}
+
+// testDatagram has a flow sample, an expanded flow sample and a counter
+// sample, as exported by a switch.
+func testDatagram() []byte {
+	return datagramWith(flowSample(), expandedFlowSample(), counterSample())
+}
+
+func TestDecode(t *testing.T) {
+	now := time.Unix(1563000000, 0)
+	metrics, err := decode(testDatagram(), now)
+	require.NoError(t, err)
+
+	ethernet := map[string]interface{}{}
+	for i, name := range ethernetCounters {
+		ethernet[name] = uint64(i)
+	}
+	counterFields := map[string]interface{}{
+		"if_type":            uint64(6),
+		"if_speed":           uint64(10000000000),
+		"if_direction":       uint64(1),
+		"if_admin_status":    uint64(1),
+		"if_oper_status":     uint64(1),
+		"in_octets":          uint64(123456789),
+		"in_ucast_pkts":      uint64(1000),
+		"in_multicast_pkts":  uint64(20),
+		"in_broadcast_pkts":  uint64(30),
+		"in_discards":        uint64(1),
+		"in_errors":          uint64(2),
+		"in_unknown_protos":  uint64(0),
+		"out_octets":         uint64(987654321),
+		"out_ucast_pkts":     uint64(2000),
+		"out_multicast_pkts": uint64(40),
+		"out_broadcast_pkts": uint64(50),
+		"out_discards":       uint64(3),
+		"out_errors":         uint64(4),
+		"promiscuous_mode":   uint64(0),
+	}
+	for k, v := range ethernet {
+		counterFields[k] = v
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("sflow",
+			map[string]string{
+				"agent_address":   "192.0.2.1",
+				"sub_agent_id":    "0",
+				"source_id_type":  "0",
+				"source_id_index": "12",
+				"input_ifindex":   "12",
+				"output_ifindex":  "unknown",
+			},
+			map[string]interface{}{
+				"sampling_rate":      uint64(1024),
+				"sample_pool":        uint64(65536),
+				"drops":              uint64(0),
+				"header_protocol":    uint64(1),
+				"frame_length":       uint64(1518),
+				"header_length":      uint64(len(tcpFrame)),
+				"bytes":              uint64(1518 * 1024),
+				"dst_mac":            "00:11:22:33:44:55",
+				"src_mac":            "66:77:88:99:aa:bb",
+				"vlan":               uint64(100),
+				"ether_type":         uint64(0x0800),
+				"ip_version":         uint64(4),
+				"ip_tos":             uint64(0x10),
+				"ip_total_length":    uint64(60),
+				"ip_fragment_offset": uint64(0),
+				"ip_ttl":             uint64(64),
+				"protocol":           uint64(6),
+				"src_ip":             "10.0.0.1",
+				"dst_ip":             "10.0.0.2",
+				"src_port":           uint64(51000),
+				"dst_port":           uint64(443),
+				"tcp_flags":          uint64(0x02),
+				"src_vlan":           uint64(100),
+				"src_priority":       uint64(0),
+				"dst_vlan":           uint64(200),
+				"dst_priority":       uint64(3),
+			},
+			now,
+		),
+		testutil.MustMetric("sflow",
+			map[string]string{
+				"agent_address":   "192.0.2.1",
+				"sub_agent_id":    "0",
+				"source_id_type":  "0",
+				"source_id_index": "3",
+				"input_ifindex":   "3",
+				"output_ifindex":  "multiple",
+			},
+			map[string]interface{}{
+				"sampling_rate":     uint64(512),
+				"sample_pool":       uint64(1024),
+				"drops":             uint64(1),
+				"header_protocol":   uint64(1),
+				"frame_length":      uint64(80),
+				"header_length":     uint64(len(udpFrame)),
+				"bytes":             uint64(80 * 512),
+				"dst_mac":           "00:11:22:33:44:55",
+				"src_mac":           "66:77:88:99:aa:bb",
+				"ether_type":        uint64(0x86dd),
+				"ip_version":        uint64(6),
+				"ip_tos":            uint64(0),
+				"ip_flow_label":     uint64(1),
+				"ip_payload_length": uint64(29),
+				"protocol":          uint64(17),
+				"ip_ttl":            uint64(64),
+				"src_ip":            "2001:db8::1",
+				"dst_ip":            "2001:db8::2",
+				"src_port":          uint64(54321),
+				"dst_port":          uint64(53),
+			},
+			now,
+		),
+		testutil.MustMetric("sflow_interface",
+			map[string]string{
+				"agent_address":  "192.0.2.1",
+				"sub_agent_id":   "0",
+				"source_id_type": "0",
+				"if_index":       "3",
+			},
+			counterFields,
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+// TestDecodeCapturedDatagram decodes a datagram of a switch captured on a
+// live network, the sFlow payload of SFlowTestPacket1 in the layers tests of
+// github.com/google/gopacket.
+func TestDecodeCapturedDatagram(t *testing.T) {
+	buf, err := ioutil.ReadFile("testdata/sflow_v5.bin")
+	require.NoError(t, err)
+
+	now := time.Unix(1563000000, 0)
+	metrics, err := decode(buf, now)
+	require.NoError(t, err)
+	require.Len(t, metrics, 7)
+
+	ethernet := map[string]interface{}{}
+	for _, name := range ethernetCounters {
+		ethernet[name] = uint64(0)
+	}
+	counterFields := map[string]interface{}{
+		"if_type":            uint64(6),
+		"if_speed":           uint64(10000000000),
+		"if_direction":       uint64(1),
+		"if_admin_status":    uint64(1),
+		"if_oper_status":     uint64(1),
+		"in_octets":          uint64(327115139476491),
+		"in_ucast_pkts":      uint64(3406683542),
+		"in_multicast_pkts":  uint64(436422),
+		"in_broadcast_pkts":  uint64(123),
+		"in_discards":        uint64(0),
+		"in_errors":          uint64(0),
+		"in_unknown_protos":  uint64(0),
+		"out_octets":         uint64(57184089570462),
+		"out_ucast_pkts":     uint64(1449403761),
+		"out_multicast_pkts": uint64(7363268),
+		"out_broadcast_pkts": uint64(592113),
+		"out_discards":       uint64(0),
+		"out_errors":         uint64(0),
+		"promiscuous_mode":   uint64(0),
+	}
+	for k, v := range ethernet {
+		counterFields[k] = v
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("sflow",
+			map[string]string{
+				"agent_address":   "10.1.248.22",
+				"sub_agent_id":    "17",
+				"source_id_type":  "0",
+				"source_id_index": "531",
+				"input_ifindex":   "531",
+				"output_ifindex":  "0",
+			},
+			map[string]interface{}{
+				"sampling_rate":      uint64(16000),
+				"sample_pool":        uint64(1354622336),
+				"drops":              uint64(0),
+				"header_protocol":    uint64(1),
+				"frame_length":       uint64(1490),
+				"header_length":      uint64(128),
+				"bytes":              uint64(23840000),
+				"src_mac":            "b8:ca:3a:6d:f0:40",
+				"dst_mac":            "3c:8a:b0:e7:54:41",
+				"ether_type":         uint64(2048),
+				"src_vlan":           uint64(514),
+				"src_priority":       uint64(0),
+				"dst_vlan":           uint64(0),
+				"dst_priority":       uint64(0),
+				"ip_version":         uint64(4),
+				"ip_tos":             uint64(0),
+				"ip_total_length":    uint64(1472),
+				"ip_fragment_offset": uint64(0),
+				"ip_ttl":             uint64(64),
+				"protocol":           uint64(6),
+				"src_ip":             "10.1.14.22",
+				"dst_ip":             "54.240.235.69",
+				"src_port":           uint64(30461),
+				"dst_port":           uint64(80),
+				"tcp_flags":          uint64(16),
+			},
+			now,
+		),
+		testutil.MustMetric("sflow_interface",
+			map[string]string{
+				"agent_address":  "10.1.248.22",
+				"sub_agent_id":   "17",
+				"source_id_type": "0",
+				"if_index":       "522",
+			},
+			counterFields,
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, []telegraf.Metric{metrics[0], metrics[3]})
+
+	// the other samples are flow samples of TCP packets
+	for _, i := range []int{1, 2, 4, 5, 6} {
+		require.Equal(t, "sflow", metrics[i].Name())
+		require.Equal(t, uint64(6), metrics[i].Fields()["protocol"])
+	}
+}
+
+func TestDecodeIPv6Agent(t *testing.T) {
+	d := &xdr{}
+	d.put(uint32(5), uint32(2),
+		[]byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x01},
+		uint32(1), uint32(1), uint32(1000), uint32(1))
+	d.put(uint32(formatCounterSample)).opaque(counterSample())
+
+	metrics, err := decode(d.Bytes(), time.Now())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "2001:db8::1", metrics[0].Tags()["agent_address"])
+	require.Equal(t, "1", metrics[0].Tags()["sub_agent_id"])
+}
+
+func TestDecodeSkipsUnknownSamples(t *testing.T) {
+	d := &xdr{}
+	d.put(uint32(5), uint32(1), []byte{192, 0, 2, 1}, uint32(0), uint32(1),
+		uint32(1000), uint32(2))
+	d.put(uint32(4413<<12 | 5)).opaque([]byte{1, 2, 3, 4, 5, 6})
+	d.put(uint32(formatCounterSample)).opaque(counterSample())
+
+	metrics, err := decode(d.Bytes(), time.Now())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "sflow_interface", metrics[0].Name())
+}
+
+func TestDecodeErrors(t *testing.T) {
+	_, err := decode([]byte{0, 0, 0, 4}, time.Now())
+	require.EqualError(t, err, "unsupported version 4")
+
+	_, err = decode((&xdr{}).put(uint32(5), uint32(3)).Bytes(), time.Now())
+	require.EqualError(t, err, "unsupported agent address type 3")
+
+	_, err = decode(testDatagram()[:100], time.Now())
+	require.Equal(t, errTruncated, err)
+}
+
+func TestDecodeTruncatedHeader(t *testing.T) {
+	// the fields of the complete headers are kept
+	for length, expected := range map[int][]string{
+		10: {},
+		14: {"src_mac"},
+		30: {"vlan", "ether_type"},
+		38: {"src_ip", "protocol"},
+		50: {"src_ip", "protocol"},
+	} {
+		fields := make(map[string]interface{})
+		decodeHeader(headerProtocolEthernet, tcpFrame[:length], fields)
+		for _, name := range expected {
+			require.Contains(t, fields, name, "length %d", length)
+		}
+		require.NotContains(t, fields, "src_port", "length %d", length)
+	}
+}
+
+// TestDecodeFuzz decodes truncated and randomly mutated datagrams, which
+// must return an error or metrics without panicking.
+func TestDecodeFuzz(t *testing.T) {
+	datagrams := [][]byte{
+		testDatagram(),
+		datagramWith(flowSample()),
+		datagramWith(counterSample()),
+	}
+
+	for _, datagram := range datagrams {
+		for i := 0; i <= len(datagram); i++ {
+			decode(datagram[:i], time.Now())
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		datagram := datagrams[i%len(datagrams)]
+		mutated := make([]byte, len(datagram))
+		copy(mutated, datagram)
+		for j := 0; j < 1+rnd.Intn(8); j++ {
+			pos := rnd.Intn(len(mutated))
+			switch rnd.Intn(3) {
+			case 0:
+				mutated[pos] = byte(rnd.Intn(256))
+			case 1:
+				mutated[pos] = 0xff
+			case 2:
+				mutated[pos] ^= 1 << uint(rnd.Intn(8))
+			}
+		}
+		decode(mutated, time.Now())
+	}
+}
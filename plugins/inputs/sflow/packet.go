@@ -0,0 +1,132 @@
+package sflow
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// header protocols of the raw packet header record
+const (
+	headerProtocolEthernet = 1
+	headerProtocolIPv4     = 11
+	headerProtocolIPv6     = 12
+)
+
+const (
+	etherTypeIPv4  = 0x0800
+	etherTypeIPv6  = 0x86dd
+	etherTypeVLAN  = 0x8100
+	etherTypeQinQ  = 0x88a8
+	protocolICMP   = 1
+	protocolTCP    = 6
+	protocolUDP    = 17
+	protocolICMPv6 = 58
+)
+
+// decodeHeader decodes the layer 2 to 4 headers of a sampled packet into
+// fields.  Decoding stops at the first header that is truncated or of an
+// unsupported protocol, keeping the fields of the headers before it.
+func decodeHeader(protocol uint32, header []byte, fields map[string]interface{}) {
+	switch protocol {
+	case headerProtocolEthernet:
+		decodeEthernet(header, fields)
+	case headerProtocolIPv4:
+		decodeIPv4(header, fields)
+	case headerProtocolIPv6:
+		decodeIPv6(header, fields)
+	}
+}
+
+func decodeEthernet(b []byte, fields map[string]interface{}) {
+	if len(b) < 14 {
+		return
+	}
+	fields["dst_mac"] = net.HardwareAddr(b[0:6]).String()
+	fields["src_mac"] = net.HardwareAddr(b[6:12]).String()
+	etherType := binary.BigEndian.Uint16(b[12:])
+	b = b[14:]
+
+	// the outer tag of stacked VLANs is kept
+	for etherType == etherTypeVLAN || etherType == etherTypeQinQ {
+		if len(b) < 4 {
+			return
+		}
+		if _, ok := fields["vlan"]; !ok {
+			fields["vlan"] = uint64(binary.BigEndian.Uint16(b) & 0x0fff)
+		}
+		etherType = binary.BigEndian.Uint16(b[2:])
+		b = b[4:]
+	}
+	fields["ether_type"] = uint64(etherType)
+
+	switch etherType {
+	case etherTypeIPv4:
+		decodeIPv4(b, fields)
+	case etherTypeIPv6:
+		decodeIPv6(b, fields)
+	}
+}
+
+func decodeIPv4(b []byte, fields map[string]interface{}) {
+	if len(b) < 20 || b[0]>>4 != 4 {
+		return
+	}
+	headerLength := int(b[0]&0x0f) * 4
+	fields["ip_version"] = uint64(4)
+	fields["ip_tos"] = uint64(b[1])
+	fields["ip_total_length"] = uint64(binary.BigEndian.Uint16(b[2:]))
+	fragmentOffset := binary.BigEndian.Uint16(b[6:]) & 0x1fff
+	fields["ip_fragment_offset"] = uint64(fragmentOffset)
+	fields["ip_ttl"] = uint64(b[8])
+	protocol := b[9]
+	fields["protocol"] = uint64(protocol)
+	fields["src_ip"] = net.IP(b[12:16]).String()
+	fields["dst_ip"] = net.IP(b[16:20]).String()
+
+	// only the first fragment has the transport header
+	if headerLength < 20 || len(b) < headerLength || fragmentOffset != 0 {
+		return
+	}
+	decodeTransport(protocol, b[headerLength:], fields)
+}
+
+func decodeIPv6(b []byte, fields map[string]interface{}) {
+	if len(b) < 40 || b[0]>>4 != 6 {
+		return
+	}
+	fields["ip_version"] = uint64(6)
+	fields["ip_tos"] = uint64(binary.BigEndian.Uint16(b) >> 4 & 0xff)
+	fields["ip_flow_label"] = uint64(binary.BigEndian.Uint32(b) & 0x000fffff)
+	fields["ip_payload_length"] = uint64(binary.BigEndian.Uint16(b[4:]))
+	protocol := b[6]
+	fields["protocol"] = uint64(protocol)
+	fields["ip_ttl"] = uint64(b[7])
+	fields["src_ip"] = net.IP(b[8:24]).String()
+	fields["dst_ip"] = net.IP(b[24:40]).String()
+
+	decodeTransport(protocol, b[40:], fields)
+}
+
+func decodeTransport(protocol uint8, b []byte, fields map[string]interface{}) {
+	switch protocol {
+	case protocolTCP:
+		if len(b) < 14 {
+			return
+		}
+		fields["src_port"] = uint64(binary.BigEndian.Uint16(b))
+		fields["dst_port"] = uint64(binary.BigEndian.Uint16(b[2:]))
+		fields["tcp_flags"] = uint64(binary.BigEndian.Uint16(b[12:]) & 0x01ff)
+	case protocolUDP:
+		if len(b) < 8 {
+			return
+		}
+		fields["src_port"] = uint64(binary.BigEndian.Uint16(b))
+		fields["dst_port"] = uint64(binary.BigEndian.Uint16(b[2:]))
+	case protocolICMP, protocolICMPv6:
+		if len(b) < 2 {
+			return
+		}
+		fields["icmp_type"] = uint64(b[0])
+		fields["icmp_code"] = uint64(b[1])
+	}
+}
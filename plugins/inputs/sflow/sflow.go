@@ -0,0 +1,144 @@
+package sflow
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+type setReadBufferer interface {
+	SetReadBuffer(bytes int) error
+}
+
+type SFlow struct {
+	ServiceAddress string        `toml:"service_address"`
+	ReadBufferSize internal.Size `toml:"read_buffer_size"`
+
+	conn net.PacketConn
+	wg   sync.WaitGroup
+}
+
+const sampleConfig = `
+  ## URL to listen on
+  # service_address = "udp://:6343"
+  # service_address = "udp4://:6343"
+  # service_address = "udp6://:6343"
+
+  ## Maximum socket buffer size (in bytes when no unit specified).
+  ## Once the buffer fills up, datagrams will start dropping.
+  ## Defaults to the OS default.
+  # read_buffer_size = "64KiB"
+`
+
+func (s *SFlow) Description() string {
+	return "sFlow v5 collector"
+}
+
+func (s *SFlow) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SFlow) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (s *SFlow) Start(acc telegraf.Accumulator) error {
+	spl := strings.SplitN(s.ServiceAddress, "://", 2)
+	if len(spl) != 2 {
+		return fmt.Errorf("invalid service address: %s", s.ServiceAddress)
+	}
+
+	protocol := spl[0]
+	switch protocol {
+	case "udp", "udp4", "udp6":
+	default:
+		return fmt.Errorf("unknown protocol '%s' in '%s'", protocol, s.ServiceAddress)
+	}
+
+	conn, err := udpListen(protocol, spl[1])
+	if err != nil {
+		return err
+	}
+
+	if s.ReadBufferSize.Size > 0 {
+		if srb, ok := conn.(setReadBufferer); ok {
+			srb.SetReadBuffer(int(s.ReadBufferSize.Size))
+		} else {
+			log.Printf("W! Unable to set read buffer on a %s socket", protocol)
+		}
+	}
+
+	log.Printf("I! [inputs.sflow] Listening on %s://%s", protocol, conn.LocalAddr())
+
+	s.conn = conn
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.listen(conn, acc)
+	}()
+	return nil
+}
+
+func (s *SFlow) listen(conn net.PacketConn, acc telegraf.Accumulator) {
+	buf := make([]byte, 64*1024) // 64kb - maximum size of IP packet
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), ": use of closed network connection") {
+				acc.AddError(err)
+			}
+			break
+		}
+
+		metrics, err := decode(buf[:n], time.Now())
+		for _, m := range metrics {
+			acc.AddMetric(m)
+		}
+		if err != nil {
+			acc.AddError(fmt.Errorf("unable to decode datagram from %s: %s", addr, err))
+		}
+	}
+}
+
+func (s *SFlow) Stop() {
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.wg.Wait()
+	s.conn = nil
+}
+
+func udpListen(network string, address string) (net.PacketConn, error) {
+	var ifi *net.Interface
+	if spl := strings.SplitN(address, "%", 2); len(spl) == 2 {
+		address = spl[0]
+		var err error
+		ifi, err = net.InterfaceByName(spl[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	addr, err := net.ResolveUDPAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if addr.IP.IsMulticast() {
+		return net.ListenMulticastUDP(network, ifi, addr)
+	}
+	return net.ListenUDP(network, addr)
+}
+
+func init() {
+	inputs.Add("sflow", func() telegraf.Input {
+		return &SFlow{
+			ServiceAddress: "udp://:6343",
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package sflow
+
+import (
+	"net"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFlowListener(t *testing.T) {
+	s := &SFlow{ServiceAddress: "udp://127.0.0.1:0"}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+	defer s.Stop()
+
+	client, err := net.Dial("udp", s.conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Write(testDatagram())
+	require.NoError(t, err)
+
+	acc.Wait(3)
+	require.True(t, acc.HasMeasurement("sflow"))
+	require.True(t, acc.HasMeasurement("sflow_interface"))
+	require.Equal(t, "192.0.2.1", acc.TagValue("sflow_interface", "agent_address"))
+	require.Equal(t, "3", acc.TagValue("sflow_interface", "if_index"))
+}
+
+func TestSFlowDecodeError(t *testing.T) {
+	s := &SFlow{ServiceAddress: "udp://127.0.0.1:0"}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, s.Start(acc))
+	defer s.Stop()
+
+	client, err := net.Dial("udp", s.conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Write([]byte{0, 0, 0, 4})
+	require.NoError(t, err)
+
+	acc.WaitError(1)
+	require.Contains(t, acc.FirstError().Error(), "unsupported version 4")
+}
+
+func TestSFlowInvalidAddress(t *testing.T) {
+	s := &SFlow{ServiceAddress: "tcp://127.0.0.1:0"}
+	require.Error(t, s.Start(&testutil.Accumulator{}))
+}
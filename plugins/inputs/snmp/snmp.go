@@ -15,6 +15,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/discovery"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/wlog"
 	"github.com/soniah/gosnmp"
@@ -147,6 +148,11 @@ type Snmp struct {
 
 	connectionCache []snmpConnection
 	initialized     bool
+
+	// targets found by discovery plugins and their connections
+	targets     discovery.Targets
+	targetLock  sync.Mutex
+	targetCache map[string]snmpConnection
 }
 
 func (s *Snmp) init() error {
@@ -376,31 +382,81 @@ func (s *Snmp) Gather(acc telegraf.Accumulator) error {
 				acc.AddError(Errorf(err, "agent %s", agent))
 				return
 			}
-
-			// First is the top-level fields. We treat the fields as table prefixes with an empty index.
-			t := Table{
-				Name:   s.Name,
-				Fields: s.Fields,
-			}
-			topTags := map[string]string{}
-			if err := s.gatherTable(acc, gs, t, topTags, false); err != nil {
-				acc.AddError(Errorf(err, "agent %s", agent))
-			}
-
-			// Now is the real tables.
-			for _, t := range s.Tables {
-				if err := s.gatherTable(acc, gs, t, topTags, true); err != nil {
-					acc.AddError(Errorf(err, "agent %s: gathering table %s", agent, t.Name))
-				}
-			}
+			s.gatherAgent(acc, gs, agent, nil)
 		}(i, agent)
 	}
+	for _, target := range s.listTargets() {
+		wg.Add(1)
+		go func(target telegraf.Target) {
+			defer wg.Done()
+			gs, err := s.getTargetConnection(target.Address)
+			if err != nil {
+				acc.AddError(Errorf(err, "agent %s", target.Address))
+				return
+			}
+			s.gatherAgent(acc, gs, target.Address, target.Tags)
+		}(target)
+	}
 	wg.Wait()
 
 	return nil
 }
 
-func (s *Snmp) gatherTable(acc telegraf.Accumulator, gs snmpConnection, t Table, topTags map[string]string, walk bool) error {
+// gatherAgent gathers the fields and tables of an agent, adding the extra
+// tags to all metrics.
+func (s *Snmp) gatherAgent(acc telegraf.Accumulator, gs snmpConnection, agent string, extraTags map[string]string) {
+	// First is the top-level fields. We treat the fields as table prefixes with an empty index.
+	t := Table{
+		Name:   s.Name,
+		Fields: s.Fields,
+	}
+	topTags := map[string]string{}
+	if err := s.gatherTable(acc, gs, t, topTags, extraTags, false); err != nil {
+		acc.AddError(Errorf(err, "agent %s", agent))
+	}
+
+	// Now is the real tables.
+	for _, t := range s.Tables {
+		if err := s.gatherTable(acc, gs, t, topTags, extraTags, true); err != nil {
+			acc.AddError(Errorf(err, "agent %s: gathering table %s", agent, t.Name))
+		}
+	}
+}
+
+// AddTarget adds an agent found by a discovery plugin, the address is in the
+// same format as the agents option.
+func (s *Snmp) AddTarget(target telegraf.Target) {
+	s.targets.Add(target)
+}
+
+// RemoveTarget removes an agent found by a discovery plugin.
+func (s *Snmp) RemoveTarget(target telegraf.Target) {
+	s.targets.Remove(target)
+}
+
+// listTargets returns the discovered agents and closes the connections
+// cached for removed agents.
+func (s *Snmp) listTargets() []telegraf.Target {
+	targets := s.targets.List()
+
+	s.targetLock.Lock()
+	defer s.targetLock.Unlock()
+	current := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		current[target.Address] = true
+	}
+	for address, gs := range s.targetCache {
+		if !current[address] {
+			if w, ok := gs.(gosnmpWrapper); ok && w.Conn != nil {
+				w.Conn.Close()
+			}
+			delete(s.targetCache, address)
+		}
+	}
+	return targets
+}
+
+func (s *Snmp) gatherTable(acc telegraf.Accumulator, gs snmpConnection, t Table, topTags map[string]string, extraTags map[string]string, walk bool) error {
 	rt, err := t.Build(gs, walk)
 	if err != nil {
 		return err
@@ -423,6 +479,9 @@ func (s *Snmp) gatherTable(acc telegraf.Accumulator, gs snmpConnection, t Table,
 		if _, ok := tr.Tags["agent_host"]; !ok {
 			tr.Tags["agent_host"] = gs.Host()
 		}
+		for k, v := range extraTags {
+			tr.Tags[k] = v
+		}
 		acc.AddFields(rt.Name, tr.Fields, tr.Tags, rt.Time)
 	}
 
@@ -618,15 +677,45 @@ func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
 		return gs, nil
 	}
 
-	agent := s.Agents[idx]
-
 	gs := gosnmpWrapper{&gosnmp.GoSNMP{}}
 	s.connectionCache[idx] = gs
 
+	if err := s.connect(gs, s.Agents[idx]); err != nil {
+		return nil, err
+	}
+	return gs, nil
+}
+
+// getTargetConnection returns the connection to a discovered agent, cached
+// using the address as the key.
+func (s *Snmp) getTargetConnection(agent string) (snmpConnection, error) {
+	s.targetLock.Lock()
+	if s.targetCache == nil {
+		s.targetCache = make(map[string]snmpConnection)
+	}
+	if gs, ok := s.targetCache[agent]; ok {
+		s.targetLock.Unlock()
+		return gs, nil
+	}
+	s.targetLock.Unlock()
+
+	gs := gosnmpWrapper{&gosnmp.GoSNMP{}}
+	if err := s.connect(gs, agent); err != nil {
+		return nil, err
+	}
+
+	s.targetLock.Lock()
+	defer s.targetLock.Unlock()
+	s.targetCache[agent] = gs
+	return gs, nil
+}
+
+// connect configures the connection to the agent and connects.
+func (s *Snmp) connect(gs gosnmpWrapper, agent string) error {
 	host, portStr, err := net.SplitHostPort(agent)
 	if err != nil {
 		if err, ok := err.(*net.AddrError); !ok || err.Err != "missing port in address" {
-			return nil, Errorf(err, "parsing host")
+			return Errorf(err, "parsing host")
 		}
 		host = agent
 		portStr = "161"
@@ -635,7 +724,7 @@ func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
 
 	port, err := strconv.ParseUint(portStr, 10, 16)
 	if err != nil {
-		return nil, Errorf(err, "parsing port")
+		return Errorf(err, "parsing port")
 	}
 	gs.Port = uint16(port)
 
@@ -651,7 +740,7 @@ func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
 	case 1:
 		gs.Version = gosnmp.Version1
 	default:
-		return nil, fmt.Errorf("invalid version")
+		return fmt.Errorf("invalid version")
 	}
 
 	if s.Version < 3 {
@@ -679,7 +768,7 @@ func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
 		case "authpriv":
 			gs.MsgFlags = gosnmp.AuthPriv
 		default:
-			return nil, fmt.Errorf("invalid secLevel")
+			return fmt.Errorf("invalid secLevel")
 		}
 
 		sp.UserName = s.SecName
@@ -692,7 +781,7 @@ func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
 		case "":
 			sp.AuthenticationProtocol = gosnmp.NoAuth
 		default:
-			return nil, fmt.Errorf("invalid authProtocol")
+			return fmt.Errorf("invalid authProtocol")
 		}
 
 		sp.AuthenticationPassphrase = s.AuthPassword
@@ -705,7 +794,7 @@ func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
 		case "":
 			sp.PrivacyProtocol = gosnmp.NoPriv
 		default:
-			return nil, fmt.Errorf("invalid privProtocol")
+			return fmt.Errorf("invalid privProtocol")
 		}
 
 		sp.PrivacyPassphrase = s.PrivPassword
@@ -718,10 +807,10 @@ func (s *Snmp) getConnection(idx int) (snmpConnection, error) {
 	}
 
 	if err := gs.Connect(); err != nil {
-		return nil, Errorf(err, "setting up connection")
+		return Errorf(err, "setting up connection")
 	}
 
-	return gs, nil
+	return nil
 }
 
 // fieldConvert converts from any type according to the conv specification
@@ -0,0 +1,225 @@
+package sql
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/influxdata/toml"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+const schema = `
+CREATE TABLE stats(name TEXT, host TEXT, value INTEGER, ratio REAL, enabled TEXT, updated INTEGER, note TEXT);
+INSERT INTO stats VALUES('queue', 'a', 12, 0.5, 'true', 1565000000, NULL);
+INSERT INTO stats VALUES('jobs', NULL, 3, NULL, 'false', 1565000060, 'slow');
+CREATE TABLE events(at DATETIME, count INTEGER);
+INSERT INTO events VALUES('2019-08-05 10:00:00', 7);
+`
+
+func newSQLite(t *testing.T, queries ...*Query) (*SQL, func()) {
+	dir, err := ioutil.TempDir("", "telegraf-sql")
+	require.NoError(t, err)
+
+	s := &SQL{
+		Driver:             "sqlite3",
+		DataSourceName:     filepath.Join(dir, "stats.db"),
+		Timeout:            internal.Duration{Duration: 5 * time.Second},
+		MaxIdleConnections: 2,
+		Queries:            queries,
+	}
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Start(nil))
+
+	_, err = s.db.Exec(schema)
+	require.NoError(t, err)
+
+	return s, func() {
+		s.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGather(t *testing.T) {
+	s, cleanup := newSQLite(t, &Query{
+		Query:       "SELECT name, host, value, ratio, enabled, updated, note FROM stats ORDER BY updated",
+		Measurement: "stats",
+		TagColumns:  []string{"name", "host"},
+		TimeColumn:  "updated",
+		FieldTypes:  map[string]string{"enabled": "boolean", "value": "float"},
+	})
+	defer cleanup()
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"stats",
+			map[string]string{"name": "queue", "host": "a"},
+			map[string]interface{}{"value": 12.0, "ratio": 0.5, "enabled": true},
+			time.Unix(1565000000, 0),
+		),
+		testutil.MustMetric(
+			"stats",
+			map[string]string{"name": "jobs"},
+			map[string]interface{}{"value": 3.0, "enabled": false, "note": "slow"},
+			time.Unix(1565000060, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestGatherFieldColumns(t *testing.T) {
+	s, cleanup := newSQLite(t,
+		&Query{
+			Query:        "SELECT name, value, ratio FROM stats WHERE name = 'queue'",
+			TagColumns:   []string{"name"},
+			FieldColumns: []string{"value"},
+		},
+		&Query{
+			Query:       "SELECT at, count FROM events",
+			Measurement: "events",
+			TimeColumn:  "at",
+			FieldTypes:  map[string]string{"count": "string"},
+		},
+	)
+	defer cleanup()
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 2)
+
+	acc.AssertContainsTaggedFields(t, "sql",
+		map[string]interface{}{"value": int64(12)},
+		map[string]string{"name": "queue"})
+
+	m, ok := acc.Get("events")
+	require.True(t, ok)
+	require.Equal(t, map[string]interface{}{"count": "7"}, m.Fields)
+	require.Equal(t, time.Date(2019, 8, 5, 10, 0, 0, 0, time.UTC), m.Time.UTC())
+}
+
+func TestGatherInterval(t *testing.T) {
+	s, cleanup := newSQLite(t,
+		&Query{Query: "SELECT count(*) AS n FROM stats", Measurement: "every"},
+		&Query{Query: "SELECT count(*) AS n FROM events", Measurement: "hourly", Interval: internal.Duration{Duration: time.Hour}},
+	)
+	defer cleanup()
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.NoError(t, s.Gather(&acc))
+
+	var every, hourly int
+	for _, m := range acc.Metrics {
+		switch m.Measurement {
+		case "every":
+			every++
+		case "hourly":
+			hourly++
+		}
+	}
+	require.Equal(t, 2, every)
+	require.Equal(t, 1, hourly)
+}
+
+func TestGatherErrors(t *testing.T) {
+	s, cleanup := newSQLite(t,
+		&Query{Query: "SELECT * FROM missing"},
+		&Query{Query: "SELECT name, value FROM stats", TagColumns: []string{"host"}},
+		&Query{Query: "SELECT name, value FROM stats", FieldTypes: map[string]string{"name": "integer"}},
+		&Query{Query: "SELECT value FROM stats", Measurement: "ok"},
+	)
+	defer cleanup()
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Len(t, acc.Errors, 3)
+	// the other queries still run
+	require.True(t, acc.HasMeasurement("ok"))
+}
+
+func TestConvert(t *testing.T) {
+	ts := time.Date(2019, 8, 5, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    interface{}
+		typ      string
+		expected interface{}
+	}{
+		{int64(-1), "", int64(-1)},
+		{int32(2), "", int64(2)},
+		{float32(0.5), "", 0.5},
+		{ts, "", ts.UnixNano()},
+		{"42", "integer", int64(42)},
+		{2.9, "integer", int64(2)},
+		{true, "integer", int64(1)},
+		{"42", "unsigned", uint64(42)},
+		{int64(3), "float", 3.0},
+		{"1.5", "float", 1.5},
+		{int64(0), "boolean", false},
+		{"true", "boolean", true},
+		{int64(7), "string", "7"},
+		{ts, "string", "2019-08-05T10:00:00Z"},
+	}
+	for _, tt := range tests {
+		v, err := convert(tt.value, tt.typ)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, v)
+	}
+
+	_, err := convert("abc", "integer")
+	require.Error(t, err)
+	_, err = convert(-1.0, "unsigned")
+	require.Error(t, err)
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  *SQL
+	}{
+		{"no driver", &SQL{Queries: []*Query{{Query: "SELECT 1"}}}},
+		{"no queries", &SQL{Driver: "pgx"}},
+		{"empty query", &SQL{Driver: "pgx", Queries: []*Query{{}}}},
+		{"bad type", &SQL{Driver: "pgx", Queries: []*Query{{Query: "SELECT 1", FieldTypes: map[string]string{"a": "int"}}}}},
+		{"time tag", &SQL{Driver: "pgx", Queries: []*Query{{Query: "SELECT 1", TimeColumn: "t", TagColumns: []string{"t"}}}}},
+		{"tag field", &SQL{Driver: "pgx", Queries: []*Query{{Query: "SELECT 1", TagColumns: []string{"a"}, FieldColumns: []string{"a"}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.sql.Init())
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	conf := []byte(`
+driver = "mysql"
+data_source_name = "telegraf:secret@tcp(localhost)/app"
+max_open_connections = 4
+
+[[query]]
+  query = "SELECT name, value FROM stats"
+  interval = "1m"
+  tag_columns = ["name"]
+  [query.field_types]
+    value = "float"
+`)
+	s := &SQL{}
+	require.NoError(t, toml.Unmarshal(conf, s))
+	require.NoError(t, s.Init())
+	require.Len(t, s.Queries, 1)
+	require.Equal(t, time.Minute, s.Queries[0].Interval.Duration)
+	require.Equal(t, map[string]string{"value": "float"}, s.Queries[0].FieldTypes)
+	require.Equal(t, "sql", s.Queries[0].Measurement)
+	require.Equal(t, 4, s.MaxOpenConnections)
+}
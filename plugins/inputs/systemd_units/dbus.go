@@ -0,0 +1,183 @@
+// +build linux
+
+package systemd_units
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	systemdBusName    = "org.freedesktop.systemd1"
+	systemdObjectPath = "/org/freedesktop/systemd1"
+
+	// systemdSocket is the private socket of systemd, only accessible to
+	// root.
+	systemdSocket = "unix:path=/run/systemd/private"
+)
+
+// unitStatus is an entry of the reply of ListUnitsByPatterns.
+type unitStatus struct {
+	Name        string
+	Description string
+	LoadState   string
+	ActiveState string
+	SubState    string
+	Followed    string
+	Path        dbus.ObjectPath
+	JobID       uint32
+	JobType     string
+	JobPath     dbus.ObjectPath
+}
+
+// dbusClient is a client connecting to systemd over D-Bus.
+type dbusClient struct {
+	conn  *dbus.Conn
+	paths map[string]dbus.ObjectPath
+}
+
+func (c *dbusClient) Connect(ctx context.Context) error {
+	conn, err := systemBus()
+	if err != nil && os.Geteuid() == 0 {
+		conn, err = privateSocket()
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to systemd failed: %s", err)
+	}
+	c.conn = conn
+	return nil
+}
+
+// systemBus returns a new connection to the system bus.
+func systemBus() (*dbus.Conn, error) {
+	conn, err := dbus.SystemBusPrivate()
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.Hello(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// privateSocket returns a new connection to the private socket of systemd,
+// which is available without a running system bus.
+func privateSocket() (*dbus.Conn, error) {
+	conn, err := dbus.Dial(systemdSocket)
+	if err != nil {
+		return nil, err
+	}
+	auth := []dbus.Auth{dbus.AuthExternal(strconv.Itoa(os.Getuid()))}
+	if err := conn.Auth(auth); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (c *dbusClient) Close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+func (c *dbusClient) ListUnits(ctx context.Context, patterns []string) ([]*unit, error) {
+	if patterns == nil {
+		patterns = []string{}
+	}
+	manager := c.conn.Object(systemdBusName, systemdObjectPath)
+	call, err := callContext(ctx, manager, "org.freedesktop.systemd1.Manager.ListUnitsByPatterns", []string{}, patterns)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []unitStatus
+	if err := call.Store(&statuses); err != nil {
+		return nil, err
+	}
+
+	c.paths = make(map[string]dbus.ObjectPath, len(statuses))
+	units := make([]*unit, 0, len(statuses))
+	for _, status := range statuses {
+		c.paths[status.Name] = status.Path
+		units = append(units, &unit{
+			name:   status.Name,
+			load:   status.LoadState,
+			active: status.ActiveState,
+			sub:    status.SubState,
+		})
+	}
+	return units, nil
+}
+
+func (c *dbusClient) ReadDetails(ctx context.Context, units []*unit) error {
+	for _, u := range units {
+		obj := c.conn.Object(systemdBusName, c.paths[u.name])
+		call, err := callContext(ctx, obj, "org.freedesktop.DBus.Properties.GetAll", "org.freedesktop.systemd1.Unit")
+		if err != nil {
+			return fmt.Errorf("unit '%s': %s", u.name, err)
+		}
+		var props map[string]dbus.Variant
+		if err := call.Store(&props); err != nil {
+			return fmt.Errorf("unit '%s': %s", u.name, err)
+		}
+		u.stateChange = usecTime(props["StateChangeTimestamp"].Value())
+		u.activeEnter = usecTime(props["ActiveEnterTimestamp"].Value())
+		u.activeExit = usecTime(props["ActiveExitTimestamp"].Value())
+		u.inactiveEnter = usecTime(props["InactiveEnterTimestamp"].Value())
+		u.inactiveExit = usecTime(props["InactiveExitTimestamp"].Value())
+
+		if unitType(u.name) != "service" {
+			continue
+		}
+		// NRestarts is missing before systemd 235
+		call, err = callContext(ctx, obj, "org.freedesktop.DBus.Properties.Get", "org.freedesktop.systemd1.Service", "NRestarts")
+		if err != nil {
+			if err == ctx.Err() {
+				return err
+			}
+			continue
+		}
+		var prop dbus.Variant
+		if err := call.Store(&prop); err != nil {
+			continue
+		}
+		if n, ok := prop.Value().(uint32); ok {
+			restarts := uint64(n)
+			u.restarts = &restarts
+		}
+	}
+	return nil
+}
+
+// callContext calls the method of the object and waits for the reply until
+// the context is done.
+func callContext(ctx context.Context, obj dbus.BusObject, method string, args ...interface{}) (*dbus.Call, error) {
+	call := obj.Go(method, 0, make(chan *dbus.Call, 1), args...)
+	select {
+	case <-call.Done:
+		return call, call.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// usecTime converts a timestamp property in microseconds since the epoch,
+// unset timestamps are zero.
+func usecTime(v interface{}) time.Time {
+	usec, ok := v.(uint64)
+	if !ok || usec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(usec)*int64(time.Microsecond))
+}
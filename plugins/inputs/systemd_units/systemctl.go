@@ -0,0 +1,164 @@
+// +build linux
+
+package systemd_units
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// runner runs systemctl with the arguments and returns its output.
+type runner func(ctx context.Context, args ...string) ([]byte, error)
+
+func runSystemctl(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "systemctl", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("systemctl %s failed: %s: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// properties are the unit properties read by "systemctl show".  The
+// timestamps are read on the monotonic clock, which all versions of systemctl
+// print as microseconds since boot, unlike the wall clock timestamps printed
+// in the local format.
+var properties = []string{
+	"Id",
+	"NRestarts",
+	"StateChangeTimestampMonotonic",
+	"ActiveEnterTimestampMonotonic",
+	"ActiveExitTimestampMonotonic",
+	"InactiveEnterTimestampMonotonic",
+	"InactiveExitTimestampMonotonic",
+}
+
+// systemctl is a client running the systemctl command.
+type systemctl struct {
+	run      runner
+	bootTime func() (time.Time, error)
+}
+
+// monotonicBootTime returns the wall clock time at which the monotonic
+// clock started.
+func monotonicBootTime() (time.Time, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-time.Duration(ts.Nano())), nil
+}
+
+func (c *systemctl) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (c *systemctl) Close() {
+}
+
+func (c *systemctl) ListUnits(ctx context.Context, patterns []string) ([]*unit, error) {
+	args := append([]string{"list-units", "--all", "--plain", "--no-legend", "--no-pager", "--"}, patterns...)
+	out, err := c.run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var units []*unit
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		// <name> <load> <active> <sub> <description>, failed units are
+		// marked by a leading bullet in some versions
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && (fields[0] == "●" || fields[0] == "*") {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("unexpected unit line '%s'", scanner.Text())
+		}
+		units = append(units, &unit{
+			name:   fields[0],
+			load:   fields[1],
+			active: fields[2],
+			sub:    fields[3],
+		})
+	}
+	return units, scanner.Err()
+}
+
+func (c *systemctl) ReadDetails(ctx context.Context, units []*unit) error {
+	args := []string{"show", "--property=" + strings.Join(properties, ","), "--"}
+	byName := make(map[string]*unit, len(units))
+	for _, u := range units {
+		args = append(args, u.name)
+		byName[u.name] = u
+	}
+	out, err := c.run(ctx, args...)
+	if err != nil {
+		return err
+	}
+	boot, err := c.bootTime()
+	if err != nil {
+		return fmt.Errorf("reading the boot time failed: %s", err)
+	}
+
+	// the properties of each unit are separated by an empty line
+	for _, block := range bytes.Split(out, []byte("\n\n")) {
+		props := make(map[string]string)
+		for _, line := range strings.Split(string(block), "\n") {
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) == 2 {
+				props[kv[0]] = kv[1]
+			}
+		}
+		u, ok := byName[props["Id"]]
+		if !ok {
+			continue
+		}
+
+		if v, ok := props["NRestarts"]; ok && v != "" {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("unit '%s': invalid NRestarts '%s'", u.name, v)
+			}
+			u.restarts = &n
+		}
+		for name, t := range map[string]*time.Time{
+			"StateChangeTimestampMonotonic":   &u.stateChange,
+			"ActiveEnterTimestampMonotonic":   &u.activeEnter,
+			"ActiveExitTimestampMonotonic":    &u.activeExit,
+			"InactiveEnterTimestampMonotonic": &u.inactiveEnter,
+			"InactiveExitTimestampMonotonic":  &u.inactiveExit,
+		} {
+			if *t, err = parseTimestamp(props[name], boot); err != nil {
+				return fmt.Errorf("unit '%s': invalid %s: %s", u.name, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseTimestamp parses a monotonic timestamp in microseconds since the boot
+// time.  Unset timestamps are empty or "0" and returned as zero time.
+func parseTimestamp(s string, boot time.Time) (time.Time, error) {
+	if s == "" || s == "0" {
+		return time.Time{}, nil
+	}
+	usec, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected timestamp '%s'", s)
+	}
+	return boot.Add(time.Duration(usec) * time.Microsecond), nil
+}
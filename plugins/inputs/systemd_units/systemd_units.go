@@ -0,0 +1,290 @@
+// +build linux
+
+package systemd_units
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+var sampleConfig = `
+  ## Backend used to query systemd, either "systemctl" to run the systemctl
+  ## command or "dbus" to connect to systemd over D-Bus.
+  # backend = "systemctl"
+
+  ## Types of units to gather, all types if empty.
+  ## Valid types: automount, device, mount, path, scope, service, slice,
+  ## socket, swap, target, timer
+  # unit_types = ["service"]
+
+  ## Patterns of unit names to gather, all units if empty.  The patterns are
+  ## shell-style globs as used by "systemctl list-units".
+  # patterns = ["sshd*", "telegraf.service"]
+
+  ## Timeout for querying systemd.
+  # timeout = "5s"
+`
+
+// unitTypes are the valid types of units.
+var unitTypes = map[string]bool{
+	"automount": true,
+	"device":    true,
+	"mount":     true,
+	"path":      true,
+	"scope":     true,
+	"service":   true,
+	"slice":     true,
+	"socket":    true,
+	"swap":      true,
+	"target":    true,
+	"timer":     true,
+}
+
+// loadStates, activeStates and subStates map the states of units to the
+// codes of the load_code, active_code and sub_code fields.
+var loadStates = map[string]int{
+	"loaded":      0,
+	"stub":        1,
+	"not-found":   2,
+	"bad-setting": 3,
+	"error":       4,
+	"merged":      5,
+	"masked":      6,
+}
+
+var activeStates = map[string]int{
+	"active":       0,
+	"reloading":    1,
+	"inactive":     2,
+	"failed":       3,
+	"activating":   4,
+	"deactivating": 5,
+}
+
+// The sub states are grouped by the unit types they were introduced by,
+// states shared by several types keep their first code.
+var subStates = map[string]int{
+	// service
+	"running":       0x0000,
+	"dead":          0x0001,
+	"start-pre":     0x0002,
+	"start":         0x0003,
+	"exited":        0x0004,
+	"reload":        0x0005,
+	"stop":          0x0006,
+	"stop-watchdog": 0x0007,
+	"stop-sigterm":  0x0008,
+	"stop-sigkill":  0x0009,
+	"stop-post":     0x000a,
+	"final-sigterm": 0x000b,
+	"failed":        0x000c,
+	"auto-restart":  0x000d,
+	"final-sigkill": 0x000e,
+	"start-post":    0x000f,
+
+	// automount
+	"waiting": 0x0010,
+
+	// device
+	"tentative": 0x0020,
+	"plugged":   0x0021,
+
+	// mount
+	"mounting":           0x0030,
+	"mounting-done":      0x0031,
+	"mounted":            0x0032,
+	"remounting":         0x0033,
+	"unmounting":         0x0034,
+	"remounting-sigterm": 0x0035,
+	"remounting-sigkill": 0x0036,
+	"unmounting-sigterm": 0x0037,
+	"unmounting-sigkill": 0x0038,
+
+	// scope
+	"abandoned": 0x0050,
+
+	// socket
+	"start-chown":      0x0070,
+	"listening":        0x0071,
+	"stop-pre":         0x0072,
+	"stop-pre-sigterm": 0x0073,
+	"stop-pre-sigkill": 0x0074,
+
+	// swap
+	"activating":           0x0080,
+	"activating-done":      0x0081,
+	"deactivating":         0x0082,
+	"deactivating-sigterm": 0x0083,
+	"deactivating-sigkill": 0x0084,
+
+	// timer
+	"elapsed": 0x00a0,
+}
+
+// unit is the state of a systemd unit.
+type unit struct {
+	name   string
+	load   string
+	active string
+	sub    string
+
+	// restarts is the number of automatic restarts of a service, nil for
+	// other units or if systemd does not count them.
+	restarts *uint64
+
+	// times of the last state changes, zero if the unit was never in the
+	// state.
+	stateChange   time.Time
+	activeEnter   time.Time
+	activeExit    time.Time
+	inactiveEnter time.Time
+	inactiveExit  time.Time
+}
+
+// client queries the units of systemd.
+type client interface {
+	Connect(ctx context.Context) error
+	Close()
+	// ListUnits returns the states of the loaded units matching the
+	// patterns, of all loaded units if there are no patterns.
+	ListUnits(ctx context.Context, patterns []string) ([]*unit, error)
+	// ReadDetails reads the restarts and state change times of the units.
+	ReadDetails(ctx context.Context, units []*unit) error
+}
+
+type SystemdUnits struct {
+	Backend   string            `toml:"backend"`
+	UnitTypes []string          `toml:"unit_types"`
+	Patterns  []string          `toml:"patterns"`
+	Timeout   internal.Duration `toml:"timeout"`
+
+	client client
+	types  map[string]bool
+}
+
+func (s *SystemdUnits) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SystemdUnits) Description() string {
+	return "Gather the states of systemd units"
+}
+
+func (s *SystemdUnits) Init() error {
+	s.types = make(map[string]bool, len(s.UnitTypes))
+	for _, typ := range s.UnitTypes {
+		if !unitTypes[typ] {
+			return fmt.Errorf("invalid unit type '%s'", typ)
+		}
+		s.types[typ] = true
+	}
+
+	if s.client != nil {
+		return nil
+	}
+	switch s.Backend {
+	case "", "systemctl":
+		s.client = &systemctl{run: runSystemctl, bootTime: monotonicBootTime}
+	case "dbus":
+		s.client = &dbusClient{}
+	default:
+		return fmt.Errorf("invalid backend '%s'", s.Backend)
+	}
+	return nil
+}
+
+func (s *SystemdUnits) Gather(acc telegraf.Accumulator) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout.Duration)
+	defer cancel()
+
+	if err := s.client.Connect(ctx); err != nil {
+		return err
+	}
+	defer s.client.Close()
+
+	all, err := s.client.ListUnits(ctx, s.Patterns)
+	if err != nil {
+		return err
+	}
+
+	units := make([]*unit, 0, len(all))
+	for _, u := range all {
+		if len(s.types) > 0 && !s.types[unitType(u.name)] {
+			continue
+		}
+		units = append(units, u)
+	}
+	if len(units) == 0 {
+		return nil
+	}
+	if err := s.client.ReadDetails(ctx, units); err != nil {
+		return err
+	}
+
+	for _, u := range units {
+		tags := map[string]string{
+			"name":   u.name,
+			"load":   u.load,
+			"active": u.active,
+			"sub":    u.sub,
+		}
+		acc.AddFields("systemd_units", u.fields(), tags)
+	}
+	return nil
+}
+
+// fields returns the fields of the unit.  The codes of states unknown to
+// the plugin, like the ones added by newer versions of systemd, are omitted.
+func (u *unit) fields() map[string]interface{} {
+	fields := make(map[string]interface{})
+	if load, ok := loadStates[u.load]; ok {
+		fields["load_code"] = load
+	}
+	if active, ok := activeStates[u.active]; ok {
+		fields["active_code"] = active
+	}
+	if sub, ok := subStates[u.sub]; ok {
+		fields["sub_code"] = sub
+	}
+	if u.restarts != nil {
+		fields["restarts"] = *u.restarts
+	}
+	addTimestamp(fields, "state_change_timestamp", u.stateChange)
+	addTimestamp(fields, "active_enter_timestamp", u.activeEnter)
+	addTimestamp(fields, "active_exit_timestamp", u.activeExit)
+	addTimestamp(fields, "inactive_enter_timestamp", u.inactiveEnter)
+	addTimestamp(fields, "inactive_exit_timestamp", u.inactiveExit)
+	return fields
+}
+
+// addTimestamp adds the time as seconds since the epoch if it is set.
+func addTimestamp(fields map[string]interface{}, name string, t time.Time) {
+	if !t.IsZero() {
+		fields[name] = t.Unix()
+	}
+}
+
+// unitType returns the type of a unit by the suffix of its name.
+func unitType(name string) string {
+	i := strings.LastIndexByte(name, '.')
+	if i < 0 {
+		return ""
+	}
+	return name[i+1:]
+}
+
+func init() {
+	inputs.Add("systemd_units", func() telegraf.Input {
+		return &SystemdUnits{
+			Backend:   "systemctl",
+			UnitTypes: []string{"service"},
+			Timeout:   internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
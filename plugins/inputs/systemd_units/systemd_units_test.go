@@ -0,0 +1,325 @@
+// +build linux
+
+package systemd_units
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/influxdata/toml"
+	"github.com/stretchr/testify/require"
+)
+
+// bootTime is the boot time of the monotonic timestamps in showOutput.
+var bootTime = time.Unix(1564990000, 0)
+
+const listUnitsOutput = `proc-sys-fs-binfmt_misc.automount loaded active   waiting   Arbitrary Executable File Formats File System Automount Point
+dev-sda1.device                   loaded active   plugged   QEMU_HARDDISK 1
+cron.service                      loaded active   running   Regular background program processing daemon
+● networkd-dispatcher.service     loaded failed   failed    Dispatcher daemon for systemd-networkd
+ntp.service                       not-found inactive dead   ntp.service
+telegraf.service                  loaded activating auto-restart The plugin-driven server agent for reporting metrics into InfluxDB
+sshd.socket                       loaded active   listening OpenBSD Secure Shell server socket
+`
+
+const showOutput = `Id=cron.service
+NRestarts=0
+StateChangeTimestampMonotonic=9200000000
+ActiveEnterTimestampMonotonic=9200000000
+ActiveExitTimestampMonotonic=0
+InactiveEnterTimestampMonotonic=0
+InactiveExitTimestampMonotonic=9199000000
+
+Id=networkd-dispatcher.service
+NRestarts=0
+StateChangeTimestampMonotonic=9205000000
+ActiveEnterTimestampMonotonic=0
+ActiveExitTimestampMonotonic=0
+InactiveEnterTimestampMonotonic=0
+InactiveExitTimestampMonotonic=9201000000
+
+Id=ntp.service
+NRestarts=0
+StateChangeTimestampMonotonic=0
+ActiveEnterTimestampMonotonic=0
+ActiveExitTimestampMonotonic=0
+InactiveEnterTimestampMonotonic=0
+InactiveExitTimestampMonotonic=0
+
+Id=telegraf.service
+NRestarts=12
+StateChangeTimestampMonotonic=90200000000
+ActiveEnterTimestampMonotonic=90190000000
+ActiveExitTimestampMonotonic=90199000000
+InactiveEnterTimestampMonotonic=90200000000
+InactiveExitTimestampMonotonic=90190000000
+
+Id=sshd.socket
+StateChangeTimestampMonotonic=9200000000
+ActiveEnterTimestampMonotonic=9200000000
+ActiveExitTimestampMonotonic=0
+InactiveEnterTimestampMonotonic=0
+InactiveExitTimestampMonotonic=9200000000
+`
+
+// fakeRunner returns the fixture output of the systemctl commands and
+// records their arguments.
+type fakeRunner struct {
+	args      [][]string
+	listUnits string
+	show      string
+	err       error
+}
+
+func (r *fakeRunner) run(ctx context.Context, args ...string) ([]byte, error) {
+	r.args = append(r.args, args)
+	if r.err != nil {
+		return nil, r.err
+	}
+	switch args[0] {
+	case "list-units":
+		return []byte(r.listUnits), nil
+	case "show":
+		return []byte(r.show), nil
+	}
+	return nil, errors.New("unexpected command")
+}
+
+func newTestSystemdUnits(r *fakeRunner, types ...string) *SystemdUnits {
+	return &SystemdUnits{
+		UnitTypes: types,
+		Timeout:   internal.Duration{Duration: time.Second},
+		client: &systemctl{
+			run:      r.run,
+			bootTime: func() (time.Time, error) { return bootTime, nil },
+		},
+	}
+}
+
+func TestGather(t *testing.T) {
+	r := &fakeRunner{listUnits: listUnitsOutput, show: showOutput}
+	s := newTestSystemdUnits(r, "service")
+	require.NoError(t, s.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Empty(t, acc.Errors)
+
+	// only the services are shown
+	require.Equal(t, []string{"show", "--property=Id,NRestarts,StateChangeTimestampMonotonic,ActiveEnterTimestampMonotonic,ActiveExitTimestampMonotonic,InactiveEnterTimestampMonotonic,InactiveExitTimestampMonotonic", "--",
+		"cron.service", "networkd-dispatcher.service", "ntp.service", "telegraf.service"}, r.args[1])
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"systemd_units",
+			map[string]string{"name": "cron.service", "load": "loaded", "active": "active", "sub": "running"},
+			map[string]interface{}{
+				"load_code":               0,
+				"active_code":             0,
+				"sub_code":                0,
+				"restarts":                uint64(0),
+				"state_change_timestamp":  int64(1564999200),
+				"active_enter_timestamp":  int64(1564999200),
+				"inactive_exit_timestamp": int64(1564999199),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"systemd_units",
+			map[string]string{"name": "networkd-dispatcher.service", "load": "loaded", "active": "failed", "sub": "failed"},
+			map[string]interface{}{
+				"load_code":               0,
+				"active_code":             3,
+				"sub_code":                0x0c,
+				"restarts":                uint64(0),
+				"state_change_timestamp":  int64(1564999205),
+				"inactive_exit_timestamp": int64(1564999201),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"systemd_units",
+			map[string]string{"name": "ntp.service", "load": "not-found", "active": "inactive", "sub": "dead"},
+			map[string]interface{}{
+				"load_code":   2,
+				"active_code": 2,
+				"sub_code":    1,
+				"restarts":    uint64(0),
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"systemd_units",
+			map[string]string{"name": "telegraf.service", "load": "loaded", "active": "activating", "sub": "auto-restart"},
+			map[string]interface{}{
+				"load_code":                0,
+				"active_code":              4,
+				"sub_code":                 0x0d,
+				"restarts":                 uint64(12),
+				"state_change_timestamp":   int64(1565080200),
+				"active_enter_timestamp":   int64(1565080190),
+				"active_exit_timestamp":    int64(1565080199),
+				"inactive_enter_timestamp": int64(1565080200),
+				"inactive_exit_timestamp":  int64(1565080190),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestGatherAllTypes(t *testing.T) {
+	r := &fakeRunner{listUnits: listUnitsOutput, show: showOutput}
+	s := newTestSystemdUnits(r)
+	s.Patterns = []string{"*.socket", "dev-*"}
+	require.NoError(t, s.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Equal(t, []string{"list-units", "--all", "--plain", "--no-legend", "--no-pager", "--", "*.socket", "dev-*"}, r.args[0])
+	require.Len(t, acc.Metrics, 7)
+
+	// the restarts of other units than services are not counted
+	acc.AssertContainsTaggedFields(t, "systemd_units",
+		map[string]interface{}{
+			"load_code":               0,
+			"active_code":             0,
+			"sub_code":                0x71,
+			"state_change_timestamp":  int64(1564999200),
+			"active_enter_timestamp":  int64(1564999200),
+			"inactive_exit_timestamp": int64(1564999200),
+		},
+		map[string]string{"name": "sshd.socket", "load": "loaded", "active": "active", "sub": "listening"})
+	acc.AssertContainsTaggedFields(t, "systemd_units",
+		map[string]interface{}{"load_code": 0, "active_code": 0, "sub_code": 0x21},
+		map[string]string{"name": "dev-sda1.device", "load": "loaded", "active": "active", "sub": "plugged"})
+}
+
+func TestGatherNoUnits(t *testing.T) {
+	r := &fakeRunner{}
+	s := newTestSystemdUnits(r, "timer")
+	require.NoError(t, s.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Empty(t, acc.Metrics)
+	// nothing to show
+	require.Len(t, r.args, 1)
+}
+
+func TestGatherErrors(t *testing.T) {
+	r := &fakeRunner{err: errors.New("systemctl list-units failed: exit status 1")}
+	s := newTestSystemdUnits(r, "service")
+	require.NoError(t, s.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, s.Gather(&acc))
+
+	r.err = nil
+	r.listUnits = "cron.service loaded\n"
+	require.Error(t, s.Gather(&acc))
+
+	r.listUnits = listUnitsOutput
+	r.show = "Id=cron.service\nNRestarts=many\n"
+	require.Error(t, s.Gather(&acc))
+
+	r.show = "Id=cron.service\nStateChangeTimestampMonotonic=yesterday\n"
+	require.Error(t, s.Gather(&acc))
+}
+
+func TestGatherUnknownStates(t *testing.T) {
+	r := &fakeRunner{
+		listUnits: strings.Replace(listUnitsOutput, "running", "reload-notify", 1),
+		show:      showOutput,
+	}
+	s := newTestSystemdUnits(r, "service")
+	require.NoError(t, s.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, s.Gather(&acc))
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 4)
+
+	// the state is tagged but has no code
+	expected := testutil.MustMetric(
+		"systemd_units",
+		map[string]string{"name": "cron.service", "load": "loaded", "active": "active", "sub": "reload-notify"},
+		map[string]interface{}{
+			"load_code":               0,
+			"active_code":             0,
+			"restarts":                uint64(0),
+			"state_change_timestamp":  int64(1564999200),
+			"active_enter_timestamp":  int64(1564999200),
+			"inactive_exit_timestamp": int64(1564999199),
+		},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, acc.GetTelegrafMetrics()[:1], testutil.IgnoreTime())
+}
+
+func TestParseTimestamp(t *testing.T) {
+	ts, err := parseTimestamp("9200000123", bootTime)
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2019, 8, 5, 10, 0, 0, 123000, time.UTC), ts.UTC())
+
+	for _, s := range []string{"", "0"} {
+		ts, err = parseTimestamp(s, bootTime)
+		require.NoError(t, err)
+		require.True(t, ts.IsZero())
+	}
+
+	for _, s := range []string{"Mon 2019-08-05 10:00:00 UTC", "@1564999200", "-1"} {
+		_, err = parseTimestamp(s, bootTime)
+		require.Error(t, err)
+	}
+}
+
+func TestMonotonicBootTime(t *testing.T) {
+	boot, err := monotonicBootTime()
+	require.NoError(t, err)
+	require.True(t, boot.Before(time.Now()))
+}
+
+func TestUsecTime(t *testing.T) {
+	require.Equal(t, time.Unix(1565000000, 123000), usecTime(uint64(1565000000000123)))
+	require.True(t, usecTime(uint64(0)).IsZero())
+	require.True(t, usecTime(nil).IsZero())
+}
+
+func TestInit(t *testing.T) {
+	s := &SystemdUnits{UnitTypes: []string{"services"}}
+	require.Error(t, s.Init())
+
+	s = &SystemdUnits{Backend: "journal"}
+	require.Error(t, s.Init())
+
+	s = &SystemdUnits{Backend: "dbus"}
+	require.NoError(t, s.Init())
+	require.IsType(t, &dbusClient{}, s.client)
+
+	s = &SystemdUnits{}
+	require.NoError(t, s.Init())
+	require.IsType(t, &systemctl{}, s.client)
+}
+
+func TestConfig(t *testing.T) {
+	conf := []byte(`
+backend = "dbus"
+unit_types = ["service", "socket"]
+patterns = ["ssh*"]
+timeout = "2s"
+`)
+	s := &SystemdUnits{}
+	require.NoError(t, toml.Unmarshal(conf, s))
+	require.NoError(t, s.Init())
+	require.Equal(t, []string{"service", "socket"}, s.UnitTypes)
+	require.Equal(t, []string{"ssh*"}, s.Patterns)
+	require.Equal(t, 2*time.Second, s.Timeout.Duration)
+}
@@ -0,0 +1,414 @@
+package websocket
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"golang.org/x/net/websocket"
+)
+
+const sampleConfig = `
+  ## Address to serve the WebSocket endpoint on.  Clients connect to the
+  ## path and send metrics in text or binary frames.
+  service_address = ":8080"
+  # path = "/telegraf"
+
+  ## Origins allowed to connect to the served endpoint, like
+  ## "https://example.org".  Browsers send the origin of the page opening the
+  ## connection; connections from any origin are accepted if empty.
+  # allowed_origins = []
+
+  ## URL of a WebSocket endpoint to connect to instead of serving one, using
+  ## the ws or wss scheme.  Metrics are read from the frames sent by the
+  ## server.  Set either url or service_address.
+  # url = "ws://example.org:8080/metrics"
+
+  ## Origin and additional HTTP headers sent in the opening handshake when
+  ## connecting to url.
+  # origin = ""
+  # [inputs.websocket.headers]
+  #   Authorization = "Bearer <token>"
+
+  ## Timeout for establishing the connection to url, including the opening
+  ## handshake.
+  # connect_timeout = "30s"
+
+  ## Minimum and maximum interval to wait before reconnecting to url after
+  ## the connection is lost; the interval doubles after each failed attempt.
+  # reconnect_interval = "1s"
+  # max_reconnect_interval = "1m"
+
+  ## Maximum size of a received frame.
+  # max_message_size = "1MB"
+
+  ## Close connections that send no frames within this duration, 0 disables
+  ## the timeout.
+  # read_timeout = "0s"
+
+  ## Optional TLS Config.  The certificate and key are used as server
+  ## certificate when serving and as client certificate when connecting to
+  ## url.  Allowed client CAs apply when serving, the CA and skipping
+  ## verification when connecting to url.
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # insecure_skip_verify = false
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+type WebSocket struct {
+	ServiceAddress       string            `toml:"service_address"`
+	Path                 string            `toml:"path"`
+	AllowedOrigins       []string          `toml:"allowed_origins"`
+	URL                  string            `toml:"url"`
+	Origin               string            `toml:"origin"`
+	Headers              map[string]string `toml:"headers"`
+	ConnectTimeout       internal.Duration `toml:"connect_timeout"`
+	ReconnectInterval    internal.Duration `toml:"reconnect_interval"`
+	MaxReconnectInterval internal.Duration `toml:"max_reconnect_interval"`
+	MaxMessageSize       internal.Size     `toml:"max_message_size"`
+	ReadTimeout          internal.Duration `toml:"read_timeout"`
+	TLSCA                string            `toml:"tls_ca"`
+	InsecureSkipVerify   bool              `toml:"insecure_skip_verify"`
+	tlsint.ServerConfig
+
+	parser parsers.Parser
+	acc    telegraf.Accumulator
+
+	listener net.Listener
+	server   *http.Server
+
+	mu    sync.Mutex
+	conns map[*websocket.Conn]struct{}
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (w *WebSocket) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *WebSocket) Description() string {
+	return "Read metrics from WebSocket frames, served or received from a server"
+}
+
+func (w *WebSocket) SetParser(parser parsers.Parser) {
+	w.parser = parser
+}
+
+func (w *WebSocket) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (w *WebSocket) Init() error {
+	if (w.URL == "") == (w.ServiceAddress == "") {
+		return fmt.Errorf("exactly one of url and service_address must be set")
+	}
+
+	if w.URL != "" {
+		u, err := url.Parse(w.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url: %v", err)
+		}
+		switch u.Scheme {
+		case "ws", "wss":
+		default:
+			return fmt.Errorf("unsupported url scheme %q, expected ws or wss", u.Scheme)
+		}
+
+		if w.Origin == "" {
+			origin := url.URL{Scheme: "http", Host: u.Host}
+			if u.Scheme == "wss" {
+				origin.Scheme = "https"
+			}
+			w.Origin = origin.String()
+		}
+	}
+
+	if w.ReconnectInterval.Duration <= 0 {
+		return fmt.Errorf("reconnect_interval must be positive")
+	}
+	if w.MaxReconnectInterval.Duration < w.ReconnectInterval.Duration {
+		w.MaxReconnectInterval.Duration = w.ReconnectInterval.Duration
+	}
+	return nil
+}
+
+func (w *WebSocket) Start(acc telegraf.Accumulator) error {
+	w.acc = acc
+	w.conns = make(map[*websocket.Conn]struct{})
+
+	if w.URL != "" {
+		return w.startClient()
+	}
+	return w.startServer()
+}
+
+func (w *WebSocket) startServer() error {
+	tlsConf, err := w.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(w.Path, websocket.Server{
+		Handshake: w.checkOrigin,
+		Handler:   w.handle,
+	})
+	w.server = &http.Server{Handler: mux, TLSConfig: tlsConf}
+
+	if tlsConf != nil {
+		w.listener, err = tls.Listen("tcp", w.ServiceAddress, tlsConf)
+	} else {
+		w.listener, err = net.Listen("tcp", w.ServiceAddress)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.server.Serve(w.listener)
+	}()
+
+	log.Printf("I! [inputs.websocket] Listening on %s", w.listener.Addr().String())
+	return nil
+}
+
+// checkOrigin rejects the handshake if the origin of the client is not
+// allowed.  Unlike websocket.Handler, clients without an origin are accepted
+// as they are not browsers.
+func (w *WebSocket) checkOrigin(_ *websocket.Config, req *http.Request) error {
+	if len(w.AllowedOrigins) == 0 {
+		return nil
+	}
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	for _, allowed := range w.AllowedOrigins {
+		if strings.EqualFold(origin, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %q not allowed", origin)
+}
+
+// handle reads the frames of a client connection.
+func (w *WebSocket) handle(conn *websocket.Conn) {
+	if !w.track(conn) {
+		return
+	}
+	defer w.untrack(conn)
+
+	w.read(conn)
+}
+
+func (w *WebSocket) startClient() error {
+	clientConfig := tlsint.ClientConfig{
+		TLSCA:              w.TLSCA,
+		TLSCert:            w.TLSCert,
+		TLSKey:             w.TLSKey,
+		InsecureSkipVerify: w.InsecureSkipVerify,
+	}
+	tlsConf, err := clientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	config, err := websocket.NewConfig(w.URL, w.Origin)
+	if err != nil {
+		return err
+	}
+	config.TlsConfig = tlsConf
+	for k, v := range w.Headers {
+		config.Header.Set(k, v)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.dial(ctx, config)
+	}()
+	return nil
+}
+
+// dial connects to the server and reads its frames, reconnecting with
+// backoff until the plugin is stopped.
+func (w *WebSocket) dial(ctx context.Context, config *websocket.Config) {
+	backoff := w.ReconnectInterval.Duration
+	for {
+		conn, err := w.connect(ctx, config)
+		if err != nil {
+			w.acc.AddError(fmt.Errorf("connecting to %s: %v", w.URL, err))
+		} else if w.track(conn) {
+			backoff = w.ReconnectInterval.Duration
+			w.read(conn)
+			w.untrack(conn)
+			log.Printf("W! [inputs.websocket] Connection to %s closed", w.URL)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		if err != nil {
+			backoff *= 2
+			if backoff > w.MaxReconnectInterval.Duration {
+				backoff = w.MaxReconnectInterval.Duration
+			}
+		}
+	}
+}
+
+// connect opens the connection to the server, the opening handshake must
+// complete within the connect timeout.
+func (w *WebSocket) connect(ctx context.Context, config *websocket.Config) (*websocket.Conn, error) {
+	addr := config.Location.Host
+	if config.Location.Port() == "" {
+		port := "80"
+		if config.Location.Scheme == "wss" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(config.Location.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: w.ConnectTimeout.Duration}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.ConnectTimeout.Duration > 0 {
+		conn.SetDeadline(time.Now().Add(w.ConnectTimeout.Duration))
+	}
+	if config.Location.Scheme == "wss" {
+		tlsConf := &tls.Config{}
+		if config.TlsConfig != nil {
+			tlsConf = config.TlsConfig.Clone()
+		}
+		if tlsConf.ServerName == "" {
+			tlsConf.ServerName = config.Location.Hostname()
+		}
+		conn = tls.Client(conn, tlsConf)
+	}
+
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return ws, nil
+}
+
+// read parses the frames of the connection until it is closed.
+func (w *WebSocket) read(conn *websocket.Conn) {
+	conn.MaxPayloadBytes = int(w.MaxMessageSize.Size)
+	for {
+		if w.ReadTimeout.Duration > 0 {
+			conn.SetReadDeadline(time.Now().Add(w.ReadTimeout.Duration))
+		}
+
+		var msg []byte
+		if err := websocket.Message.Receive(conn, &msg); err != nil {
+			if err == websocket.ErrFrameTooLarge {
+				w.acc.AddError(fmt.Errorf("received frame exceeds max_message_size"))
+			}
+			return
+		}
+
+		metrics, err := w.parser.Parse(msg)
+		if err != nil {
+			w.acc.AddError(fmt.Errorf("unable to parse frame: %v", err))
+			continue
+		}
+		for _, m := range metrics {
+			w.acc.AddMetric(m)
+		}
+	}
+}
+
+// track registers the connection to close it and wait for its reader on
+// stop, it returns false if the plugin is stopping.
+func (w *WebSocket) track(conn *websocket.Conn) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conns == nil {
+		conn.Close()
+		return false
+	}
+	w.conns[conn] = struct{}{}
+	w.wg.Add(1)
+	return true
+}
+
+func (w *WebSocket) untrack(conn *websocket.Conn) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	conn.Close()
+	delete(w.conns, conn)
+	w.wg.Done()
+}
+
+func (w *WebSocket) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	if w.listener != nil {
+		w.listener.Close()
+	}
+
+	w.mu.Lock()
+	for conn := range w.conns {
+		conn.Close()
+	}
+	w.conns = nil
+	w.mu.Unlock()
+
+	w.wg.Wait()
+}
+
+func newWebSocket() *WebSocket {
+	return &WebSocket{
+		Path:                 "/telegraf",
+		ConnectTimeout:       internal.Duration{Duration: 30 * time.Second},
+		ReconnectInterval:    internal.Duration{Duration: time.Second},
+		MaxReconnectInterval: internal.Duration{Duration: time.Minute},
+		MaxMessageSize:       internal.Size{Size: 1024 * 1024},
+	}
+}
+
+func init() {
+	inputs.Add("websocket", func() telegraf.Input {
+		return newWebSocket()
+	})
+}
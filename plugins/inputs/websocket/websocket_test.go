@@ -0,0 +1,179 @@
+package websocket
+
+import (
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func newPlugin(t *testing.T) *WebSocket {
+	plugin := newWebSocket()
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+	plugin.SetParser(parser)
+	return plugin
+}
+
+func TestServe(t *testing.T) {
+	plugin := newPlugin(t)
+	plugin.ServiceAddress = "localhost:0"
+	require.NoError(t, plugin.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	addr := plugin.listener.Addr().String()
+	conn, err := websocket.Dial("ws://"+addr+"/telegraf", "", "http://example.org")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, websocket.Message.Send(conn, "cpu,host=a usage_idle=42 0\n"))
+	require.NoError(t, websocket.Message.Send(conn, []byte("mem,host=a used=1i 0\nmem,host=b used=2i 0\n")))
+	require.NoError(t, websocket.Message.Send(conn, "invalid"))
+	acc.Wait(3)
+	acc.WaitError(1)
+
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_idle": 42.0},
+		map[string]string{"host": "a"})
+	acc.AssertContainsTaggedFields(t, "mem",
+		map[string]interface{}{"used": int64(1)},
+		map[string]string{"host": "a"})
+	acc.AssertContainsTaggedFields(t, "mem",
+		map[string]interface{}{"used": int64(2)},
+		map[string]string{"host": "b"})
+}
+
+func TestServeMaxMessageSize(t *testing.T) {
+	plugin := newPlugin(t)
+	plugin.ServiceAddress = "localhost:0"
+	plugin.MaxMessageSize.Size = 16
+	require.NoError(t, plugin.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	addr := plugin.listener.Addr().String()
+	conn, err := websocket.Dial("ws://"+addr+"/telegraf", "", "http://example.org")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, websocket.Message.Send(conn, "cpu,host=a usage_idle=42 0\n"))
+	acc.WaitError(1)
+	require.Contains(t, acc.Errors[0].Error(), "max_message_size")
+	require.Equal(t, uint64(0), acc.NMetrics())
+}
+
+func TestServeAllowedOrigins(t *testing.T) {
+	plugin := newPlugin(t)
+	plugin.ServiceAddress = "localhost:0"
+	plugin.AllowedOrigins = []string{"https://example.org"}
+	require.NoError(t, plugin.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	addr := plugin.listener.Addr().String()
+	_, err := websocket.Dial("ws://"+addr+"/telegraf", "", "https://attacker.example")
+	require.Error(t, err)
+
+	conn, err := websocket.Dial("ws://"+addr+"/telegraf", "", "https://example.org")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, websocket.Message.Send(conn, "cpu,host=a usage_idle=42 0\n"))
+	acc.Wait(1)
+}
+
+func TestDial(t *testing.T) {
+	connections := make(chan struct{}, 10)
+	ts := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		select {
+		case connections <- struct{}{}:
+		default:
+		}
+		websocket.Message.Send(conn, "cpu,host=a usage_idle=42 0\n")
+		// Close the connection, the plugin reconnects.
+	}))
+	defer ts.Close()
+
+	plugin := newPlugin(t)
+	plugin.URL = strings.Replace(ts.URL, "http://", "ws://", 1)
+	plugin.ReconnectInterval.Duration = 10 * time.Millisecond
+	require.NoError(t, plugin.Init())
+	require.Equal(t, ts.URL, plugin.Origin)
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	acc.Wait(2)
+	require.True(t, len(connections) >= 2)
+	acc.AssertContainsTaggedFields(t, "cpu",
+		map[string]interface{}{"usage_idle": 42.0},
+		map[string]string{"host": "a"})
+}
+
+func TestDialError(t *testing.T) {
+	plugin := newPlugin(t)
+	plugin.URL = "ws://127.0.0.1:1/"
+	plugin.ReconnectInterval.Duration = time.Millisecond
+	require.NoError(t, plugin.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+	acc.WaitError(2)
+	plugin.Stop()
+}
+
+func TestDialTimeout(t *testing.T) {
+	// The server accepts the connection but never answers the handshake.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	plugin := newPlugin(t)
+	plugin.URL = "ws://" + listener.Addr().String() + "/"
+	plugin.ConnectTimeout.Duration = 10 * time.Millisecond
+	plugin.ReconnectInterval.Duration = time.Millisecond
+	require.NoError(t, plugin.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+	acc.WaitError(2)
+	require.Contains(t, acc.Errors[0].Error(), "timeout")
+	plugin.Stop()
+}
+
+func TestInitErrors(t *testing.T) {
+	plugin := newPlugin(t)
+	require.Error(t, plugin.Init())
+
+	plugin = newPlugin(t)
+	plugin.ServiceAddress = ":8080"
+	plugin.URL = "ws://localhost:8080"
+	require.Error(t, plugin.Init())
+
+	plugin = newPlugin(t)
+	plugin.URL = "http://localhost:8080"
+	require.Error(t, plugin.Init())
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	_tls "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/discovery"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
@@ -41,6 +42,9 @@ type X509Cert struct {
 	Sources []string          `toml:"sources"`
 	Timeout internal.Duration `toml:"timeout"`
 	_tls.ClientConfig
+
+	// targets found by discovery plugins
+	targets discovery.Targets
 }
 
 // Description returns description of the plugin.
@@ -164,20 +168,45 @@ func (c *X509Cert) Gather(acc telegraf.Accumulator) error {
 	now := time.Now()
 
 	for _, location := range c.Sources {
-		certs, err := c.getCert(location, c.Timeout.Duration*time.Second)
-		if err != nil {
-			acc.AddError(fmt.Errorf("cannot get SSL cert '%s': %s", location, err.Error()))
+		c.gatherSource(acc, location, nil, now)
+	}
+	for _, target := range c.targets.List() {
+		location := target.Address
+		if !strings.Contains(location, "://") {
+			location = "tcp://" + location
 		}
+		c.gatherSource(acc, location, target.Tags, now)
+	}
+
+	return nil
+}
 
-		for _, cert := range certs {
-			fields := getFields(cert, now)
-			tags := getTags(cert.Subject, location)
+func (c *X509Cert) gatherSource(acc telegraf.Accumulator, location string, extraTags map[string]string, now time.Time) {
+	certs, err := c.getCert(location, c.Timeout.Duration*time.Second)
+	if err != nil {
+		acc.AddError(fmt.Errorf("cannot get SSL cert '%s': %s", location, err.Error()))
+	}
 
-			acc.AddFields("x509_cert", fields, tags)
+	for _, cert := range certs {
+		fields := getFields(cert, now)
+		tags := getTags(cert.Subject, location)
+		for k, v := range extraTags {
+			tags[k] = v
 		}
+
+		acc.AddFields("x509_cert", fields, tags)
 	}
+}
 
-	return nil
+// AddTarget adds a target found by a discovery plugin, addresses without a
+// scheme are connected to over tcp.
+func (c *X509Cert) AddTarget(target telegraf.Target) {
+	c.targets.Add(target)
+}
+
+// RemoveTarget removes a target found by a discovery plugin.
+func (c *X509Cert) RemoveTarget(target telegraf.Target) {
+	c.targets.Remove(target)
 }
 
 func init() {
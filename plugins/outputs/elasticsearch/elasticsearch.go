@@ -29,11 +29,20 @@ type Elasticsearch struct {
 	ManageTemplate      bool
 	TemplateName        string
 	OverwriteTemplate   bool
+	ForceDocumentID     bool   `toml:"force_document_id"`
+	DataStream          bool   `toml:"data_stream"`
+	ILMRollover         bool   `toml:"ilm_rollover"`
+	ILMPolicy           string `toml:"ilm_policy"`
+	MaxRetries          int    `toml:"max_retries"`
 	tls.ClientConfig
 
 	Client *elastic.Client
 }
 
+// retryBackoff is the time to wait before the first retry of failed
+// documents, doubled on each further retry.
+var retryBackoff = 100 * time.Millisecond
+
 var sampleConfig = `
   ## The full HTTP endpoint URL for your Elasticsearch instance
   ## Multiple urls can be specified as part of the same cluster,
@@ -83,6 +92,26 @@ var sampleConfig = `
   template_name = "telegraf"
   ## Set to true if you want telegraf to overwrite an existing template
   overwrite_template = false
+
+  ## Set to true to use a document id derived from the metric series and
+  ## timestamp, so metrics written again after a failure are not duplicated.
+  # force_document_id = false
+
+  ## Documents that failed to index with a temporary error are retried up to
+  ## this many times and then logged and dropped; documents rejected by
+  ## Elasticsearch, for example due to a mapping conflict, are dropped at once.
+  # max_retries = 3
+
+  ## Set to true to write to index_name as a data stream (Elasticsearch 7.9+).
+  ## The template is created as a composable index template for data streams.
+  # data_stream = false
+
+  ## Set to true to write to index_name as an ILM rollover alias.  The first
+  ## index "<index_name>-000001" is created with the alias if it not exists.
+  # ilm_rollover = false
+
+  ## Index lifecycle policy set in the template for new indexes.
+  # ilm_policy = ""
 `
 
 func (a *Elasticsearch) Connect() error {
@@ -90,6 +119,14 @@ func (a *Elasticsearch) Connect() error {
 		return fmt.Errorf("Elasticsearch urls or index_name is not defined")
 	}
 
+	if a.DataStream && a.ILMRollover {
+		return fmt.Errorf("Elasticsearch data_stream and ilm_rollover cannot be used together")
+	}
+
+	if a.ILMRollover && strings.ContainsAny(a.IndexName, "%{") {
+		return fmt.Errorf("Elasticsearch index_name cannot be dynamic with ilm_rollover")
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), a.Timeout.Duration)
 	defer cancel()
 
@@ -142,11 +179,16 @@ func (a *Elasticsearch) Connect() error {
 	}
 
 	// quit if ES version is not supported
-	i, err := strconv.Atoi(strings.Split(esVersion, ".")[0])
-	if err != nil || i < 5 {
+	major, minor, err := parseVersion(esVersion)
+	if err != nil || major < 5 {
 		return fmt.Errorf("Elasticsearch version not supported: %s", esVersion)
 	}
 
+	// data streams were added in 7.9
+	if a.DataStream && (major < 7 || major == 7 && minor < 9) {
+		return fmt.Errorf("Elasticsearch version does not support data streams: %s", esVersion)
+	}
+
 	log.Println("I! Elasticsearch version: " + esVersion)
 
 	a.Client = client
@@ -158,6 +200,13 @@ func (a *Elasticsearch) Connect() error {
 		}
 	}
 
+	if a.ILMRollover {
+		err := a.bootstrapRolloverAlias(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
 	a.IndexName, a.TagKeys = a.GetTagKeys(a.IndexName)
 
 	return nil
@@ -168,47 +217,146 @@ func (a *Elasticsearch) Write(metrics []telegraf.Metric) error {
 		return nil
 	}
 
-	bulkRequest := a.Client.Bulk()
-
+	requests := make([]*elastic.BulkIndexRequest, 0, len(metrics))
 	for _, metric := range metrics {
-		var name = metric.Name()
+		requests = append(requests, a.newBulkRequest(metric))
+	}
 
-		// index name has to be re-evaluated each time for telegraf
-		// to send the metric to the correct time-based index
-		indexName := a.GetIndexName(a.IndexName, metric.Time(), a.TagKeys, metric.Tags())
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		failed, err := a.bulk(requests)
+		if err != nil {
+			return fmt.Errorf("Error sending bulk request to Elasticsearch: %s", err)
+		}
 
-		m := make(map[string]interface{})
+		if len(failed) == 0 {
+			return nil
+		}
 
-		m["@timestamp"] = metric.Time()
-		m["measurement_name"] = name
-		m["tag"] = metric.Tags()
-		m[name] = metric.Fields()
+		if attempt >= a.MaxRetries {
+			// Returning an error would send all metrics of the write again,
+			// duplicating the documents that were indexed.
+			log.Printf("E! Elasticsearch dropped %d metrics that failed to index after %d retries",
+				len(failed), a.MaxRetries)
+			return nil
+		}
 
-		bulkRequest.Add(elastic.NewBulkIndexRequest().
-			Index(indexName).
-			Type("metrics").
-			Doc(m))
+		log.Printf("W! Elasticsearch failed to index %d metrics, retrying in %s", len(failed), backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		requests = failed
+	}
+}
+
+func (a *Elasticsearch) newBulkRequest(metric telegraf.Metric) *elastic.BulkIndexRequest {
+	var name = metric.Name()
+
+	// index name has to be re-evaluated each time for telegraf
+	// to send the metric to the correct time-based index
+	indexName := a.GetIndexName(a.IndexName, metric.Time(), a.TagKeys, metric.Tags())
+
+	m := make(map[string]interface{})
+
+	m["@timestamp"] = metric.Time()
+	m["measurement_name"] = name
+	m["tag"] = metric.Tags()
+	m[name] = metric.Fields()
+
+	req := elastic.NewBulkIndexRequest().
+		Index(indexName).
+		Doc(m)
+
+	// data streams only accept create operations and have no mapping types
+	if a.DataStream {
+		req.OpType("create")
+	} else {
+		req.Type("metrics")
+	}
 
+	if a.ForceDocumentID {
+		req.Id(documentID(metric))
+	}
+
+	return req
+}
+
+// bulk sends the requests in a bulk request and returns the requests of the
+// documents that failed with a temporary error and should be retried.
+// Documents rejected by Elasticsearch are logged and dropped.
+func (a *Elasticsearch) bulk(requests []*elastic.BulkIndexRequest) ([]*elastic.BulkIndexRequest, error) {
+	bulkRequest := a.Client.Bulk()
+	for _, req := range requests {
+		bulkRequest.Add(req)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), a.Timeout.Duration)
 	defer cancel()
 
 	res, err := bulkRequest.Do(ctx)
-
 	if err != nil {
-		return fmt.Errorf("Error sending bulk request to Elasticsearch: %s", err)
+		return nil, err
+	}
+
+	if !res.Errors {
+		return nil, nil
 	}
 
-	if res.Errors {
-		for id, err := range res.Failed() {
-			log.Printf("E! Elasticsearch indexing failure, id: %d, error: %s, caused by: %s, %s", id, err.Error.Reason, err.Error.CausedBy["reason"], err.Error.CausedBy["type"])
+	if len(res.Items) != len(requests) {
+		return nil, fmt.Errorf("bulk response has %d items for %d documents", len(res.Items), len(requests))
+	}
+
+	var failed []*elastic.BulkIndexRequest
+	for i, item := range res.Items {
+		for _, result := range item {
+			switch {
+			case result.Status >= 200 && result.Status < 300:
+			case result.Status == http.StatusConflict && a.ForceDocumentID:
+				// the document was written by an earlier attempt
+			case result.Status == http.StatusTooManyRequests || result.Status >= 500:
+				failed = append(failed, requests[i])
+			default:
+				log.Printf("E! Elasticsearch dropped metric rejected with status %d, index: %s, error: %s",
+					result.Status, result.Index, errorReason(result.Error))
+			}
 		}
-		return fmt.Errorf("W! Elasticsearch failed to index %d metrics", len(res.Failed()))
 	}
 
-	return nil
+	return failed, nil
+}
 
+// parseVersion returns the major and minor version of an Elasticsearch
+// version number like "7.10.2".
+func parseVersion(version string) (int, int, error) {
+	parts := strings.SplitN(version, ".", 3)
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(parts) < 2 {
+		return major, 0, nil
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return major, minor, nil
+}
+
+// errorReason formats the reason of a bulk item error and its cause.
+func errorReason(details *elastic.ErrorDetails) string {
+	if details == nil {
+		return "unknown"
+	}
+	if details.CausedBy == nil {
+		return details.Reason
+	}
+	return fmt.Sprintf("%s, caused by: %s, %s", details.Reason, details.CausedBy["reason"], details.CausedBy["type"])
+}
+
+// documentID returns an id derived from the series and timestamp of the
+// metric, so writing the metric again replaces the existing document.
+func documentID(metric telegraf.Metric) string {
+	return strconv.FormatUint(metric.HashID(), 16) + "-" + strconv.FormatInt(metric.Time().UnixNano(), 10)
 }
 
 func (a *Elasticsearch) manageTemplate(ctx context.Context) error {
@@ -216,24 +364,20 @@ func (a *Elasticsearch) manageTemplate(ctx context.Context) error {
 		return fmt.Errorf("Elasticsearch template_name configuration not defined")
 	}
 
-	templateExists, errExists := a.Client.IndexTemplateExists(a.TemplateName).Do(ctx)
+	templatePattern := a.templatePattern()
 
-	if errExists != nil {
-		return fmt.Errorf("Elasticsearch template check failed, template name: %s, error: %s", a.TemplateName, errExists)
+	if templatePattern == "" {
+		return fmt.Errorf("Template cannot be created for dynamic index names without an index prefix")
 	}
 
-	templatePattern := a.IndexName
-
-	if strings.Contains(templatePattern, "%") {
-		templatePattern = templatePattern[0:strings.Index(templatePattern, "%")]
+	if a.DataStream {
+		return a.manageIndexTemplate(ctx, templatePattern)
 	}
 
-	if strings.Contains(templatePattern, "{{") {
-		templatePattern = templatePattern[0:strings.Index(templatePattern, "{{")]
-	}
+	templateExists, errExists := a.Client.IndexTemplateExists(a.TemplateName).Do(ctx)
 
-	if templatePattern == "" {
-		return fmt.Errorf("Template cannot be created for dynamic index names without an index prefix")
+	if errExists != nil {
+		return fmt.Errorf("Elasticsearch template check failed, template name: %s, error: %s", a.TemplateName, errExists)
 	}
 
 	if (a.OverwriteTemplate) || (!templateExists) || (templatePattern != "") {
@@ -244,7 +388,7 @@ func (a *Elasticsearch) manageTemplate(ctx context.Context) error {
 				"settings": {
 					"index": {
 						"refresh_interval": "10s",
-						"mapping.total_fields.limit": 5000
+						"mapping.total_fields.limit": 5000%s
 					}
 				},
 				"mappings" : {
@@ -294,7 +438,7 @@ func (a *Elasticsearch) manageTemplate(ctx context.Context) error {
 						]
 					}
 				}
-			}`, templatePattern+"*")
+			}`, templatePattern+"*", a.lifecycleSettings())
 		_, errCreateTemplate := a.Client.IndexPutTemplate(a.TemplateName).BodyString(tmpl).Do(ctx)
 
 		if errCreateTemplate != nil {
@@ -311,6 +455,142 @@ func (a *Elasticsearch) manageTemplate(ctx context.Context) error {
 	return nil
 }
 
+// templatePattern returns the static prefix of the index name.
+func (a *Elasticsearch) templatePattern() string {
+	templatePattern := a.IndexName
+
+	if strings.Contains(templatePattern, "%") {
+		templatePattern = templatePattern[0:strings.Index(templatePattern, "%")]
+	}
+
+	if strings.Contains(templatePattern, "{{") {
+		templatePattern = templatePattern[0:strings.Index(templatePattern, "{{")]
+	}
+
+	return templatePattern
+}
+
+// lifecycleSettings returns the index lifecycle settings of the template,
+// formatted to be appended to the index settings.
+func (a *Elasticsearch) lifecycleSettings() string {
+	var settings string
+	if a.ILMPolicy != "" {
+		settings += fmt.Sprintf(`,
+						"lifecycle.name": %s`, strconv.Quote(a.ILMPolicy))
+	}
+	if a.ILMRollover {
+		settings += fmt.Sprintf(`,
+						"lifecycle.rollover_alias": %s`, strconv.Quote(a.IndexName))
+	}
+	return settings
+}
+
+// manageIndexTemplate creates or updates the composable index template used
+// for data streams.
+func (a *Elasticsearch) manageIndexTemplate(ctx context.Context, templatePattern string) error {
+	path := "/_index_template/" + a.TemplateName
+
+	res, err := a.Client.PerformRequest(ctx, "GET", path, nil, nil, http.StatusNotFound)
+	if err != nil {
+		return fmt.Errorf("Elasticsearch template check failed, template name: %s, error: %s", a.TemplateName, err)
+	}
+
+	if res.StatusCode == http.StatusOK && !a.OverwriteTemplate {
+		log.Println("D! Found existing Elasticsearch template. Skipping template management")
+		return nil
+	}
+
+	tmpl := fmt.Sprintf(`
+		{
+			"index_patterns": ["%s"],
+			"data_stream": {},
+			"priority": 200,
+			"template": {
+				"settings": {
+					"index": {
+						"refresh_interval": "10s",
+						"mapping.total_fields.limit": 5000%s
+					}
+				},
+				"mappings": {
+					"properties" : {
+						"@timestamp" : { "type" : "date" },
+						"measurement_name" : { "type" : "keyword" }
+					},
+					"dynamic_templates": [
+						{
+							"tags": {
+								"match_mapping_type": "string",
+								"path_match": "tag.*",
+								"mapping": {
+									"ignore_above": 512,
+									"type": "keyword"
+								}
+							}
+						},
+						{
+							"metrics_long": {
+								"match_mapping_type": "long",
+								"mapping": {
+									"type": "float",
+									"index": false
+								}
+							}
+						},
+						{
+							"metrics_double": {
+								"match_mapping_type": "double",
+								"mapping": {
+									"type": "float",
+									"index": false
+								}
+							}
+						},
+						{
+							"text_fields": {
+								"match": "*",
+								"mapping": {
+									"norms": false
+								}
+							}
+						}
+					]
+				}
+			}
+		}`, templatePattern+"*", a.lifecycleSettings())
+
+	_, err = a.Client.PerformRequest(ctx, "PUT", path, nil, tmpl)
+	if err != nil {
+		return fmt.Errorf("Elasticsearch failed to create index template %s : %s", a.TemplateName, err)
+	}
+
+	log.Printf("D! Elasticsearch index template %s created or updated\n", a.TemplateName)
+	return nil
+}
+
+// bootstrapRolloverAlias creates the first index of the rollover alias,
+// unless the alias exists.
+func (a *Elasticsearch) bootstrapRolloverAlias(ctx context.Context) error {
+	res, err := a.Client.PerformRequest(ctx, "HEAD", "/_alias/"+a.IndexName, nil, nil, http.StatusNotFound)
+	if err != nil {
+		return fmt.Errorf("Elasticsearch alias check failed, alias: %s, error: %s", a.IndexName, err)
+	}
+
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	index := a.IndexName + "-000001"
+	body := fmt.Sprintf(`{"aliases": {%s: {"is_write_index": true}}}`, strconv.Quote(a.IndexName))
+	_, err = a.Client.CreateIndex(index).BodyString(body).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("Elasticsearch failed to create index %s for alias %s: %s", index, a.IndexName, err)
+	}
+
+	log.Printf("D! Elasticsearch index %s created for rollover alias %s\n", index, a.IndexName)
+	return nil
+}
+
 func (a *Elasticsearch) GetTagKeys(indexName string) (string, []string) {
 
 	tagKeys := []string{}
@@ -391,6 +671,7 @@ func init() {
 		return &Elasticsearch{
 			Timeout:             internal.Duration{Duration: time.Second * 5},
 			HealthCheckInterval: internal.Duration{Duration: time.Second * 10},
+			MaxRetries:          3,
 		}
 	})
 }
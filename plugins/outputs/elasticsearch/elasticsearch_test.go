@@ -2,10 +2,17 @@ package elasticsearch
 
 import (
 	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
@@ -257,3 +264,222 @@ func TestGetIndexName(t *testing.T) {
 		}
 	}
 }
+
+// esServer is a fake Elasticsearch server responding to bulk requests with
+// the configured item statuses.
+type esServer struct {
+	sync.Mutex
+
+	version      string
+	bulkStatuses [][]int
+	bulkBodies   []string
+	requests     map[string]string
+}
+
+func (s *esServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	body, _ := ioutil.ReadAll(r.Body)
+	if s.requests == nil {
+		s.requests = make(map[string]string)
+	}
+	s.requests[r.Method+" "+r.URL.Path] = string(body)
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.URL.Path == "/":
+		version := s.version
+		if version == "" {
+			version = "7.10.0"
+		}
+		w.Write([]byte(`{"version": {"number": "` + version + `"}}`))
+	case r.URL.Path == "/_bulk":
+		s.bulkBodies = append(s.bulkBodies, string(body))
+		statuses := s.bulkStatuses[0]
+		if len(s.bulkStatuses) > 1 {
+			s.bulkStatuses = s.bulkStatuses[1:]
+		}
+
+		n := strings.Count(string(body), "\n") / 2
+		var items []string
+		errors := false
+		for i := 0; i < n; i++ {
+			status := statuses[i%len(statuses)]
+			if status >= 300 {
+				errors = true
+				items = append(items, fmt.Sprintf(`{"index": {"_index": "test", "status": %d, "error": {"type": "mapper_parsing_exception", "reason": "failed to parse"}}}`, status))
+			} else {
+				items = append(items, fmt.Sprintf(`{"index": {"_index": "test", "status": %d}}`, status))
+			}
+		}
+		fmt.Fprintf(w, `{"took": 1, "errors": %t, "items": [%s]}`, errors, strings.Join(items, ","))
+	case r.Method == "HEAD" || r.Method == "GET":
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{}`))
+	default:
+		w.Write([]byte(`{"acknowledged": true}`))
+	}
+}
+
+func newTestElasticsearch(url string) *Elasticsearch {
+	return &Elasticsearch{
+		URLs:       []string{url},
+		IndexName:  "test-%Y.%m.%d",
+		Timeout:    internal.Duration{Duration: time.Second * 5},
+		MaxRetries: 3,
+	}
+}
+
+func bulkLines(body string) []string {
+	return strings.Split(strings.TrimSpace(body), "\n")
+}
+
+func TestWriteRetriesFailedDocuments(t *testing.T) {
+	retryBackoff = 0
+
+	s := &esServer{bulkStatuses: [][]int{{201, 429, 400}, {201}}}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	e := newTestElasticsearch(ts.URL)
+	require.NoError(t, e.Connect())
+
+	metrics := []telegraf.Metric{
+		testutil.TestMetric(1.0, "first"),
+		testutil.TestMetric(2.0, "second"),
+		testutil.TestMetric(3.0, "third"),
+	}
+	require.NoError(t, e.Write(metrics))
+
+	require.Len(t, s.bulkBodies, 2)
+	require.Len(t, bulkLines(s.bulkBodies[0]), 6)
+
+	// only the document failed with a temporary error is retried
+	retried := bulkLines(s.bulkBodies[1])
+	require.Len(t, retried, 2)
+	require.Contains(t, retried[1], `"measurement_name":"second"`)
+}
+
+func TestWriteRetriesExhausted(t *testing.T) {
+	retryBackoff = 0
+
+	s := &esServer{bulkStatuses: [][]int{{503}}}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	e := newTestElasticsearch(ts.URL)
+	require.NoError(t, e.Connect())
+
+	// the documents are dropped instead of sending the whole write again
+	require.NoError(t, e.Write([]telegraf.Metric{testutil.TestMetric(1.0, "first")}))
+	require.Len(t, s.bulkBodies, 4)
+}
+
+func TestWriteDocumentID(t *testing.T) {
+	s := &esServer{bulkStatuses: [][]int{{409}}}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	e := newTestElasticsearch(ts.URL)
+	e.ForceDocumentID = true
+	require.NoError(t, e.Connect())
+
+	m := testutil.TestMetric(1.0, "first")
+	// documents written by an earlier attempt are not an error
+	require.NoError(t, e.Write([]telegraf.Metric{m}))
+	require.Len(t, s.bulkBodies, 1)
+
+	id := documentID(m)
+	require.Contains(t, bulkLines(s.bulkBodies[0])[0], `"_id":"`+id+`"`)
+
+	// the id is the same for a copy of the metric
+	require.Equal(t, id, documentID(m.Copy()))
+	require.NotEqual(t, id, documentID(testutil.TestMetric(1.0, "second")))
+}
+
+func TestDataStream(t *testing.T) {
+	s := &esServer{bulkStatuses: [][]int{{201}}}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	e := newTestElasticsearch(ts.URL)
+	e.IndexName = "metrics-telegraf"
+	e.DataStream = true
+	e.ManageTemplate = true
+	e.TemplateName = "telegraf"
+	e.ILMPolicy = "metrics"
+	require.NoError(t, e.Connect())
+
+	tmpl := s.requests["PUT /_index_template/telegraf"]
+	require.Contains(t, tmpl, `"data_stream": {}`)
+	require.Contains(t, tmpl, `"index_patterns": ["metrics-telegraf*"]`)
+	require.Contains(t, tmpl, `"lifecycle.name": "metrics"`)
+
+	require.NoError(t, e.Write([]telegraf.Metric{testutil.TestMetric(1.0, "first")}))
+	action := bulkLines(s.bulkBodies[0])[0]
+	require.Equal(t, `{"create":{"_index":"metrics-telegraf"}}`, action)
+}
+
+func TestDataStreamVersion(t *testing.T) {
+	for _, tt := range []struct {
+		version string
+		ok      bool
+	}{
+		{"6.8.0", false},
+		{"7.8.1", false},
+		{"7.9.0", true},
+		{"7.10.2", true},
+		{"8.0.0", true},
+	} {
+		s := &esServer{version: tt.version}
+		ts := httptest.NewServer(s)
+
+		e := newTestElasticsearch(ts.URL)
+		e.IndexName = "metrics-telegraf"
+		e.DataStream = true
+		err := e.Connect()
+		ts.Close()
+		if tt.ok {
+			require.NoError(t, err, tt.version)
+		} else {
+			require.Error(t, err, tt.version)
+		}
+	}
+}
+
+func TestILMRollover(t *testing.T) {
+	s := &esServer{bulkStatuses: [][]int{{201}}}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	e := newTestElasticsearch(ts.URL)
+	e.IndexName = "telegraf"
+	e.ILMRollover = true
+	e.ILMPolicy = "metrics"
+	e.ManageTemplate = true
+	e.TemplateName = "telegraf"
+	require.NoError(t, e.Connect())
+
+	require.Contains(t, s.requests, "HEAD /_alias/telegraf")
+	require.JSONEq(t, `{"aliases": {"telegraf": {"is_write_index": true}}}`, s.requests["PUT /telegraf-000001"])
+
+	tmpl := s.requests["PUT /_template/telegraf"]
+	require.Contains(t, tmpl, `"lifecycle.name": "metrics"`)
+	require.Contains(t, tmpl, `"lifecycle.rollover_alias": "telegraf"`)
+
+	require.NoError(t, e.Write([]telegraf.Metric{testutil.TestMetric(1.0, "first")}))
+	action := bulkLines(s.bulkBodies[0])[0]
+	require.Equal(t, `{"index":{"_index":"telegraf","_type":"metrics"}}`, action)
+}
+
+func TestConnectInvalidIndexOptions(t *testing.T) {
+	e := newTestElasticsearch("http://localhost:9200")
+	e.DataStream = true
+	e.ILMRollover = true
+	require.Error(t, e.Connect())
+
+	e = newTestElasticsearch("http://localhost:9200")
+	e.ILMRollover = true
+	require.Error(t, e.Connect())
+}
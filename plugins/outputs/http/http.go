@@ -1,17 +1,17 @@
 package http
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpwriter"
 	"github.com/influxdata/telegraf/internal/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
@@ -56,10 +56,14 @@ var sampleConfig = `
   ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
   # data_format = "influx"
 
-  ## HTTP Content-Encoding for write request body, can be set to "gzip" to
-  ## compress body or "identity" to apply no encoding.
+  ## HTTP Content-Encoding for write request body, can be set to "gzip",
+  ## "zstd" or "snappy" to compress body or "identity" to apply no encoding.
   # content_encoding = "identity"
 
+  ## Maximum size of the uncompressed request body, batches are split into
+  ## multiple requests to stay below the limit.  0 disables the limit.
+  # max_request_bytes = 0
+
   ## Additional HTTP headers
   # [outputs.http.headers]
   #   # Should be set manually to "application/json" for json data_format
@@ -84,9 +88,11 @@ type HTTP struct {
 	TokenURL        string            `toml:"token_url"`
 	Scopes          []string          `toml:"scopes"`
 	ContentEncoding string            `toml:"content_encoding"`
+	MaxRequestBytes internal.Size     `toml:"max_request_bytes"`
 	tls.ClientConfig
 
 	client     *http.Client
+	writer     *httpwriter.Writer
 	serializer serializers.Serializer
 }
 
@@ -143,6 +149,15 @@ func (h *HTTP) Connect() error {
 
 	h.client = client
 
+	h.writer, err = httpwriter.New(client, httpwriter.Config{
+		ContentEncoding: h.ContentEncoding,
+		MaxRequestBytes: int(h.MaxRequestBytes.Size),
+		Tags:            map[string]string{"output": "http", "url": statsURL(h.URL)},
+	})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -159,32 +174,16 @@ func (h *HTTP) SampleConfig() string {
 }
 
 func (h *HTTP) Write(metrics []telegraf.Metric) error {
-	reqBody, err := h.serializer.SerializeBatch(metrics)
-	if err != nil {
-		return err
-	}
-
-	if err := h.write(reqBody); err != nil {
-		return err
+	if err := h.writer.Write(metrics, h.serializer.SerializeBatch, h.newRequest); err != nil {
+		return fmt.Errorf("when writing to [%s]: %v", h.URL, err)
 	}
-
 	return nil
 }
 
-func (h *HTTP) write(reqBody []byte) error {
-	var reqBodyBuffer io.Reader = bytes.NewBuffer(reqBody)
-
-	var err error
-	if h.ContentEncoding == "gzip" {
-		reqBodyBuffer, err = internal.CompressWithGzip(reqBodyBuffer)
-		if err != nil {
-			return err
-		}
-	}
-
-	req, err := http.NewRequest(h.Method, h.URL, reqBodyBuffer)
+func (h *HTTP) newRequest(body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(h.Method, h.URL, body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if h.Username != "" || h.Password != "" {
@@ -193,9 +192,6 @@ func (h *HTTP) write(reqBody []byte) error {
 
 	req.Header.Set("User-Agent", "Telegraf/"+internal.Version())
 	req.Header.Set("Content-Type", defaultContentType)
-	if h.ContentEncoding == "gzip" {
-		req.Header.Set("Content-Encoding", "gzip")
-	}
 	for k, v := range h.Headers {
 		if strings.ToLower(k) == "host" {
 			req.Host = v
@@ -203,18 +199,18 @@ func (h *HTTP) write(reqBody []byte) error {
 		req.Header.Set(k, v)
 	}
 
-	resp, err := h.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	_, err = ioutil.ReadAll(resp.Body)
+	return req, nil
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("when writing to [%s] received status code: %d", h.URL, resp.StatusCode)
+// statsURL returns the URL without credentials and query, for use as a tag.
+func statsURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
 	}
-
-	return nil
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
 }
 
 func init() {
@@ -164,6 +164,18 @@ func TestStatusCode(t *testing.T) {
 				require.Error(t, err)
 			},
 		},
+		{
+			name: "error contains url",
+			plugin: &HTTP{
+				URL: u.String(),
+			},
+			statusCode: http.StatusInternalServerError,
+			errFunc: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "when writing to ["+u.String()+"]")
+				require.Contains(t, err.Error(), "500")
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -453,3 +465,30 @@ func TestDefaultUserAgent(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestMaxRequestBytes(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		payload, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		decoder := internal.NewSnappyDecoder()
+		payload, err = decoder.Decode(payload)
+		require.NoError(t, err)
+		bodies = append(bodies, string(payload))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	plugin := &HTTP{
+		URL:             ts.URL,
+		ContentEncoding: "snappy",
+		MaxRequestBytes: internal.Size{Size: 16},
+	}
+	plugin.SetSerializer(influx.NewSerializer())
+	require.NoError(t, plugin.Connect())
+
+	err := plugin.Write([]telegraf.Metric{getMetric(), getMetric()})
+	require.NoError(t, err)
+	require.Equal(t, []string{"cpu value=42 0\n", "cpu value=42 0\n"}, bodies)
+}
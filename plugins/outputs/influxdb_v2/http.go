@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -12,11 +11,11 @@ import (
 	"net/http"
 	"net/url"
 	"path"
-	"strconv"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/httpwriter"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 )
 
@@ -35,7 +34,6 @@ func (e APIError) Error() string {
 
 const (
 	defaultRequestTimeout = time.Second * 5
-	defaultMaxWait        = 10 // seconds
 	defaultDatabase       = "telegraf"
 )
 
@@ -50,6 +48,7 @@ type HTTPConfig struct {
 	Proxy           *url.URL
 	UserAgent       string
 	ContentEncoding string
+	MaxRequestBytes int
 	TLSConfig       *tls.Config
 
 	Serializer *influx.Serializer
@@ -64,9 +63,9 @@ type httpClient struct {
 	BucketTag       string
 
 	client     *http.Client
+	writer     *httpwriter.Writer
 	serializer *influx.Serializer
 	url        *url.URL
-	retryTime  time.Time
 }
 
 func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
@@ -138,6 +137,20 @@ func NewHTTPClient(config *HTTPConfig) (*httpClient, error) {
 		Bucket:          config.Bucket,
 		BucketTag:       config.BucketTag,
 	}
+
+	writer, err := httpwriter.New(client.client, httpwriter.Config{
+		ContentEncoding: config.ContentEncoding,
+		MaxRequestBytes: config.MaxRequestBytes,
+		OnError:         responseError,
+		Tags: map[string]string{
+			"output": "influxdb_v2",
+			"url":    (&url.URL{Scheme: config.URL.Scheme, Host: config.URL.Host, Path: config.URL.Path}).String(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	client.writer = writer
 	return client, nil
 }
 
@@ -164,10 +177,6 @@ func (g genericRespError) Error() string {
 }
 
 func (c *httpClient) Write(ctx context.Context, metrics []telegraf.Metric) error {
-	if c.retryTime.After(time.Now()) {
-		return errors.New("Retry time has not elapsed")
-	}
-
 	batches := make(map[string][]telegraf.Metric)
 	if c.BucketTag == "" {
 		err := c.writeBatch(ctx, c.Bucket, metrics)
@@ -204,70 +213,46 @@ func (c *httpClient) writeBatch(ctx context.Context, bucket string, metrics []te
 		return err
 	}
 
-	reader := influx.NewReader(metrics, c.serializer)
-	req, err := c.makeWriteRequest(url, reader)
-	if err != nil {
-		return err
-	}
-
-	resp, err := c.client.Do(req.WithContext(ctx))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusNoContent {
-		return nil
-	}
+	return c.writer.Write(metrics, c.serializer.SerializeBatch, func(body io.Reader) (*http.Request, error) {
+		req, err := c.makeWriteRequest(url, body)
+		if err != nil {
+			return nil, err
+		}
+		return req.WithContext(ctx), nil
+	})
+}
 
+// responseError handles the error responses of the write endpoint, invalid
+// data is logged and dropped.
+func responseError(err *httpwriter.ResponseError) error {
 	writeResp := &genericRespError{}
-	err = json.NewDecoder(resp.Body).Decode(writeResp)
-	desc := writeResp.Error()
-	if err != nil {
-		desc = resp.Status
+	desc := err.Status
+	if json.Unmarshal(err.Body, writeResp) == nil {
+		desc = writeResp.Error()
 	}
 
-	switch resp.StatusCode {
+	switch err.StatusCode {
 	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
 		log.Printf("E! [outputs.influxdb_v2] Failed to write metric: %s\n", desc)
 		return nil
 	case http.StatusUnauthorized, http.StatusForbidden:
 		return fmt.Errorf("failed to write metric: %s", desc)
-	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
-		retryAfter := resp.Header.Get("Retry-After")
-		retry, err := strconv.Atoi(retryAfter)
-		if err != nil {
-			retry = 0
-		}
-		if retry > defaultMaxWait {
-			retry = defaultMaxWait
-		}
-		c.retryTime = time.Now().Add(time.Duration(retry) * time.Second)
-		return fmt.Errorf("Waiting %ds for server before sending metric again", retry)
 	}
 
 	// This is only until platform spec is fully implemented. As of the
 	// time of writing, there is no error body returned.
-	if xErr := resp.Header.Get("X-Influx-Error"); xErr != "" {
+	if xErr := err.Header.Get("X-Influx-Error"); xErr != "" {
 		desc = fmt.Sprintf("%s; %s", desc, xErr)
 	}
 
 	return &APIError{
-		StatusCode:  resp.StatusCode,
-		Title:       resp.Status,
+		StatusCode:  err.StatusCode,
+		Title:       err.Status,
 		Description: desc,
 	}
 }
 
 func (c *httpClient) makeWriteRequest(url string, body io.Reader) (*http.Request, error) {
-	var err error
-	if c.ContentEncoding == "gzip" {
-		body, err = internal.CompressWithGzip(body)
-		if err != nil {
-			return nil, err
-		}
-	}
-
 	req, err := http.NewRequest("POST", url, body)
 	if err != nil {
 		return nil, err
@@ -276,10 +261,6 @@ func (c *httpClient) makeWriteRequest(url string, body io.Reader) (*http.Request
 	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
 	c.addHeaders(req)
 
-	if c.ContentEncoding == "gzip" {
-		req.Header.Set("Content-Encoding", "gzip")
-	}
-
 	return req, nil
 }
 
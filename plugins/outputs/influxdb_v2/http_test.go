@@ -1,10 +1,18 @@
 package influxdb_v2_test
 
 import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf"
 	influxdb "github.com/influxdata/telegraf/plugins/outputs/influxdb_v2"
+	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -47,3 +55,63 @@ func TestNewHTTPClient(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteStatusCodes(t *testing.T) {
+	var status int
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v2/write", r.URL.Path)
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		body, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		payload, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+
+		if status != 0 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"code":"invalid","message":"unable to parse points"}`))
+			return
+		}
+		bodies = append(bodies, string(payload))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	client, err := influxdb.NewHTTPClient(&influxdb.HTTPConfig{
+		URL:             genURL(ts.URL),
+		Bucket:          "telegraf",
+		ContentEncoding: "gzip",
+		MaxRequestBytes: 20,
+	})
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+	}
+
+	// Batches are split by max_request_bytes.
+	require.NoError(t, client.Write(context.Background(), metrics))
+	require.Equal(t, []string{"cpu value=1 0\n", "cpu value=2 0\n"}, bodies)
+
+	// Invalid data is dropped.
+	status = http.StatusBadRequest
+	require.NoError(t, client.Write(context.Background(), metrics))
+
+	status = http.StatusUnauthorized
+	err = client.Write(context.Background(), metrics)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to parse points")
+
+	status = http.StatusInternalServerError
+	err = client.Write(context.Background(), metrics)
+	require.Error(t, err)
+	require.Equal(t, http.StatusInternalServerError, err.(*influxdb.APIError).StatusCode)
+
+	// Throttled requests are not retried before Retry-After elapsed.
+	status = http.StatusTooManyRequests
+	require.Error(t, client.Write(context.Background(), metrics))
+	status = 0
+	require.Error(t, client.Write(context.Background(), metrics))
+}
@@ -55,10 +55,14 @@ var sampleConfig = `
   ## HTTP User-Agent
   # user_agent = "telegraf"
 
-  ## Content-Encoding for write request body, can be set to "gzip" to
-  ## compress body or "identity" to apply no encoding.
+  ## Content-Encoding for write request body, can be set to "gzip", "zstd"
+  ## or "snappy" to compress body or "identity" to apply no encoding.
   # content_encoding = "gzip"
 
+  ## Maximum size of the uncompressed request body, batches are split into
+  ## multiple requests to stay below the limit.  0 disables the limit.
+  # max_request_bytes = 0
+
   ## Enable or disable uint support for writing uints influxdb 2.0.
   # influx_uint_support = false
 
@@ -88,6 +92,7 @@ type InfluxDB struct {
 	HTTPProxy       string            `toml:"http_proxy"`
 	UserAgent       string            `toml:"user_agent"`
 	ContentEncoding string            `toml:"content_encoding"`
+	MaxRequestBytes internal.Size     `toml:"max_request_bytes"`
 	UintSupport     bool              `toml:"influx_uint_support"`
 	tls.ClientConfig
 
@@ -189,6 +194,7 @@ func (i *InfluxDB) getHTTPClient(ctx context.Context, url *url.URL, proxy *url.U
 		Proxy:           proxy,
 		UserAgent:       i.UserAgent,
 		ContentEncoding: i.ContentEncoding,
+		MaxRequestBytes: int(i.MaxRequestBytes.Size),
 		TLSConfig:       tlsConfig,
 		Serializer:      i.serializer,
 	}
@@ -1,10 +1,12 @@
 package kafka
 
 import (
+	"bytes"
 	"crypto/tls"
 	"fmt"
 	"log"
 	"strings"
+	"text/template"
 
 	"github.com/Shopify/sarama"
 	"github.com/influxdata/telegraf"
@@ -35,6 +37,12 @@ type (
 
 		Version string `toml:"version"`
 
+		IdempotentWrites   bool     `toml:"idempotent_writes"`
+		HeaderTags         []string `toml:"header_tags"`
+		TopicTemplate      string   `toml:"topic_template"`
+		UseMetricTimestamp bool     `toml:"use_metric_timestamp"`
+		BatchMessages      bool     `toml:"batch_messages"`
+
 		// Legacy TLS config options
 		// TLS client certificate
 		Certificate string
@@ -53,6 +61,11 @@ type (
 		tlsConfig tls.Config
 		producer  sarama.SyncProducer
 
+		// producerFunc creates the producer, sarama.NewSyncProducer if nil
+		producerFunc    func(addrs []string, config *sarama.Config) (sarama.SyncProducer, error)
+		topicTemplate   *template.Template
+		maxMessageBytes int
+
 		serializer serializers.Serializer
 	}
 	TopicSuffix struct {
@@ -104,6 +117,12 @@ var sampleConfig = `
   #   keys = ["foo", "bar"]
   #   separator = "_"
 
+  ## Optional topic template, overriding topic and topic_suffix.  The template
+  ## is a Go template with the measurement name available as {{ .Name }} and
+  ## the tag values as {{ .Tag "key" }}; missing tags are empty.
+  ##   ex: topic_template = "telegraf.{{ .Name }}.{{ .Tag \"dc\" }}"
+  # topic_template = ""
+
   ## Telegraf tag to use as a routing key
   ##  ie, if this tag exists, its value will be used as the routing key
   routing_tag = "host"
@@ -147,6 +166,24 @@ var sampleConfig = `
   ## smaller than the broker's 'message.max.bytes'.
   # max_message_bytes = 1000000
 
+  ## Enable the idempotent producer, so retried messages are not duplicated
+  ## by the broker.  Requires version 0.11.0.0 or later (used if version is
+  ## not set), required_acks = -1 and max_retry of at least 1.
+  # idempotent_writes = false
+
+  ## Tags to add as record headers instead of only in the message.  Requires
+  ## version 0.11.0.0 or later.
+  # header_tags = []
+
+  ## Use the metric timestamp as the record timestamp instead of the time the
+  ## message is sent.  Requires version 0.10.0.0 or later.
+  # use_metric_timestamp = false
+
+  ## Send multiple metrics in each message, serialized as a batch by the data
+  ## format, up to max_message_bytes.  Metrics are only batched with metrics of
+  ## the same topic, routing tag and header tags.
+  # batch_messages = false
+
   ## Optional TLS Config
   # tls_ca = "/etc/telegraf/ca.pem"
   # tls_cert = "/etc/telegraf/cert.pem"
@@ -174,7 +211,33 @@ func ValidateTopicSuffixMethod(method string) error {
 	return fmt.Errorf("Unknown topic suffix method provided: %s", method)
 }
 
+// templateMetric is the data of the topic template.
+type templateMetric struct {
+	metric telegraf.Metric
+}
+
+// Name returns the measurement name.
+func (m templateMetric) Name() string {
+	return m.metric.Name()
+}
+
+// Tag returns the value of the tag, or an empty string if it does not exist.
+func (m templateMetric) Tag(key string) string {
+	value, _ := m.metric.GetTag(key)
+	return value
+}
+
 func (k *Kafka) GetTopicName(metric telegraf.Metric) string {
+	if k.topicTemplate != nil {
+		var buf bytes.Buffer
+		err := k.topicTemplate.Execute(&buf, templateMetric{metric: metric})
+		if err != nil {
+			log.Printf("E! [outputs.kafka] Could not execute topic template: %v", err)
+			return k.Topic
+		}
+		return buf.String()
+	}
+
 	var topicName string
 	switch k.TopicSuffix.Method {
 	case "measurement":
@@ -212,6 +275,19 @@ func (k *Kafka) Connect() error {
 			return err
 		}
 		config.Version = version
+	} else if k.IdempotentWrites {
+		config.Version = sarama.V0_11_0_0
+	}
+
+	if len(k.HeaderTags) > 0 && !config.Version.IsAtLeast(sarama.V0_11_0_0) {
+		return fmt.Errorf("header_tags requires version 0.11.0.0 or later")
+	}
+
+	if k.TopicTemplate != "" {
+		k.topicTemplate, err = template.New("topic").Option("missingkey=zero").Parse(k.TopicTemplate)
+		if err != nil {
+			return fmt.Errorf("invalid topic_template: %v", err)
+		}
 	}
 
 	if k.ClientID != "" {
@@ -228,6 +304,12 @@ func (k *Kafka) Connect() error {
 	if k.MaxMessageBytes > 0 {
 		config.Producer.MaxMessageBytes = k.MaxMessageBytes
 	}
+	k.maxMessageBytes = config.Producer.MaxMessageBytes
+
+	if k.IdempotentWrites {
+		config.Producer.Idempotent = true
+		config.Net.MaxOpenRequests = 1
+	}
 
 	// Legacy support ssl config
 	if k.Certificate != "" {
@@ -252,7 +334,12 @@ func (k *Kafka) Connect() error {
 		config.Net.SASL.Enable = true
 	}
 
-	producer, err := sarama.NewSyncProducer(k.Brokers, config)
+	producerFunc := k.producerFunc
+	if producerFunc == nil {
+		producerFunc = sarama.NewSyncProducer
+	}
+
+	producer, err := producerFunc(k.Brokers, config)
 	if err != nil {
 		return err
 	}
@@ -288,24 +375,53 @@ func (k *Kafka) routingKey(metric telegraf.Metric) string {
 	return k.RoutingKey
 }
 
-func (k *Kafka) Write(metrics []telegraf.Metric) error {
-	msgs := make([]*sarama.ProducerMessage, 0, len(metrics))
-	for _, metric := range metrics {
-		buf, err := k.serializer.Serialize(metric)
-		if err != nil {
-			log.Printf("D! [outputs.kafka] Could not serialize metric: %v", err)
-			continue
+// headers returns the record headers of the metric's header tags.
+func (k *Kafka) headers(metric telegraf.Metric) []sarama.RecordHeader {
+	var headers []sarama.RecordHeader
+	for _, key := range k.HeaderTags {
+		if value, ok := metric.GetTag(key); ok {
+			headers = append(headers, sarama.RecordHeader{
+				Key:   []byte(key),
+				Value: []byte(value),
+			})
 		}
+	}
+	return headers
+}
 
-		m := &sarama.ProducerMessage{
-			Topic: k.GetTopicName(metric),
-			Value: sarama.ByteEncoder(buf),
-		}
-		key := k.routingKey(metric)
-		if key != "" {
-			m.Key = sarama.StringEncoder(key)
+// newMessage returns a message for the metric without a value.
+func (k *Kafka) newMessage(metric telegraf.Metric) *sarama.ProducerMessage {
+	m := &sarama.ProducerMessage{
+		Topic:   k.GetTopicName(metric),
+		Headers: k.headers(metric),
+	}
+	key := k.routingKey(metric)
+	if key != "" {
+		m.Key = sarama.StringEncoder(key)
+	}
+	if k.UseMetricTimestamp {
+		m.Timestamp = metric.Time()
+	}
+	return m
+}
+
+func (k *Kafka) Write(metrics []telegraf.Metric) error {
+	var msgs []*sarama.ProducerMessage
+	if k.BatchMessages {
+		msgs = k.batchMessages(metrics)
+	} else {
+		msgs = make([]*sarama.ProducerMessage, 0, len(metrics))
+		for _, metric := range metrics {
+			buf, err := k.serializer.Serialize(metric)
+			if err != nil {
+				log.Printf("D! [outputs.kafka] Could not serialize metric: %v", err)
+				continue
+			}
+
+			m := k.newMessage(metric)
+			m.Value = sarama.ByteEncoder(buf)
+			msgs = append(msgs, m)
 		}
-		msgs = append(msgs, m)
 	}
 
 	err := k.producer.SendMessages(msgs)
@@ -326,6 +442,90 @@ func (k *Kafka) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// batchKey identifies the metrics that can be sent in the same message.
+type batchKey struct {
+	topic      string
+	routingTag string
+	headers    string
+}
+
+// batch is a group of metrics sent in messages with the same topic, routing
+// key and headers as message.
+type batch struct {
+	message *sarama.ProducerMessage
+	metrics []telegraf.Metric
+}
+
+// batchMessages groups the metrics by topic, routing tag and header tags and
+// serializes each group into as few messages as max_message_bytes allows.
+func (k *Kafka) batchMessages(metrics []telegraf.Metric) []*sarama.ProducerMessage {
+	var batches []*batch
+	index := make(map[batchKey]*batch)
+	for _, metric := range metrics {
+		key := batchKey{topic: k.GetTopicName(metric)}
+		if k.RoutingTag != "" {
+			key.routingTag, _ = metric.GetTag(k.RoutingTag)
+		}
+		for _, h := range k.headers(metric) {
+			key.headers += string(h.Key) + "=" + string(h.Value) + "\n"
+		}
+
+		b, ok := index[key]
+		if !ok {
+			b = &batch{message: k.newMessage(metric)}
+			index[key] = b
+			batches = append(batches, b)
+		}
+		b.metrics = append(b.metrics, metric)
+	}
+
+	var msgs []*sarama.ProducerMessage
+	for _, b := range batches {
+		maxBytes := k.maxMessageBytes - messageOverhead(b.message)
+		for _, buf := range k.serializeBatches(b.metrics, maxBytes) {
+			// Each message gets its own key, so random keys are not shared.
+			m := k.newMessage(b.metrics[0])
+			m.Value = sarama.ByteEncoder(buf)
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs
+}
+
+// serializeBatches serializes the metrics, splitting them into batches
+// that are at most maxBytes long.  A single metric longer than maxBytes is
+// serialized on its own.
+func (k *Kafka) serializeBatches(metrics []telegraf.Metric, maxBytes int) [][]byte {
+	buf, err := k.serializer.SerializeBatch(metrics)
+	if err != nil {
+		if len(metrics) == 1 {
+			log.Printf("D! [outputs.kafka] Could not serialize metric: %v", err)
+			return nil
+		}
+	} else if len(buf) <= maxBytes || len(metrics) == 1 {
+		return [][]byte{buf}
+	}
+
+	// Split the batch to either fit into a message or to find the metric
+	// that could not be serialized.
+	half := len(metrics) / 2
+	return append(k.serializeBatches(metrics[:half], maxBytes), k.serializeBatches(metrics[half:], maxBytes)...)
+}
+
+// messageOverhead returns the size of a message without its value, as
+// counted against max_message_bytes.
+func messageOverhead(m *sarama.ProducerMessage) int {
+	// The maximum overhead of a record, see sarama.maximumRecordOverhead
+	size := 36
+	for _, h := range m.Headers {
+		size += len(h.Key) + len(h.Value) + 10
+	}
+	if m.Key != nil {
+		size += m.Key.Length()
+	}
+	return size
+}
+
 func init() {
 	outputs.Add("kafka", func() telegraf.Output {
 		return &Kafka{
@@ -1,9 +1,11 @@
 package kafka
 
 import (
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/Shopify/sarama"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/serializers"
@@ -155,3 +157,185 @@ func TestRoutingKey(t *testing.T) {
 		})
 	}
 }
+
+// fakeProducer is a sync producer recording the sent messages.
+type fakeProducer struct {
+	config *sarama.Config
+	msgs   []*sarama.ProducerMessage
+}
+
+func (p *fakeProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	p.msgs = append(p.msgs, msg)
+	return 0, int64(len(p.msgs)), nil
+}
+
+func (p *fakeProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	p.msgs = append(p.msgs, msgs...)
+	return nil
+}
+
+func (p *fakeProducer) Close() error {
+	return nil
+}
+
+func newTestKafka(t *testing.T, k *Kafka) *fakeProducer {
+	producer := &fakeProducer{}
+	k.producerFunc = func(addrs []string, config *sarama.Config) (sarama.SyncProducer, error) {
+		producer.config = config
+		return producer, config.Validate()
+	}
+	if k.Topic == "" {
+		k.Topic = "telegraf"
+	}
+	if k.serializer == nil {
+		k.serializer, _ = serializers.NewInfluxSerializer()
+	}
+	require.NoError(t, k.Connect())
+	return producer
+}
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a", "dc": "east"},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(10, 0),
+		),
+		testutil.MustMetric("mem",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"value": 2.0},
+			time.Unix(20, 0),
+		),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a", "dc": "east"},
+			map[string]interface{}{"value": 3.0},
+			time.Unix(30, 0),
+		),
+	}
+}
+
+func TestIdempotentWrites(t *testing.T) {
+	k := &Kafka{
+		IdempotentWrites: true,
+		RequiredAcks:     -1,
+		MaxRetry:         3,
+	}
+	producer := newTestKafka(t, k)
+	require.True(t, producer.config.Producer.Idempotent)
+	require.Equal(t, sarama.V0_11_0_0, producer.config.Version)
+	require.Equal(t, 1, producer.config.Net.MaxOpenRequests)
+
+	// the producer requires acknowledgement by all replicas
+	k = &Kafka{
+		IdempotentWrites: true,
+		RequiredAcks:     1,
+		MaxRetry:         3,
+		producerFunc: func(addrs []string, config *sarama.Config) (sarama.SyncProducer, error) {
+			return nil, config.Validate()
+		},
+	}
+	require.Error(t, k.Connect())
+}
+
+func TestHeaderTagsRequireVersion(t *testing.T) {
+	k := &Kafka{
+		Version:    "0.10.2.0",
+		HeaderTags: []string{"host"},
+	}
+	require.Error(t, k.Connect())
+}
+
+func TestWriteHeadersAndTimestamp(t *testing.T) {
+	k := &Kafka{
+		Version:            "0.11.0.0",
+		HeaderTags:         []string{"host", "dc"},
+		UseMetricTimestamp: true,
+		RoutingTag:         "host",
+	}
+	producer := newTestKafka(t, k)
+	require.NoError(t, k.Write(testMetrics()))
+
+	require.Len(t, producer.msgs, 3)
+	msg := producer.msgs[0]
+	require.Equal(t, []sarama.RecordHeader{
+		{Key: []byte("host"), Value: []byte("a")},
+		{Key: []byte("dc"), Value: []byte("east")},
+	}, msg.Headers)
+	require.Equal(t, time.Unix(10, 0), msg.Timestamp)
+	require.Equal(t, sarama.StringEncoder("a"), msg.Key)
+
+	msg = producer.msgs[1]
+	require.Equal(t, []sarama.RecordHeader{
+		{Key: []byte("host"), Value: []byte("b")},
+	}, msg.Headers)
+	require.Equal(t, time.Unix(20, 0), msg.Timestamp)
+}
+
+func TestTopicTemplate(t *testing.T) {
+	k := &Kafka{
+		TopicTemplate: `telegraf.{{ .Name }}.{{ .Tag "dc" }}`,
+		TopicSuffix:   TopicSuffix{Method: "measurement"},
+	}
+	producer := newTestKafka(t, k)
+	require.NoError(t, k.Write(testMetrics()))
+
+	require.Equal(t, "telegraf.cpu.east", producer.msgs[0].Topic)
+	require.Equal(t, "telegraf.mem.", producer.msgs[1].Topic)
+
+	k = &Kafka{TopicTemplate: "{{ .Name "}
+	require.Error(t, k.Connect())
+}
+
+func TestBatchMessages(t *testing.T) {
+	k := &Kafka{
+		BatchMessages:      true,
+		RoutingTag:         "host",
+		UseMetricTimestamp: true,
+	}
+	producer := newTestKafka(t, k)
+	require.NoError(t, k.Write(testMetrics()))
+
+	require.Len(t, producer.msgs, 2)
+	msg := producer.msgs[0]
+	require.Equal(t, sarama.StringEncoder("a"), msg.Key)
+	require.Equal(t, time.Unix(10, 0), msg.Timestamp)
+	require.Equal(t,
+		"cpu,dc=east,host=a value=1 10000000000\ncpu,dc=east,host=a value=3 30000000000\n",
+		string(msg.Value.(sarama.ByteEncoder)))
+
+	msg = producer.msgs[1]
+	require.Equal(t, sarama.StringEncoder("b"), msg.Key)
+	require.Equal(t, "mem,host=b value=2 20000000000\n", string(msg.Value.(sarama.ByteEncoder)))
+}
+
+func TestBatchMessagesMaxBytes(t *testing.T) {
+	var metrics []telegraf.Metric
+	for i := 0; i < 10; i++ {
+		metrics = append(metrics, testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"value": float64(i)},
+			time.Unix(int64(i), 0),
+		))
+	}
+
+	k := &Kafka{
+		BatchMessages:   true,
+		RoutingKey:      "random",
+		MaxMessageBytes: 36 + 36 + 3*len("cpu value=0 0000000000\n"),
+	}
+	producer := newTestKafka(t, k)
+	require.NoError(t, k.Write(metrics))
+
+	var lines int
+	keys := make(map[sarama.Encoder]bool)
+	for _, msg := range producer.msgs {
+		require.True(t, msg.Value.Length()+messageOverhead(msg) <= k.MaxMessageBytes)
+		lines += strings.Count(string(msg.Value.(sarama.ByteEncoder)), "\n")
+		keys[msg.Key] = true
+	}
+	require.Equal(t, 10, lines)
+	require.True(t, len(producer.msgs) >= 4)
+
+	// each message has its own random key
+	require.Len(t, keys, len(producer.msgs))
+}
@@ -0,0 +1,266 @@
+package loki
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+var sampleConfig = `
+  ## URL of the Loki push API.
+  # url = "http://localhost:3100/loki/api/v1/push"
+
+  ## Timeout for each push.
+  # timeout = "5s"
+
+  ## Format of the push request, "json" or "protobuf".  Protobuf requests are
+  ## always snappy compressed.
+  # format = "json"
+
+  ## HTTP Content-Encoding of JSON push requests, can be set to "gzip" to
+  ## compress the body or "identity" to apply no encoding.
+  # content_encoding = "identity"
+
+  ## String field used as the log line; metrics without it are skipped.
+  # line_field = "message"
+
+  ## Label the measurement name is added as.  If empty the measurement name
+  ## is only added to metrics without tags, as "__name", since Loki requires
+  ## at least one label per stream.
+  # name_label = "__name"
+
+  ## HTTP Basic Auth credentials
+  # username = "username"
+  # password = "pa$$word"
+
+  ## Bearer token, sent in the Authorization header.
+  # token = ""
+
+  ## Additional HTTP headers, such as the tenant of a multi-tenant Loki.
+  # [outputs.loki.headers]
+  #   X-Scope-OrgID = "telegraf"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+// defaultNameLabel is the label of the measurement name of metrics without
+// tags if name_label is empty.
+const defaultNameLabel = "__name"
+
+const (
+	formatJSON     = "json"
+	formatProtobuf = "protobuf"
+
+	// maxErrorBody is the length of the response body included in errors.
+	maxErrorBody = 1024
+)
+
+type Loki struct {
+	URL             string            `toml:"url"`
+	Timeout         internal.Duration `toml:"timeout"`
+	Format          string            `toml:"format"`
+	ContentEncoding string            `toml:"content_encoding"`
+	LineField       string            `toml:"line_field"`
+	NameLabel       string            `toml:"name_label"`
+	Username        string            `toml:"username"`
+	Password        string            `toml:"password"`
+	Token           string            `toml:"token"`
+	Headers         map[string]string `toml:"headers"`
+	tls.ClientConfig
+
+	client *http.Client
+}
+
+func (l *Loki) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Loki) Description() string {
+	return "Send log lines to Loki using the push API"
+}
+
+func (l *Loki) Init() error {
+	switch l.Format {
+	case formatJSON, formatProtobuf:
+	default:
+		return fmt.Errorf("invalid format %q", l.Format)
+	}
+
+	switch l.ContentEncoding {
+	case "", "identity", "gzip":
+	default:
+		return fmt.Errorf("invalid content encoding %q", l.ContentEncoding)
+	}
+
+	if l.LineField == "" {
+		return fmt.Errorf("line_field must be set")
+	}
+	return nil
+}
+
+func (l *Loki) Connect() error {
+	tlsCfg, err := l.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	l.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: l.Timeout.Duration,
+	}
+	return nil
+}
+
+func (l *Loki) Close() error {
+	return nil
+}
+
+func (l *Loki) Write(metrics []telegraf.Metric) error {
+	s := newStreams()
+	for _, m := range metrics {
+		line, ok := m.GetField(l.LineField)
+		if !ok {
+			continue
+		}
+		str, ok := line.(string)
+		if !ok {
+			continue
+		}
+		s.Add(l.labels(m), m.Time(), str)
+	}
+	if s.Len() == 0 {
+		return nil
+	}
+	s.Sort()
+
+	body, contentType, err := l.encode(s)
+	if err != nil {
+		return err
+	}
+	return l.push(body, contentType)
+}
+
+// labels returns the labels of the stream of the metric.
+func (l *Loki) labels(m telegraf.Metric) map[string]string {
+	labels := make(map[string]string, len(m.TagList())+1)
+	for _, tag := range m.TagList() {
+		labels[sanitizeLabelName(tag.Key)] = tag.Value
+	}
+	if l.NameLabel != "" {
+		labels[sanitizeLabelName(l.NameLabel)] = m.Name()
+	} else if len(labels) == 0 {
+		labels[defaultNameLabel] = m.Name()
+	}
+	return labels
+}
+
+// encode returns the body and content type of the push request.
+func (l *Loki) encode(s *streams) ([]byte, string, error) {
+	if l.Format == formatProtobuf {
+		body, err := s.MarshalProtobuf()
+		if err != nil {
+			return nil, "", err
+		}
+		return snappy.Encode(nil, body), "application/x-protobuf", nil
+	}
+
+	body, err := s.MarshalJSON()
+	if err != nil {
+		return nil, "", err
+	}
+	return body, "application/json", nil
+}
+
+func (l *Loki) push(body []byte, contentType string) error {
+	var reader io.Reader = bytes.NewReader(body)
+	gzipped := l.Format == formatJSON && l.ContentEncoding == "gzip"
+	if gzipped {
+		var err error
+		reader, err = internal.CompressWithGzip(reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest("POST", l.URL, reader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", internal.ProductToken())
+	req.Header.Set("Content-Type", contentType)
+	if gzipped {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if l.Username != "" || l.Password != "" {
+		req.SetBasicAuth(l.Username, l.Password)
+	}
+	if l.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+l.Token)
+	}
+	for k, v := range l.Headers {
+		if strings.ToLower(k) == "host" {
+			req.Host = v
+		}
+		req.Header.Set(k, v)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	desc := strings.TrimSpace(string(respBody))
+	if desc == "" {
+		desc = resp.Status
+	}
+
+	// Loki rejects invalid or out of order entries with a bad request, these
+	// will not be accepted when retried.
+	if resp.StatusCode == http.StatusBadRequest {
+		log.Printf("E! [outputs.loki] Failed to push log lines: %s", desc)
+		return nil
+	}
+	return fmt.Errorf("when writing to [%s] received status code %d: %s", l.URL, resp.StatusCode, desc)
+}
+
+func newLoki() *Loki {
+	return &Loki{
+		URL:       "http://localhost:3100/loki/api/v1/push",
+		Timeout:   internal.Duration{Duration: 5 * time.Second},
+		Format:    formatJSON,
+		LineField: "message",
+		NameLabel: defaultNameLabel,
+	}
+}
+
+func init() {
+	outputs.Add("loki", func() telegraf.Output {
+		return newLoki()
+	})
+}
@@ -0,0 +1,247 @@
+package loki
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric("tail",
+			map[string]string{"path": "/var/log/b.log"},
+			map[string]interface{}{"message": "b1"},
+			time.Unix(0, 30),
+		),
+		testutil.MustMetric("tail",
+			map[string]string{"path": "/var/log/a.log"},
+			map[string]interface{}{"message": "a2"},
+			time.Unix(0, 20),
+		),
+		testutil.MustMetric("tail",
+			map[string]string{"path": "/var/log/a.log"},
+			map[string]interface{}{"message": "a1"},
+			time.Unix(0, 10),
+		),
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"usage_idle": 42.0},
+			time.Unix(0, 10),
+		),
+		testutil.MustMetric("tail",
+			map[string]string{"path": "/var/log/a.log"},
+			map[string]interface{}{"message": 42},
+			time.Unix(0, 10),
+		),
+	}
+}
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+func newServer(t *testing.T, status int, requests chan<- request) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests <- request{header: r.Header, body: body}
+		w.WriteHeader(status)
+		if status == http.StatusBadRequest {
+			w.Write([]byte("entry out of order"))
+		}
+	}))
+}
+
+func newPlugin(t *testing.T, url string) *Loki {
+	plugin := newLoki()
+	plugin.URL = url
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	return plugin
+}
+
+func TestWriteJSON(t *testing.T) {
+	requests := make(chan request, 1)
+	ts := newServer(t, http.StatusNoContent, requests)
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	plugin.NameLabel = "measurement"
+	require.NoError(t, plugin.Write(testMetrics()))
+
+	req := <-requests
+	require.Equal(t, "application/json", req.header.Get("Content-Type"))
+	require.Equal(t, "", req.header.Get("Content-Encoding"))
+	require.JSONEq(t, `{
+		"streams": [
+			{
+				"stream": {"path": "/var/log/b.log", "measurement": "tail"},
+				"values": [["30", "b1"]]
+			},
+			{
+				"stream": {"path": "/var/log/a.log", "measurement": "tail"},
+				"values": [["10", "a1"], ["20", "a2"]]
+			}
+		]
+	}`, string(req.body))
+}
+
+func TestWriteJSONGzip(t *testing.T) {
+	requests := make(chan request, 1)
+	ts := newServer(t, http.StatusNoContent, requests)
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	plugin.ContentEncoding = "gzip"
+	require.NoError(t, plugin.Write(testMetrics()))
+
+	req := <-requests
+	require.Equal(t, "gzip", req.header.Get("Content-Encoding"))
+
+	r, err := gzip.NewReader(bytes.NewReader(req.body))
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+
+	var push jsonPushRequest
+	require.NoError(t, json.Unmarshal(body, &push))
+	require.Len(t, push.Streams, 2)
+}
+
+func TestWriteProtobuf(t *testing.T) {
+	requests := make(chan request, 1)
+	ts := newServer(t, http.StatusNoContent, requests)
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	plugin.Format = formatProtobuf
+	plugin.ContentEncoding = "gzip"
+	require.NoError(t, plugin.Write(testMetrics()))
+
+	req := <-requests
+	require.Equal(t, "application/x-protobuf", req.header.Get("Content-Type"))
+	require.Equal(t, "", req.header.Get("Content-Encoding"))
+
+	body, err := snappy.Decode(nil, req.body)
+	require.NoError(t, err)
+
+	var push pushRequest
+	require.NoError(t, proto.Unmarshal(body, &push))
+	require.Len(t, push.Streams, 2)
+	require.Equal(t, `{__name="tail", path="/var/log/b.log"}`, push.Streams[0].Labels)
+	require.Equal(t, `{__name="tail", path="/var/log/a.log"}`, push.Streams[1].Labels)
+
+	entries := push.Streams[1].Entries
+	require.Len(t, entries, 2)
+	require.Equal(t, "a1", entries[0].Line)
+	require.Equal(t, int32(10), entries[0].Timestamp.Nanos)
+	require.Equal(t, "a2", entries[1].Line)
+	require.Equal(t, int32(20), entries[1].Timestamp.Nanos)
+}
+
+func TestWriteAuth(t *testing.T) {
+	requests := make(chan request, 2)
+	ts := newServer(t, http.StatusNoContent, requests)
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	plugin.Username = "telegraf"
+	plugin.Password = "secret"
+	plugin.Headers = map[string]string{"X-Scope-OrgID": "tenant"}
+	require.NoError(t, plugin.Write(testMetrics()))
+
+	req := <-requests
+	require.Equal(t, "Basic dGVsZWdyYWY6c2VjcmV0", req.header.Get("Authorization"))
+	require.Equal(t, "tenant", req.header.Get("X-Scope-OrgID"))
+
+	plugin = newPlugin(t, ts.URL)
+	plugin.Token = "token"
+	require.NoError(t, plugin.Write(testMetrics()))
+
+	req = <-requests
+	require.Equal(t, "Bearer token", req.header.Get("Authorization"))
+}
+
+func TestWriteErrors(t *testing.T) {
+	requests := make(chan request, 1)
+	ts := newServer(t, http.StatusBadRequest, requests)
+	defer ts.Close()
+
+	// Rejected entries are dropped
+	plugin := newPlugin(t, ts.URL)
+	require.NoError(t, plugin.Write(testMetrics()))
+	<-requests
+
+	ts = newServer(t, http.StatusInternalServerError, requests)
+	defer ts.Close()
+
+	plugin = newPlugin(t, ts.URL)
+	require.Error(t, plugin.Write(testMetrics()))
+	<-requests
+}
+
+func TestWriteNoLines(t *testing.T) {
+	requests := make(chan request, 1)
+	ts := newServer(t, http.StatusNoContent, requests)
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	plugin.LineField = "line"
+	require.NoError(t, plugin.Write(testMetrics()))
+	require.Empty(t, requests)
+}
+
+func TestLabels(t *testing.T) {
+	tagged := testutil.MustMetric("tail",
+		map[string]string{"path": "/var/log/a.log"},
+		map[string]interface{}{"message": "a1"},
+		time.Unix(0, 10),
+	)
+	untagged := testutil.MustMetric("tail",
+		map[string]string{},
+		map[string]interface{}{"message": "a1"},
+		time.Unix(0, 10),
+	)
+
+	plugin := newLoki()
+	require.Equal(t, map[string]string{"__name": "tail", "path": "/var/log/a.log"}, plugin.labels(tagged))
+	require.Equal(t, map[string]string{"__name": "tail"}, plugin.labels(untagged))
+
+	// Loki requires at least one label
+	plugin.NameLabel = ""
+	require.Equal(t, map[string]string{"path": "/var/log/a.log"}, plugin.labels(tagged))
+	require.Equal(t, map[string]string{"__name": "tail"}, plugin.labels(untagged))
+}
+
+func TestSanitizeLabelName(t *testing.T) {
+	require.Equal(t, "host", sanitizeLabelName("host"))
+	require.Equal(t, "service_name", sanitizeLabelName("service.name"))
+	require.Equal(t, "_0s", sanitizeLabelName("0s"))
+	require.Equal(t, "a_b9", sanitizeLabelName("a-b9"))
+}
+
+func TestInitErrors(t *testing.T) {
+	plugin := newLoki()
+	plugin.Format = "xml"
+	require.Error(t, plugin.Init())
+
+	plugin = newLoki()
+	plugin.ContentEncoding = "br"
+	require.Error(t, plugin.Init())
+
+	plugin = newLoki()
+	plugin.LineField = ""
+	require.Error(t, plugin.Init())
+}
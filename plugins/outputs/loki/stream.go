@@ -0,0 +1,176 @@
+package loki
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+)
+
+// entry is a single log line of a stream.
+type entry struct {
+	time time.Time
+	line string
+}
+
+// stream is a set of log lines with the same labels.
+type stream struct {
+	labels  map[string]string
+	entries []entry
+}
+
+// streams groups log lines into streams by their labels, keeping the order
+// in which the streams are first seen.
+type streams struct {
+	list  []*stream
+	index map[string]*stream
+}
+
+func newStreams() *streams {
+	return &streams{index: make(map[string]*stream)}
+}
+
+// Add adds a log line to the stream with the labels.
+func (s *streams) Add(labels map[string]string, t time.Time, line string) {
+	key := formatLabels(labels)
+	st, ok := s.index[key]
+	if !ok {
+		st = &stream{labels: labels}
+		s.index[key] = st
+		s.list = append(s.list, st)
+	}
+	st.entries = append(st.entries, entry{time: t, line: line})
+}
+
+// Len returns the number of streams.
+func (s *streams) Len() int {
+	return len(s.list)
+}
+
+// Sort sorts the entries of each stream by time; Loki rejects entries older
+// than the newest entry of a stream.
+func (s *streams) Sort() {
+	for _, st := range s.list {
+		entries := st.entries
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].time.Before(entries[j].time)
+		})
+	}
+}
+
+type jsonPushRequest struct {
+	Streams []jsonStream `json:"streams"`
+}
+
+type jsonStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// MarshalJSON encodes the streams as a JSON push request.
+func (s *streams) MarshalJSON() ([]byte, error) {
+	req := jsonPushRequest{Streams: make([]jsonStream, 0, len(s.list))}
+	for _, st := range s.list {
+		js := jsonStream{
+			Stream: st.labels,
+			Values: make([][2]string, 0, len(st.entries)),
+		}
+		for _, e := range st.entries {
+			js.Values = append(js.Values, [2]string{strconv.FormatInt(e.time.UnixNano(), 10), e.line})
+		}
+		req.Streams = append(req.Streams, js)
+	}
+	return json.Marshal(req)
+}
+
+// MarshalProtobuf encodes the streams as a protobuf push request.
+func (s *streams) MarshalProtobuf() ([]byte, error) {
+	req := &pushRequest{}
+	for _, st := range s.list {
+		ps := &streamAdapter{Labels: formatLabels(st.labels)}
+		for _, e := range st.entries {
+			ps.Entries = append(ps.Entries, &entryAdapter{
+				Timestamp: &timestamp.Timestamp{
+					Seconds: e.time.Unix(),
+					Nanos:   int32(e.time.Nanosecond()),
+				},
+				Line: e.line,
+			})
+		}
+		req.Streams = append(req.Streams, ps)
+	}
+	return proto.Marshal(req)
+}
+
+// formatLabels formats labels in the label selector format used by the
+// protobuf push request, for example {host="a", path="/var/log/syslog"}.
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// sanitizeLabelName replaces the characters not allowed in a label name with
+// underscores, and prefixes names starting with a digit with an underscore.
+func sanitizeLabelName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		default:
+			b[i] = '_'
+		}
+	}
+	if len(b) > 0 && b[0] >= '0' && b[0] <= '9' {
+		return "_" + string(b)
+	}
+	return string(b)
+}
+
+// The following types match the messages of the Loki push API in
+// pkg/logproto/logproto.proto.
+
+type pushRequest struct {
+	Streams []*streamAdapter `protobuf:"bytes,1,rep,name=streams,proto3"`
+}
+
+func (m *pushRequest) Reset()         { *m = pushRequest{} }
+func (m *pushRequest) String() string { return proto.CompactTextString(m) }
+func (*pushRequest) ProtoMessage()    {}
+
+type streamAdapter struct {
+	Labels  string          `protobuf:"bytes,1,opt,name=labels,proto3"`
+	Entries []*entryAdapter `protobuf:"bytes,2,rep,name=entries,proto3"`
+}
+
+func (m *streamAdapter) Reset()         { *m = streamAdapter{} }
+func (m *streamAdapter) String() string { return proto.CompactTextString(m) }
+func (*streamAdapter) ProtoMessage()    {}
+
+type entryAdapter struct {
+	Timestamp *timestamp.Timestamp `protobuf:"bytes,1,opt,name=timestamp,proto3"`
+	Line      string               `protobuf:"bytes,2,opt,name=line,proto3"`
+}
+
+func (m *entryAdapter) Reset()         { *m = entryAdapter{} }
+func (m *entryAdapter) String() string { return proto.CompactTextString(m) }
+func (*entryAdapter) ProtoMessage()    {}
@@ -0,0 +1,310 @@
+package opentelemetry
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/otlp"
+)
+
+// metricKey identifies an OTLP metric in a request.
+type metricKey struct {
+	name      string
+	valueType telegraf.ValueType
+}
+
+// series is the state of a cumulative series.
+type series struct {
+	start uint64
+	last  uint64
+	value float64
+}
+
+// startTimes tracks the start times of cumulative series across writes.  A
+// series starts when the plugin is started, or at its first data point if
+// that is earlier, and restarts after the last data point when its value
+// decreases.
+type startTimes struct {
+	start  uint64
+	series map[string]*series
+}
+
+func newStartTimes(start time.Time) *startTimes {
+	return &startTimes{
+		start:  uint64(start.UnixNano()),
+		series: make(map[string]*series),
+	}
+}
+
+// get returns the start time of the data point of the series at time ts.
+func (t *startTimes) get(key string, ts uint64, value float64) uint64 {
+	s, ok := t.series[key]
+	if !ok {
+		s = &series{start: t.start, last: ts, value: value}
+		if ts < s.start {
+			s.start = ts
+		}
+		t.series[key] = s
+		return s.start
+	}
+
+	if ts <= s.last {
+		// A retried or out of order data point
+		if ts < s.start {
+			return ts
+		}
+		return s.start
+	}
+
+	if value < s.value {
+		s.start = s.last
+	}
+	s.last = ts
+	s.value = value
+	return s.start
+}
+
+// seriesKey identifies the series of an OTLP metric name and the tags of the
+// telegraf metric.
+func seriesKey(name string, tags []*telegraf.Tag) string {
+	var sb strings.Builder
+	sb.WriteString(name)
+	for _, tag := range tags {
+		sb.WriteString("\x00")
+		sb.WriteString(tag.Key)
+		sb.WriteString("=")
+		sb.WriteString(tag.Value)
+	}
+	return sb.String()
+}
+
+// converter collects the data points of telegraf metrics into OTLP metrics.
+type converter struct {
+	metrics []*otlp.Metric
+	index   map[metricKey]*otlp.Metric
+	starts  *startTimes
+}
+
+func newConverter(starts *startTimes) *converter {
+	return &converter{
+		index:  make(map[metricKey]*otlp.Metric),
+		starts: starts,
+	}
+}
+
+// metric returns the OTLP metric with the name and type, creating it if needed.
+func (c *converter) metric(name string, valueType telegraf.ValueType) *otlp.Metric {
+	key := metricKey{name: name, valueType: valueType}
+	if m, ok := c.index[key]; ok {
+		return m
+	}
+
+	m := &otlp.Metric{Name: name}
+	switch valueType {
+	case telegraf.Counter:
+		m.Data = &otlp.Metric_Sum{Sum: &otlp.Sum{
+			AggregationTemporality: otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}}
+	case telegraf.Histogram:
+		m.Data = &otlp.Metric_Histogram{Histogram: &otlp.Histogram{
+			AggregationTemporality: otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		}}
+	case telegraf.Summary:
+		m.Data = &otlp.Metric_Summary{Summary: &otlp.Summary{}}
+	default:
+		m.Data = &otlp.Metric_Gauge{Gauge: &otlp.Gauge{}}
+	}
+
+	c.index[key] = m
+	c.metrics = append(c.metrics, m)
+	return m
+}
+
+// Add adds the data points of the telegraf metric.  Histograms and summaries
+// are expected in the format of the prometheus input: a "count" and "sum"
+// field and a field for each bucket upper bound or quantile.  For other types
+// each numeric field is a data point; string and boolean fields are skipped.
+// The data points of counters, histograms and summaries have the start time
+// of their series.
+func (c *converter) Add(m telegraf.Metric) {
+	attributes := attributesOf(m.TagList())
+	ts := uint64(m.Time().UnixNano())
+
+	switch m.Type() {
+	case telegraf.Histogram:
+		point := histogramPoint(m)
+		point.Attributes = attributes
+		point.TimeUnixNano = ts
+		point.StartTimeUnixNano = c.starts.get(seriesKey(m.Name(), m.TagList()), ts, float64(point.Count))
+		h := c.metric(m.Name(), telegraf.Histogram).GetHistogram()
+		h.DataPoints = append(h.DataPoints, point)
+	case telegraf.Summary:
+		point := summaryPoint(m)
+		point.Attributes = attributes
+		point.TimeUnixNano = ts
+		point.StartTimeUnixNano = c.starts.get(seriesKey(m.Name(), m.TagList()), ts, float64(point.Count))
+		s := c.metric(m.Name(), telegraf.Summary).GetSummary()
+		s.DataPoints = append(s.DataPoints, point)
+	default:
+		for _, field := range m.FieldList() {
+			value, ok := toFloat(field.Value)
+			if !ok {
+				continue
+			}
+
+			name := metricName(m, field.Key)
+			point := &otlp.NumberDataPoint{
+				Attributes:   attributes,
+				TimeUnixNano: ts,
+			}
+			switch v := field.Value.(type) {
+			case int64:
+				point.Value = &otlp.NumberDataPoint_AsInt{AsInt: v}
+			case uint64:
+				if v <= math.MaxInt64 {
+					point.Value = &otlp.NumberDataPoint_AsInt{AsInt: int64(v)}
+				} else {
+					point.Value = &otlp.NumberDataPoint_AsDouble{AsDouble: float64(v)}
+				}
+			case float64:
+				point.Value = &otlp.NumberDataPoint_AsDouble{AsDouble: v}
+			}
+
+			metric := c.metric(name, m.Type())
+			switch data := metric.Data.(type) {
+			case *otlp.Metric_Sum:
+				point.StartTimeUnixNano = c.starts.get(seriesKey(name, m.TagList()), ts, value)
+				data.Sum.DataPoints = append(data.Sum.DataPoints, point)
+			case *otlp.Metric_Gauge:
+				data.Gauge.DataPoints = append(data.Gauge.DataPoints, point)
+			}
+		}
+	}
+}
+
+// Metrics returns the OTLP metrics.
+func (c *converter) Metrics() []*otlp.Metric {
+	return c.metrics
+}
+
+// metricName returns the OTLP metric name of a field.  Like in the
+// prometheus_client output, the "counter", "gauge" and "value" fields use the
+// measurement name.
+func metricName(m telegraf.Metric, field string) string {
+	switch {
+	case m.Type() == telegraf.Counter && field == "counter",
+		m.Type() == telegraf.Gauge && field == "gauge",
+		field == "value":
+		return m.Name()
+	}
+	return m.Name() + "_" + field
+}
+
+func attributesOf(tags []*telegraf.Tag) []*otlp.KeyValue {
+	attributes := make([]*otlp.KeyValue, 0, len(tags))
+	for _, tag := range tags {
+		attributes = append(attributes, stringAttribute(tag.Key, tag.Value))
+	}
+	return attributes
+}
+
+func stringAttribute(key, value string) *otlp.KeyValue {
+	return &otlp.KeyValue{
+		Key:   key,
+		Value: &otlp.AnyValue{Value: &otlp.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// bucket is a histogram bucket upper bound or summary quantile and its
+// value.
+type bucket struct {
+	bound float64
+	value float64
+}
+
+// splitFields returns the count and sum fields and the remaining fields with
+// numeric names, sorted.
+func splitFields(m telegraf.Metric) (count uint64, sum float64, buckets []bucket) {
+	for _, field := range m.FieldList() {
+		value, ok := toFloat(field.Value)
+		if !ok {
+			continue
+		}
+
+		switch field.Key {
+		case "count":
+			count = uint64(value)
+		case "sum":
+			sum = value
+		default:
+			bound, err := strconv.ParseFloat(field.Key, 64)
+			if err == nil {
+				buckets = append(buckets, bucket{bound: bound, value: value})
+			}
+		}
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].bound < buckets[j].bound })
+	return count, sum, buckets
+}
+
+// histogramPoint converts the cumulative bucket counts of the metric to an
+// OTLP histogram data point with explicit bounds.
+func histogramPoint(m telegraf.Metric) *otlp.HistogramDataPoint {
+	count, sum, buckets := splitFields(m)
+	point := &otlp.HistogramDataPoint{
+		Count: count,
+		XSum:  &otlp.HistogramDataPoint_Sum{Sum: sum},
+	}
+
+	var previous uint64
+	for _, b := range buckets {
+		if math.IsInf(b.bound, 1) {
+			break
+		}
+		cumulative := uint64(b.value)
+		point.ExplicitBounds = append(point.ExplicitBounds, b.bound)
+		point.BucketCounts = append(point.BucketCounts, subtract(cumulative, previous))
+		previous = cumulative
+	}
+	point.BucketCounts = append(point.BucketCounts, subtract(count, previous))
+	return point
+}
+
+func subtract(a, b uint64) uint64 {
+	if a < b {
+		return 0
+	}
+	return a - b
+}
+
+func summaryPoint(m telegraf.Metric) *otlp.SummaryDataPoint {
+	count, sum, quantiles := splitFields(m)
+	point := &otlp.SummaryDataPoint{
+		Count: count,
+		Sum:   sum,
+	}
+	for _, q := range quantiles {
+		point.QuantileValues = append(point.QuantileValues, &otlp.SummaryDataPoint_ValueAtQuantile{
+			Quantile: q.bound,
+			Value:    q.value,
+		})
+	}
+	return point
+}
@@ -0,0 +1,293 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/otlp"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+)
+
+var sampleConfig = `
+  ## Protocol used to export the metrics, "grpc" or "http" (protobuf over
+  ## HTTP).
+  # protocol = "grpc"
+
+  ## Address of the OTLP gRPC receiver, used with the grpc protocol.
+  # service_address = "localhost:4317"
+
+  ## URL of the OTLP HTTP receiver, used with the http protocol.
+  # url = "http://localhost:4318/v1/metrics"
+
+  ## Timeout for each export.
+  # timeout = "5s"
+
+  ## Compression of the exported data, "gzip" or "none".
+  # compression = "gzip"
+
+  ## Additional headers, sent as gRPC metadata with the grpc protocol.
+  # [outputs.opentelemetry.headers]
+  #   key1 = "value1"
+
+  ## Attributes of the resource the metrics are exported for.
+  # [outputs.opentelemetry.resource_attributes]
+  #   "service.name" = "telegraf"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+const (
+	protocolGRPC = "grpc"
+	protocolHTTP = "http"
+
+	scopeName = "telegraf"
+)
+
+type OpenTelemetry struct {
+	Protocol           string            `toml:"protocol"`
+	ServiceAddress     string            `toml:"service_address"`
+	URL                string            `toml:"url"`
+	Timeout            internal.Duration `toml:"timeout"`
+	Compression        string            `toml:"compression"`
+	Headers            map[string]string `toml:"headers"`
+	ResourceAttributes map[string]string `toml:"resource_attributes"`
+	tls.ClientConfig
+
+	grpcConn   *grpc.ClientConn
+	grpcClient otlp.MetricsServiceClient
+	httpClient *http.Client
+	encoder    internal.ContentEncoder
+	starts     *startTimes
+}
+
+func (o *OpenTelemetry) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpenTelemetry) Description() string {
+	return "Send metrics to an OpenTelemetry collector using OTLP"
+}
+
+func (o *OpenTelemetry) Init() error {
+	switch o.Protocol {
+	case protocolGRPC, protocolHTTP:
+	default:
+		return fmt.Errorf("invalid protocol %q", o.Protocol)
+	}
+
+	switch o.Compression {
+	case "gzip", "none", "":
+	default:
+		return fmt.Errorf("invalid compression %q", o.Compression)
+	}
+	return nil
+}
+
+func (o *OpenTelemetry) Connect() error {
+	tlsConfig, err := o.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	if o.Protocol == protocolHTTP {
+		o.encoder, err = internal.NewContentEncoder(o.contentEncoding())
+		if err != nil {
+			return err
+		}
+		o.httpClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+			Timeout: o.Timeout.Duration,
+		}
+		return nil
+	}
+
+	opts := []grpc.DialOption{}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	if o.Compression == "gzip" {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
+
+	o.grpcConn, err = grpc.Dial(o.ServiceAddress, opts...)
+	if err != nil {
+		return err
+	}
+	o.grpcClient = otlp.NewMetricsServiceClient(o.grpcConn)
+	return nil
+}
+
+func (o *OpenTelemetry) Close() error {
+	if o.grpcConn != nil {
+		return o.grpcConn.Close()
+	}
+	return nil
+}
+
+func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
+	req := o.newRequest(metrics)
+	if req == nil {
+		return nil
+	}
+
+	var resp *otlp.ExportMetricsServiceResponse
+	var err error
+	if o.Protocol == protocolHTTP {
+		resp, err = o.exportHTTP(req)
+	} else {
+		resp, err = o.exportGRPC(req)
+	}
+	if err != nil {
+		return err
+	}
+
+	if partial := resp.GetPartialSuccess(); partial.GetRejectedDataPoints() > 0 {
+		log.Printf("W! [outputs.opentelemetry] Receiver rejected %d data points: %s",
+			partial.GetRejectedDataPoints(), partial.GetErrorMessage())
+	}
+	return nil
+}
+
+// newRequest converts the metrics to an export request, or returns nil if no
+// metric has a value that can be exported.
+func (o *OpenTelemetry) newRequest(metrics []telegraf.Metric) *otlp.ExportMetricsServiceRequest {
+	c := newConverter(o.starts)
+	for _, m := range metrics {
+		c.Add(m)
+	}
+	if len(c.Metrics()) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(o.ResourceAttributes))
+	for key := range o.ResourceAttributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	resource := &otlp.Resource{}
+	for _, key := range keys {
+		resource.Attributes = append(resource.Attributes, stringAttribute(key, o.ResourceAttributes[key]))
+	}
+
+	return &otlp.ExportMetricsServiceRequest{
+		ResourceMetrics: []*otlp.ResourceMetrics{
+			{
+				Resource: resource,
+				ScopeMetrics: []*otlp.ScopeMetrics{
+					{
+						Scope: &otlp.InstrumentationScope{
+							Name:    scopeName,
+							Version: internal.Version(),
+						},
+						Metrics: c.Metrics(),
+					},
+				},
+			},
+		},
+	}
+}
+
+func (o *OpenTelemetry) exportGRPC(req *otlp.ExportMetricsServiceRequest) (*otlp.ExportMetricsServiceResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), o.Timeout.Duration)
+	defer cancel()
+
+	if len(o.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.Headers))
+	}
+	return o.grpcClient.Export(ctx, req)
+}
+
+func (o *OpenTelemetry) exportHTTP(req *otlp.ExportMetricsServiceRequest) (*otlp.ExportMetricsServiceResponse, error) {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err = o.encoder.Encode(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest("POST", o.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("User-Agent", internal.ProductToken())
+	if o.contentEncoding() == "gzip" {
+		httpReq.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range o.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	httpResp, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, fmt.Errorf("when writing to [%s] received status code: %d", o.URL, httpResp.StatusCode)
+	}
+
+	resp := &otlp.ExportMetricsServiceResponse{}
+	if httpResp.Header.Get("Content-Type") == "application/x-protobuf" {
+		if err := proto.Unmarshal(respBody, resp); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (o *OpenTelemetry) contentEncoding() string {
+	if o.Compression == "gzip" {
+		return "gzip"
+	}
+	return "identity"
+}
+
+func newOpenTelemetry() *OpenTelemetry {
+	return &OpenTelemetry{
+		Protocol:       protocolGRPC,
+		ServiceAddress: "localhost:4317",
+		URL:            "http://localhost:4318/v1/metrics",
+		Timeout:        internal.Duration{Duration: 5 * time.Second},
+		Compression:    "gzip",
+		starts:         newStartTimes(time.Now()),
+	}
+}
+
+func init() {
+	outputs.Add("opentelemetry", func() telegraf.Output {
+		return newOpenTelemetry()
+	})
+}
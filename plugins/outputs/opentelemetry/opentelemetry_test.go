@@ -0,0 +1,403 @@
+package opentelemetry
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/otlp"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func stringKV(key, value string) *otlp.KeyValue {
+	return &otlp.KeyValue{
+		Key:   key,
+		Value: &otlp.AnyValue{Value: &otlp.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func TestConvert(t *testing.T) {
+	ts := time.Unix(0, 1000)
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{
+				"usage_idle": 99.5,
+				"processes":  int64(12),
+				"big":        uint64(math.MaxUint64),
+				"state":      "ok",
+				"up":         true,
+			},
+			ts,
+		),
+		testutil.MustMetric("http_requests_total",
+			map[string]string{"code": "200"},
+			map[string]interface{}{"counter": 42.0},
+			ts,
+			telegraf.Counter,
+		),
+		testutil.MustMetric("http_requests_total",
+			map[string]string{"code": "500"},
+			map[string]interface{}{"counter": 1.0},
+			ts,
+			telegraf.Counter,
+		),
+		testutil.MustMetric("temperature",
+			map[string]string{},
+			map[string]interface{}{"gauge": 21.5},
+			ts,
+			telegraf.Gauge,
+		),
+		testutil.MustMetric("humidity",
+			map[string]string{},
+			map[string]interface{}{"value": 40.0},
+			ts,
+		),
+		testutil.MustMetric("latency",
+			map[string]string{},
+			map[string]interface{}{
+				"count": 10.0,
+				"sum":   4.5,
+				"0.1":   2.0,
+				"0.5":   7.0,
+				"+Inf":  10.0,
+			},
+			ts,
+			telegraf.Histogram,
+		),
+		testutil.MustMetric("rpc",
+			map[string]string{},
+			map[string]interface{}{
+				"count": 5.0,
+				"sum":   2.0,
+				"0.5":   0.3,
+				"0.99":  0.9,
+			},
+			ts,
+			telegraf.Summary,
+		),
+	}
+
+	c := newConverter(newStartTimes(time.Unix(0, 500)))
+	for _, m := range metrics {
+		c.Add(m)
+	}
+
+	host := []*otlp.KeyValue{stringKV("host", "a")}
+	none := []*otlp.KeyValue{}
+	gauge := func(name string, point *otlp.NumberDataPoint) *otlp.Metric {
+		point.Attributes = host
+		point.TimeUnixNano = 1000
+		return &otlp.Metric{
+			Name: name,
+			Data: &otlp.Metric_Gauge{Gauge: &otlp.Gauge{
+				DataPoints: []*otlp.NumberDataPoint{point},
+			}},
+		}
+	}
+
+	expected := []*otlp.Metric{
+		gauge("cpu_big", &otlp.NumberDataPoint{
+			Value: &otlp.NumberDataPoint_AsDouble{AsDouble: float64(math.MaxUint64)},
+		}),
+		gauge("cpu_processes", &otlp.NumberDataPoint{
+			Value: &otlp.NumberDataPoint_AsInt{AsInt: 12},
+		}),
+		gauge("cpu_usage_idle", &otlp.NumberDataPoint{
+			Value: &otlp.NumberDataPoint_AsDouble{AsDouble: 99.5},
+		}),
+		{
+			Name: "http_requests_total",
+			Data: &otlp.Metric_Sum{Sum: &otlp.Sum{
+				AggregationTemporality: otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				IsMonotonic:            true,
+				DataPoints: []*otlp.NumberDataPoint{
+					{
+						Attributes:        []*otlp.KeyValue{stringKV("code", "200")},
+						StartTimeUnixNano: 500,
+						TimeUnixNano:      1000,
+						Value:             &otlp.NumberDataPoint_AsDouble{AsDouble: 42},
+					},
+					{
+						Attributes:        []*otlp.KeyValue{stringKV("code", "500")},
+						StartTimeUnixNano: 500,
+						TimeUnixNano:      1000,
+						Value:             &otlp.NumberDataPoint_AsDouble{AsDouble: 1},
+					},
+				},
+			}},
+		},
+		{
+			Name: "temperature",
+			Data: &otlp.Metric_Gauge{Gauge: &otlp.Gauge{
+				DataPoints: []*otlp.NumberDataPoint{
+					{Attributes: none, TimeUnixNano: 1000, Value: &otlp.NumberDataPoint_AsDouble{AsDouble: 21.5}},
+				},
+			}},
+		},
+		{
+			Name: "humidity",
+			Data: &otlp.Metric_Gauge{Gauge: &otlp.Gauge{
+				DataPoints: []*otlp.NumberDataPoint{
+					{Attributes: none, TimeUnixNano: 1000, Value: &otlp.NumberDataPoint_AsDouble{AsDouble: 40}},
+				},
+			}},
+		},
+		{
+			Name: "latency",
+			Data: &otlp.Metric_Histogram{Histogram: &otlp.Histogram{
+				AggregationTemporality: otlp.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				DataPoints: []*otlp.HistogramDataPoint{
+					{
+						Attributes:        none,
+						StartTimeUnixNano: 500,
+						TimeUnixNano:      1000,
+						Count:             10,
+						XSum:              &otlp.HistogramDataPoint_Sum{Sum: 4.5},
+						ExplicitBounds:    []float64{0.1, 0.5},
+						BucketCounts:      []uint64{2, 5, 3},
+					},
+				},
+			}},
+		},
+		{
+			Name: "rpc",
+			Data: &otlp.Metric_Summary{Summary: &otlp.Summary{
+				DataPoints: []*otlp.SummaryDataPoint{
+					{
+						Attributes:        none,
+						StartTimeUnixNano: 500,
+						TimeUnixNano:      1000,
+						Count:             5,
+						Sum:               2,
+						QuantileValues: []*otlp.SummaryDataPoint_ValueAtQuantile{
+							{Quantile: 0.5, Value: 0.3},
+							{Quantile: 0.99, Value: 0.9},
+						},
+					},
+				},
+			}},
+		},
+	}
+
+	// The order of the fields of a metric is not defined
+	actual := make(map[string]*otlp.Metric)
+	for _, m := range c.Metrics() {
+		actual[m.Name] = m
+	}
+	require.Len(t, actual, len(expected))
+	for _, m := range expected {
+		require.True(t, proto.Equal(m, actual[m.Name]),
+			"expected %v\nactual   %v", m, actual[m.Name])
+	}
+}
+
+func TestStartTimes(t *testing.T) {
+	starts := newStartTimes(time.Unix(0, 1000))
+	counter := func(host string, value float64, ts int64) uint64 {
+		c := newConverter(starts)
+		c.Add(testutil.MustMetric("requests",
+			map[string]string{"host": host},
+			map[string]interface{}{"counter": value},
+			time.Unix(0, ts),
+			telegraf.Counter,
+		))
+		return c.Metrics()[0].GetSum().GetDataPoints()[0].GetStartTimeUnixNano()
+	}
+
+	// A series starts with the plugin or its first data point if earlier
+	require.Equal(t, uint64(1000), counter("a", 1, 2000))
+	require.Equal(t, uint64(500), counter("b", 1, 500))
+	require.Equal(t, uint64(1000), counter("a", 5, 3000))
+
+	// A retried data point keeps the start time
+	require.Equal(t, uint64(1000), counter("a", 5, 3000))
+
+	// The series restarts after the last data point if the counter decreases
+	require.Equal(t, uint64(3000), counter("a", 2, 4000))
+	require.Equal(t, uint64(3000), counter("a", 3, 5000))
+	require.Equal(t, uint64(500), counter("b", 2, 5000))
+}
+
+// receiver is an in-process OTLP gRPC receiver.
+type receiver struct {
+	sync.Mutex
+	requests []*otlp.ExportMetricsServiceRequest
+	metadata []metadata.MD
+	err      error
+}
+
+func (r *receiver) Export(ctx context.Context, req *otlp.ExportMetricsServiceRequest) (*otlp.ExportMetricsServiceResponse, error) {
+	r.Lock()
+	defer r.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	r.requests = append(r.requests, req)
+	r.metadata = append(r.metadata, md)
+	return &otlp.ExportMetricsServiceResponse{}, nil
+}
+
+func startReceiver(t *testing.T) (*receiver, string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	r := &receiver{}
+	server := grpc.NewServer()
+	otlp.RegisterMetricsServiceServer(server, r)
+	go server.Serve(listener)
+	return r, listener.Addr().String(), server.Stop
+}
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage_idle": 99.5},
+			time.Unix(0, 1000),
+		),
+	}
+}
+
+func TestWriteGRPC(t *testing.T) {
+	r, addr, stop := startReceiver(t)
+	defer stop()
+
+	plugin := newOpenTelemetry()
+	plugin.ServiceAddress = addr
+	plugin.Headers = map[string]string{"authorization": "Bearer token"}
+	plugin.ResourceAttributes = map[string]string{
+		"service.name": "telegraf",
+		"host.name":    "a",
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write(testMetrics()))
+
+	r.Lock()
+	defer r.Unlock()
+	require.Len(t, r.requests, 1)
+	require.Equal(t, []string{"Bearer token"}, r.metadata[0].Get("authorization"))
+
+	resourceMetrics := r.requests[0].GetResourceMetrics()
+	require.Len(t, resourceMetrics, 1)
+	require.Equal(t,
+		[]*otlp.KeyValue{stringKV("host.name", "a"), stringKV("service.name", "telegraf")},
+		resourceMetrics[0].GetResource().GetAttributes())
+
+	scopeMetrics := resourceMetrics[0].GetScopeMetrics()
+	require.Len(t, scopeMetrics, 1)
+	require.Equal(t, "telegraf", scopeMetrics[0].GetScope().GetName())
+	require.Len(t, scopeMetrics[0].GetMetrics(), 1)
+	require.Equal(t, "cpu_usage_idle", scopeMetrics[0].GetMetrics()[0].GetName())
+}
+
+func TestWriteGRPCError(t *testing.T) {
+	r, addr, stop := startReceiver(t)
+	defer stop()
+	r.err = status.Error(codes.Unavailable, "unavailable")
+
+	plugin := newOpenTelemetry()
+	plugin.ServiceAddress = addr
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.Error(t, plugin.Write(testMetrics()))
+}
+
+func TestWriteHTTP(t *testing.T) {
+	var requests []*otlp.ExportMetricsServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/metrics", r.URL.Path)
+		require.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		require.Equal(t, "value", r.Header.Get("X-Test"))
+
+		body, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		buf, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+
+		req := &otlp.ExportMetricsServiceRequest{}
+		require.NoError(t, proto.Unmarshal(buf, req))
+		requests = append(requests, req)
+
+		resp, err := proto.Marshal(&otlp.ExportMetricsServiceResponse{})
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(resp)
+	}))
+	defer server.Close()
+
+	plugin := newOpenTelemetry()
+	plugin.Protocol = "http"
+	plugin.URL = server.URL + "/v1/metrics"
+	plugin.Headers = map[string]string{"X-Test": "value"}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write(testMetrics()))
+	require.Len(t, requests, 1)
+
+	metrics := requests[0].GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()
+	require.Len(t, metrics, 1)
+	require.Equal(t, "cpu_usage_idle", metrics[0].GetName())
+	require.Equal(t, 99.5, metrics[0].GetGauge().GetDataPoints()[0].GetAsDouble())
+}
+
+func TestWriteHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	plugin := newOpenTelemetry()
+	plugin.Protocol = "http"
+	plugin.URL = server.URL
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	require.Error(t, plugin.Write(testMetrics()))
+}
+
+func TestWriteNothingToExport(t *testing.T) {
+	plugin := newOpenTelemetry()
+	require.NoError(t, plugin.Init())
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testutil.MustMetric("log",
+			map[string]string{},
+			map[string]interface{}{"message": "hello"},
+			time.Unix(0, 0),
+		),
+	}))
+}
+
+func TestInitErrors(t *testing.T) {
+	plugin := newOpenTelemetry()
+	plugin.Protocol = "udp"
+	require.Error(t, plugin.Init())
+
+	plugin = newOpenTelemetry()
+	plugin.Compression = "zstd"
+	require.Error(t, plugin.Init())
+}
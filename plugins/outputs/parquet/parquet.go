@@ -0,0 +1,273 @@
+package parquet
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+var sampleConfig = `
+  ## Directory to write the files to.
+  directory = "/var/lib/telegraf/parquet"
+
+  ## Length of the time window stored in each partition, windows are aligned
+  ## to UTC.
+  # partition_interval = "1h"
+
+  ## Layout of the partition directories below the measurement directory,
+  ## formatted as a Go reference time using the start of the window.
+  # partition_format = "date=2006-01-02/hour=15"
+
+  ## Time to wait for late metrics after the end of a window before the files
+  ## of the window are written.  Metrics arriving later are written to an
+  ## additional file in the partition.
+  # finalize_delay = "1m"
+
+  ## WARNING: metrics are held in memory until their files are written and
+  ## are lost if Telegraf crashes or is killed before, even though the write
+  ## has succeeded.  Maximum time metrics are held in memory before they are
+  ## written to an additional file in their partition, even if the window has
+  ## not ended.  Lower values lose less data on a crash but create more and
+  ## smaller files.  If zero the metrics are held until the window ends.
+  # max_buffer_time = "0s"
+
+  ## Maximum number of rows in a file, when a window has more metrics they
+  ## are split across multiple files.
+  # max_rows = 100000
+
+  ## Name of the timestamp column.
+  # time_column = "time"
+
+  ## Compression codec, one of "none", "snappy" or "gzip".
+  # compression = "snappy"
+`
+
+const tempPrefix = ".tmp-"
+
+type Parquet struct {
+	Directory         string            `toml:"directory"`
+	PartitionInterval internal.Duration `toml:"partition_interval"`
+	PartitionFormat   string            `toml:"partition_format"`
+	FinalizeDelay     internal.Duration `toml:"finalize_delay"`
+	MaxBufferTime     internal.Duration `toml:"max_buffer_time"`
+	MaxRows           int               `toml:"max_rows"`
+	TimeColumn        string            `toml:"time_column"`
+	Compression       string            `toml:"compression"`
+
+	compressor *compressor
+	partitions map[partitionKey]*partition
+	now        func() time.Time
+	seq        int
+}
+
+type partitionKey struct {
+	measurement string
+	start       int64
+}
+
+// partition holds the metrics of a measurement and time window that have not
+// been written yet.
+type partition struct {
+	dir     string
+	end     time.Time
+	metrics []telegraf.Metric
+
+	// since is the time the oldest of the metrics was added.
+	since time.Time
+
+	// full contains batches of max_rows metrics waiting to be written.
+	full [][]telegraf.Metric
+}
+
+func (p *Parquet) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Parquet) Description() string {
+	return "Write metrics to Parquet files partitioned by measurement and time"
+}
+
+func (p *Parquet) Init() error {
+	if p.Directory == "" {
+		return fmt.Errorf("directory is required")
+	}
+	if p.PartitionInterval.Duration <= 0 {
+		return fmt.Errorf("partition_interval must be positive")
+	}
+	if p.MaxBufferTime.Duration < 0 {
+		return fmt.Errorf("max_buffer_time must not be negative")
+	}
+	if p.MaxRows <= 0 {
+		return fmt.Errorf("max_rows must be positive")
+	}
+	if p.TimeColumn == "" {
+		return fmt.Errorf("time_column is required")
+	}
+
+	var err error
+	p.compressor, err = newCompressor(p.Compression)
+	return err
+}
+
+func (p *Parquet) Connect() error {
+	return os.MkdirAll(p.Directory, 0755)
+}
+
+// Close writes the files of all partitions.
+func (p *Parquet) Close() error {
+	return p.finalize(func(*partition) bool { return true })
+}
+
+// Write adds the metrics to their partitions.  The files of a partition are
+// written during the next write once its window has ended, once it holds
+// max_rows metrics, or once its metrics are held for max_buffer_time.
+func (p *Parquet) Write(metrics []telegraf.Metric) error {
+	// Finalize before adding the new metrics, if this fails the metrics will
+	// be retried without being added twice.
+	now := p.now()
+	err := p.finalize(func(part *partition) bool {
+		if p.MaxBufferTime.Duration > 0 && !now.Before(part.since.Add(p.MaxBufferTime.Duration)) {
+			return true
+		}
+		return !now.Before(part.end.Add(p.FinalizeDelay.Duration))
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, m := range metrics {
+		start := m.Time().UTC().Truncate(p.PartitionInterval.Duration)
+		key := partitionKey{measurement: m.Name(), start: start.UnixNano()}
+
+		part, ok := p.partitions[key]
+		if !ok {
+			part = &partition{
+				dir: filepath.Join(p.Directory,
+					"measurement="+escapePathSegment(m.Name()),
+					filepath.FromSlash(start.Format(p.PartitionFormat))),
+				end: start.Add(p.PartitionInterval.Duration),
+			}
+			p.partitions[key] = part
+		}
+
+		if len(part.metrics) == 0 {
+			part.since = now
+		}
+		part.metrics = append(part.metrics, m.Copy())
+		if len(part.metrics) >= p.MaxRows {
+			part.full = append(part.full, part.metrics)
+			part.metrics = nil
+		}
+	}
+	return nil
+}
+
+// finalize writes the full batches of all partitions, and the remaining
+// metrics of the partitions that have ended or are held for too long.  The
+// metrics of a partition are kept if its files could not be written, so they
+// are retried on the next call.
+func (p *Parquet) finalize(ended func(*partition) bool) error {
+	// Write the partitions in a stable order
+	keys := make([]partitionKey, 0, len(p.partitions))
+	for key := range p.partitions {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].start != keys[j].start {
+			return keys[i].start < keys[j].start
+		}
+		return keys[i].measurement < keys[j].measurement
+	})
+
+	var lastErr error
+	for _, key := range keys {
+		part := p.partitions[key]
+		if len(part.metrics) > 0 && ended(part) {
+			part.full = append(part.full, part.metrics)
+			part.metrics = nil
+		}
+
+		for len(part.full) > 0 {
+			if err := p.writePartitionFile(part.dir, part.full[0]); err != nil {
+				log.Printf("E! [outputs.parquet] Could not write file to %q: %v", part.dir, err)
+				lastErr = err
+				break
+			}
+			part.full = part.full[1:]
+		}
+
+		if len(part.full) == 0 && len(part.metrics) == 0 {
+			delete(p.partitions, key)
+		}
+	}
+	return lastErr
+}
+
+// writePartitionFile writes the metrics to a new file in the directory.  The
+// file is written under a temporary name and renamed once complete, so
+// readers never see partial files.
+func (p *Parquet) writePartitionFile(dir string, metrics []telegraf.Metric) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	p.seq++
+	name := fmt.Sprintf("part-%d-%d.parquet", p.now().UnixNano(), p.seq)
+
+	f, err := os.Create(filepath.Join(dir, tempPrefix+name))
+	if err != nil {
+		return err
+	}
+
+	columns := schemaOf(p.TimeColumn, metrics)
+	err = writeFile(f, columns, metrics, p.compressor)
+	if err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), filepath.Join(dir, name))
+}
+
+// escapePathSegment escapes a measurement name for use as a directory name.
+func escapePathSegment(s string) string {
+	s = url.PathEscape(s)
+	if strings.HasPrefix(s, ".") {
+		s = "%2E" + s[1:]
+	}
+	return s
+}
+
+func newParquet() *Parquet {
+	return &Parquet{
+		PartitionInterval: internal.Duration{Duration: time.Hour},
+		PartitionFormat:   "date=2006-01-02/hour=15",
+		FinalizeDelay:     internal.Duration{Duration: time.Minute},
+		MaxRows:           100000,
+		TimeColumn:        "time",
+		Compression:       "snappy",
+		partitions:        make(map[partitionKey]*partition),
+		now:               time.Now,
+	}
+}
+
+func init() {
+	outputs.Add("parquet", func() telegraf.Output {
+		return newParquet()
+	})
+}
@@ -0,0 +1,519 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// thriftStruct is a decoded Thrift struct, indexed by field id.
+type thriftStruct map[int16]interface{}
+
+func readThriftValue(t *testing.T, p *thrift.TCompactProtocol, typ thrift.TType) interface{} {
+	var v interface{}
+	var err error
+	switch typ {
+	case thrift.BOOL:
+		v, err = p.ReadBool()
+	case thrift.BYTE:
+		v, err = p.ReadByte()
+	case thrift.I32:
+		v, err = p.ReadI32()
+	case thrift.I64:
+		v, err = p.ReadI64()
+	case thrift.STRING:
+		v, err = p.ReadString()
+	case thrift.STRUCT:
+		v = readThriftStruct(t, p)
+	case thrift.LIST:
+		elemType, size, err := p.ReadListBegin()
+		require.NoError(t, err)
+		list := make([]interface{}, 0, size)
+		for i := 0; i < size; i++ {
+			list = append(list, readThriftValue(t, p, elemType))
+		}
+		v = list
+	default:
+		t.Fatalf("unexpected thrift type %v", typ)
+	}
+	require.NoError(t, err)
+	return v
+}
+
+func readThriftStruct(t *testing.T, p *thrift.TCompactProtocol) thriftStruct {
+	_, err := p.ReadStructBegin()
+	require.NoError(t, err)
+
+	s := make(thriftStruct)
+	for {
+		_, typ, id, err := p.ReadFieldBegin()
+		require.NoError(t, err)
+		if typ == thrift.STOP {
+			break
+		}
+		s[id] = readThriftValue(t, p, typ)
+	}
+	require.NoError(t, p.ReadStructEnd())
+	return s
+}
+
+// decodeThrift decodes a struct from the start of buf and returns it with
+// the number of bytes read.
+func decodeThrift(t *testing.T, buf []byte) (thriftStruct, int) {
+	mem := thrift.NewTMemoryBuffer()
+	mem.Write(buf)
+	s := readThriftStruct(t, thrift.NewTCompactProtocol(mem))
+	return s, len(buf) - mem.Len()
+}
+
+// readFile reads a file written by writeFile and returns the column names and
+// the rows.
+func readFile(t *testing.T, filename string) ([]string, []map[string]interface{}) {
+	buf, err := ioutil.ReadFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, magic, string(buf[:4]))
+	require.Equal(t, magic, string(buf[len(buf)-4:]))
+
+	footerLen := int(binary.LittleEndian.Uint32(buf[len(buf)-8:]))
+	meta, _ := decodeThrift(t, buf[len(buf)-8-footerLen:len(buf)-8])
+
+	numRows := int(meta[3].(int64))
+	rows := make([]map[string]interface{}, numRows)
+	for i := range rows {
+		rows[i] = make(map[string]interface{})
+	}
+
+	schema := meta[2].([]interface{})[1:]
+	rowGroup := meta[4].([]interface{})[0].(thriftStruct)
+	chunks := rowGroup[1].([]interface{})
+
+	var names []string
+	for i, element := range schema {
+		element := element.(thriftStruct)
+		name := element[4].(string)
+		names = append(names, name)
+
+		chunk := chunks[i].(thriftStruct)[3].(thriftStruct)
+		offset := int(chunk[9].(int64))
+		header, n := decodeThrift(t, buf[offset:])
+		page := buf[offset+n : offset+n+int(header[3].(int32))]
+
+		switch chunk[4].(int32) {
+		case codecSnappy:
+			page, err = snappy.Decode(nil, page)
+			require.NoError(t, err)
+		case codecGzip:
+			r, err := gzip.NewReader(bytes.NewReader(page))
+			require.NoError(t, err)
+			page, err = ioutil.ReadAll(r)
+			require.NoError(t, err)
+		}
+		require.Equal(t, int(header[2].(int32)), len(page))
+
+		defined := make([]bool, numRows)
+		if element[3].(int32) == repetitionRequired {
+			for j := range defined {
+				defined[j] = true
+			}
+		} else {
+			levelsLen := int(binary.LittleEndian.Uint32(page))
+			levels := page[4 : 4+levelsLen]
+			page = page[4+levelsLen:]
+			for j := 0; len(levels) > 0; {
+				header, n := binary.Uvarint(levels)
+				require.Equal(t, uint64(0), header&1, "bit-packed run")
+				for k := 0; k < int(header>>1); k++ {
+					defined[j] = levels[n] == 1
+					j++
+				}
+				levels = levels[n+1:]
+			}
+		}
+
+		bit := 0
+		for j := range rows {
+			if !defined[j] {
+				continue
+			}
+			switch element[1].(int32) {
+			case typeBoolean:
+				rows[j][name] = page[bit/8]&(1<<uint(bit%8)) != 0
+				bit++
+			case typeInt64:
+				v := binary.LittleEndian.Uint64(page)
+				if _, ok := element[6]; ok {
+					rows[j][name] = v
+				} else {
+					rows[j][name] = int64(v)
+				}
+				page = page[8:]
+			case typeDouble:
+				rows[j][name] = math.Float64frombits(binary.LittleEndian.Uint64(page))
+				page = page[8:]
+			case typeByteArray:
+				l := int(binary.LittleEndian.Uint32(page))
+				rows[j][name] = string(page[4 : 4+l])
+				page = page[4+l:]
+			}
+		}
+	}
+	return names, rows
+}
+
+// listFiles returns the files below the directory, relative to it.
+func listFiles(t *testing.T, dir string) []string {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return err
+	})
+	require.NoError(t, err)
+	sort.Strings(files)
+	return files
+}
+
+func newTestParquet(t *testing.T) (*Parquet, func()) {
+	dir, err := ioutil.TempDir("", "parquet")
+	require.NoError(t, err)
+
+	plugin := newParquet()
+	plugin.Directory = dir
+	plugin.now = func() time.Time { return time.Unix(1000000, 0) }
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	return plugin, func() { os.RemoveAll(dir) }
+}
+
+func TestWriteAndRead(t *testing.T) {
+	for _, compression := range []string{"none", "snappy", "gzip"} {
+		t.Run(compression, func(t *testing.T) {
+			plugin, cleanup := newTestParquet(t)
+			defer cleanup()
+			plugin.Compression = compression
+			require.NoError(t, plugin.Init())
+
+			metrics := []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{"host": "a"},
+					map[string]interface{}{
+						"idle":    99.5,
+						"count":   int64(-3),
+						"bytes":   uint64(math.MaxUint64),
+						"up":      true,
+						"message": "hello",
+					},
+					time.Unix(0, 1),
+				),
+				testutil.MustMetric("cpu",
+					map[string]string{"cpu": "cpu0"},
+					map[string]interface{}{
+						"idle": 1.0,
+						"up":   false,
+					},
+					time.Unix(0, 2),
+				),
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"up": true,
+					},
+					time.Unix(0, 3),
+				),
+			}
+			require.NoError(t, plugin.Write(metrics))
+			require.NoError(t, plugin.Close())
+
+			files := listFiles(t, plugin.Directory)
+			require.Len(t, files, 1)
+			require.True(t, strings.HasPrefix(files[0], "measurement=cpu/date=1970-01-01/hour=00/part-"))
+			require.True(t, strings.HasSuffix(files[0], ".parquet"))
+
+			names, rows := readFile(t, filepath.Join(plugin.Directory, files[0]))
+			require.Equal(t, []string{"time", "bytes", "count", "cpu", "host", "idle", "message", "up"}, names)
+			require.Equal(t, []map[string]interface{}{
+				{
+					"time":    int64(1),
+					"host":    "a",
+					"idle":    99.5,
+					"count":   int64(-3),
+					"bytes":   uint64(math.MaxUint64),
+					"up":      true,
+					"message": "hello",
+				},
+				{
+					"time": int64(2),
+					"cpu":  "cpu0",
+					"idle": 1.0,
+					"up":   false,
+				},
+				{
+					"time": int64(3),
+					"up":   true,
+				},
+			}, rows)
+		})
+	}
+}
+
+// testdata/cpu.parquet was checked with the Parquet reader of Apache Arrow,
+// which reads the columns as:
+//
+//	time: timestamp[ns, tz=UTC] [1560540094000000001 1560540094000000002 1560540094000000003]
+//	bytes: uint64 [18446744073709551615 null null]
+//	count: int64 [-3 null null]
+//	cpu: utf8 [null "cpu0" null]
+//	host: utf8 ["a" null null]
+//	idle: float64 [99.5 1 null]
+//	message: utf8 ["hello" null null]
+//	up: bool [true false true]
+func TestReferenceFile(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{
+				"idle":    99.5,
+				"count":   int64(-3),
+				"bytes":   uint64(math.MaxUint64),
+				"up":      true,
+				"message": "hello",
+			},
+			time.Unix(1560540094, 1),
+		),
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{
+				"idle": 1.0,
+				"up":   false,
+			},
+			time.Unix(1560540094, 2),
+		),
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{
+				"up": true,
+			},
+			time.Unix(1560540094, 3),
+		),
+	}
+
+	comp, err := newCompressor("snappy")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeFile(&buf, schemaOf("time", metrics), metrics, comp))
+
+	expected, err := ioutil.ReadFile("testdata/cpu.parquet")
+	require.NoError(t, err)
+	require.Equal(t, expected, buf.Bytes())
+}
+
+func TestMixedTypes(t *testing.T) {
+	plugin, cleanup := newTestParquet(t)
+	defer cleanup()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("m",
+			map[string]string{"value": "tag"},
+			map[string]interface{}{"number": int64(1), "mixed": int64(1)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("m",
+			map[string]string{},
+			map[string]interface{}{"number": 1.5, "mixed": "a", "value": int64(3)},
+			time.Unix(0, 0),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Close())
+
+	files := listFiles(t, plugin.Directory)
+	require.Len(t, files, 1)
+
+	_, rows := readFile(t, filepath.Join(plugin.Directory, files[0]))
+	require.Equal(t, []map[string]interface{}{
+		{"time": int64(0), "number": 1.0, "mixed": "1"},
+		{"time": int64(0), "number": 1.5, "mixed": "a", "value": int64(3)},
+	}, rows)
+}
+
+func TestPartitioning(t *testing.T) {
+	plugin, cleanup := newTestParquet(t)
+	defer cleanup()
+
+	now := time.Date(2019, 6, 14, 13, 30, 0, 0, time.UTC)
+	plugin.now = func() time.Time { return now }
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"v": 1.0},
+			time.Date(2019, 6, 14, 12, 59, 0, 0, time.UTC)),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"v": 2.0},
+			time.Date(2019, 6, 14, 13, 1, 0, 0, time.UTC)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"v": 3.0},
+			time.Date(2019, 6, 14, 13, 2, 0, 0, time.UTC)),
+		testutil.MustMetric("a/b", map[string]string{}, map[string]interface{}{"v": 4.0},
+			time.Date(2019, 6, 14, 13, 3, 0, 0, time.UTC)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.Empty(t, listFiles(t, plugin.Directory))
+
+	// The window of the 12:00 partition has ended
+	require.NoError(t, plugin.Write(nil))
+	files := listFiles(t, plugin.Directory)
+	require.Len(t, files, 1)
+	require.True(t, strings.HasPrefix(files[0], "measurement=cpu/date=2019-06-14/hour=12/part-"))
+
+	// A late metric is written to a new file in the same partition
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"v": 5.0},
+			time.Date(2019, 6, 14, 12, 58, 0, 0, time.UTC)),
+	}))
+	require.NoError(t, plugin.Write(nil))
+	require.Len(t, listFiles(t, plugin.Directory), 2)
+
+	// The 13:00 windows are written after the finalize delay
+	now = time.Date(2019, 6, 14, 14, 0, 30, 0, time.UTC)
+	require.NoError(t, plugin.Write(nil))
+	require.Len(t, listFiles(t, plugin.Directory), 2)
+
+	now = time.Date(2019, 6, 14, 14, 1, 0, 0, time.UTC)
+	require.NoError(t, plugin.Write(nil))
+	files = listFiles(t, plugin.Directory)
+	require.Len(t, files, 5)
+	require.True(t, strings.HasPrefix(files[0], "measurement=a%2Fb/date=2019-06-14/hour=13/part-"))
+	require.True(t, strings.HasPrefix(files[3], "measurement=cpu/date=2019-06-14/hour=13/part-"))
+	require.True(t, strings.HasPrefix(files[4], "measurement=mem/date=2019-06-14/hour=13/part-"))
+	require.Empty(t, plugin.partitions)
+}
+
+func TestMaxRows(t *testing.T) {
+	plugin, cleanup := newTestParquet(t)
+	defer cleanup()
+	plugin.MaxRows = 2
+	plugin.PartitionInterval = internal.Duration{Duration: 24 * time.Hour}
+	plugin.PartitionFormat = "date=2006-01-02"
+	plugin.now = func() time.Time { return time.Unix(0, 0) }
+
+	var metrics []telegraf.Metric
+	for i := 0; i < 5; i++ {
+		metrics = append(metrics, testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"v": int64(i)},
+			time.Unix(int64(i), 0),
+		))
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.Empty(t, listFiles(t, plugin.Directory))
+
+	// Full files are written on the next write, the rest on close
+	require.NoError(t, plugin.Write(nil))
+	require.Len(t, listFiles(t, plugin.Directory), 2)
+
+	require.NoError(t, plugin.Close())
+	files := listFiles(t, plugin.Directory)
+	require.Len(t, files, 3)
+
+	var total int
+	for _, file := range files {
+		require.True(t, strings.HasPrefix(file, "measurement=cpu/date=1970-01-01/part-"))
+		_, rows := readFile(t, filepath.Join(plugin.Directory, file))
+		total += len(rows)
+	}
+	require.Equal(t, 5, total)
+}
+
+func TestMaxBufferTime(t *testing.T) {
+	plugin, cleanup := newTestParquet(t)
+	defer cleanup()
+	plugin.MaxBufferTime = internal.Duration{Duration: 10 * time.Second}
+
+	now := time.Date(2019, 6, 14, 13, 30, 0, 0, time.UTC)
+	plugin.now = func() time.Time { return now }
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"v": 1.0},
+			time.Date(2019, 6, 14, 13, 29, 0, 0, time.UTC)),
+	}))
+
+	now = now.Add(5 * time.Second)
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"v": 2.0},
+			time.Date(2019, 6, 14, 13, 29, 5, 0, time.UTC)),
+	}))
+	require.Empty(t, listFiles(t, plugin.Directory))
+
+	// The window has not ended but the first metric is held for too long
+	now = now.Add(5 * time.Second)
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"v": 3.0},
+			time.Date(2019, 6, 14, 13, 29, 10, 0, time.UTC)),
+	}))
+	files := listFiles(t, plugin.Directory)
+	require.Len(t, files, 1)
+	_, rows := readFile(t, filepath.Join(plugin.Directory, files[0]))
+	require.Len(t, rows, 2)
+
+	// The time is counted from the first metric added after the write
+	now = now.Add(9 * time.Second)
+	require.NoError(t, plugin.Write(nil))
+	require.Len(t, listFiles(t, plugin.Directory), 1)
+
+	now = now.Add(time.Second)
+	require.NoError(t, plugin.Write(nil))
+	require.Len(t, listFiles(t, plugin.Directory), 2)
+	require.Empty(t, plugin.partitions)
+}
+
+func TestWriteErrorKeepsMetrics(t *testing.T) {
+	plugin, cleanup := newTestParquet(t)
+	defer cleanup()
+
+	require.NoError(t, plugin.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"v": 1.0},
+			time.Unix(0, 0)),
+	}))
+
+	// Block the creation of the measurement directory
+	require.NoError(t, ioutil.WriteFile(filepath.Join(plugin.Directory, "measurement=cpu"), nil, 0644))
+	require.Error(t, plugin.Write(nil))
+	require.Len(t, plugin.partitions, 1)
+
+	require.NoError(t, os.Remove(filepath.Join(plugin.Directory, "measurement=cpu")))
+	require.NoError(t, plugin.Write(nil))
+	require.Empty(t, plugin.partitions)
+
+	files := listFiles(t, plugin.Directory)
+	require.Len(t, files, 1)
+	require.False(t, strings.Contains(files[0], tempPrefix))
+}
+
+func TestInitErrors(t *testing.T) {
+	plugin := newParquet()
+	require.Error(t, plugin.Init())
+
+	plugin.Directory = "/tmp"
+	plugin.Compression = "lz4"
+	require.Error(t, plugin.Init())
+
+	plugin.Compression = "none"
+	plugin.MaxBufferTime = internal.Duration{Duration: -time.Second}
+	require.Error(t, plugin.Init())
+}
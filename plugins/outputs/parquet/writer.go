@@ -0,0 +1,551 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+)
+
+// This file implements the subset of the Parquet file format needed to write
+// metrics: a single row group with one PLAIN encoded data page per column.
+// The file metadata is encoded with the Thrift compact protocol, see
+// https://github.com/apache/parquet-format for the format definition.
+
+const magic = "PAR1"
+
+// Physical types
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+)
+
+// Converted types
+const (
+	convertedUTF8   = 0
+	convertedUint64 = 14
+)
+
+// Repetition types
+const (
+	repetitionRequired = 0
+	repetitionOptional = 1
+)
+
+// Encodings
+const (
+	encodingPlain = 0
+	encodingRLE   = 3
+)
+
+// Compression codecs
+const (
+	codecUncompressed = 0
+	codecSnappy       = 1
+	codecGzip         = 2
+)
+
+const pageTypeData = 0
+
+// kind is the type of the values stored in a column.
+type kind int
+
+const (
+	kindTimestamp kind = iota
+	kindInteger
+	kindUnsigned
+	kindFloat
+	kindBoolean
+	kindString
+)
+
+// column is a column of a file, the column of the timestamp must be first.
+type column struct {
+	name string
+	kind kind
+}
+
+// compressor compresses page data with a Parquet compression codec.
+type compressor struct {
+	codec    int32
+	compress func([]byte) ([]byte, error)
+}
+
+func newCompressor(name string) (*compressor, error) {
+	switch name {
+	case "", "none":
+		return &compressor{
+			codec:    codecUncompressed,
+			compress: func(b []byte) ([]byte, error) { return b, nil },
+		}, nil
+	case "snappy":
+		return &compressor{
+			codec:    codecSnappy,
+			compress: func(b []byte) ([]byte, error) { return snappy.Encode(nil, b), nil },
+		}, nil
+	case "gzip":
+		return &compressor{
+			codec: codecGzip,
+			compress: func(b []byte) ([]byte, error) {
+				var buf bytes.Buffer
+				w := gzip.NewWriter(&buf)
+				if _, err := w.Write(b); err != nil {
+					return nil, err
+				}
+				if err := w.Close(); err != nil {
+					return nil, err
+				}
+				return buf.Bytes(), nil
+			},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", name)
+}
+
+// schemaOf returns the columns needed to store the metrics: the timestamp
+// followed by the tags and fields sorted by name.  Fields take precedence over
+// tags with the same name.  When a field has values of different types the
+// column is a float column if all values are numbers and a string column
+// otherwise.
+func schemaOf(timeColumn string, metrics []telegraf.Metric) []column {
+	kinds := make(map[string]kind)
+	fields := make(map[string]bool)
+	for _, m := range metrics {
+		for _, tag := range m.TagList() {
+			if !fields[tag.Key] {
+				kinds[tag.Key] = kindString
+			}
+		}
+		for _, field := range m.FieldList() {
+			k := kindOf(field.Value)
+			if existing, ok := kinds[field.Key]; ok && fields[field.Key] {
+				k = mergeKinds(existing, k)
+			}
+			kinds[field.Key] = k
+			fields[field.Key] = true
+		}
+	}
+	delete(kinds, timeColumn)
+
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	columns := make([]column, 0, len(names)+1)
+	columns = append(columns, column{name: timeColumn, kind: kindTimestamp})
+	for _, name := range names {
+		columns = append(columns, column{name: name, kind: kinds[name]})
+	}
+	return columns
+}
+
+func kindOf(v interface{}) kind {
+	switch v.(type) {
+	case int64:
+		return kindInteger
+	case uint64:
+		return kindUnsigned
+	case float64:
+		return kindFloat
+	case bool:
+		return kindBoolean
+	}
+	return kindString
+}
+
+func isNumber(k kind) bool {
+	return k == kindInteger || k == kindUnsigned || k == kindFloat
+}
+
+func mergeKinds(a, b kind) kind {
+	switch {
+	case a == b:
+		return a
+	case isNumber(a) && isNumber(b):
+		return kindFloat
+	}
+	return kindString
+}
+
+// value returns the value of the column for the metric converted to the type
+// of the column, and false if the metric has no value for the column.
+func (c *column) value(m telegraf.Metric) (interface{}, bool) {
+	if c.kind == kindTimestamp {
+		return m.Time().UnixNano(), true
+	}
+
+	v, ok := m.GetField(c.name)
+	if !ok {
+		tag, ok := m.GetTag(c.name)
+		if !ok || c.kind != kindString {
+			return nil, false
+		}
+		return tag, true
+	}
+
+	switch c.kind {
+	case kindFloat:
+		switch v := v.(type) {
+		case int64:
+			return float64(v), true
+		case uint64:
+			return float64(v), true
+		}
+	case kindString:
+		switch v := v.(type) {
+		case int64:
+			return strconv.FormatInt(v, 10), true
+		case uint64:
+			return strconv.FormatUint(v, 10), true
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+	}
+	return v, true
+}
+
+// writeFile writes the metrics as a Parquet file with the given columns.
+func writeFile(w io.Writer, columns []column, metrics []telegraf.Metric, comp *compressor) error {
+	cw := &countingWriter{w: w}
+	if _, err := io.WriteString(cw, magic); err != nil {
+		return err
+	}
+
+	chunks := make([]*columnChunk, 0, len(columns))
+	for i := range columns {
+		chunk, err := writeColumn(cw, &columns[i], metrics, comp)
+		if err != nil {
+			return err
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	footer := encodeFileMetaData(columns, chunks, int64(len(metrics)), comp.codec)
+	if _, err := cw.Write(footer); err != nil {
+		return err
+	}
+
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+	if _, err := cw.Write(length[:]); err != nil {
+		return err
+	}
+	_, err := io.WriteString(cw, magic)
+	return err
+}
+
+// columnChunk is the location and size of a written column.
+type columnChunk struct {
+	offset           int64
+	numValues        int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// writeColumn writes the values of the column as a single data page.
+func writeColumn(w *countingWriter, col *column, metrics []telegraf.Metric, comp *compressor) (*columnChunk, error) {
+	var values bytes.Buffer
+	levels := make([]bool, 0, len(metrics))
+	var bits []bool
+	for _, m := range metrics {
+		v, ok := col.value(m)
+		levels = append(levels, ok)
+		if !ok {
+			continue
+		}
+
+		switch v := v.(type) {
+		case int64:
+			binary.Write(&values, binary.LittleEndian, v)
+		case uint64:
+			binary.Write(&values, binary.LittleEndian, v)
+		case float64:
+			binary.Write(&values, binary.LittleEndian, math.Float64bits(v))
+		case bool:
+			bits = append(bits, v)
+		case string:
+			binary.Write(&values, binary.LittleEndian, uint32(len(v)))
+			values.WriteString(v)
+		}
+	}
+	if col.kind == kindBoolean {
+		values.Write(packBits(bits))
+	}
+
+	var page bytes.Buffer
+	if col.kind != kindTimestamp {
+		rle := encodeLevels(levels)
+		binary.Write(&page, binary.LittleEndian, uint32(len(rle)))
+		page.Write(rle)
+	}
+	page.Write(values.Bytes())
+
+	compressed, err := comp.compress(page.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	header := encodePageHeader(int32(len(metrics)), int32(page.Len()), int32(len(compressed)))
+
+	chunk := &columnChunk{
+		offset:           w.n,
+		numValues:        int64(len(metrics)),
+		uncompressedSize: int64(len(header) + page.Len()),
+		compressedSize:   int64(len(header) + len(compressed)),
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(compressed); err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// packBits packs booleans into bytes, least significant bit first.
+func packBits(bits []bool) []byte {
+	buf := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		if b {
+			buf[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return buf
+}
+
+// encodeLevels encodes definition levels with a bit width of one using the
+// RLE/bit-packing hybrid encoding.  Only RLE runs are used.
+func encodeLevels(levels []bool) []byte {
+	var buf []byte
+	var varint [binary.MaxVarintLen64]byte
+	for i := 0; i < len(levels); {
+		j := i + 1
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+
+		n := binary.PutUvarint(varint[:], uint64(j-i)<<1)
+		buf = append(buf, varint[:n]...)
+		if levels[i] {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		i = j
+	}
+	return buf
+}
+
+func encodePageHeader(numValues, uncompressedSize, compressedSize int32) []byte {
+	e := newThriftEncoder()
+	e.i32(1, pageTypeData)
+	e.i32(2, uncompressedSize)
+	e.i32(3, compressedSize)
+	e.structBegin(5)
+	e.i32(1, numValues)
+	e.i32(2, encodingPlain)
+	e.i32(3, encodingRLE)
+	e.i32(4, encodingRLE)
+	e.structEnd()
+	return e.bytes()
+}
+
+func encodeFileMetaData(columns []column, chunks []*columnChunk, numRows int64, codec int32) []byte {
+	e := newThriftEncoder()
+	e.i32(1, 1)
+
+	e.listBegin(2, thrift.STRUCT, len(columns)+1)
+	e.elemBegin()
+	e.str(4, "schema")
+	e.i32(5, int32(len(columns)))
+	e.elemEnd()
+	for _, col := range columns {
+		e.elemBegin()
+		encodeSchemaElement(e, &col)
+		e.elemEnd()
+	}
+
+	e.i64(3, numRows)
+
+	var totalSize int64
+	for _, chunk := range chunks {
+		totalSize += chunk.uncompressedSize
+	}
+
+	e.listBegin(4, thrift.STRUCT, 1)
+	e.elemBegin()
+	e.listBegin(1, thrift.STRUCT, len(chunks))
+	for i, chunk := range chunks {
+		e.elemBegin()
+		e.i64(2, chunk.offset)
+		e.structBegin(3)
+		e.i32(1, physicalType(columns[i].kind))
+		e.listBegin(2, thrift.I32, 2)
+		e.p.WriteI32(encodingPlain)
+		e.p.WriteI32(encodingRLE)
+		e.listBegin(3, thrift.STRING, 1)
+		e.p.WriteString(columns[i].name)
+		e.i32(4, codec)
+		e.i64(5, chunk.numValues)
+		e.i64(6, chunk.uncompressedSize)
+		e.i64(7, chunk.compressedSize)
+		e.i64(9, chunk.offset)
+		e.structEnd()
+		e.elemEnd()
+	}
+	e.i64(2, totalSize)
+	e.i64(3, numRows)
+	e.elemEnd()
+
+	e.str(6, "telegraf")
+	return e.bytes()
+}
+
+func encodeSchemaElement(e *thriftEncoder, col *column) {
+	e.i32(1, physicalType(col.kind))
+	if col.kind == kindTimestamp {
+		e.i32(3, repetitionRequired)
+	} else {
+		e.i32(3, repetitionOptional)
+	}
+	e.str(4, col.name)
+
+	switch col.kind {
+	case kindTimestamp:
+		// TIMESTAMP(isAdjustedToUTC=true, unit=NANOS)
+		e.structBegin(10)
+		e.structBegin(8)
+		e.boolean(1, true)
+		e.structBegin(2)
+		e.structBegin(3)
+		e.structEnd()
+		e.structEnd()
+		e.structEnd()
+		e.structEnd()
+	case kindUnsigned:
+		e.i32(6, convertedUint64)
+		// INTEGER(bitWidth=64, isSigned=false)
+		e.structBegin(10)
+		e.structBegin(10)
+		e.byte(1, 64)
+		e.boolean(2, false)
+		e.structEnd()
+		e.structEnd()
+	case kindString:
+		e.i32(6, convertedUTF8)
+		// STRING
+		e.structBegin(10)
+		e.structBegin(1)
+		e.structEnd()
+		e.structEnd()
+	}
+}
+
+func physicalType(k kind) int32 {
+	switch k {
+	case kindFloat:
+		return typeDouble
+	case kindBoolean:
+		return typeBoolean
+	case kindString:
+		return typeByteArray
+	}
+	return typeInt64
+}
+
+// thriftEncoder writes Thrift compact protocol structs to a memory buffer.
+// Writes to the buffer cannot fail, so errors are not checked.
+type thriftEncoder struct {
+	buf *thrift.TMemoryBuffer
+	p   *thrift.TCompactProtocol
+}
+
+func newThriftEncoder() *thriftEncoder {
+	buf := thrift.NewTMemoryBuffer()
+	e := &thriftEncoder{buf: buf, p: thrift.NewTCompactProtocol(buf)}
+	e.p.WriteStructBegin("")
+	return e
+}
+
+func (e *thriftEncoder) bytes() []byte {
+	e.p.WriteFieldStop()
+	e.p.WriteStructEnd()
+	e.p.Flush(context.Background())
+	return e.buf.Bytes()
+}
+
+func (e *thriftEncoder) i32(id int16, v int32) {
+	e.p.WriteFieldBegin("", thrift.I32, id)
+	e.p.WriteI32(v)
+}
+
+func (e *thriftEncoder) i64(id int16, v int64) {
+	e.p.WriteFieldBegin("", thrift.I64, id)
+	e.p.WriteI64(v)
+}
+
+func (e *thriftEncoder) byte(id int16, v int8) {
+	e.p.WriteFieldBegin("", thrift.BYTE, id)
+	e.p.WriteByte(v)
+}
+
+func (e *thriftEncoder) boolean(id int16, v bool) {
+	e.p.WriteFieldBegin("", thrift.BOOL, id)
+	e.p.WriteBool(v)
+}
+
+func (e *thriftEncoder) str(id int16, v string) {
+	e.p.WriteFieldBegin("", thrift.STRING, id)
+	e.p.WriteString(v)
+}
+
+func (e *thriftEncoder) structBegin(id int16) {
+	e.p.WriteFieldBegin("", thrift.STRUCT, id)
+	e.p.WriteStructBegin("")
+}
+
+func (e *thriftEncoder) structEnd() {
+	e.p.WriteFieldStop()
+	e.p.WriteStructEnd()
+}
+
+// listBegin starts a list field, the elements follow directly.
+func (e *thriftEncoder) listBegin(id int16, elemType thrift.TType, size int) {
+	e.p.WriteFieldBegin("", thrift.LIST, id)
+	e.p.WriteListBegin(elemType, size)
+}
+
+func (e *thriftEncoder) elemBegin() {
+	e.p.WriteStructBegin("")
+}
+
+func (e *thriftEncoder) elemEnd() {
+	e.structEnd()
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	w.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,297 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// server is a stub of the Redis protocol recording the received commands.
+type server struct {
+	sync.Mutex
+
+	listener net.Listener
+	conns    []net.Conn
+	password string
+	commands [][]string
+}
+
+func newServer(t *testing.T) *server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &server{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.Lock()
+			s.conns = append(s.conns, conn)
+			s.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *server) Close() {
+	s.listener.Close()
+
+	s.Lock()
+	defer s.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *server) url() string {
+	return "tcp://" + s.listener.Addr().String()
+}
+
+func (s *server) serve(conn net.Conn) {
+	defer conn.Close()
+
+	var authenticated bool
+	r := bufio.NewReader(conn)
+	for {
+		cmd, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := conn.Write([]byte(s.reply(cmd, &authenticated))); err != nil {
+			return
+		}
+	}
+}
+
+func (s *server) reply(cmd []string, authenticated *bool) string {
+	s.Lock()
+	defer s.Unlock()
+
+	name := strings.ToLower(cmd[0])
+	if name == "auth" {
+		if cmd[1] != s.password {
+			return "-ERR invalid password\r\n"
+		}
+		*authenticated = true
+		return "+OK\r\n"
+	}
+	if s.password != "" && !*authenticated {
+		return "-NOAUTH Authentication required.\r\n"
+	}
+	if name == "ping" {
+		return "+PONG\r\n"
+	}
+
+	s.commands = append(s.commands, cmd)
+	switch name {
+	case "select":
+		return "+OK\r\n"
+	case "xadd":
+		if cmd[1] == "telegraf:invalid" {
+			return "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+		}
+		id := fmt.Sprintf("%d-0", len(s.commands))
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(id), id)
+	case "zadd":
+		return ":1\r\n"
+	case "zremrangebyscore":
+		return ":0\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func (s *server) received() [][]string {
+	s.Lock()
+	defer s.Unlock()
+
+	commands := s.commands
+	s.commands = nil
+	return commands
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		cmd = append(cmd, string(buf[:size]))
+	}
+	return cmd, nil
+}
+
+func testMetrics() []telegraf.Metric {
+	cpu := testutil.MustMetric("cpu",
+		map[string]string{"cpu": "cpu0", "host": "a"},
+		map[string]interface{}{"state": "ok"},
+		time.Unix(1529708430, 500000000),
+	)
+	cpu.AddField("usage_idle", 42.5)
+
+	mem := testutil.MustMetric("mem",
+		map[string]string{},
+		map[string]interface{}{"swap": true},
+		time.Unix(1529708431, 0),
+	)
+	mem.AddField("used", int64(1))
+
+	return []telegraf.Metric{cpu, mem}
+}
+
+func newPlugin(t *testing.T, s *server) *Redis {
+	plugin := newRedis()
+	plugin.Server = s.url()
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	return plugin
+}
+
+func TestWriteStream(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	plugin := newPlugin(t, s)
+	defer plugin.Close()
+	plugin.MaxLen = 1000
+
+	require.NoError(t, plugin.Write(testMetrics()))
+	require.Equal(t, [][]string{
+		{"xadd", "telegraf:cpu", "maxlen", "~", "1000", "*",
+			"timestamp", "1529708430500000000", "cpu", "cpu0", "host", "a", "state", "ok", "usage_idle", "42.5"},
+		{"xadd", "telegraf:mem", "maxlen", "~", "1000", "*",
+			"timestamp", "1529708431000000000", "swap", "true", "used", "1"},
+	}, s.received())
+}
+
+func TestWriteTimeseries(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	plugin := newRedis()
+	plugin.Server = s.url()
+	plugin.Mode = "timeseries"
+	plugin.Retention.Duration = time.Hour
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := append(testMetrics(), testutil.MustMetric("mem",
+		map[string]string{},
+		map[string]interface{}{"used": int64(2)},
+		time.Unix(1529708432, 0),
+	))
+	require.NoError(t, plugin.Write(metrics))
+
+	commands := s.received()
+	require.Len(t, commands, 7)
+	require.Equal(t, []string{"zadd", "telegraf:cpu,cpu=cpu0,host=a:usage_idle", "1529708430500", "1529708430500000000:42.5"}, commands[0])
+	require.Equal(t, "zremrangebyscore", commands[1][0])
+	require.Equal(t, "telegraf:cpu,cpu=cpu0,host=a:usage_idle", commands[1][1])
+	require.Equal(t, "-inf", commands[1][2])
+	require.True(t, strings.HasPrefix(commands[1][3], "("))
+	require.Equal(t, []string{"zadd", "telegraf:mem:swap", "1529708431000", "1529708431000000000:1"}, commands[2])
+	require.Equal(t, "zremrangebyscore", commands[3][0])
+	require.Equal(t, []string{"zadd", "telegraf:mem:used", "1529708431000", "1529708431000000000:1"}, commands[4])
+	require.Equal(t, "zremrangebyscore", commands[5][0])
+	// The retention is applied once per key and batch.
+	require.Equal(t, []string{"zadd", "telegraf:mem:used", "1529708432000", "1529708432000000000:2"}, commands[6])
+}
+
+func TestKeyTemplate(t *testing.T) {
+	s := newServer(t)
+	defer s.Close()
+
+	plugin := newRedis()
+	plugin.Server = s.url()
+	plugin.Key = `metrics:{{ .Tag "host" }}:{{ .Name }}`
+	plugin.TimestampField = ""
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write(testMetrics()))
+	commands := s.received()
+	require.Len(t, commands, 2)
+	require.Equal(t, []string{"xadd", "metrics:a:cpu", "*"}, commands[0][:3])
+	require.Equal(t, []string{"xadd", "metrics::mem", "*", "swap", "true", "used", "1"}, commands[1])
+}
+
+func TestAuthAndDatabase(t *testing.T) {
+	s := newServer(t)
+	s.password = "secret"
+	defer s.Close()
+
+	plugin := newRedis()
+	plugin.Server = s.url()
+	require.NoError(t, plugin.Init())
+	require.Error(t, plugin.Connect())
+
+	plugin.Server = "tcp://:secret@" + s.listener.Addr().String()
+	plugin.Database = 2
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+	require.Equal(t, [][]string{{"select", "2"}}, s.received())
+}
+
+func TestWriteErrors(t *testing.T) {
+	s := newServer(t)
+
+	plugin := newPlugin(t, s)
+	defer plugin.Close()
+
+	// Commands rejected by the server are dropped.
+	metrics := []telegraf.Metric{testutil.MustMetric("invalid",
+		map[string]string{},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0),
+	)}
+	require.NoError(t, plugin.Write(append(metrics, testMetrics()...)))
+	require.Len(t, s.received(), 3)
+
+	// Connection errors fail the batch.
+	s.Close()
+	require.Error(t, plugin.Write(testMetrics()))
+}
+
+func TestInitErrors(t *testing.T) {
+	plugin := newRedis()
+	plugin.Mode = "hash"
+	require.Error(t, plugin.Init())
+
+	plugin = newRedis()
+	plugin.Key = "{{ .Name"
+	require.Error(t, plugin.Init())
+}
@@ -0,0 +1,158 @@
+package splunk_hec
+
+import (
+	"encoding/json"
+
+	"github.com/influxdata/telegraf"
+)
+
+// event is an event of the HEC event endpoint.
+type event struct {
+	Time       float64                `json:"time"`
+	Host       string                 `json:"host,omitempty"`
+	Index      string                 `json:"index,omitempty"`
+	Source     string                 `json:"source,omitempty"`
+	SourceType string                 `json:"sourcetype,omitempty"`
+	Event      interface{}            `json:"event"`
+	Fields     map[string]interface{} `json:"fields,omitempty"`
+}
+
+// metadata holds the default values of the event metadata.
+type metadata struct {
+	Host       string
+	Index      string
+	Source     string
+	SourceType string
+}
+
+// newEvent returns an event with the metadata of the metric.  The host,
+// index, source and sourcetype tags override the defaults, other tags are
+// added to the indexed fields.
+func newEvent(m telegraf.Metric, defaults metadata) *event {
+	e := &event{
+		Time:       float64(m.Time().UnixNano()) / 1e9,
+		Host:       defaults.Host,
+		Index:      defaults.Index,
+		Source:     defaults.Source,
+		SourceType: defaults.SourceType,
+		Fields:     make(map[string]interface{}),
+	}
+
+	for _, tag := range m.TagList() {
+		switch tag.Key {
+		case "host":
+			e.Host = tag.Value
+		case "index":
+			e.Index = tag.Value
+		case "source":
+			e.Source = tag.Value
+		case "sourcetype":
+			e.SourceType = tag.Value
+		default:
+			e.Fields[tag.Key] = tag.Value
+		}
+	}
+	return e
+}
+
+// metricEvents converts the metric to events of the metrics index.  When
+// multiMetric is set all fields are sent in one event using the multiple
+// metric format, otherwise each field is sent as a separate event.  String
+// fields are skipped and booleans are sent as 0 or 1.
+func metricEvents(m telegraf.Metric, defaults metadata, multiMetric bool) []*event {
+	var events []*event
+	var multi *event
+	for _, field := range m.FieldList() {
+		value, ok := metricValue(field.Value)
+		if !ok {
+			continue
+		}
+
+		if multiMetric {
+			if multi == nil {
+				multi = newEvent(m, defaults)
+				multi.Event = "metric"
+				events = append(events, multi)
+			}
+			multi.Fields["metric_name:"+m.Name()+"."+field.Key] = value
+			continue
+		}
+
+		e := newEvent(m, defaults)
+		e.Event = "metric"
+		e.Fields["metric_name"] = m.Name() + "." + field.Key
+		e.Fields["_value"] = value
+		events = append(events, e)
+	}
+	return events
+}
+
+// logEvent converts the metric to an event with the fields and the
+// measurement name as the event data.
+func logEvent(m telegraf.Metric, defaults metadata) *event {
+	data := make(map[string]interface{}, len(m.FieldList())+1)
+	for _, field := range m.FieldList() {
+		data[field.Key] = field.Value
+	}
+	data["metric_name"] = m.Name()
+
+	e := newEvent(m, defaults)
+	e.Event = data
+	return e
+}
+
+func metricValue(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case string:
+		return nil, false
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return v, true
+	}
+}
+
+// batcher splits encoded events into batches limited by the number of
+// events and the size of the batch.
+type batcher struct {
+	maxEvents int
+	maxSize   int
+
+	batches [][]byte
+	current []byte
+	count   int
+}
+
+// Add adds the event to the current batch, starting a new batch if a limit
+// would be exceeded.  An event larger than the size limit is sent in a batch
+// of its own.
+func (b *batcher) Add(e *event) error {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if b.count > 0 && (b.count >= b.maxEvents || len(b.current)+len(buf) > b.maxSize) {
+		b.flush()
+	}
+	b.current = append(b.current, buf...)
+	b.count++
+	return nil
+}
+
+// Batches returns the batches of events.
+func (b *batcher) Batches() [][]byte {
+	if b.count > 0 {
+		b.flush()
+	}
+	return b.batches
+}
+
+func (b *batcher) flush() {
+	b.batches = append(b.batches, b.current)
+	b.current = nil
+	b.count = 0
+}
@@ -0,0 +1,409 @@
+package splunk_hec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	uuid "github.com/satori/go.uuid"
+)
+
+var sampleConfig = `
+  ## URL of the HTTP Event Collector, without the path.
+  url = "https://localhost:8088"
+
+  ## HEC token.
+  token = ""
+
+  ## Channel identifier (a GUID) sent with each request; a random channel is
+  ## used if empty.
+  # channel = ""
+
+  ## Timeout for each request.
+  # timeout = "5s"
+
+  ## Shape of the sent events, "metric" to send metrics to a metrics index or
+  ## "event" to send the fields of each metric as an event.
+  # event_type = "metric"
+
+  ## Send all fields of a metric in one event using the multiple metric
+  ## format, supported by Splunk 8.0 and later.
+  # multi_metric = false
+
+  ## Default event metadata, overridden by the host, index, source and
+  ## sourcetype tags.
+  # host = ""
+  # index = ""
+  # source = ""
+  # sourcetype = ""
+
+  ## Maximum number of events and size of each request.
+  # max_batch_events = 1000
+  # max_batch_size = "1MB"
+
+  ## HTTP Content-Encoding for the requests, can be set to "gzip" to compress
+  ## the body or "identity" to apply no encoding.
+  # content_encoding = "identity"
+
+  ## Wait for indexer acknowledgement of the events before the metrics are
+  ## considered written.  Requires acknowledgement to be enabled for the token.
+  # use_ack = false
+
+  ## Interval to poll for acknowledgements and the maximum time to wait for
+  ## them; unacknowledged metrics are written again.
+  # ack_interval = "1s"
+  # ack_timeout = "1m"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+const (
+	eventPath = "/services/collector/event"
+	ackPath   = "/services/collector/ack"
+
+	channelHeader = "X-Splunk-Request-Channel"
+)
+
+type SplunkHEC struct {
+	URL             string            `toml:"url"`
+	Token           string            `toml:"token"`
+	Channel         string            `toml:"channel"`
+	Timeout         internal.Duration `toml:"timeout"`
+	EventType       string            `toml:"event_type"`
+	MultiMetric     bool              `toml:"multi_metric"`
+	Host            string            `toml:"host"`
+	Index           string            `toml:"index"`
+	Source          string            `toml:"source"`
+	SourceType      string            `toml:"sourcetype"`
+	MaxBatchEvents  int               `toml:"max_batch_events"`
+	MaxBatchSize    internal.Size     `toml:"max_batch_size"`
+	ContentEncoding string            `toml:"content_encoding"`
+	UseAck          bool              `toml:"use_ack"`
+	AckInterval     internal.Duration `toml:"ack_interval"`
+	AckTimeout      internal.Duration `toml:"ack_timeout"`
+	tls.ClientConfig
+
+	client *http.Client
+
+	// delivered contains the checksums of the batches of a failed write that
+	// were delivered, they are not sent again when the write is retried.
+	delivered map[[sha256.Size]byte]bool
+}
+
+// hecResponse is the response of the event and ack endpoints on errors, and
+// of the event endpoint on success.
+type hecResponse struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckID *int64 `json:"ackId"`
+}
+
+type ackRequest struct {
+	Acks []int64 `json:"acks"`
+}
+
+type ackResponse struct {
+	Acks map[string]bool `json:"acks"`
+}
+
+// hecError is an error response of the collector.
+type hecError struct {
+	StatusCode int
+	Text       string
+	Code       int
+}
+
+func (e *hecError) Error() string {
+	return fmt.Sprintf("received status code %d: %s (code %d)", e.StatusCode, e.Text, e.Code)
+}
+
+func (s *SplunkHEC) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SplunkHEC) Description() string {
+	return "Send metrics to a Splunk HTTP Event Collector"
+}
+
+func (s *SplunkHEC) Init() error {
+	switch s.EventType {
+	case "metric", "event":
+	default:
+		return fmt.Errorf("invalid event_type %q", s.EventType)
+	}
+
+	switch s.ContentEncoding {
+	case "", "identity", "gzip":
+	default:
+		return fmt.Errorf("invalid content_encoding %q", s.ContentEncoding)
+	}
+
+	if s.MaxBatchEvents <= 0 {
+		return fmt.Errorf("max_batch_events must be positive")
+	}
+	if s.MaxBatchSize.Size <= 0 {
+		return fmt.Errorf("max_batch_size must be positive")
+	}
+
+	if s.Channel == "" {
+		s.Channel = uuid.NewV4().String()
+	}
+	s.URL = strings.TrimSuffix(s.URL, "/")
+	s.delivered = make(map[[sha256.Size]byte]bool)
+	return nil
+}
+
+func (s *SplunkHEC) Connect() error {
+	tlsCfg, err := s.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	s.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: s.Timeout.Duration,
+	}
+	return nil
+}
+
+func (s *SplunkHEC) Close() error {
+	return nil
+}
+
+// Write sends the metrics in batches.  With use_ack the metrics are only
+// considered written once all batches are acknowledged.  If the write fails,
+// the batches that were delivered are skipped when the metrics are retried.
+func (s *SplunkHEC) Write(metrics []telegraf.Metric) error {
+	defaults := metadata{
+		Host:       s.Host,
+		Index:      s.Index,
+		Source:     s.Source,
+		SourceType: s.SourceType,
+	}
+
+	b := &batcher{maxEvents: s.MaxBatchEvents, maxSize: int(s.MaxBatchSize.Size)}
+	for _, m := range metrics {
+		var events []*event
+		if s.EventType == "event" {
+			events = []*event{logEvent(m, defaults)}
+		} else {
+			events = metricEvents(m, defaults, s.MultiMetric)
+		}
+
+		for _, e := range events {
+			if err := b.Add(e); err != nil {
+				log.Printf("E! [outputs.splunk_hec] Could not serialize metric %s: %v", m.Name(), err)
+			}
+		}
+	}
+
+	var writeErr error
+	pending := make(map[int64][sha256.Size]byte)
+	for _, batch := range b.Batches() {
+		sum := sha256.Sum256(batch)
+		if s.delivered[sum] {
+			continue
+		}
+
+		resp, err := s.send(batch)
+		if err != nil {
+			if hecErr, ok := err.(*hecError); ok && hecErr.StatusCode == http.StatusBadRequest {
+				// Invalid events will be rejected again when retried.
+				log.Printf("E! [outputs.splunk_hec] Failed to write events: %v", err)
+				s.delivered[sum] = true
+				continue
+			}
+			writeErr = err
+			break
+		}
+
+		if s.UseAck && resp.AckID == nil {
+			log.Printf("W! [outputs.splunk_hec] No acknowledgement ID received, " +
+				"indexer acknowledgement may be disabled for the token")
+		}
+		if !s.UseAck || resp.AckID == nil {
+			s.delivered[sum] = true
+			continue
+		}
+		pending[*resp.AckID] = sum
+	}
+
+	// Wait for the batches that were sent even if a later batch failed, so
+	// they are not sent again.
+	if len(pending) > 0 {
+		ackIDs := make([]int64, 0, len(pending))
+		for id := range pending {
+			ackIDs = append(ackIDs, id)
+		}
+		acked, err := s.waitForAcks(ackIDs)
+		for _, id := range acked {
+			s.delivered[pending[id]] = true
+		}
+		if writeErr == nil {
+			writeErr = err
+		}
+	}
+
+	if writeErr != nil {
+		return writeErr
+	}
+	s.delivered = make(map[[sha256.Size]byte]bool)
+	return nil
+}
+
+// send sends a batch of events to the event endpoint.
+func (s *SplunkHEC) send(batch []byte) (*hecResponse, error) {
+	var body io.Reader = bytes.NewReader(batch)
+	if s.ContentEncoding == "gzip" {
+		var err error
+		body, err = internal.CompressWithGzip(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := s.newRequest(eventPath, body)
+	if err != nil {
+		return nil, err
+	}
+	if s.ContentEncoding == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp := &hecResponse{}
+	if err := s.do(req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// waitForAcks polls the ack endpoint until all IDs are acknowledged or the
+// ack timeout expires.  It returns the acknowledged IDs, and an error if not
+// all IDs were acknowledged.
+func (s *SplunkHEC) waitForAcks(ackIDs []int64) ([]int64, error) {
+	deadline := time.Now().Add(s.AckTimeout.Duration)
+	pending := ackIDs
+	var acked []int64
+	for {
+		resp, err := s.queryAcks(pending)
+		if err != nil {
+			return acked, err
+		}
+
+		var remaining []int64
+		for _, id := range pending {
+			if resp[strconv.FormatInt(id, 10)] {
+				acked = append(acked, id)
+			} else {
+				remaining = append(remaining, id)
+			}
+		}
+		pending = remaining
+		if len(pending) == 0 {
+			return acked, nil
+		}
+
+		if time.Now().Add(s.AckInterval.Duration).After(deadline) {
+			return acked, fmt.Errorf("%d of %d batches not acknowledged within %s",
+				len(pending), len(ackIDs), s.AckTimeout.Duration)
+		}
+		time.Sleep(s.AckInterval.Duration)
+	}
+}
+
+func (s *SplunkHEC) queryAcks(ackIDs []int64) (map[string]bool, error) {
+	body, err := json.Marshal(ackRequest{Acks: ackIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.newRequest(ackPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ackResponse{}
+	if err := s.do(req, resp); err != nil {
+		return nil, err
+	}
+	return resp.Acks, nil
+}
+
+func (s *SplunkHEC) newRequest(path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest("POST", s.URL+path+"?channel="+s.Channel, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", internal.ProductToken())
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Splunk "+s.Token)
+	req.Header.Set(channelHeader, s.Channel)
+	return req, nil
+}
+
+// do sends the request and decodes the response into v.
+func (s *SplunkHEC) do(req *http.Request, v interface{}) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		hecErr := &hecError{StatusCode: resp.StatusCode, Text: resp.Status}
+		var errResp hecResponse
+		if err := json.Unmarshal(body, &errResp); err == nil && errResp.Text != "" {
+			hecErr.Text = errResp.Text
+			hecErr.Code = errResp.Code
+		}
+		return hecErr
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("invalid response from %s: %v", req.URL.Path, err)
+	}
+	return nil
+}
+
+func newSplunkHEC() *SplunkHEC {
+	return &SplunkHEC{
+		URL:            "https://localhost:8088",
+		Timeout:        internal.Duration{Duration: 5 * time.Second},
+		EventType:      "metric",
+		MaxBatchEvents: 1000,
+		MaxBatchSize:   internal.Size{Size: 1024 * 1024},
+		AckInterval:    internal.Duration{Duration: time.Second},
+		AckTimeout:     internal.Duration{Duration: time.Minute},
+	}
+}
+
+func init() {
+	outputs.Add("splunk_hec", func() telegraf.Output {
+		return newSplunkHEC()
+	})
+}
@@ -0,0 +1,311 @@
+package splunk_hec
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// collector is a fake HTTP Event Collector.
+type collector struct {
+	sync.Mutex
+
+	status     int
+	ackAfter   int
+	unacked    map[int64]bool
+	batches    [][]map[string]interface{}
+	channels   []string
+	encodings  []string
+	ackQueries int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.Lock()
+	defer c.Unlock()
+
+	if r.Header.Get("Authorization") != "Splunk token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"text":"Invalid token","code":4}`))
+		return
+	}
+	c.channels = append(c.channels, r.Header.Get(channelHeader))
+
+	switch r.URL.Path {
+	case eventPath:
+		if c.status != 0 {
+			w.WriteHeader(c.status)
+			w.Write([]byte(`{"text":"Invalid data format","code":6}`))
+			return
+		}
+
+		var body io.Reader = r.Body
+		c.encodings = append(c.encodings, r.Header.Get("Content-Encoding"))
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			body, _ = gzip.NewReader(r.Body)
+		}
+
+		var events []map[string]interface{}
+		dec := json.NewDecoder(body)
+		for dec.More() {
+			var e map[string]interface{}
+			if err := dec.Decode(&e); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			events = append(events, e)
+		}
+		c.batches = append(c.batches, events)
+		w.Write([]byte(`{"text":"Success","code":0,"ackId":` + strconv.Itoa(len(c.batches)-1) + `}`))
+	case ackPath:
+		var req ackRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		c.ackQueries++
+
+		resp := ackResponse{Acks: make(map[string]bool)}
+		for _, id := range req.Acks {
+			resp.Acks[strconv.FormatInt(id, 10)] = c.ackAfter >= 0 && c.ackQueries > c.ackAfter && !c.unacked[id]
+		}
+		json.NewEncoder(w).Encode(resp)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (c *collector) events() []map[string]interface{} {
+	c.Lock()
+	defer c.Unlock()
+
+	var events []map[string]interface{}
+	for _, batch := range c.batches {
+		events = append(events, batch...)
+	}
+	return events
+}
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu0", "host": "server01"},
+			map[string]interface{}{"usage_idle": 98.5},
+			time.Unix(1529708430, 500000000),
+		),
+		testutil.MustMetric("system",
+			map[string]string{"index": "infra"},
+			map[string]interface{}{"load1": 0.5, "uptime_format": "1 day", "online": true},
+			time.Unix(1529708431, 0),
+		),
+	}
+}
+
+func newPlugin(t *testing.T, url string) *SplunkHEC {
+	plugin := newSplunkHEC()
+	plugin.URL = url + "/"
+	plugin.Token = "token"
+	plugin.Host = "default"
+	plugin.AckInterval.Duration = time.Millisecond
+	plugin.AckTimeout.Duration = time.Second
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	return plugin
+}
+
+func findEvent(t *testing.T, events []map[string]interface{}, name string) map[string]interface{} {
+	for _, e := range events {
+		if fields, ok := e["fields"].(map[string]interface{}); ok && fields["metric_name"] == name {
+			return e
+		}
+	}
+	require.FailNow(t, "event not found", name)
+	return nil
+}
+
+func TestWriteMetric(t *testing.T) {
+	c := &collector{}
+	ts := httptest.NewServer(c)
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	require.NoError(t, plugin.Write(testMetrics()))
+
+	events := c.events()
+	require.Len(t, events, 3)
+	require.Equal(t, map[string]interface{}{
+		"time":  1529708430.5,
+		"host":  "server01",
+		"event": "metric",
+		"fields": map[string]interface{}{
+			"metric_name": "cpu.usage_idle",
+			"_value":      98.5,
+			"cpu":         "cpu0",
+		},
+	}, findEvent(t, events, "cpu.usage_idle"))
+	require.Equal(t, map[string]interface{}{
+		"time":  1529708431.0,
+		"host":  "default",
+		"index": "infra",
+		"event": "metric",
+		"fields": map[string]interface{}{
+			"metric_name": "system.online",
+			"_value":      1.0,
+		},
+	}, findEvent(t, events, "system.online"))
+	findEvent(t, events, "system.load1")
+}
+
+func TestWriteMultiMetric(t *testing.T) {
+	c := &collector{}
+	ts := httptest.NewServer(c)
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	plugin.MultiMetric = true
+	require.NoError(t, plugin.Write(testMetrics()[1:]))
+
+	events := c.events()
+	require.Len(t, events, 1)
+	require.Equal(t, map[string]interface{}{
+		"metric_name:system.load1":  0.5,
+		"metric_name:system.online": 1.0,
+	}, events[0]["fields"])
+}
+
+func TestWriteEvent(t *testing.T) {
+	c := &collector{}
+	ts := httptest.NewServer(c)
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	plugin.EventType = "event"
+	plugin.SourceType = "telegraf"
+	require.NoError(t, plugin.Write(testMetrics()))
+
+	events := c.events()
+	require.Len(t, events, 2)
+	require.Equal(t, map[string]interface{}{
+		"time":       1529708431.0,
+		"host":       "default",
+		"index":      "infra",
+		"sourcetype": "telegraf",
+		"event": map[string]interface{}{
+			"metric_name":   "system",
+			"load1":         0.5,
+			"uptime_format": "1 day",
+			"online":        true,
+		},
+	}, events[1])
+}
+
+func TestBatching(t *testing.T) {
+	c := &collector{}
+	ts := httptest.NewServer(c)
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	plugin.MaxBatchEvents = 2
+	plugin.ContentEncoding = "gzip"
+	require.NoError(t, plugin.Write(testMetrics()))
+	require.Len(t, c.batches, 2)
+	require.Len(t, c.batches[0], 2)
+	require.Len(t, c.batches[1], 1)
+	require.Equal(t, []string{"gzip", "gzip"}, c.encodings)
+
+	c.batches = nil
+	plugin.MaxBatchEvents = 1000
+	plugin.MaxBatchSize.Size = 1
+	require.NoError(t, plugin.Write(testMetrics()))
+	require.Len(t, c.batches, 3)
+}
+
+func TestAck(t *testing.T) {
+	c := &collector{ackAfter: 2}
+	ts := httptest.NewServer(c)
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	plugin.UseAck = true
+	plugin.MaxBatchEvents = 1
+	require.NoError(t, plugin.Write(testMetrics()))
+	require.Equal(t, 3, c.ackQueries)
+
+	for _, channel := range c.channels {
+		require.Equal(t, plugin.Channel, channel)
+	}
+	require.NotEmpty(t, plugin.Channel)
+}
+
+func TestAckTimeout(t *testing.T) {
+	c := &collector{ackAfter: -1}
+	ts := httptest.NewServer(c)
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	plugin.UseAck = true
+	plugin.AckTimeout.Duration = 20 * time.Millisecond
+	require.Error(t, plugin.Write(testMetrics()))
+}
+
+func TestAckPartial(t *testing.T) {
+	c := &collector{unacked: map[int64]bool{1: true}}
+	ts := httptest.NewServer(c)
+	defer ts.Close()
+
+	plugin := newPlugin(t, ts.URL)
+	plugin.UseAck = true
+	plugin.MaxBatchEvents = 1
+	plugin.AckTimeout.Duration = 20 * time.Millisecond
+	require.Error(t, plugin.Write(testMetrics()))
+	require.Len(t, c.batches, 3)
+
+	// Only the unacknowledged batch is sent again
+	c.unacked = nil
+	require.NoError(t, plugin.Write(testMetrics()))
+	require.Len(t, c.batches, 4)
+	require.Equal(t, c.batches[1], c.batches[3])
+
+	// Once written all batches are sent again
+	require.NoError(t, plugin.Write(testMetrics()))
+	require.Len(t, c.batches, 7)
+}
+
+func TestWriteErrors(t *testing.T) {
+	c := &collector{status: http.StatusBadRequest}
+	ts := httptest.NewServer(c)
+	defer ts.Close()
+
+	// Invalid events are dropped
+	plugin := newPlugin(t, ts.URL)
+	require.NoError(t, plugin.Write(testMetrics()))
+
+	c.status = http.StatusServiceUnavailable
+	require.Error(t, plugin.Write(testMetrics()))
+
+	c.status = 0
+	plugin.Token = "invalid"
+	err := plugin.Write(testMetrics())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Invalid token")
+}
+
+func TestInitErrors(t *testing.T) {
+	plugin := newSplunkHEC()
+	plugin.EventType = "log"
+	require.Error(t, plugin.Init())
+
+	plugin = newSplunkHEC()
+	plugin.ContentEncoding = "br"
+	require.Error(t, plugin.Init())
+
+	plugin = newSplunkHEC()
+	plugin.MaxBatchEvents = 0
+	require.Error(t, plugin.Init())
+}
@@ -0,0 +1,263 @@
+package sql
+
+import (
+	gosql "database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"github.com/go-sql-driver/mysql"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/jackc/pgx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func newSQLite(t *testing.T) (*SQL, func()) {
+	dir, err := ioutil.TempDir("", "telegraf-sql")
+	require.NoError(t, err)
+
+	p := newSQL()
+	p.Driver = "sqlite3"
+	p.DataSourceName = filepath.Join(dir, "metrics.db")
+	require.NoError(t, p.Connect())
+
+	return p, func() {
+		p.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+type row map[string]interface{}
+
+func query(t *testing.T, db *gosql.DB, q string) []row {
+	rows, err := db.Query(q)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+
+	var result []row
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		require.NoError(t, rows.Scan(ptrs...))
+
+		r := row{}
+		for i, name := range columns {
+			// text is returned as bytes by some versions of the sqlite
+			// driver
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			r[name] = values[i]
+		}
+		result = append(result, r)
+	}
+	require.NoError(t, rows.Err())
+	return result
+}
+
+func TestCreateTableAndInsert(t *testing.T) {
+	p, cleanup := newSQLite(t)
+	defer cleanup()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{
+				"usage":   42.5,
+				"count":   int64(7),
+				"ok":      true,
+				"message": "hello",
+				"big":     uint64(1 << 62),
+			},
+			time.Unix(1, 0),
+		),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "b"},
+			map[string]interface{}{
+				"usage": 10.0,
+			},
+			time.Unix(2, 0),
+		),
+		testutil.MustMetric("mem",
+			map[string]string{"host": "a"},
+			map[string]interface{}{
+				"free": int64(1024),
+			},
+			time.Unix(1, 0),
+		),
+	}
+	require.NoError(t, p.Write(metrics))
+
+	rows := query(t, p.db, `SELECT host, usage, count, ok, message, big FROM cpu ORDER BY "timestamp"`)
+	require.Equal(t, []row{
+		{"host": "a", "usage": 42.5, "count": int64(7), "ok": true, "message": "hello", "big": int64(1 << 62)},
+		{"host": "b", "usage": 10.0, "count": nil, "ok": nil, "message": nil, "big": nil},
+	}, rows)
+
+	rows = query(t, p.db, `SELECT host, free FROM mem`)
+	require.Equal(t, []row{{"host": "a", "free": int64(1024)}}, rows)
+}
+
+func TestAddColumns(t *testing.T) {
+	p, cleanup := newSQLite(t)
+	defer cleanup()
+
+	require.NoError(t, p.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 1.0},
+			time.Unix(1, 0),
+		),
+	}))
+
+	require.NoError(t, p.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage": 2.0, "idle": 98.0},
+			time.Unix(2, 0),
+		),
+	}))
+
+	rows := query(t, p.db, `SELECT host, cpu, usage, idle FROM cpu ORDER BY "timestamp"`)
+	require.Equal(t, []row{
+		{"host": "a", "cpu": nil, "usage": 1.0, "idle": nil},
+		{"host": "a", "cpu": "cpu0", "usage": 2.0, "idle": 98.0},
+	}, rows)
+}
+
+func TestExistingTable(t *testing.T) {
+	p, cleanup := newSQLite(t)
+	defer cleanup()
+
+	_, err := p.db.Exec(`CREATE TABLE cpu("timestamp" TIMESTAMP, host TEXT, usage DOUBLE PRECISION)`)
+	require.NoError(t, err)
+
+	require.NoError(t, p.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 1.0, "idle": 99.0},
+			time.Unix(1, 0),
+		),
+	}))
+
+	rows := query(t, p.db, `SELECT host, usage, idle FROM cpu`)
+	require.Equal(t, []row{{"host": "a", "usage": 1.0, "idle": 99.0}}, rows)
+}
+
+func TestTableTemplate(t *testing.T) {
+	p, cleanup := newSQLite(t)
+	defer cleanup()
+
+	p.TableTemplate = "CREATE TABLE {TABLE}({COLUMNS}, note TEXT DEFAULT {TABLELITERAL})"
+
+	require.NoError(t, p.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"usage": 1.0},
+			time.Unix(1, 0),
+		),
+	}))
+
+	rows := query(t, p.db, `SELECT usage, note FROM cpu`)
+	require.Equal(t, []row{{"usage": 1.0, "note": "cpu"}}, rows)
+}
+
+func TestBatches(t *testing.T) {
+	p, cleanup := newSQLite(t)
+	defer cleanup()
+
+	// 3 columns, 333 rows per insert
+	var metrics []telegraf.Metric
+	for i := 0; i < 1000; i++ {
+		metrics = append(metrics, testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": int64(i)},
+			time.Unix(int64(i), 0),
+		))
+	}
+	require.NoError(t, p.Write(metrics))
+
+	rows := query(t, p.db, `SELECT COUNT(*) AS n, SUM(value) AS total FROM cpu`)
+	require.Equal(t, []row{{"n": int64(1000), "total": int64(999 * 1000 / 2)}}, rows)
+}
+
+func TestUnsignedOutOfRange(t *testing.T) {
+	p, cleanup := newSQLite(t)
+	defer cleanup()
+
+	p.Convert.Unsigned = "TEXT"
+	require.NoError(t, p.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"big": uint64(1<<63 + 1)},
+			time.Unix(1, 0),
+		),
+	}))
+
+	rows := query(t, p.db, `SELECT big FROM cpu`)
+	require.Equal(t, []row{{"big": "9223372036854775809"}}, rows)
+}
+
+func TestTableExistsError(t *testing.T) {
+	p, cleanup := newSQLite(t)
+	defer cleanup()
+
+	// only missing tables are created
+	p.TableExistsTemplate = "SELECT * FROM {TABLE} WHERE"
+	err := p.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"usage": 1.0},
+			time.Unix(1, 0),
+		),
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "reading columns")
+
+	rows := query(t, p.db, `SELECT name FROM sqlite_master WHERE type = 'table'`)
+	require.Empty(t, rows)
+}
+
+func TestIsMissingTable(t *testing.T) {
+	require.True(t, isMissingTable(pgx.PgError{Code: "42P01"}))
+	require.False(t, isMissingTable(pgx.PgError{Code: "42501"}))
+	require.True(t, isMissingTable(&mysql.MySQLError{Number: 1146}))
+	require.False(t, isMissingTable(&mysql.MySQLError{Number: 1045}))
+	require.True(t, isMissingTable(mssql.Error{Number: 208}))
+	require.False(t, isMissingTable(mssql.Error{Number: 18456}))
+	require.True(t, isMissingTable(errors.New("no such table: cpu")))
+	require.False(t, isMissingTable(errors.New("database is locked")))
+}
+
+func TestQuoting(t *testing.T) {
+	tests := []struct {
+		driver      string
+		ident       string
+		placeholder string
+	}{
+		{"pgx", `"a""b"`, "$2"},
+		{"mysql", "`a\"b`", "?"},
+		{"mssql", `"a""b"`, "@p2"},
+		{"sqlite3", `"a""b"`, "?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			p := newSQL()
+			p.Driver = tt.driver
+			require.Equal(t, tt.ident, p.quoteIdent(`a"b`))
+			require.Equal(t, tt.placeholder, p.placeholder(2))
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package websocket
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"golang.org/x/net/websocket"
+)
+
+var sampleConfig = `
+  ## URL of the WebSocket endpoint, using the ws or wss scheme.
+  url = "ws://127.0.0.1:8080/telegraf"
+
+  ## Origin sent in the opening handshake, defaults to the URL with the http
+  ## or https scheme.
+  # origin = ""
+
+  ## Additional HTTP headers sent in the opening handshake.
+  # [outputs.websocket.headers]
+  #   Authorization = "Bearer <token>"
+
+  ## Timeouts for establishing the connection and for writing a batch.
+  # connect_timeout = "30s"
+  # write_timeout = "30s"
+
+  ## Send the batches as text frames instead of binary frames.
+  # use_text_frames = false
+
+  ## Minimum and maximum interval to wait before reconnecting after the
+  ## connection is lost; the interval doubles after each failed attempt.
+  # reconnect_interval = "1s"
+  # max_reconnect_interval = "1m"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  # data_format = "influx"
+`
+
+type WebSocket struct {
+	URL                  string            `toml:"url"`
+	Origin               string            `toml:"origin"`
+	Headers              map[string]string `toml:"headers"`
+	ConnectTimeout       internal.Duration `toml:"connect_timeout"`
+	WriteTimeout         internal.Duration `toml:"write_timeout"`
+	UseTextFrames        bool              `toml:"use_text_frames"`
+	ReconnectInterval    internal.Duration `toml:"reconnect_interval"`
+	MaxReconnectInterval internal.Duration `toml:"max_reconnect_interval"`
+	tlsint.ClientConfig
+
+	serializer serializers.Serializer
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+
+	// backoff is the current wait before reconnecting and retryAt the
+	// earliest time of the next connection attempt.
+	backoff time.Duration
+	retryAt time.Time
+}
+
+func (w *WebSocket) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *WebSocket) Description() string {
+	return "Send metrics over a WebSocket connection"
+}
+
+func (w *WebSocket) SetSerializer(serializer serializers.Serializer) {
+	w.serializer = serializer
+}
+
+func (w *WebSocket) Init() error {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %v", err)
+	}
+
+	switch u.Scheme {
+	case "ws", "wss":
+	default:
+		return fmt.Errorf("unsupported url scheme %q, expected ws or wss", u.Scheme)
+	}
+
+	if w.Origin == "" {
+		origin := *u
+		origin.Scheme = "http"
+		if u.Scheme == "wss" {
+			origin.Scheme = "https"
+		}
+		origin.Path = ""
+		origin.RawQuery = ""
+		w.Origin = origin.String()
+	}
+
+	if w.ReconnectInterval.Duration <= 0 {
+		return fmt.Errorf("reconnect_interval must be positive")
+	}
+	if w.MaxReconnectInterval.Duration < w.ReconnectInterval.Duration {
+		w.MaxReconnectInterval.Duration = w.ReconnectInterval.Duration
+	}
+	return nil
+}
+
+func (w *WebSocket) Connect() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.connect()
+}
+
+// connect opens the connection, the caller must hold the lock.
+func (w *WebSocket) connect() error {
+	config, err := websocket.NewConfig(w.URL, w.Origin)
+	if err != nil {
+		return err
+	}
+
+	config.TlsConfig, err = w.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	for k, v := range w.Headers {
+		config.Header.Set(k, v)
+	}
+
+	conn, err := w.dial(config)
+	if err != nil {
+		return err
+	}
+	if w.UseTextFrames {
+		conn.PayloadType = websocket.TextFrame
+	} else {
+		conn.PayloadType = websocket.BinaryFrame
+	}
+
+	w.conn = conn
+	w.backoff = 0
+	go w.read(conn)
+	return nil
+}
+
+// dial opens the connection to the server, the opening handshake must
+// complete within the connect timeout.
+func (w *WebSocket) dial(config *websocket.Config) (*websocket.Conn, error) {
+	addr := config.Location.Host
+	if config.Location.Port() == "" {
+		port := "80"
+		if config.Location.Scheme == "wss" {
+			port = "443"
+		}
+		addr = net.JoinHostPort(config.Location.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: w.ConnectTimeout.Duration}
+	conn, err := dialer.DialContext(context.Background(), "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.ConnectTimeout.Duration > 0 {
+		conn.SetDeadline(time.Now().Add(w.ConnectTimeout.Duration))
+	}
+	if config.Location.Scheme == "wss" {
+		tlsConf := &tls.Config{}
+		if config.TlsConfig != nil {
+			tlsConf = config.TlsConfig.Clone()
+		}
+		if tlsConf.ServerName == "" {
+			tlsConf.ServerName = config.Location.Hostname()
+		}
+		conn = tls.Client(conn, tlsConf)
+	}
+
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return ws, nil
+}
+
+// read consumes the frames sent by the server, so that pings are answered,
+// and closes the connection once the server closes it.
+func (w *WebSocket) read(conn *websocket.Conn) {
+	var msg []byte
+	for {
+		if err := websocket.Message.Receive(conn, &msg); err != nil {
+			break
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == conn {
+		log.Printf("W! [outputs.websocket] Connection to %s closed by server", w.URL)
+		w.disconnect()
+	}
+}
+
+// reconnect reconnects if the connection was lost and the reconnect
+// interval has passed, the caller must hold the lock.
+func (w *WebSocket) reconnect() error {
+	if w.conn != nil {
+		return nil
+	}
+
+	now := time.Now()
+	if now.Before(w.retryAt) {
+		return fmt.Errorf("not connected, reconnecting in %s", w.retryAt.Sub(now).Round(time.Millisecond))
+	}
+
+	if err := w.connect(); err != nil {
+		if w.backoff == 0 {
+			w.backoff = w.ReconnectInterval.Duration
+		} else {
+			w.backoff *= 2
+			if w.backoff > w.MaxReconnectInterval.Duration {
+				w.backoff = w.MaxReconnectInterval.Duration
+			}
+		}
+		w.retryAt = now.Add(w.backoff)
+		return err
+	}
+	return nil
+}
+
+// disconnect closes the connection, the caller must hold the lock.
+func (w *WebSocket) disconnect() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// Write sends the batch in a single frame.
+func (w *WebSocket) Write(metrics []telegraf.Metric) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.reconnect(); err != nil {
+		return err
+	}
+
+	buf, err := w.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return err
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+
+	if w.WriteTimeout.Duration > 0 {
+		w.conn.SetWriteDeadline(time.Now().Add(w.WriteTimeout.Duration))
+	}
+	if _, err := w.conn.Write(buf); err != nil {
+		w.disconnect()
+		w.retryAt = time.Time{}
+		return fmt.Errorf("closing connection: %v", err)
+	}
+	return nil
+}
+
+func (w *WebSocket) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.disconnect()
+}
+
+func newWebSocket() *WebSocket {
+	return &WebSocket{
+		ConnectTimeout:       internal.Duration{Duration: 30 * time.Second},
+		WriteTimeout:         internal.Duration{Duration: 30 * time.Second},
+		ReconnectInterval:    internal.Duration{Duration: time.Second},
+		MaxReconnectInterval: internal.Duration{Duration: time.Minute},
+	}
+}
+
+func init() {
+	outputs.Add("websocket", func() telegraf.Output {
+		return newWebSocket()
+	})
+}
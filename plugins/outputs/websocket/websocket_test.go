@@ -0,0 +1,211 @@
+package websocket
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+type frame struct {
+	payloadType byte
+	data        string
+}
+
+// frameCodec receives a frame with its payload type.
+var frameCodec = websocket.Codec{
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		*v.(*frame) = frame{payloadType: payloadType, data: string(data)}
+		return nil
+	},
+}
+
+// server is a WebSocket server recording the received frames.
+type server struct {
+	*httptest.Server
+	frames  chan frame
+	headers chan http.Header
+}
+
+func newServer() *server {
+	s := &server{
+		frames:  make(chan frame, 10),
+		headers: make(chan http.Header, 10),
+	}
+	s.Server = httptest.NewServer(websocket.Server{
+		Handler: func(conn *websocket.Conn) {
+			s.headers <- conn.Request().Header
+			for {
+				var f frame
+				if err := frameCodec.Receive(conn, &f); err != nil {
+					return
+				}
+				s.frames <- f
+			}
+		},
+	})
+	return s
+}
+
+func (s *server) url() string {
+	return strings.Replace(s.URL, "http://", "ws://", 1)
+}
+
+func (s *server) frame(t *testing.T) frame {
+	select {
+	case f := <-s.frames:
+		return f
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timeout waiting for frame")
+	}
+	return frame{}
+}
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 42.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("mem",
+			map[string]string{},
+			map[string]interface{}{"used": 1},
+			time.Unix(0, 0),
+		),
+	}
+}
+
+func newPlugin(t *testing.T, url string) *WebSocket {
+	plugin := newWebSocket()
+	plugin.URL = url
+	plugin.SetSerializer(influx.NewSerializer())
+	require.NoError(t, plugin.Init())
+	return plugin
+}
+
+func TestWrite(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+
+	plugin := newPlugin(t, s.url())
+	plugin.Headers = map[string]string{"Authorization": "Bearer token"}
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	headers := <-s.headers
+	require.Equal(t, "Bearer token", headers.Get("Authorization"))
+	require.Equal(t, s.URL, headers.Get("Origin"))
+
+	require.NoError(t, plugin.Write(testMetrics()))
+	f := s.frame(t)
+	require.Equal(t, byte(websocket.BinaryFrame), f.payloadType)
+	require.Equal(t, "cpu,cpu=cpu0 usage_idle=42 0\nmem used=1i 0\n", f.data)
+}
+
+func TestWriteTextFrames(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+
+	plugin := newPlugin(t, s.url())
+	plugin.UseTextFrames = true
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.NoError(t, plugin.Write(testMetrics()[:1]))
+	f := s.frame(t)
+	require.Equal(t, byte(websocket.TextFrame), f.payloadType)
+	require.Equal(t, "cpu,cpu=cpu0 usage_idle=42 0\n", f.data)
+}
+
+func TestReconnect(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+
+	plugin := newPlugin(t, s.url())
+	plugin.ReconnectInterval.Duration = 50 * time.Millisecond
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// A lost connection is reestablished on the next write.
+	plugin.mu.Lock()
+	plugin.disconnect()
+	plugin.mu.Unlock()
+	require.NoError(t, plugin.Write(testMetrics()[:1]))
+	require.Equal(t, "cpu,cpu=cpu0 usage_idle=42 0\n", s.frame(t).data)
+
+	// Failed attempts are retried after the reconnect interval.
+	plugin.mu.Lock()
+	plugin.disconnect()
+	plugin.mu.Unlock()
+	plugin.URL = "ws://127.0.0.1:1/"
+	require.Error(t, plugin.Write(testMetrics()))
+	err := plugin.Write(testMetrics())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not connected")
+
+	plugin.URL = s.url()
+	time.Sleep(plugin.ReconnectInterval.Duration)
+	require.NoError(t, plugin.Write(testMetrics()[:1]))
+	require.Equal(t, "cpu,cpu=cpu0 usage_idle=42 0\n", s.frame(t).data)
+}
+
+func TestBackoff(t *testing.T) {
+	plugin := newPlugin(t, "ws://127.0.0.1:1/")
+	plugin.ReconnectInterval.Duration = time.Millisecond
+	plugin.MaxReconnectInterval.Duration = 3 * time.Millisecond
+
+	var backoffs []time.Duration
+	for i := 0; i < 4; i++ {
+		plugin.retryAt = time.Time{}
+		require.Error(t, plugin.Write(testMetrics()))
+		backoffs = append(backoffs, plugin.backoff)
+	}
+	require.Equal(t, []time.Duration{
+		time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 3 * time.Millisecond,
+	}, backoffs)
+}
+
+func TestConnectTimeout(t *testing.T) {
+	// The server accepts the connection but never answers the handshake.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	plugin := newPlugin(t, "ws://"+listener.Addr().String()+"/")
+	plugin.ConnectTimeout.Duration = 10 * time.Millisecond
+
+	start := time.Now()
+	err = plugin.Connect()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "timeout")
+	require.True(t, time.Since(start) < time.Second)
+}
+
+func TestInitErrors(t *testing.T) {
+	plugin := newWebSocket()
+	plugin.URL = "http://localhost:8080"
+	require.Error(t, plugin.Init())
+
+	plugin = newWebSocket()
+	plugin.URL = "wss://localhost:8080/path?q=1"
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "https://localhost:8080", plugin.Origin)
+}
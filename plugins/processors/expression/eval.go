@@ -0,0 +1,603 @@
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/influxdata/telegraf"
+)
+
+// valueType is the static type of an expression.  Field values are only
+// known when a metric is processed so they have type typeAny and are checked
+// during evaluation.
+type valueType int
+
+const (
+	typeAny valueType = iota
+	typeNumber
+	typeBool
+	typeString
+)
+
+func (t valueType) String() string {
+	switch t {
+	case typeNumber:
+		return "number"
+	case typeBool:
+		return "boolean"
+	case typeString:
+		return "string"
+	}
+	return "any"
+}
+
+// accepts reports if a value of type other can be used where t is expected.
+func (t valueType) accepts(other valueType) bool {
+	return t == typeAny || other == typeAny || t == other
+}
+
+// errMissing is returned when the expression references a field or tag that
+// is not present on the metric.
+var errMissing = errors.New("missing field or tag")
+
+type node interface {
+	// check validates the operand types of the node and returns the type of
+	// its result.
+	check() (valueType, error)
+
+	// eval evaluates the node against the metric.  The result is an int64,
+	// uint64, float64, bool or string.
+	eval(m telegraf.Metric) (interface{}, error)
+}
+
+// Expression is a parsed expression.
+type Expression struct {
+	root   node
+	typ    valueType
+	source string
+}
+
+// Type returns the static type of the expression result.
+func (e *Expression) Type() valueType {
+	return e.typ
+}
+
+// Eval evaluates the expression against the metric.
+func (e *Expression) Eval(m telegraf.Metric) (interface{}, error) {
+	return e.root.eval(m)
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) check() (valueType, error) {
+	return typeOf(n.value), nil
+}
+
+func (n *literalNode) eval(m telegraf.Metric) (interface{}, error) {
+	return n.value, nil
+}
+
+type fieldNode struct {
+	name string
+}
+
+func (n *fieldNode) check() (valueType, error) {
+	return typeAny, nil
+}
+
+func (n *fieldNode) eval(m telegraf.Metric) (interface{}, error) {
+	v, ok := m.GetField(n.name)
+	if !ok {
+		return nil, errMissing
+	}
+	return normalize(v)
+}
+
+type tagNode struct {
+	name string
+}
+
+func (n *tagNode) check() (valueType, error) {
+	return typeString, nil
+}
+
+func (n *tagNode) eval(m telegraf.Metric) (interface{}, error) {
+	v, ok := m.GetTag(n.name)
+	if !ok {
+		return nil, errMissing
+	}
+	return v, nil
+}
+
+type hasNode struct {
+	name  string
+	isTag bool
+}
+
+func (n *hasNode) check() (valueType, error) {
+	return typeBool, nil
+}
+
+func (n *hasNode) eval(m telegraf.Metric) (interface{}, error) {
+	if n.isTag {
+		return m.HasTag(n.name), nil
+	}
+	return m.HasField(n.name), nil
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) check() (valueType, error) {
+	t, err := n.operand.check()
+	if err != nil {
+		return typeAny, err
+	}
+
+	want := typeNumber
+	if n.op == "!" {
+		want = typeBool
+	}
+	if !want.accepts(t) {
+		return typeAny, fmt.Errorf("operator %q requires %s operand, found %s", n.op, want, t)
+	}
+	return want, nil
+}
+
+func (n *unaryNode) eval(m telegraf.Metric) (interface{}, error) {
+	v, err := n.operand.eval(m)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.op == "!" {
+		b, err := toBool(v)
+		if err != nil {
+			return nil, err
+		}
+		return !b, nil
+	}
+
+	if i, ok := toBigInt(v); ok {
+		if r, ok := fromBigInt(i.Neg(i), false); ok {
+			return r, nil
+		}
+	}
+	f, err := toFloat(v)
+	if err != nil {
+		return nil, err
+	}
+	return -f, nil
+}
+
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *binaryNode) check() (valueType, error) {
+	lt, err := n.left.check()
+	if err != nil {
+		return typeAny, err
+	}
+	rt, err := n.right.check()
+	if err != nil {
+		return typeAny, err
+	}
+
+	switch n.op {
+	case "&&", "||":
+		if !typeBool.accepts(lt) || !typeBool.accepts(rt) {
+			return typeAny, fmt.Errorf("operator %q requires boolean operands, found %s and %s", n.op, lt, rt)
+		}
+		return typeBool, nil
+	case "==", "!=":
+		if !lt.accepts(rt) {
+			return typeAny, fmt.Errorf("cannot compare %s and %s", lt, rt)
+		}
+		return typeBool, nil
+	case "<", "<=", ">", ">=":
+		if !typeNumber.accepts(lt) || !typeNumber.accepts(rt) {
+			return typeAny, fmt.Errorf("operator %q requires numeric operands, found %s and %s", n.op, lt, rt)
+		}
+		return typeBool, nil
+	default:
+		if !typeNumber.accepts(lt) || !typeNumber.accepts(rt) {
+			return typeAny, fmt.Errorf("operator %q requires numeric operands, found %s and %s", n.op, lt, rt)
+		}
+		return typeNumber, nil
+	}
+}
+
+func (n *binaryNode) eval(m telegraf.Metric) (interface{}, error) {
+	lv, err := n.left.eval(m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short circuit the boolean operators.
+	switch n.op {
+	case "&&", "||":
+		lb, err := toBool(lv)
+		if err != nil {
+			return nil, err
+		}
+		if (n.op == "&&" && !lb) || (n.op == "||" && lb) {
+			return lb, nil
+		}
+		rv, err := n.right.eval(m)
+		if err != nil {
+			return nil, err
+		}
+		return toBool(rv)
+	}
+
+	rv, err := n.right.eval(m)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(lv, rv)
+	case "!=":
+		eq, err := equal(lv, rv)
+		if err != nil {
+			return nil, err
+		}
+		return !eq, nil
+	}
+
+	switch n.op {
+	case "<", "<=", ">", ">=":
+		return compare(n.op, lv, rv)
+	}
+	return arith(n.op, lv, rv)
+}
+
+// compare compares two numbers, integers are compared exactly.
+func compare(op string, lv, rv interface{}) (bool, error) {
+	if li, ok := toBigInt(lv); ok {
+		if ri, ok := toBigInt(rv); ok {
+			c := li.Cmp(ri)
+			switch op {
+			case "==":
+				return c == 0, nil
+			case "<":
+				return c < 0, nil
+			case "<=":
+				return c <= 0, nil
+			case ">":
+				return c > 0, nil
+			case ">=":
+				return c >= 0, nil
+			}
+			return false, fmt.Errorf("unknown operator %q", op)
+		}
+	}
+
+	l, err := toFloat(lv)
+	if err != nil {
+		return false, err
+	}
+	r, err := toFloat(rv)
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "==":
+		return l == r, nil
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+// arith applies an arithmetic operator.  Addition, subtraction,
+// multiplication and remainder of two integers are exact as long as the
+// result fits in an int64 or uint64, otherwise and for division the operands
+// are converted to float64.
+func arith(op string, lv, rv interface{}) (interface{}, error) {
+	if li, ok := toBigInt(lv); ok {
+		if ri, ok := toBigInt(rv); ok {
+			z := new(big.Int)
+			switch op {
+			case "+":
+				z.Add(li, ri)
+			case "-":
+				z.Sub(li, ri)
+			case "*":
+				z.Mul(li, ri)
+			case "%":
+				if ri.Sign() == 0 {
+					return nil, errors.New("integer division by zero")
+				}
+				z.Rem(li, ri)
+			default:
+				z = nil
+			}
+			if z != nil {
+				_, lu := lv.(uint64)
+				_, ru := rv.(uint64)
+				if v, ok := fromBigInt(z, lu || ru); ok {
+					return v, nil
+				}
+			}
+		}
+	}
+
+	l, err := toFloat(lv)
+	if err != nil {
+		return nil, err
+	}
+	r, err := toFloat(rv)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		return l / r, nil
+	case "%":
+		return math.Mod(l, r), nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", op)
+}
+
+type ifNode struct {
+	cond node
+	then node
+	els  node
+}
+
+func (n *ifNode) check() (valueType, error) {
+	ct, err := n.cond.check()
+	if err != nil {
+		return typeAny, err
+	}
+	if !typeBool.accepts(ct) {
+		return typeAny, fmt.Errorf("if requires boolean condition, found %s", ct)
+	}
+
+	tt, err := n.then.check()
+	if err != nil {
+		return typeAny, err
+	}
+	et, err := n.els.check()
+	if err != nil {
+		return typeAny, err
+	}
+	if !tt.accepts(et) {
+		return typeAny, fmt.Errorf("if branches have different types %s and %s", tt, et)
+	}
+	if tt == typeAny {
+		return et, nil
+	}
+	return tt, nil
+}
+
+func (n *ifNode) eval(m telegraf.Metric) (interface{}, error) {
+	cv, err := n.cond.eval(m)
+	if err != nil {
+		return nil, err
+	}
+	c, err := toBool(cv)
+	if err != nil {
+		return nil, err
+	}
+	if c {
+		return n.then.eval(m)
+	}
+	return n.els.eval(m)
+}
+
+// mathFunc is a function over numeric arguments.
+type mathFunc struct {
+	minArgs int
+	maxArgs int // -1 for no limit
+	fn      func(args []float64) float64
+}
+
+var mathFuncs = map[string]mathFunc{
+	"abs":   {1, 1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"ceil":  {1, 1, func(a []float64) float64 { return math.Ceil(a[0]) }},
+	"floor": {1, 1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"round": {1, 1, func(a []float64) float64 { return math.Round(a[0]) }},
+	"sqrt":  {1, 1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"exp":   {1, 1, func(a []float64) float64 { return math.Exp(a[0]) }},
+	"log":   {1, 1, func(a []float64) float64 { return math.Log(a[0]) }},
+	"log2":  {1, 1, func(a []float64) float64 { return math.Log2(a[0]) }},
+	"log10": {1, 1, func(a []float64) float64 { return math.Log10(a[0]) }},
+	"pow":   {2, 2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"min": {1, -1, func(a []float64) float64 {
+		v := a[0]
+		for _, x := range a[1:] {
+			v = math.Min(v, x)
+		}
+		return v
+	}},
+	"max": {1, -1, func(a []float64) float64 {
+		v := a[0]
+		for _, x := range a[1:] {
+			v = math.Max(v, x)
+		}
+		return v
+	}},
+}
+
+type callNode struct {
+	name string
+	fn   mathFunc
+	args []node
+}
+
+func (n *callNode) check() (valueType, error) {
+	for i, arg := range n.args {
+		t, err := arg.check()
+		if err != nil {
+			return typeAny, err
+		}
+		if !typeNumber.accepts(t) {
+			return typeAny, fmt.Errorf("argument %d of %s must be a number, found %s", i+1, n.name, t)
+		}
+	}
+	return typeNumber, nil
+}
+
+func (n *callNode) eval(m telegraf.Metric) (interface{}, error) {
+	args := make([]float64, 0, len(n.args))
+	for _, arg := range n.args {
+		v, err := arg.eval(m)
+		if err != nil {
+			return nil, err
+		}
+		f, err := toFloat(v)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, f)
+	}
+	return n.fn.fn(args), nil
+}
+
+// newCallNode creates the node for a function call, validating the function
+// name and number of arguments.
+func newCallNode(name string, args []node) (node, error) {
+	switch name {
+	case "if":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("if requires 3 arguments, found %d", len(args))
+		}
+		return &ifNode{cond: args[0], then: args[1], els: args[2]}, nil
+	case "tag", "field", "has_tag", "has_field":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s requires 1 argument, found %d", name, len(args))
+		}
+		lit, ok := args[0].(*literalNode)
+		if !ok {
+			return nil, fmt.Errorf("argument of %s must be a string literal", name)
+		}
+		key, ok := lit.value.(string)
+		if !ok {
+			return nil, fmt.Errorf("argument of %s must be a string literal", name)
+		}
+		switch name {
+		case "tag":
+			return &tagNode{name: key}, nil
+		case "field":
+			return &fieldNode{name: key}, nil
+		case "has_tag":
+			return &hasNode{name: key, isTag: true}, nil
+		default:
+			return &hasNode{name: key}, nil
+		}
+	}
+
+	fn, ok := mathFuncs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments to %s: %d", name, len(args))
+	}
+	return &callNode{name: name, fn: fn, args: args}, nil
+}
+
+func typeOf(v interface{}) valueType {
+	switch v.(type) {
+	case int64, uint64, float64:
+		return typeNumber
+	case bool:
+		return typeBool
+	case string:
+		return typeString
+	}
+	return typeAny
+}
+
+// normalize converts a field value to one of the evaluation types.
+func normalize(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case int64, uint64, float64, bool, string:
+		return v, nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", v)
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	}
+	return 0, fmt.Errorf("expected number, found %s %v", typeOf(v), v)
+}
+
+// toBigInt returns the value of an integer, it is false for other types.
+func toBigInt(v interface{}) (*big.Int, bool) {
+	switch v := v.(type) {
+	case int64:
+		return big.NewInt(v), true
+	case uint64:
+		return new(big.Int).SetUint64(v), true
+	}
+	return nil, false
+}
+
+// fromBigInt converts an integer result to an int64, or to an uint64 if
+// unsigned is preferred or the value is too large for an int64.  It is false
+// if the value fits in neither.
+func fromBigInt(z *big.Int, unsigned bool) (interface{}, bool) {
+	if unsigned && z.IsUint64() {
+		return z.Uint64(), true
+	}
+	if z.IsInt64() {
+		return z.Int64(), true
+	}
+	if z.IsUint64() {
+		return z.Uint64(), true
+	}
+	return nil, false
+}
+
+func toBool(v interface{}) (bool, error) {
+	if b, ok := v.(bool); ok {
+		return b, nil
+	}
+	return false, fmt.Errorf("expected boolean, found %s %v", typeOf(v), v)
+}
+
+func equal(l, r interface{}) (bool, error) {
+	if typeOf(l) == typeNumber && typeOf(r) == typeNumber {
+		return compare("==", l, r)
+	}
+	if typeOf(l) != typeOf(r) {
+		return false, fmt.Errorf("cannot compare %s and %s", typeOf(l), typeOf(r))
+	}
+	return l == r, nil
+}
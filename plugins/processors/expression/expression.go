@@ -0,0 +1,183 @@
+package expression
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Fields are computed in order, so an expression may refer to the result of
+  ## a previous one.  If a field referenced by the expression is missing the
+  ## field is not set.
+  [[processors.expression.field]]
+    ## Name of the field to create or overwrite.
+    name = "bits_recv"
+
+    ## Expression to compute.  Fields are referenced by name, or with
+    ## field("name") if the name is not a valid identifier, and tags with
+    ## tag("name").
+    ##
+    ## Operators: + - * / % == != < <= > >= && || !
+    ## Functions: abs, ceil, floor, round, sqrt, exp, log, log2, log10, pow,
+    ##            min, max, if(cond, then, else), has_field, has_tag
+    expression = "bytes_recv * 8"
+
+    ## Type of the resulting field: float, integer, unsigned, boolean or
+    ## string.  By default the type of the expression result is used.
+    # type = "float"
+`
+
+type Field struct {
+	Name       string `toml:"name"`
+	Expression string `toml:"expression"`
+	Type       string `toml:"type"`
+
+	expr *Expression
+}
+
+type Processor struct {
+	Fields []*Field `toml:"field"`
+}
+
+func (p *Processor) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Processor) Description() string {
+	return "Compute fields from expressions over the fields and tags of a metric."
+}
+
+func (p *Processor) Init() error {
+	for _, field := range p.Fields {
+		if field.Name == "" {
+			return fmt.Errorf("field is missing name")
+		}
+
+		expr, err := Parse(field.Expression)
+		if err != nil {
+			return fmt.Errorf("field %q: %v", field.Name, err)
+		}
+
+		var want valueType
+		switch field.Type {
+		case "":
+			want = typeAny
+		case "float", "integer", "unsigned":
+			want = typeNumber
+		case "boolean":
+			want = typeBool
+		case "string":
+			want = typeAny
+		default:
+			return fmt.Errorf("field %q: unknown type %q", field.Name, field.Type)
+		}
+
+		if !want.accepts(expr.Type()) {
+			return fmt.Errorf("field %q: expression of type %s cannot be converted to %s",
+				field.Name, expr.Type(), field.Type)
+		}
+
+		field.expr = expr
+	}
+	return nil
+}
+
+func (p *Processor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		for _, field := range p.Fields {
+			v, err := field.expr.Eval(m)
+			if err == errMissing {
+				continue
+			}
+			if err != nil {
+				log.Printf("E! [processors.expression] field %q: %v", field.Name, err)
+				continue
+			}
+
+			v, err = convert(v, field.Type)
+			if err != nil {
+				log.Printf("E! [processors.expression] field %q: %v", field.Name, err)
+				continue
+			}
+
+			m.RemoveField(field.Name)
+			m.AddField(field.Name, v)
+		}
+	}
+	return in
+}
+
+// convert converts the result of an expression to the field type.
+func convert(v interface{}, typ string) (interface{}, error) {
+	if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return nil, fmt.Errorf("result is not a finite number: %v", f)
+	}
+
+	switch typ {
+	case "":
+		return v, nil
+	case "float":
+		return toFloat(v)
+	case "boolean":
+		return toBool(v)
+	case "integer":
+		switch v := v.(type) {
+		case int64:
+			return v, nil
+		case uint64:
+			if v > math.MaxInt64 {
+				return nil, fmt.Errorf("result %d out of range for integer", v)
+			}
+			return int64(v), nil
+		case float64:
+			if v < -(1<<63) || v >= 1<<63 {
+				return nil, fmt.Errorf("result %v out of range for integer", v)
+			}
+			return int64(v), nil
+		}
+		return nil, fmt.Errorf("expected number, found %s %v", typeOf(v), v)
+	case "unsigned":
+		switch v := v.(type) {
+		case int64:
+			if v < 0 {
+				return nil, fmt.Errorf("cannot convert negative result %v to unsigned", v)
+			}
+			return uint64(v), nil
+		case uint64:
+			return v, nil
+		case float64:
+			if v < 0 {
+				return nil, fmt.Errorf("cannot convert negative result %v to unsigned", v)
+			}
+			if v >= 1<<64 {
+				return nil, fmt.Errorf("result %v out of range for unsigned", v)
+			}
+			return uint64(v), nil
+		}
+		return nil, fmt.Errorf("expected number, found %s %v", typeOf(v), v)
+	case "string":
+		switch v := v.(type) {
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		case uint64:
+			return strconv.FormatUint(v, 10), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+func init() {
+	processors.Add("expression", func() telegraf.Processor {
+		return &Processor{}
+	})
+}
@@ -0,0 +1,275 @@
+package expression
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric() telegraf.Metric {
+	return testutil.MustMetric("mem",
+		map[string]string{
+			"host":   "localhost",
+			"region": "us-east-1",
+		},
+		map[string]interface{}{
+			"used":       int64(25),
+			"total":      uint64(100),
+			"bytes_recv": int64(1000),
+			"ratio":      0.5,
+			"up":         true,
+			"state":      "ok",
+			"big":        int64(1<<62 + 1),
+			"huge":       uint64(1<<63 + 1),
+		},
+		time.Unix(0, 0),
+	)
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"used / total * 100", 25.0},
+		{"bytes_recv * 8", int64(8000)},
+		{"1 + 2 * 3", int64(7)},
+		{"(1 + 2) * 3", int64(9)},
+		{"-used + 5", int64(-20)},
+		{"10 % 4", int64(2)},
+		{"10.5 % 4", 2.5},
+		{"2 * -3", int64(-6)},
+		{"1.5e3", 1500.0},
+		{"total - 1", uint64(99)},
+		{"used - total", int64(-75)},
+		{"-total", int64(-100)},
+		{"big + 1", int64(1<<62 + 2)},
+		{"big * 2", uint64(1<<63 + 2)},
+		{"big * 4", 1.8446744073709552e19},
+		{"huge - 1", uint64(1 << 63)},
+		{"huge > 9223372036854775808", true},
+		{"big == 4611686018427387904", false},
+		{"big == 4611686018427387905", true},
+		{"used == 25.0", true},
+		{"used + 0.5", 25.5},
+		{"18446744073709551615", uint64(1<<64 - 1)},
+		{"18446744073709551616", 1.8446744073709552e19},
+		{"abs(-3)", 3.0},
+		{"round(2.5)", 3.0},
+		{"floor(ratio)", 0.0},
+		{"ceil(ratio)", 1.0},
+		{"pow(2, 10)", 1024.0},
+		{"log10(1000)", 3.0},
+		{"min(used, total, 3)", 3.0},
+		{"max(used, total)", 100.0},
+		{"used > 20 && used < 30", true},
+		{"used > 30 || !up", false},
+		{"state == 'ok'", true},
+		{"state != \"ok\"", false},
+		{"tag('host') == 'localhost'", true},
+		{"if(used > 50, 'high', 'low')", "low"},
+		{"if(has_field('missing'), missing, 0)", int64(0)},
+		{"has_tag('region')", true},
+		{"field('ratio') * 2", 1.0},
+		{"false && missing", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expr, err := Parse(tt.expression)
+			require.NoError(t, err)
+
+			actual, err := expr.Eval(newMetric())
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"1 +",
+		"(1 + 2",
+		"1 2",
+		"'unterminated",
+		"1 $ 2",
+		"unknown(1)",
+		"pow(1)",
+		"if(true, 1)",
+		"tag(host)",
+		"1 + 'a'",
+		"'a' < 'b'",
+		"!1",
+		"-true",
+		"1 && true",
+		"1 == 'a'",
+		"if(1, 2, 3)",
+		"if(true, 1, 'a')",
+		"abs(tag('host'))",
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			_, err := Parse(tt)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []string{
+		"state + 1",
+		"!used",
+		"used == state",
+		"used % 0",
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			expr, err := Parse(tt)
+			require.NoError(t, err)
+
+			_, err = expr.Eval(newMetric())
+			require.Error(t, err)
+			require.NotEqual(t, errMissing, err)
+		})
+	}
+}
+
+func TestInitTypeCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		field *Field
+		ok    bool
+	}{
+		{
+			name:  "number to integer",
+			field: &Field{Name: "x", Expression: "1 + 1", Type: "integer"},
+			ok:    true,
+		},
+		{
+			name:  "field to boolean",
+			field: &Field{Name: "x", Expression: "up", Type: "boolean"},
+			ok:    true,
+		},
+		{
+			name:  "number to string",
+			field: &Field{Name: "x", Expression: "1 + 1", Type: "string"},
+			ok:    true,
+		},
+		{
+			name:  "boolean to float",
+			field: &Field{Name: "x", Expression: "1 > 2", Type: "float"},
+		},
+		{
+			name:  "string to unsigned",
+			field: &Field{Name: "x", Expression: "tag('host')", Type: "unsigned"},
+		},
+		{
+			name:  "unknown type",
+			field: &Field{Name: "x", Expression: "1", Type: "decimal"},
+		},
+		{
+			name:  "missing name",
+			field: &Field{Expression: "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Processor{Fields: []*Field{tt.field}}
+			err := plugin.Init()
+			if tt.ok {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	plugin := &Processor{
+		Fields: []*Field{
+			{Name: "used_percent", Expression: "used / total * 100"},
+			{Name: "bits_recv", Expression: "bytes_recv * 8", Type: "integer"},
+			{Name: "high", Expression: "used_percent > 90"},
+			{Name: "used", Expression: "used * 2", Type: "unsigned"},
+			{Name: "label", Expression: "ratio", Type: "string"},
+			{Name: "skipped", Expression: "missing + 1"},
+			{Name: "invalid", Expression: "used / 0"},
+		},
+	}
+	require.NoError(t, plugin.Init())
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("mem",
+			map[string]string{
+				"host":   "localhost",
+				"region": "us-east-1",
+			},
+			map[string]interface{}{
+				"used":         uint64(50),
+				"total":        uint64(100),
+				"bytes_recv":   int64(1000),
+				"ratio":        0.5,
+				"up":           true,
+				"state":        "ok",
+				"big":          int64(1<<62 + 1),
+				"huge":         uint64(1<<63 + 1),
+				"used_percent": 25.0,
+				"bits_recv":    int64(8000),
+				"high":         false,
+				"label":        "0.5",
+			},
+			time.Unix(0, 0),
+		),
+	}
+
+	actual := plugin.Apply(newMetric())
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		typ      string
+		expected interface{}
+	}{
+		{int64(1<<62 + 1), "integer", int64(1<<62 + 1)},
+		{uint64(1<<62 + 1), "integer", int64(1<<62 + 1)},
+		{-2.5, "integer", int64(-2)},
+		{-9223372036854775808.0, "integer", int64(-1 << 63)},
+		{uint64(1<<63 + 1), "unsigned", uint64(1<<63 + 1)},
+		{int64(5), "unsigned", uint64(5)},
+		{1e19, "unsigned", uint64(1e19)},
+		{int64(5), "float", 5.0},
+		{uint64(1<<64 - 1), "string", "18446744073709551615"},
+		{int64(-5), "string", "-5"},
+	}
+	for _, tt := range tests {
+		actual, err := convert(tt.value, tt.typ)
+		require.NoError(t, err)
+		require.Equal(t, tt.expected, actual)
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		typ   string
+	}{
+		{uint64(1 << 63), "integer"},
+		{9223372036854775808.0, "integer"},
+		{-1e19, "integer"},
+		{int64(-1), "unsigned"},
+		{-0.5, "unsigned"},
+		{18446744073709551616.0, "unsigned"},
+		{"a", "integer"},
+		{true, "unsigned"},
+	}
+	for _, tt := range tests {
+		_, err := convert(tt.value, tt.typ)
+		require.Error(t, err, "%v to %s", tt.value, tt.typ)
+	}
+}
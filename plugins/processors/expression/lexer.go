@@ -0,0 +1,164 @@
+package expression
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// operators are ordered so that the longest operators are matched first.
+var operators = []string{
+	"&&", "||", "==", "!=", "<=", ">=",
+	"+", "-", "*", "/", "%", "<", ">", "!",
+}
+
+// tokenize splits an expression into tokens.
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	pos := 0
+	for pos < len(input) {
+		c := rune(input[pos])
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: pos})
+			pos++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: pos})
+			pos++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: pos})
+			pos++
+		case c == '"' || c == '\'':
+			tok, n, err := scanString(input[pos:])
+			if err != nil {
+				return nil, fmt.Errorf("position %d: %v", pos, err)
+			}
+			tok.pos = pos
+			tokens = append(tokens, tok)
+			pos += n
+		case isDigit(c) || c == '.':
+			n := scanNumber(input[pos:])
+			tokens = append(tokens, token{
+				kind: tokenNumber,
+				text: input[pos : pos+n],
+				pos:  pos,
+			})
+			pos += n
+		case isIdentStart(c):
+			start := pos
+			for pos < len(input) && isIdentPart(rune(input[pos])) {
+				pos++
+			}
+			tokens = append(tokens, token{
+				kind: tokenIdent,
+				text: input[start:pos],
+				pos:  start,
+			})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(input[pos:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+					pos += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("position %d: unexpected character %q", pos, c)
+			}
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: pos})
+	return tokens, nil
+}
+
+func scanNumber(input string) int {
+	n := 0
+	for n < len(input) && (isDigit(rune(input[n])) || input[n] == '.') {
+		n++
+	}
+	// exponent
+	if n < len(input) && (input[n] == 'e' || input[n] == 'E') {
+		m := n + 1
+		if m < len(input) && (input[m] == '+' || input[m] == '-') {
+			m++
+		}
+		if m < len(input) && isDigit(rune(input[m])) {
+			for m < len(input) && isDigit(rune(input[m])) {
+				m++
+			}
+			n = m
+		}
+	}
+	return n
+}
+
+func scanString(input string) (token, int, error) {
+	quote := input[0]
+	var sb strings.Builder
+	for i := 1; i < len(input); i++ {
+		c := input[i]
+		switch c {
+		case quote:
+			return token{kind: tokenString, text: input[:i+1], value: sb.String()}, i + 1, nil
+		case '\\':
+			i++
+			if i >= len(input) {
+				break
+			}
+			switch input[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			default:
+				sb.WriteByte(input[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return token{}, 0, fmt.Errorf("unterminated string")
+}
+
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c rune) bool {
+	return isIdentStart(c) || isDigit(c)
+}
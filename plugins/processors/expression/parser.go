@@ -0,0 +1,174 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// binding power of the binary operators, higher binds tighter.
+var precedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+const unaryPrecedence = 7
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses and type checks an expression.
+func Parse(input string) (*Expression, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("position %d: unexpected %s", tok.pos, tok)
+	}
+
+	typ, err := root.check()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expression{root: root, typ: typ, source: input}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	tok := p.next()
+	if tok.kind != kind {
+		return fmt.Errorf("position %d: expected %q, found %s", tok.pos, text, tok)
+	}
+	return nil
+}
+
+// parseExpr parses binary operators using precedence climbing.
+func (p *parser) parseExpr(minPrec int) (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		if tok.kind != tokenOperator {
+			return left, nil
+		}
+		prec, ok := precedence[tok.text]
+		if !ok || prec <= minPrec {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseExpr(prec)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: tok.text, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	tok := p.peek()
+	if tok.kind == tokenOperator && (tok.text == "-" || tok.text == "!") {
+		p.next()
+		operand, err := p.parseExpr(unaryPrecedence)
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: tok.text, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		// numbers without a fraction or exponent are integers
+		if i, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+			return &literalNode{value: i}, nil
+		}
+		if u, err := strconv.ParseUint(tok.text, 10, 64); err == nil {
+			return &literalNode{value: u}, nil
+		}
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("position %d: invalid number %s", tok.pos, tok)
+		}
+		return &literalNode{value: v}, nil
+	case tokenString:
+		return &literalNode{value: tok.value}, nil
+	case tokenLParen:
+		n, err := p.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenRParen, ")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case tokenIdent:
+		if p.peek().kind == tokenLParen {
+			return p.parseCall(tok)
+		}
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		}
+		return &fieldNode{name: tok.text}, nil
+	}
+	return nil, fmt.Errorf("position %d: unexpected %s", tok.pos, tok)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	p.next() // (
+
+	var args []node
+	if p.peek().kind != tokenRParen {
+		for {
+			arg, err := p.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if err := p.expect(tokenRParen, ")"); err != nil {
+		return nil, err
+	}
+
+	return newCallNode(name.text, args)
+}
@@ -0,0 +1,93 @@
+package reverse_dns
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+type network struct {
+	ipnet *net.IPNet
+	ones  int
+	label string
+}
+
+// networkTable maps addresses to the label of the most specific network
+// containing them.
+type networkTable []network
+
+// loadNetworks reads a networks file.  Each line contains a network in CIDR
+// notation followed by whitespace and the label, blank lines and lines
+// starting with '#' are ignored:
+//
+//	10.0.0.0/8      internal
+//	10.1.0.0/16     datacenter-1
+func loadNetworks(filename string) (networkTable, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	table, err := parseNetworks(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return table, nil
+}
+
+func parseNetworks(r io.Reader) (networkTable, error) {
+	var table networkTable
+
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("line %d: expected network and label", lineno)
+		}
+
+		_, ipnet, err := net.ParseCIDR(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineno, err)
+		}
+
+		ones, _ := ipnet.Mask.Size()
+		table = append(table, network{
+			ipnet: ipnet,
+			ones:  ones,
+			label: strings.Join(parts[1:], " "),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Most specific networks first so that the first match is the longest
+	// prefix match.
+	sort.SliceStable(table, func(i, j int) bool {
+		return table[i].ones > table[j].ones
+	})
+	return table, nil
+}
+
+// Lookup returns the label of the most specific network containing the
+// address.
+func (t networkTable) Lookup(ip net.IP) (string, bool) {
+	for _, n := range t {
+		if n.ipnet.Contains(ip) {
+			return n.label, true
+		}
+	}
+	return "", false
+}
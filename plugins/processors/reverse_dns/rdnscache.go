@@ -0,0 +1,205 @@
+package reverse_dns
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Resolver looks up the names for an IP address.  *net.Resolver implements
+// this interface.
+type Resolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// cacheEntry is a resolved name along with its expiration time.  An empty
+// name records a failed lookup so that it is not retried until it expires.
+type cacheEntry struct {
+	ip      string
+	name    string
+	expires time.Time
+}
+
+// pendingLookup is a queued or in flight lookup; done is closed when the
+// lookup completes and name is safe to read.
+type pendingLookup struct {
+	ip   string
+	done chan struct{}
+	name string
+}
+
+// rdnsCache is a size bounded LRU cache of reverse lookups with entry
+// expiration.  Lookups are queued and performed asynchronously by a limited
+// number of workers, and concurrent requests for the same address share a
+// single lookup.  Workers are started when lookups are queued and exit when
+// the queue is empty.
+type rdnsCache struct {
+	resolver      Resolver
+	ttl           time.Duration
+	lookupTimeout time.Duration
+	maxSize       int
+	maxParallel   int
+
+	queue chan *pendingLookup
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+	pending map[string]*pendingLookup
+	workers int
+
+	now func() time.Time
+}
+
+func newRDNSCache(
+	resolver Resolver,
+	ttl time.Duration,
+	lookupTimeout time.Duration,
+	maxSize int,
+	maxParallel int,
+	maxPending int,
+) *rdnsCache {
+	return &rdnsCache{
+		resolver:      resolver,
+		ttl:           ttl,
+		lookupTimeout: lookupTimeout,
+		maxSize:       maxSize,
+		maxParallel:   maxParallel,
+		queue:         make(chan *pendingLookup, maxPending),
+		lru:           list.New(),
+		entries:       make(map[string]*list.Element),
+		pending:       make(map[string]*pendingLookup),
+		now:           time.Now,
+	}
+}
+
+// Get returns the cached name for the address, if present and not expired.
+func (c *rdnsCache) Get(ip string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(ip)
+}
+
+func (c *rdnsCache) get(ip string) (string, bool) {
+	elem, ok := c.entries[ip]
+	if !ok {
+		return "", false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if c.now().After(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, ip)
+		return "", false
+	}
+
+	c.lru.MoveToFront(elem)
+	return entry.name, true
+}
+
+// Lookup returns the result of a lookup for the address.  If the name is not
+// cached a lookup is queued; the caller can wait on the done channel of the
+// returned lookup for as long as it is willing to.  If the queue is full the
+// lookup is skipped, the returned lookup is done without a name and the
+// address is looked up again on the next call.
+func (c *rdnsCache) Lookup(ip string) *pendingLookup {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if name, ok := c.get(ip); ok {
+		return doneLookup(ip, name)
+	}
+
+	if p, ok := c.pending[ip]; ok {
+		return p
+	}
+
+	p := &pendingLookup{ip: ip, done: make(chan struct{})}
+	select {
+	case c.queue <- p:
+	default:
+		return doneLookup(ip, "")
+	}
+	c.pending[ip] = p
+
+	if c.workers < c.maxParallel {
+		c.workers++
+		go c.work()
+	}
+	return p
+}
+
+func doneLookup(ip, name string) *pendingLookup {
+	p := &pendingLookup{ip: ip, done: make(chan struct{}), name: name}
+	close(p.done)
+	return p
+}
+
+// work resolves queued lookups until the queue is empty.  The queue is
+// checked while holding the lock, so a lookup queued by Lookup is either
+// picked up by a running worker or a new worker is started for it.
+func (c *rdnsCache) work() {
+	for {
+		c.mu.Lock()
+		var p *pendingLookup
+		select {
+		case p = <-c.queue:
+		default:
+			c.workers--
+			c.mu.Unlock()
+			return
+		}
+		c.mu.Unlock()
+
+		c.resolve(p)
+	}
+}
+
+func (c *rdnsCache) resolve(p *pendingLookup) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.lookupTimeout)
+	defer cancel()
+
+	var name string
+	names, err := c.resolver.LookupAddr(ctx, p.ip)
+	if err == nil && len(names) > 0 {
+		name = strings.TrimSuffix(names[0], ".")
+	}
+
+	c.mu.Lock()
+	c.add(p.ip, name)
+	delete(c.pending, p.ip)
+	p.name = name
+	c.mu.Unlock()
+
+	close(p.done)
+}
+
+func (c *rdnsCache) add(ip, name string) {
+	expires := c.now().Add(c.ttl)
+	if elem, ok := c.entries[ip]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.name = name
+		entry.expires = expires
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	elem := c.lru.PushFront(&cacheEntry{ip: ip, name: name, expires: expires})
+	c.entries[ip] = elem
+
+	for c.maxSize > 0 && c.lru.Len() > c.maxSize {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).ip)
+	}
+}
+
+// Len returns the number of cached entries, including expired entries that
+// have not yet been evicted.
+func (c *rdnsCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
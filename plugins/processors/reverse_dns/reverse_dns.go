@@ -0,0 +1,227 @@
+package reverse_dns
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## For optimal performance, you may want to limit which metrics are passed to
+  ## this processor. eg:
+  ## namepass = ["conntrack", "ipvs*"]
+
+  ## How long a resolved name, or a failed lookup, is cached.
+  # cache_ttl = "24h"
+
+  ## Maximum number of addresses kept in the cache, the least recently used
+  ## entries are evicted first.
+  # cache_size = 10000
+
+  ## Maximum time a single DNS lookup may take.
+  # lookup_timeout = "3s"
+
+  ## Maximum number of concurrent DNS lookups.
+  # max_parallel_lookups = 10
+
+  ## Maximum number of addresses waiting for a lookup.  While the queue is
+  ## full no new lookups are started; metrics pass through without the name
+  ## and the address is looked up again with a following metric.
+  # max_pending_lookups = 1000
+
+  ## Maximum time to hold up a batch of metrics waiting for lookups to
+  ## complete.  Metrics whose lookups are still outstanding pass through
+  ## without the name; the lookup continues in the background and the result
+  ## is cached for following metrics.
+  # deadline = "100ms"
+
+  ## Optional file mapping networks to labels, one "<cidr> <label>" pair per
+  ## line.  The most specific matching network is used.
+  # networks_file = "/etc/telegraf/networks.txt"
+
+  [[processors.reverse_dns.lookup]]
+    ## Tag containing the IP address to look up.
+    tag = "source"
+
+    ## Tag to write the resolved hostname to.  If unset no DNS lookup is done.
+    dest = "source_name"
+
+    ## Tag to write the network label from networks_file to.
+    # network_dest = "source_network"
+`
+
+type Lookup struct {
+	Tag         string `toml:"tag"`
+	Dest        string `toml:"dest"`
+	NetworkDest string `toml:"network_dest"`
+}
+
+type ReverseDNS struct {
+	Lookups            []Lookup          `toml:"lookup"`
+	CacheTTL           internal.Duration `toml:"cache_ttl"`
+	CacheSize          int               `toml:"cache_size"`
+	LookupTimeout      internal.Duration `toml:"lookup_timeout"`
+	MaxParallelLookups int               `toml:"max_parallel_lookups"`
+	MaxPendingLookups  int               `toml:"max_pending_lookups"`
+	Deadline           internal.Duration `toml:"deadline"`
+	NetworksFile       string            `toml:"networks_file"`
+
+	Resolver Resolver `toml:"-"`
+
+	cache    *rdnsCache
+	networks networkTable
+}
+
+// request is a tag waiting on a lookup.
+type request struct {
+	metric telegraf.Metric
+	dest   string
+	lookup *pendingLookup
+}
+
+func (r *ReverseDNS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *ReverseDNS) Description() string {
+	return "Resolve IP address tags to hostnames and network labels."
+}
+
+func (r *ReverseDNS) Init() error {
+	if len(r.Lookups) == 0 {
+		return fmt.Errorf("no lookups configured")
+	}
+
+	needNetworks := false
+	for _, lookup := range r.Lookups {
+		if lookup.Tag == "" {
+			return fmt.Errorf("lookup is missing tag")
+		}
+		if lookup.Dest == "" && lookup.NetworkDest == "" {
+			return fmt.Errorf("lookup of tag %q requires dest or network_dest", lookup.Tag)
+		}
+		if lookup.NetworkDest != "" {
+			needNetworks = true
+		}
+	}
+
+	if needNetworks && r.NetworksFile == "" {
+		return fmt.Errorf("network_dest requires networks_file")
+	}
+
+	if r.NetworksFile != "" {
+		networks, err := loadNetworks(r.NetworksFile)
+		if err != nil {
+			return err
+		}
+		r.networks = networks
+	}
+
+	if r.MaxParallelLookups <= 0 {
+		return fmt.Errorf("max_parallel_lookups must be greater than 0")
+	}
+	if r.MaxPendingLookups <= 0 {
+		return fmt.Errorf("max_pending_lookups must be greater than 0")
+	}
+
+	if r.Resolver == nil {
+		r.Resolver = net.DefaultResolver
+	}
+
+	r.cache = newRDNSCache(r.Resolver, r.CacheTTL.Duration,
+		r.LookupTimeout.Duration, r.CacheSize, r.MaxParallelLookups,
+		r.MaxPendingLookups)
+	return nil
+}
+
+func (r *ReverseDNS) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	var requests []request
+	for _, m := range in {
+		for _, lookup := range r.Lookups {
+			value, ok := m.GetTag(lookup.Tag)
+			if !ok {
+				continue
+			}
+
+			ip := net.ParseIP(value)
+			if ip == nil {
+				continue
+			}
+
+			if lookup.NetworkDest != "" {
+				if label, ok := r.networks.Lookup(ip); ok {
+					m.AddTag(lookup.NetworkDest, label)
+				}
+			}
+
+			if lookup.Dest == "" {
+				continue
+			}
+
+			if name, ok := r.cache.Get(value); ok {
+				if name != "" {
+					m.AddTag(lookup.Dest, name)
+				}
+				continue
+			}
+
+			requests = append(requests, request{
+				metric: m,
+				dest:   lookup.Dest,
+				lookup: r.cache.Lookup(value),
+			})
+		}
+	}
+
+	if len(requests) == 0 {
+		return in
+	}
+
+	timer := time.NewTimer(r.Deadline.Duration)
+	defer timer.Stop()
+
+	expired := false
+	for _, req := range requests {
+		if expired {
+			select {
+			case <-req.lookup.done:
+			default:
+				continue
+			}
+		} else {
+			select {
+			case <-req.lookup.done:
+			case <-timer.C:
+				expired = true
+				continue
+			}
+		}
+
+		if req.lookup.name != "" {
+			req.metric.AddTag(req.dest, req.lookup.name)
+		}
+	}
+
+	return in
+}
+
+func New() *ReverseDNS {
+	return &ReverseDNS{
+		CacheTTL:           internal.Duration{Duration: 24 * time.Hour},
+		CacheSize:          10000,
+		LookupTimeout:      internal.Duration{Duration: 3 * time.Second},
+		MaxParallelLookups: 10,
+		MaxPendingLookups:  1000,
+		Deadline:           internal.Duration{Duration: 100 * time.Millisecond},
+	}
+}
+
+func init() {
+	processors.Add("reverse_dns", func() telegraf.Processor {
+		return New()
+	})
+}
@@ -0,0 +1,358 @@
+package reverse_dns
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type MockResolver struct {
+	sync.Mutex
+	names   map[string]string
+	delay   time.Duration
+	lookups int
+}
+
+func (r *MockResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	r.Lock()
+	r.lookups++
+	r.Unlock()
+
+	select {
+	case <-time.After(r.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	name, ok := r.names[addr]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return []string{name + "."}, nil
+}
+
+func (r *MockResolver) Lookups() int {
+	r.Lock()
+	defer r.Unlock()
+	return r.lookups
+}
+
+func newProcessor(t *testing.T, resolver Resolver) *ReverseDNS {
+	plugin := New()
+	plugin.Resolver = resolver
+	plugin.Lookups = []Lookup{{Tag: "source", Dest: "source_name"}}
+	require.NoError(t, plugin.Init())
+	return plugin
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		lookups []Lookup
+		netfile string
+		pending int
+	}{
+		{
+			name: "no lookups",
+		},
+		{
+			name:    "no tag",
+			lookups: []Lookup{{Dest: "name"}},
+		},
+		{
+			name:    "no destination",
+			lookups: []Lookup{{Tag: "source"}},
+		},
+		{
+			name:    "network without file",
+			lookups: []Lookup{{Tag: "source", NetworkDest: "net"}},
+		},
+		{
+			name:    "missing networks file",
+			lookups: []Lookup{{Tag: "source", NetworkDest: "net"}},
+			netfile: "/nonexistent/networks.txt",
+		},
+		{
+			name:    "no pending lookups",
+			lookups: []Lookup{{Tag: "source", Dest: "name"}},
+			pending: -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := New()
+			plugin.Lookups = tt.lookups
+			plugin.NetworksFile = tt.netfile
+			if tt.pending != 0 {
+				plugin.MaxPendingLookups = tt.pending
+			}
+			require.Error(t, plugin.Init())
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	resolver := &MockResolver{
+		names: map[string]string{"192.168.1.1": "router.example.org"},
+	}
+	plugin := newProcessor(t, resolver)
+
+	m := testutil.MustMetric("conntrack",
+		map[string]string{"source": "192.168.1.1"},
+		map[string]interface{}{"value": 42},
+		time.Unix(0, 0))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("conntrack",
+			map[string]string{
+				"source":      "192.168.1.1",
+				"source_name": "router.example.org",
+			},
+			map[string]interface{}{"value": 42},
+			time.Unix(0, 0)),
+	}
+
+	actual := plugin.Apply(m)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestIgnoresNonIPTags(t *testing.T) {
+	resolver := &MockResolver{}
+	plugin := newProcessor(t, resolver)
+
+	m := testutil.MustMetric("conntrack",
+		map[string]string{"source": "localhost"},
+		map[string]interface{}{"value": 42},
+		time.Unix(0, 0))
+
+	actual := plugin.Apply(m)
+	require.Equal(t, map[string]string{"source": "localhost"}, actual[0].Tags())
+	require.Equal(t, 0, resolver.Lookups())
+}
+
+func TestFailedLookupIsCached(t *testing.T) {
+	resolver := &MockResolver{}
+	plugin := newProcessor(t, resolver)
+
+	for i := 0; i < 3; i++ {
+		m := testutil.MustMetric("conntrack",
+			map[string]string{"source": "10.0.0.1"},
+			map[string]interface{}{"value": 42},
+			time.Unix(0, 0))
+		actual := plugin.Apply(m)
+		require.Equal(t, map[string]string{"source": "10.0.0.1"}, actual[0].Tags())
+	}
+	require.Equal(t, 1, resolver.Lookups())
+}
+
+func TestDeadline(t *testing.T) {
+	resolver := &MockResolver{
+		names: map[string]string{"192.168.1.1": "router.example.org"},
+		delay: 200 * time.Millisecond,
+	}
+	plugin := newProcessor(t, resolver)
+	plugin.Deadline = internal.Duration{Duration: 10 * time.Millisecond}
+
+	newMetric := func() telegraf.Metric {
+		return testutil.MustMetric("conntrack",
+			map[string]string{"source": "192.168.1.1"},
+			map[string]interface{}{"value": 42},
+			time.Unix(0, 0))
+	}
+
+	start := time.Now()
+	actual := plugin.Apply(newMetric())
+	require.True(t, time.Since(start) < 200*time.Millisecond)
+	require.False(t, actual[0].HasTag("source_name"))
+
+	// The lookup completes in the background and is used by later metrics.
+	for plugin.cache.Len() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	actual = plugin.Apply(newMetric())
+	tag, _ := actual[0].GetTag("source_name")
+	require.Equal(t, "router.example.org", tag)
+	require.Equal(t, 1, resolver.Lookups())
+}
+
+func TestConcurrentLookupsShared(t *testing.T) {
+	resolver := &MockResolver{
+		names: map[string]string{"192.168.1.1": "router.example.org"},
+		delay: 10 * time.Millisecond,
+	}
+	plugin := newProcessor(t, resolver)
+
+	var metrics []telegraf.Metric
+	for i := 0; i < 10; i++ {
+		metrics = append(metrics, testutil.MustMetric("conntrack",
+			map[string]string{"source": "192.168.1.1"},
+			map[string]interface{}{"value": i},
+			time.Unix(0, 0)))
+	}
+
+	actual := plugin.Apply(metrics...)
+	for _, m := range actual {
+		tag, _ := m.GetTag("source_name")
+		require.Equal(t, "router.example.org", tag)
+	}
+	require.Equal(t, 1, resolver.Lookups())
+}
+
+func TestCacheEviction(t *testing.T) {
+	resolver := &MockResolver{
+		names: map[string]string{
+			"10.0.0.1": "a",
+			"10.0.0.2": "b",
+			"10.0.0.3": "c",
+		},
+	}
+	cache := newRDNSCache(resolver, time.Hour, time.Second, 2, 1, 10)
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		<-cache.Lookup(ip).done
+	}
+	require.Equal(t, 2, cache.Len())
+
+	_, ok := cache.Get("10.0.0.1")
+	require.False(t, ok)
+	name, ok := cache.Get("10.0.0.3")
+	require.True(t, ok)
+	require.Equal(t, "c", name)
+}
+
+func TestLookupQueueFull(t *testing.T) {
+	resolver := &MockResolver{
+		names: map[string]string{
+			"10.0.0.1": "a",
+			"10.0.0.2": "b",
+			"10.0.0.3": "c",
+		},
+		delay: 50 * time.Millisecond,
+	}
+	cache := newRDNSCache(resolver, time.Hour, time.Second, 10, 1, 1)
+
+	// The first lookup is taken from the queue by the only worker, the
+	// second waits in the queue and the third is skipped.
+	first := cache.Lookup("10.0.0.1")
+	for resolver.Lookups() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	second := cache.Lookup("10.0.0.2")
+	third := cache.Lookup("10.0.0.3")
+
+	<-third.done
+	require.Equal(t, "", third.name)
+	cache.mu.Lock()
+	require.Equal(t, 1, cache.workers)
+	require.Len(t, cache.pending, 2)
+	cache.mu.Unlock()
+
+	<-first.done
+	<-second.done
+	require.Equal(t, "a", first.name)
+	require.Equal(t, "b", second.name)
+	_, ok := cache.Get("10.0.0.3")
+	require.False(t, ok)
+
+	// The skipped address is looked up once the queue has room.
+	third = cache.Lookup("10.0.0.3")
+	<-third.done
+	require.Equal(t, "c", third.name)
+
+	// Workers exit once the queue is drained.
+	for {
+		cache.mu.Lock()
+		workers := cache.workers
+		cache.mu.Unlock()
+		if workers == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCacheExpiration(t *testing.T) {
+	resolver := &MockResolver{
+		names: map[string]string{"10.0.0.1": "a"},
+	}
+	cache := newRDNSCache(resolver, time.Minute, time.Second, 10, 1, 10)
+
+	now := time.Unix(0, 0)
+	cache.now = func() time.Time { return now }
+
+	<-cache.Lookup("10.0.0.1").done
+	_, ok := cache.Get("10.0.0.1")
+	require.True(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.Get("10.0.0.1")
+	require.False(t, ok)
+}
+
+func TestParseNetworks(t *testing.T) {
+	table, err := parseNetworks(strings.NewReader(`
+# comment
+10.0.0.0/8      internal
+10.1.0.0/16     datacenter 1
+2001:db8::/32   documentation
+`))
+	require.NoError(t, err)
+	require.Len(t, table, 3)
+
+	_, err = parseNetworks(strings.NewReader("10.0.0.0/8\n"))
+	require.Error(t, err)
+
+	_, err = parseNetworks(strings.NewReader("10.0.0.0/33 invalid\n"))
+	require.Error(t, err)
+}
+
+func TestNetworkLabel(t *testing.T) {
+	f, err := ioutil.TempFile("", "networks")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString("10.0.0.0/8 internal\n10.1.0.0/16 dc1\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	plugin := New()
+	plugin.NetworksFile = f.Name()
+	plugin.Lookups = []Lookup{
+		{Tag: "source", NetworkDest: "source_network"},
+		{Tag: "dest", NetworkDest: "dest_network"},
+	}
+	require.NoError(t, plugin.Init())
+
+	m := testutil.MustMetric("conntrack",
+		map[string]string{
+			"source": "10.1.2.3",
+			"dest":   "10.2.2.3",
+		},
+		map[string]interface{}{"value": 42},
+		time.Unix(0, 0))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("conntrack",
+			map[string]string{
+				"source":         "10.1.2.3",
+				"source_network": "dc1",
+				"dest":           "10.2.2.3",
+				"dest_network":   "internal",
+			},
+			map[string]interface{}{"value": 42},
+			time.Unix(0, 0)),
+	}
+
+	actual := plugin.Apply(m)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
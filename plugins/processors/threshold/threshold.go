@@ -0,0 +1,313 @@
+package threshold
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Tag added to metrics with a checked field, containing the most severe
+  ## status of its fields: "ok", "warn" or "crit".
+  # status_tag = "status"
+
+  ## Name of the event metric emitted when the status of a field changes.
+  # event_name = "threshold_event"
+
+  ## Forget the status of series that have not been seen for this long.
+  # series_timeout = "1h"
+
+  [[processors.threshold.field]]
+    ## Field to check, only metrics with this measurement name are checked if
+    ## measurement is set.
+    field = "usage_idle"
+    # measurement = "cpu"
+
+    ## The status is raised when the value is above or equal to the threshold
+    ## if direction is "above", or below or equal to the threshold if direction
+    ## is "below".  Either threshold may be omitted.
+    direction = "below"
+    warn = 20.0
+    crit = 5.0
+
+    ## Amount the value must move back past a threshold before the status is
+    ## lowered again, this avoids flapping when the value is near a threshold.
+    # hysteresis = 0.0
+
+    ## Time the new status must be held before the status changes.
+    # min_duration = "0s"
+`
+
+const (
+	directionAbove = "above"
+	directionBelow = "below"
+)
+
+// Status is the alert level of a field.
+type Status int
+
+const (
+	StatusOK Status = iota
+	StatusWarn
+	StatusCrit
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusWarn:
+		return "warn"
+	case StatusCrit:
+		return "crit"
+	}
+	return "ok"
+}
+
+type Field struct {
+	Field       string            `toml:"field"`
+	Measurement string            `toml:"measurement"`
+	Direction   string            `toml:"direction"`
+	Warn        *float64          `toml:"warn"`
+	Crit        *float64          `toml:"crit"`
+	Hysteresis  float64           `toml:"hysteresis"`
+	MinDuration internal.Duration `toml:"min_duration"`
+}
+
+// seriesState is the status of a field of a series.
+type seriesState struct {
+	status       Status
+	pending      Status
+	pendingSince time.Time
+	lastSeen     time.Time
+}
+
+type Threshold struct {
+	StatusTag     string            `toml:"status_tag"`
+	EventName     string            `toml:"event_name"`
+	SeriesTimeout internal.Duration `toml:"series_timeout"`
+	Fields        []*Field          `toml:"field"`
+
+	states      map[uint64]map[string]*seriesState
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+func (t *Threshold) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *Threshold) Description() string {
+	return "Set a status tag from field thresholds and emit events on status changes."
+}
+
+func (t *Threshold) Init() error {
+	if len(t.Fields) == 0 {
+		return fmt.Errorf("no fields configured")
+	}
+
+	for _, f := range t.Fields {
+		if f.Field == "" {
+			return fmt.Errorf("threshold is missing field")
+		}
+		if f.Warn == nil && f.Crit == nil {
+			return fmt.Errorf("field %q: at least one of warn or crit is required", f.Field)
+		}
+		if f.Hysteresis < 0 {
+			return fmt.Errorf("field %q: hysteresis must not be negative", f.Field)
+		}
+
+		switch f.Direction {
+		case directionAbove:
+			if f.Warn != nil && f.Crit != nil && *f.Crit < *f.Warn {
+				return fmt.Errorf("field %q: crit must not be less than warn", f.Field)
+			}
+		case directionBelow:
+			if f.Warn != nil && f.Crit != nil && *f.Crit > *f.Warn {
+				return fmt.Errorf("field %q: crit must not be greater than warn", f.Field)
+			}
+		default:
+			return fmt.Errorf("field %q: direction must be %q or %q", f.Field, directionAbove, directionBelow)
+		}
+	}
+	return nil
+}
+
+func (t *Threshold) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	now := t.now()
+	var events []telegraf.Metric
+	for _, m := range in {
+		worst := Status(-1)
+		for _, f := range t.Fields {
+			if f.Measurement != "" && f.Measurement != m.Name() {
+				continue
+			}
+
+			v, ok := m.GetField(f.Field)
+			if !ok {
+				continue
+			}
+			value, ok := toFloat(v)
+			if !ok {
+				continue
+			}
+
+			state := t.state(m, f.Field)
+			state.lastSeen = now
+
+			previous := state.status
+			if f.update(state, value, m.Time()) {
+				events = append(events, t.event(m, f.Field, value, previous, state.status))
+			}
+			if state.status > worst {
+				worst = state.status
+			}
+		}
+
+		if worst >= StatusOK {
+			m.AddTag(t.StatusTag, worst.String())
+		}
+	}
+
+	if t.SeriesTimeout.Duration > 0 && now.Sub(t.lastCleanup) > t.SeriesTimeout.Duration {
+		t.expire(now)
+	}
+
+	return append(in, events...)
+}
+
+// state returns the state of the field of a series, creating it if needed.
+func (t *Threshold) state(m telegraf.Metric, field string) *seriesState {
+	id := m.HashID()
+	fields, ok := t.states[id]
+	if !ok {
+		fields = make(map[string]*seriesState)
+		t.states[id] = fields
+	}
+
+	state, ok := fields[field]
+	if !ok {
+		state = &seriesState{}
+		fields[field] = state
+	}
+	return state
+}
+
+// expire removes the state of series not seen within the series timeout.
+func (t *Threshold) expire(now time.Time) {
+	for id, fields := range t.states {
+		for field, state := range fields {
+			if now.Sub(state.lastSeen) > t.SeriesTimeout.Duration {
+				delete(fields, field)
+			}
+		}
+		if len(fields) == 0 {
+			delete(t.states, id)
+		}
+	}
+	t.lastCleanup = now
+}
+
+func (t *Threshold) event(
+	m telegraf.Metric,
+	field string,
+	value float64,
+	previous Status,
+	current Status,
+) telegraf.Metric {
+	tags := m.Tags()
+	tags["measurement"] = m.Name()
+	tags["field"] = field
+	tags[t.StatusTag] = current.String()
+
+	fields := map[string]interface{}{
+		"value":           value,
+		"previous_status": previous.String(),
+	}
+
+	event, _ := metric.New(t.EventName, tags, fields, m.Time())
+	return event
+}
+
+// update applies a value observed at tm to the state, and reports if the
+// status changed.
+func (f *Field) update(state *seriesState, value float64, tm time.Time) bool {
+	target := f.level(value, 0)
+	if target < state.status {
+		// Lowering the status requires the value to move past the threshold
+		// by the hysteresis.
+		sticky := f.level(value, f.Hysteresis)
+		if sticky > target {
+			target = sticky
+			if target > state.status {
+				target = state.status
+			}
+		}
+	}
+
+	if target == state.status {
+		state.pending = state.status
+		return false
+	}
+
+	if target != state.pending {
+		state.pending = target
+		state.pendingSince = tm
+	}
+
+	if tm.Sub(state.pendingSince) < f.MinDuration.Duration {
+		return false
+	}
+
+	state.status = target
+	return true
+}
+
+// level returns the status for the value, with the thresholds relaxed by
+// margin.
+func (f *Field) level(value float64, margin float64) Status {
+	if f.Crit != nil && f.exceeds(value, *f.Crit, margin) {
+		return StatusCrit
+	}
+	if f.Warn != nil && f.exceeds(value, *f.Warn, margin) {
+		return StatusWarn
+	}
+	return StatusOK
+}
+
+func (f *Field) exceeds(value, threshold, margin float64) bool {
+	if f.Direction == directionBelow {
+		return value <= threshold+margin
+	}
+	return value >= threshold-margin
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func New() *Threshold {
+	return &Threshold{
+		StatusTag:     "status",
+		EventName:     "threshold_event",
+		SeriesTimeout: internal.Duration{Duration: time.Hour},
+		states:        make(map[uint64]map[string]*seriesState),
+		now:           time.Now,
+	}
+}
+
+func init() {
+	processors.Add("threshold", func() telegraf.Processor {
+		return New()
+	})
+}
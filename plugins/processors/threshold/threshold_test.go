@@ -0,0 +1,272 @@
+package threshold
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func newMetric(value float64, sec int64) telegraf.Metric {
+	return testutil.MustMetric("cpu",
+		map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_busy": value},
+		time.Unix(sec, 0),
+	)
+}
+
+func newThreshold(t *testing.T, fields ...*Field) *Threshold {
+	plugin := New()
+	plugin.Fields = fields
+	require.NoError(t, plugin.Init())
+	return plugin
+}
+
+// apply processes the metric and returns its status tag and the status
+// changes of the emitted events.
+func apply(plugin *Threshold, m telegraf.Metric) (string, []string) {
+	var status string
+	var events []string
+	for _, out := range plugin.Apply(m) {
+		if out.Name() == plugin.EventName {
+			s, _ := out.GetTag("status")
+			p, _ := out.GetField("previous_status")
+			events = append(events, p.(string)+"->"+s)
+			continue
+		}
+		status, _ = out.GetTag("status")
+	}
+	return status, events
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		field *Field
+	}{
+		{
+			name:  "no field",
+			field: &Field{Direction: "above", Warn: float(1)},
+		},
+		{
+			name:  "no thresholds",
+			field: &Field{Field: "x", Direction: "above"},
+		},
+		{
+			name:  "invalid direction",
+			field: &Field{Field: "x", Direction: "sideways", Warn: float(1)},
+		},
+		{
+			name:  "crit below warn",
+			field: &Field{Field: "x", Direction: "above", Warn: float(10), Crit: float(5)},
+		},
+		{
+			name:  "crit above warn",
+			field: &Field{Field: "x", Direction: "below", Warn: float(5), Crit: float(10)},
+		},
+		{
+			name:  "negative hysteresis",
+			field: &Field{Field: "x", Direction: "above", Warn: float(1), Hysteresis: -1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := New()
+			plugin.Fields = []*Field{tt.field}
+			require.Error(t, plugin.Init())
+		})
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	plugin := newThreshold(t, &Field{
+		Field:     "usage_busy",
+		Direction: "above",
+		Warn:      float(80),
+		Crit:      float(95),
+	})
+
+	tests := []struct {
+		value  float64
+		status string
+		events []string
+	}{
+		{10, "ok", nil},
+		{85, "warn", []string{"ok->warn"}},
+		{90, "warn", nil},
+		{99, "crit", []string{"warn->crit"}},
+		{97, "crit", nil},
+		{50, "ok", []string{"crit->ok"}},
+		{99, "crit", []string{"ok->crit"}},
+	}
+	for i, tt := range tests {
+		status, events := apply(plugin, newMetric(tt.value, int64(i)))
+		require.Equal(t, tt.status, status, "value %v", tt.value)
+		require.Equal(t, tt.events, events, "value %v", tt.value)
+	}
+}
+
+func TestDirectionBelow(t *testing.T) {
+	plugin := newThreshold(t, &Field{
+		Field:     "usage_busy",
+		Direction: "below",
+		Crit:      float(5),
+	})
+
+	status, events := apply(plugin, newMetric(50, 0))
+	require.Equal(t, "ok", status)
+	require.Empty(t, events)
+
+	status, events = apply(plugin, newMetric(5, 1))
+	require.Equal(t, "crit", status)
+	require.Equal(t, []string{"ok->crit"}, events)
+}
+
+func TestHysteresis(t *testing.T) {
+	plugin := newThreshold(t, &Field{
+		Field:      "usage_busy",
+		Direction:  "above",
+		Warn:       float(80),
+		Crit:       float(95),
+		Hysteresis: 5,
+	})
+
+	tests := []struct {
+		value  float64
+		status string
+		events []string
+	}{
+		{96, "crit", []string{"ok->crit"}},
+		{92, "crit", nil},
+		{89, "warn", []string{"crit->warn"}},
+		{76, "warn", nil},
+		{74, "ok", []string{"warn->ok"}},
+		{79, "ok", nil},
+	}
+	for i, tt := range tests {
+		status, events := apply(plugin, newMetric(tt.value, int64(i)))
+		require.Equal(t, tt.status, status, "value %v", tt.value)
+		require.Equal(t, tt.events, events, "value %v", tt.value)
+	}
+}
+
+func TestMinDuration(t *testing.T) {
+	plugin := newThreshold(t, &Field{
+		Field:       "usage_busy",
+		Direction:   "above",
+		Crit:        float(95),
+		MinDuration: internal.Duration{Duration: 30 * time.Second},
+	})
+
+	tests := []struct {
+		value  float64
+		sec    int64
+		status string
+		events []string
+	}{
+		{99, 0, "ok", nil},
+		{99, 20, "ok", nil},
+		{50, 25, "ok", nil},
+		{99, 30, "ok", nil},
+		{99, 60, "crit", []string{"ok->crit"}},
+		{50, 70, "crit", nil},
+		{50, 100, "ok", []string{"crit->ok"}},
+	}
+	for _, tt := range tests {
+		status, events := apply(plugin, newMetric(tt.value, tt.sec))
+		require.Equal(t, tt.status, status, "time %v", tt.sec)
+		require.Equal(t, tt.events, events, "time %v", tt.sec)
+	}
+}
+
+func TestSeriesAreIndependent(t *testing.T) {
+	plugin := newThreshold(t, &Field{
+		Field:     "usage_busy",
+		Direction: "above",
+		Crit:      float(95),
+	})
+
+	m1 := newMetric(99, 0)
+	m2 := newMetric(10, 0)
+	m2.AddTag("cpu", "cpu1")
+
+	out := plugin.Apply(m1, m2)
+	require.Len(t, out, 3)
+
+	status, _ := out[0].GetTag("status")
+	require.Equal(t, "crit", status)
+	status, _ = out[1].GetTag("status")
+	require.Equal(t, "ok", status)
+}
+
+func TestEventMetric(t *testing.T) {
+	plugin := newThreshold(t, &Field{
+		Field:     "usage_busy",
+		Direction: "above",
+		Warn:      float(80),
+	})
+
+	actual := plugin.Apply(newMetric(85, 0))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu0", "status": "warn"},
+			map[string]interface{}{"usage_busy": 85.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("threshold_event",
+			map[string]string{
+				"cpu":         "cpu0",
+				"measurement": "cpu",
+				"field":       "usage_busy",
+				"status":      "warn",
+			},
+			map[string]interface{}{
+				"value":           85.0,
+				"previous_status": "ok",
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestMeasurementFilter(t *testing.T) {
+	plugin := newThreshold(t, &Field{
+		Field:       "usage_busy",
+		Measurement: "mem",
+		Direction:   "above",
+		Warn:        float(80),
+	})
+
+	actual := plugin.Apply(newMetric(85, 0))
+	require.Len(t, actual, 1)
+	require.False(t, actual[0].HasTag("status"))
+}
+
+func TestSeriesTimeout(t *testing.T) {
+	plugin := newThreshold(t, &Field{
+		Field:     "usage_busy",
+		Direction: "above",
+		Warn:      float(80),
+	})
+
+	now := time.Unix(0, 0)
+	plugin.now = func() time.Time { return now }
+
+	plugin.Apply(newMetric(85, 0))
+	require.Len(t, plugin.states, 1)
+
+	now = now.Add(2 * time.Hour)
+	m := newMetric(85, 7200)
+	m.AddTag("cpu", "cpu1")
+	plugin.Apply(m)
+	require.Len(t, plugin.states, 1)
+}